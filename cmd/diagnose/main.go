@@ -2,20 +2,34 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/metadata"
 	"github.com/rusik69/trtg/pkg/parser"
 )
 
 func main() {
 	dbURL := flag.String("db", "", "PostgreSQL connection URL (or use DATABASE_URL env)")
 	sampleSize := flag.Int("samples", 20, "Number of uncategorized samples to show")
+	rulesFile := flag.String("rules", os.Getenv("PARSER_RULES_FILE"), "JSON file with parser rules (or use PARSER_RULES_FILE env)")
+	gaps := flag.Bool("gaps", false, "Report missing episodes across the library instead")
 	flag.Parse()
 
+	if *rulesFile != "" {
+		if err := parser.LoadRules(*rulesFile); err != nil {
+			log.Fatalf("Failed to load parser rules: %v", err)
+		}
+	}
+
 	// Get database URL from flag or environment
 	connURL := *dbURL
 	if connURL == "" {
@@ -31,6 +45,18 @@ func main() {
 	}
 	defer db.Close()
 
+	if *gaps {
+		reportGaps(db)
+		return
+	}
+
+	// Resolve show names with the configured resolvers, cached by torrent name
+	resolver, err := parser.NewShowNameResolver(parser.ResolverConfigFromEnv(), db)
+	if err != nil {
+		log.Fatalf("Failed to configure show name resolvers: %v", err)
+	}
+	parser.SetShowNameResolver(resolver)
+
 	videos, err := db.GetAllVideos()
 	if err != nil {
 		log.Fatalf("Failed to get videos: %v", err)
@@ -43,7 +69,7 @@ func main() {
 	var uncategorized []database.Video
 
 	for _, video := range videos {
-		if video.SeasonNumber == 0 {
+		if video.SeasonNumber == 0 && !video.IsMovie() {
 			uncategorized = append(uncategorized, video)
 		}
 		seasonStats[video.SeasonNumber]++
@@ -94,3 +120,84 @@ func main() {
 	fmt.Printf("\nTo fix all videos, run: reparse -db=\"%s\"\n", connURL)
 	fmt.Printf("To preview changes, run: reparse -db=\"%s\" -dry-run\n", connURL)
 }
+
+// reportGaps lists the missing episodes of every season in the library, against the metadata
+// provider's episode lists when one is configured (METADATA_PROVIDER)
+func reportGaps(db *database.DB) {
+	client, err := metadata.New(metadata.ConfigFromEnv(), db)
+	if err != nil {
+		log.Fatalf("Failed to configure metadata provider: %v", err)
+	}
+
+	shows, err := db.GetAllEpisodeNumbers()
+	if err != nil {
+		log.Fatalf("Failed to get episode numbers: %v", err)
+	}
+	names := make([]string, 0, len(shows))
+	for name := range shows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	today := time.Now().Format("2006-01-02")
+	showsWithGaps, totalMissing := 0, 0
+	fmt.Println("=== Missing Episodes ===")
+	for _, name := range names {
+		var official map[int]int
+		if client != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			details, err := client.Lookup(ctx, name, 0)
+			cancel()
+			if err == nil {
+				official = details.LastAired(today)
+			} else if !errors.Is(err, metadata.ErrNotFound) {
+				log.Printf("Warning: Failed to get metadata of %s: %v", name, err)
+			}
+		}
+
+		var lines []string
+		for _, season := range database.FindGaps(shows[name], official) {
+			if len(season.Missing) == 0 {
+				continue
+			}
+			source := "highest present"
+			if season.Official {
+				source = "aired"
+			}
+			lines = append(lines, fmt.Sprintf("    Season %d: %d of %d missing (%s): %s",
+				season.SeasonNumber, len(season.Missing), season.Expected, source, episodeRanges(season.Missing)))
+			totalMissing += len(season.Missing)
+		}
+		if len(lines) > 0 {
+			showsWithGaps++
+			fmt.Printf("\n%s\n%s\n", name, strings.Join(lines, "\n"))
+		}
+	}
+
+	fmt.Printf("\n=== Summary ===\n")
+	fmt.Printf("Shows checked: %d\n", len(names))
+	fmt.Printf("Shows with gaps: %d\n", showsWithGaps)
+	fmt.Printf("Missing episodes: %d\n", totalMissing)
+	if client == nil {
+		fmt.Println("\nNo metadata provider configured: seasons are checked up to their highest episode present.")
+		fmt.Println("Set METADATA_PROVIDER to check them against the episodes that aired.")
+	}
+}
+
+// episodeRanges formats sorted episode numbers compactly: "1-3, 7, 9-10"
+func episodeRanges(episodes []int) string {
+	var parts []string
+	for i := 0; i < len(episodes); {
+		j := i
+		for j+1 < len(episodes) && episodes[j+1] == episodes[j]+1 {
+			j++
+		}
+		if j > i {
+			parts = append(parts, fmt.Sprintf("%d-%d", episodes[i], episodes[j]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d", episodes[i]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ", ")
+}
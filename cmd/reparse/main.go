@@ -6,6 +6,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/rusik69/trtg/pkg/database"
 	"github.com/rusik69/trtg/pkg/parser"
@@ -14,8 +17,16 @@ import (
 func main() {
 	dbURL := flag.String("db", "", "PostgreSQL connection URL (or use DATABASE_URL env)")
 	dryRun := flag.Bool("dry-run", false, "Show what would be updated without making changes")
+	refreshNames := flag.Bool("refresh-names", false, "Forget cached show names and resolve them again")
+	rulesFile := flag.String("rules", os.Getenv("PARSER_RULES_FILE"), "JSON file with parser rules (or use PARSER_RULES_FILE env)")
 	flag.Parse()
 
+	if *rulesFile != "" {
+		if err := parser.LoadRules(*rulesFile); err != nil {
+			log.Fatalf("Failed to load parser rules: %v", err)
+		}
+	}
+
 	// Get database URL from flag or environment
 	connURL := *dbURL
 	if connURL == "" {
@@ -32,12 +43,33 @@ func main() {
 	}
 	defer db.Close()
 
+	if *refreshNames && !*dryRun {
+		cleared, err := db.ClearNameResolutions()
+		if err != nil {
+			log.Fatalf("Failed to clear cached show names: %v", err)
+		}
+		fmt.Printf("Cleared %d cached show names\n", cleared)
+	}
+
+	// Resolve show names with the configured resolvers, cached by torrent name
+	resolver, err := parser.NewShowNameResolver(parser.ResolverConfigFromEnv(), db)
+	if err != nil {
+		log.Fatalf("Failed to configure show name resolvers: %v", err)
+	}
+	parser.SetShowNameResolver(resolver)
+
 	// Get all videos
 	videos, err := db.GetAllVideos()
 	if err != nil {
 		log.Fatalf("Failed to get videos: %v", err)
 	}
 
+	// Corrections made in the review queue win over the parser
+	overrides, err := db.GetVideoOverrides()
+	if err != nil {
+		log.Fatalf("Failed to get video overrides: %v", err)
+	}
+
 	fmt.Printf("Found %d videos in database\n", len(videos))
 	if *dryRun {
 		fmt.Println("DRY RUN MODE - no changes will be made")
@@ -46,26 +78,50 @@ func main() {
 
 	updatedCount := 0
 	unchangedCount := 0
+	overriddenCount := 0
+	reviewCount := 0
+	reclassifiedCount := 0
 	seasonStats := make(map[int]int)
+	mediaStats := make(map[string]int)
 
 	for i, video := range videos {
 		// Re-parse the video info
 		info := parser.ParseVideoInfo(video.Title, video.FilePath)
+		if override, ok := overrides[video.ID]; ok {
+			override.Apply(&info)
+			overriddenCount++
+		} else if info.Confidence < parser.ReviewThreshold {
+			reviewCount++
+		}
+
+		var airDate *time.Time
+		if !info.AirDate.IsZero() {
+			airDate = &info.AirDate
+		}
 
 		// Check if anything changed
 		changed := info.ShowName != video.ShowName ||
 			info.SeasonNumber != video.SeasonNumber ||
-			info.EpisodeNumber != video.EpisodeNumber
+			info.EpisodeNumber != video.EpisodeNumber ||
+			info.EpisodeEnd != video.EpisodeEnd ||
+			formatAirDate(airDate) != formatAirDate(video.AirDate) ||
+			info.AbsoluteNumber != video.AbsoluteNumber
+		releaseChanged := !info.Release.Equal(video.Release)
+		mediaChanged := info.MediaType != video.MediaType || info.Movie != video.Movie
+		parseChanged := info.Confidence != video.ParseConfidence || info.MatchedRule != video.ParseRule
 
 		if changed {
 			fmt.Printf("[%d/%d] UPDATE: %s\n", i+1, len(videos), video.FilePath)
-			fmt.Printf("  Old: Show='%s', Season=%d, Episode=%d\n",
-				video.ShowName, video.SeasonNumber, video.EpisodeNumber)
-			fmt.Printf("  New: Show='%s', Season=%d, Episode=%d\n",
-				info.ShowName, info.SeasonNumber, info.EpisodeNumber)
+			fmt.Printf("  Old: Show='%s', Season=%d, Episode=%d, EpisodeEnd=%d, AirDate=%s, Absolute=%d\n",
+				video.ShowName, video.SeasonNumber, video.EpisodeNumber, video.EpisodeEnd, formatAirDate(video.AirDate), video.AbsoluteNumber)
+			fmt.Printf("  New: Show='%s', Season=%d, Episode=%d, EpisodeEnd=%d, AirDate=%s, Absolute=%d\n",
+				info.ShowName, info.SeasonNumber, info.EpisodeNumber, info.EpisodeEnd, formatAirDate(airDate), info.AbsoluteNumber)
 
 			if !*dryRun {
 				err := db.UpdateVideoInfo(video.ID, info.ShowName, info.SeasonNumber, info.EpisodeNumber)
+				if err == nil {
+					err = db.UpdateVideoNumbering(video.ID, info.EpisodeEnd, airDate, info.AbsoluteNumber)
+				}
 				if err != nil {
 					log.Printf("  ERROR: Failed to update: %v", err)
 					continue
@@ -75,11 +131,50 @@ func main() {
 				fmt.Println("  (would update)")
 			}
 			updatedCount++
-		} else {
+		} else if !releaseChanged && !mediaChanged {
 			unchangedCount++
 		}
 
-		seasonStats[info.SeasonNumber]++
+		if releaseChanged {
+			fmt.Printf("[%d/%d] RELEASE: %s: %s\n", i+1, len(videos), video.FilePath, strings.Join(info.Release.Badges(), " "))
+			if !*dryRun {
+				if err := db.UpdateVideoRelease(video.ID, info.Release); err != nil {
+					log.Printf("  ERROR: Failed to update release: %v", err)
+					continue
+				}
+			}
+			if !changed {
+				updatedCount++
+			}
+		}
+
+		if mediaChanged {
+			fmt.Printf("[%d/%d] MEDIA: %s: %s -> %s\n", i+1, len(videos), video.FilePath, describeMedia(video.MediaType, video.Movie), describeMedia(info.MediaType, info.Movie))
+			if !*dryRun {
+				if err := db.UpdateVideoMedia(video.ID, info.MediaType, info.Movie); err != nil {
+					log.Printf("  ERROR: Failed to update media type: %v", err)
+					continue
+				}
+			}
+			if video.MediaType != info.MediaType {
+				reclassifiedCount++
+			}
+			if !changed && !releaseChanged {
+				updatedCount++
+			}
+		}
+
+		// The parse result is bookkeeping for the review queue, not reported as a change
+		if parseChanged && !*dryRun {
+			if err := db.UpdateVideoParse(video.ID, info.Confidence, info.MatchedRule); err != nil {
+				log.Printf("  ERROR: Failed to update parse result: %v", err)
+			}
+		}
+
+		mediaStats[info.MediaType]++
+		if info.MediaType != parser.MediaMovie {
+			seasonStats[info.SeasonNumber]++
+		}
 	}
 
 	fmt.Println()
@@ -87,18 +182,24 @@ func main() {
 	fmt.Printf("Total videos: %d\n", len(videos))
 	fmt.Printf("Updated: %d\n", updatedCount)
 	fmt.Printf("Unchanged: %d\n", unchangedCount)
+	fmt.Printf("Reclassified: %d (episodes %d, movies %d, extras %d)\n", reclassifiedCount,
+		mediaStats[parser.MediaEpisode], mediaStats[parser.MediaMovie], mediaStats[parser.MediaExtra])
+	fmt.Printf("Corrected by hand: %d\n", overriddenCount)
+	fmt.Printf("Needing review: %d (confidence below %.2f, see /review)\n", reviewCount, parser.ReviewThreshold)
 	fmt.Println()
 
 	fmt.Println("=== Season Distribution (after re-parsing) ===")
-	for season := 0; season <= 40; season++ {
-		count := seasonStats[season]
-		if count > 0 {
-			label := fmt.Sprintf("Season %d", season)
-			if season == 0 {
-				label = "Uncategorized (Season 0)"
-			}
-			fmt.Printf("%s: %d episodes\n", label, count)
+	seasons := make([]int, 0, len(seasonStats))
+	for season := range seasonStats {
+		seasons = append(seasons, season)
+	}
+	sort.Ints(seasons)
+	for _, season := range seasons {
+		label := fmt.Sprintf("Season %d", season)
+		if season == 0 {
+			label = "Uncategorized (Season 0)"
 		}
+		fmt.Printf("%s: %d episodes\n", label, seasonStats[season])
 	}
 
 	if *dryRun {
@@ -106,3 +207,23 @@ func main() {
 		fmt.Println("NOTE: This was a dry run. Run without -dry-run to apply changes.")
 	}
 }
+
+// describeMedia formats a media type for output, with the title, year and edition of movies
+func describeMedia(mediaType string, movie parser.Movie) string {
+	if mediaType != parser.MediaMovie {
+		return mediaType
+	}
+	description := fmt.Sprintf("movie '%s' (%d)", movie.Title, movie.Year)
+	if movie.Edition != "" {
+		description += " " + movie.Edition
+	}
+	return description
+}
+
+// formatAirDate formats an air date for comparison and output, "-" if there is none
+func formatAirDate(airDate *time.Time) string {
+	if airDate == nil {
+		return "-"
+	}
+	return airDate.Format("2006-01-02")
+}
@@ -0,0 +1,136 @@
+// Package main runs the interactive Telegram bot for managing the archive
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/bot"
+	"github.com/rusik69/trtg/pkg/config"
+	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/ingest"
+	"github.com/rusik69/trtg/pkg/parser"
+	"github.com/rusik69/trtg/pkg/telegram"
+	"github.com/rusik69/trtg/pkg/torrent"
+)
+
+// parserRulesInterval is how often the parser rules file is checked for changes
+const parserRulesInterval = 10 * time.Second
+
+func main() {
+	dbURL := flag.String("db", "", "PostgreSQL connection URL (or use DATABASE_URL env)")
+	allowChats := flag.String("allow-chats", os.Getenv("BOT_ALLOWED_CHATS"), "Comma-separated IDs of additional group chats where the bot accepts commands and torrents")
+	torrentPreview := flag.Bool("torrent-preview", true, "Preview magnet links and .torrent files sent to the bot")
+	torrentDir := flag.String("torrent-dir", filepath.Join(os.TempDir(), "trtg-bot-torrents"), "Directory for .torrent files and metadata fetched for previews")
+	torrentPort := flag.Int("torrent-port", 42071, "Listen port for the preview torrent client (must differ from the trtg daemon and trtg-web)")
+	worker := flag.Bool("worker", true, "Download and upload the torrents queued with /add and previews")
+	downloadDir := flag.String("download-dir", "", "Directory for queued torrents while they download (default: jobs under DOWNLOAD_DIR)")
+	downloadPort := flag.Int("download-port", 42072, "Listen port for the torrent client of queued downloads")
+	flag.Parse()
+
+	cfg, err := config.NewConfig(false)
+	if err != nil {
+		log.Fatalf("Failed to load configuration: %v", err)
+	}
+	if *dbURL != "" {
+		cfg.DatabaseURL = *dbURL
+	}
+
+	db, err := database.New(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer db.Close()
+
+	b, err := bot.New(db, cfg.TelegramToken, cfg.TelegramChatID, cfg.TelegramAPIURL)
+	if err != nil {
+		log.Fatalf("Failed to initialize bot: %v", err)
+	}
+
+	for _, field := range strings.Split(*allowChats, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		chatID, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid chat ID %q in -allow-chats: %v", field, err)
+		}
+		b.AllowChat(chatID)
+	}
+
+	// Torrent client used only to fetch metadata for the file previews
+	if *torrentPreview {
+		torrents, err := torrent.NewDownloaderWithPort(*torrentDir, *torrentPort)
+		if err != nil {
+			log.Printf("Warning: Torrent previews disabled: %v", err)
+		} else {
+			defer torrents.Close()
+			if err := b.SetTorrentDownloader(torrents, *torrentDir); err != nil {
+				log.Fatalf("Failed to enable torrent previews: %v", err)
+			}
+			log.Printf("Torrent previews enabled (dir: %s, port: %d)", *torrentDir, *torrentPort)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Queued torrents are downloaded with a separate client, since previews drop their torrents
+	if *worker {
+		if *downloadDir == "" {
+			*downloadDir = filepath.Join(cfg.DownloadDir, "jobs")
+		}
+		if err := startWorker(ctx, db, cfg, *downloadDir, *downloadPort); err != nil {
+			log.Fatalf("Failed to start the download worker: %v", err)
+		}
+		log.Printf("Download worker started (dir: %s, port: %d)", *downloadDir, *downloadPort)
+	}
+
+	if err := b.Run(ctx); err != nil {
+		log.Fatalf("Bot stopped: %v", err)
+	}
+	log.Println("Bot stopped")
+}
+
+// startWorker runs an ingest worker for the torrent_jobs queue in the background until ctx is done
+func startWorker(ctx context.Context, db *database.DB, cfg *config.Config, downloadDir string, port int) error {
+	// Files are parsed like the daemon parses them: configured rules and show name resolvers
+	resolver, err := parser.NewShowNameResolver(parser.ResolverConfigFromEnv(), db)
+	if err != nil {
+		return err
+	}
+	parser.SetShowNameResolver(resolver)
+	if cfg.ParserRules != "" {
+		go parser.WatchRules(ctx, cfg.ParserRules, parserRulesInterval)
+	}
+
+	downloader, err := torrent.NewDownloaderWithPort(downloadDir, port)
+	if err != nil {
+		return err
+	}
+	uploader, err := telegram.NewUploader(cfg.TelegramToken, cfg.TelegramChatID, cfg.TelegramAPIURL)
+	if err != nil {
+		downloader.Close()
+		return err
+	}
+	// One live status message per job in TELEGRAM_NOTIFY_CHAT_ID, if set
+	notifier := telegram.NewNotifier(uploader, cfg.NotifyChatID)
+	w, err := ingest.NewWorker(db, downloader, uploader, notifier, filepath.Join(downloadDir, ".torrent-files"))
+	if err != nil {
+		downloader.Close()
+		return err
+	}
+	go func() {
+		defer downloader.Close()
+		w.Run(ctx)
+	}()
+	return nil
+}
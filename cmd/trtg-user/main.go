@@ -0,0 +1,224 @@
+// Package main provides a tool to manage web interface users
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+const usage = `Usage: trtg-user [-db URL] <command> [flags] <username>
+
+Commands:
+  add <username> [-role admin|viewer] [-password PASSWORD]   Create a user
+  passwd <username> [-password PASSWORD]                     Change a password and log out the user's sessions
+  role <username> admin|viewer                               Change a user's role
+  delete <username>                                          Delete a user
+  list                                                       List users
+  telegram <username> <telegram-id>                          Let a user log in with their Telegram account
+  token add <username> -name NAME [-scope read|admin]        Create an API token and print it
+  token list <username>                                      List a user's API tokens
+  token revoke <username> <id>                               Revoke an API token
+
+The password is read from standard input when -password is not given.
+`
+
+func main() {
+	dbURL := flag.String("db", "", "PostgreSQL connection URL (or use DATABASE_URL env)")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Get database URL from flag or environment
+	connURL := *dbURL
+	if connURL == "" {
+		connURL = os.Getenv("DATABASE_URL")
+	}
+	if connURL == "" {
+		log.Fatal("DATABASE_URL environment variable or -db flag is required")
+	}
+
+	db, err := database.New(connURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	command, args := args[0], args[1:]
+	switch command {
+	case "add":
+		fs := flag.NewFlagSet("add", flag.ExitOnError)
+		role := fs.String("role", database.RoleViewer, "User role (admin or viewer)")
+		password := fs.String("password", "", "Password (prompted if empty)")
+		username := parseUsername(fs, args)
+
+		user, err := db.CreateUser(username, readPassword(*password), *role)
+		if err != nil {
+			log.Fatalf("Failed to add user: %v", err)
+		}
+		fmt.Printf("Created %s user %s\n", user.Role, user.Username)
+
+	case "passwd":
+		fs := flag.NewFlagSet("passwd", flag.ExitOnError)
+		password := fs.String("password", "", "New password (prompted if empty)")
+		username := parseUsername(fs, args)
+
+		if err := db.SetUserPassword(username, readPassword(*password)); err != nil {
+			log.Fatalf("Failed to change password: %v", err)
+		}
+		fmt.Printf("Changed password of %s\n", username)
+
+	case "role":
+		if len(args) != 2 {
+			log.Fatal("Usage: trtg-user role <username> admin|viewer")
+		}
+		if err := db.SetUserRole(args[0], args[1]); err != nil {
+			log.Fatalf("Failed to change role: %v", err)
+		}
+		fmt.Printf("%s is now %s\n", args[0], args[1])
+
+	case "delete":
+		username := parseUsername(flag.NewFlagSet("delete", flag.ExitOnError), args)
+		if err := db.DeleteUser(username); err != nil {
+			log.Fatalf("Failed to delete user: %v", err)
+		}
+		fmt.Printf("Deleted user %s\n", username)
+
+	case "list":
+		users, err := db.ListUsers()
+		if err != nil {
+			log.Fatalf("Failed to list users: %v", err)
+		}
+		for _, user := range users {
+			fmt.Printf("%-24s %-8s created %s\n", user.Username, user.Role, user.CreatedAt.Format("2006-01-02"))
+		}
+
+	case "telegram":
+		if len(args) != 2 {
+			log.Fatal("Usage: trtg-user telegram <username> <telegram-id>")
+		}
+		telegramID, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid Telegram ID: %s", args[1])
+		}
+		if err := db.LinkTelegramUser(args[0], telegramID); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%s can now log in as Telegram user %d\n", args[0], telegramID)
+
+	case "token":
+		runToken(db, args)
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// runToken handles the "token" subcommands
+func runToken(db *database.DB, args []string) {
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "add":
+		fs := flag.NewFlagSet("token add", flag.ExitOnError)
+		name := fs.String("name", "", "Token name")
+		scope := fs.String("scope", database.TokenScopeRead, "Token scope (read or admin)")
+		user, err := db.GetUserByUsername(parseUsername(fs, args[1:]))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		secret, token, err := db.CreateAPIToken(user, *name, *scope)
+		if err != nil {
+			log.Fatalf("Failed to create token: %v", err)
+		}
+		fmt.Fprintf(os.Stderr, "Created %s token %q (id %d) for %s; it will not be shown again:\n", token.Scope, token.Name, token.ID, user.Username)
+		fmt.Println(secret)
+
+	case "list":
+		user, err := db.GetUserByUsername(parseUsername(flag.NewFlagSet("token list", flag.ExitOnError), args[1:]))
+		if err != nil {
+			log.Fatal(err)
+		}
+		tokens, err := db.ListAPITokens(user.ID)
+		if err != nil {
+			log.Fatalf("Failed to list tokens: %v", err)
+		}
+		for _, token := range tokens {
+			lastUsed := "never"
+			if token.LastUsedAt != nil {
+				lastUsed = token.LastUsedAt.Format("2006-01-02 15:04")
+			}
+			fmt.Printf("%-6d %-24s %-6s created %s, last used %s\n", token.ID, token.Name, token.Scope, token.CreatedAt.Format("2006-01-02"), lastUsed)
+		}
+
+	case "revoke":
+		if len(args) != 3 {
+			log.Fatal("Usage: trtg-user token revoke <username> <id>")
+		}
+		user, err := db.GetUserByUsername(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		tokenID, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			log.Fatalf("Invalid token ID: %s", args[2])
+		}
+		if err := db.RevokeAPIToken(user.ID, tokenID); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("Revoked token %d of %s\n", tokenID, user.Username)
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// parseUsername parses subcommand flags, which may come before or after the username
+func parseUsername(fs *flag.FlagSet, args []string) string {
+	var username string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		username, args = args[0], args[1:]
+	}
+	fs.Parse(args)
+	if username == "" && fs.NArg() > 0 {
+		username = fs.Arg(0)
+	}
+	if username == "" {
+		log.Fatalf("Usage: trtg-user %s <username>", fs.Name())
+	}
+	return username
+}
+
+// readPassword returns the flag value or reads a password line from standard input
+func readPassword(password string) string {
+	if password != "" {
+		return password
+	}
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatalf("Failed to read password: %v", err)
+	}
+	password = strings.TrimRight(line, "\r\n")
+	if password == "" {
+		log.Fatal("Password must not be empty")
+	}
+	return password
+}
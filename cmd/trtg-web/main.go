@@ -2,20 +2,49 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/rusik69/trtg/pkg/cleanup"
 	"github.com/rusik69/trtg/pkg/config"
 	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/events"
+	"github.com/rusik69/trtg/pkg/metadata"
+	"github.com/rusik69/trtg/pkg/notify"
+	"github.com/rusik69/trtg/pkg/parser"
+	"github.com/rusik69/trtg/pkg/telegram"
+	"github.com/rusik69/trtg/pkg/torrent"
 	"github.com/rusik69/trtg/pkg/web"
 )
 
+// parserRulesInterval is how often the parser rules file is checked for changes
+const parserRulesInterval = 10 * time.Second
+
+const (
+	// streamSubdir is the directory under -torrent-dir that holds the pieces of streamed torrents
+	streamSubdir = "trtg-web-stream"
+	// streamMarker marks streamSubdir as created by trtg-web, so it is safe to empty
+	streamMarker = ".trtg-web-stream"
+)
+
 func main() {
 	dbURL := flag.String("db", "", "PostgreSQL connection URL (overrides DATABASE_URL env)")
 	port := flag.String("port", "8080", "Port to listen on")
 	downloadDir := flag.String("download-dir", "", "Download directory for videos (overrides DOWNLOAD_DIR env)")
+	torrentStream := flag.Bool("torrent-stream", true, "Allow streaming not-yet-uploaded episodes directly from the torrent swarm")
+	torrentDir := flag.String("torrent-dir", filepath.Join(os.TempDir(), "trtg-torrent-stream"), "Directory for pieces fetched while streaming from torrents (its "+streamSubdir+" subdirectory is emptied on start)")
+	torrentPort := flag.Int("torrent-port", 42070, "Listen port for the streaming torrent client (must differ from the trtg daemon)")
+	transcodeWorkers := flag.Int("transcode-workers", web.DefaultTranscodeWorkers, "Maximum number of concurrent full-file transcodes")
+	cacheMaxGB := flag.Int64("cache-max-gb", 20, "Maximum size of the re-download and HLS segment cache in GB")
+	telegramLogin := flag.Bool("telegram-login", os.Getenv("WEB_TELEGRAM_LOGIN") == "true", "Allow members of TELEGRAM_CHAT_ID to log in with the Telegram Login Widget")
+	staticDir := flag.String("static-dir", "static", "Directory served under /static/, holding the hls.js release the player loads")
+	trustedProxies := flag.String("trusted-proxies", os.Getenv("TRUSTED_PROXIES"), "Comma-separated IPs or CIDRs of reverse proxies whose X-Real-IP, X-Forwarded-For and X-Forwarded-Proto headers are trusted")
 	flag.Parse()
 
 	// Web interface no longer needs Telegram credentials - it uses trtg API instead
@@ -39,12 +68,106 @@ func main() {
 	}
 	defer db.Close()
 
+	// WEB_USERNAME/WEB_PASSWORD only seed the first admin; further users are managed with trtg-user
+	created, err := db.BootstrapAdmin(cfg.WebUsername, cfg.WebPassword)
+	if err != nil {
+		log.Fatalf("Failed to create initial admin user: %v", err)
+	}
+	if created {
+		log.Printf("Created initial admin user %s", cfg.WebUsername)
+		if cfg.WebPassword == "admin" {
+			log.Printf("Warning: Initial admin uses the default password, change it with: trtg-user passwd %s", cfg.WebUsername)
+		}
+	}
+
 	// Initialize web server
-	server := web.NewServer(db, cfg.DownloadDir, cfg.TRTGAPIURL, cfg.WebUsername, cfg.WebPassword, cfg.TelegramToken, cfg.TelegramChatID, cfg.TelegramAPIURL)
+	server := web.NewServer(db, cfg.DownloadDir, cfg.TRTGAPIURL, cfg.TelegramToken, cfg.TelegramChatID, cfg.TelegramAPIURL)
+	server.SetTranscodeWorkers(*transcodeWorkers)
+	server.SetTelegramLogin(*telegramLogin)
+	server.SetStaticDir(*staticDir)
+
+	proxies, err := web.ParseTrustedProxies(*trustedProxies)
+	if err != nil {
+		log.Fatalf("Failed to parse trusted proxies: %v", err)
+	}
+	server.SetTrustedProxies(proxies)
+
+	// Show names for the parser test on the /rules page
+	resolver, err := parser.NewShowNameResolver(parser.ResolverConfigFromEnv(), db)
+	if err != nil {
+		log.Fatalf("Failed to configure show name resolvers: %v", err)
+	}
+	parser.SetShowNameResolver(resolver)
+
+	// Parser rules edited on the /rules page; reloaded when another process changes the file
+	if cfg.ParserRules != "" {
+		server.SetParserRulesFile(cfg.ParserRules)
+		go parser.WatchRules(context.Background(), cfg.ParserRules, parserRulesInterval)
+	}
+
+	// Episode titles, overviews and artwork, cached in the database
+	metadataClient, err := metadata.New(metadata.ConfigFromEnv(), db)
+	if err != nil {
+		log.Fatalf("Failed to configure metadata provider: %v", err)
+	}
+	if metadataClient != nil {
+		server.SetMetadataClient(metadataClient)
+		log.Printf("Show metadata enabled (provider: %s)", os.Getenv("METADATA_PROVIDER"))
+	}
+
+	// Notifications for subscribed webhooks, Telegram chats and browsers
+	notifier := notify.NewDispatcher(db)
+	if cfg.TelegramToken != "" {
+		uploader, err := telegram.NewUploader(cfg.TelegramToken, cfg.TelegramChatID, cfg.TelegramAPIURL)
+		if err != nil {
+			log.Printf("Warning: Telegram notifications disabled: %v", err)
+		} else {
+			notifier.SetTelegram(uploader)
+		}
+	}
+	server.SetNotifier(notifier)
+
+	// Database changes, including those made by the trtg-bot worker, reach this process through LISTEN/NOTIFY
+	bus := events.NewBus()
+	server.SetEventBus(bus)
+	go func() {
+		if err := db.ListenChanges(context.Background(), bus.Publish); err != nil {
+			log.Printf("Warning: Live updates disabled: %v", err)
+		}
+	}()
+	// Changes the notifier misses reach it as a ChangeResync, on which it catches up from the database
+	changes, _ := bus.Subscribe()
+	go func() {
+		for change := range changes {
+			notifier.HandleChange(change)
+		}
+	}()
+
+	// Torrent client used to stream episodes that are still downloading
+	if *torrentStream {
+		dir, err := prepareStreamDir(*torrentDir)
+		if err != nil {
+			log.Printf("Warning: Torrent streaming disabled: %v", err)
+		} else if torrents, err := torrent.NewDownloaderWithPort(dir, *torrentPort); err != nil {
+			log.Printf("Warning: Torrent streaming disabled: %v", err)
+		} else {
+			defer torrents.Close()
+			server.SetTorrentDownloader(torrents)
+			log.Printf("Torrent streaming enabled (dir: %s, port: %d)", dir, *torrentPort)
+		}
+	}
+
+	// Keep re-downloaded sources and transcoded HLS segments under the configured size
+	cacheCleanup := web.NewCleanupManager(server.CacheDir(), *cacheMaxGB*1024*1024*1024, 10*time.Minute)
+	cacheCleanup.Start()
+	defer cacheCleanup.Stop()
 
 	// Start cleanup service for telegram-bot-api storage
 	// Scans /var/lib/telegram-bot-api and cleans up old files to keep storage under limits
 	cleanupSvc := cleanup.NewService("/var/lib/telegram-bot-api")
+	cleanupSvc.SetOnDelete(func(paths []string, freedBytes int64) {
+		notifier.Publish(notify.CleanupDeleted(paths, freedBytes))
+	})
 	cleanupSvc.Start()
 	log.Printf("Started telegram-bot-api storage cleanup service (max: %d GB, %d files)", cleanup.MaxStorageGB, cleanup.MaxFiles)
 
@@ -53,3 +176,29 @@ func main() {
 		log.Fatalf("Failed to start web server: %v", err)
 	}
 }
+
+// prepareStreamDir returns an empty streamSubdir under parent for the streaming torrent client
+// Pieces left by a previous run are never reused, since their torrents are gone, so the directory
+// is emptied first; one without streamMarker was not created by trtg-web and is left alone
+func prepareStreamDir(parent string) (string, error) {
+	dir := filepath.Join(parent, streamSubdir)
+	marker := filepath.Join(dir, streamMarker)
+	if _, err := os.Stat(dir); err == nil {
+		if _, err := os.Stat(marker); err != nil {
+			return "", fmt.Errorf("refusing to empty %s: it was not created by trtg-web", dir)
+		}
+		if err := os.RemoveAll(dir); err != nil {
+			return "", fmt.Errorf("failed to clean torrent stream directory: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to check torrent stream directory: %w", err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create torrent stream directory: %w", err)
+	}
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		return "", fmt.Errorf("failed to mark torrent stream directory: %w", err)
+	}
+	return dir, nil
+}
@@ -0,0 +1,210 @@
+// Package bot implements the interactive Telegram bot for managing the archive
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/telegram"
+	"github.com/rusik69/trtg/pkg/torrent"
+)
+
+// maxMessageLength is Telegram's limit for the text of one message
+const maxMessageLength = 4096
+
+// Bot answers commands and previews torrents sent in the archive chat, allow-listed chats
+// or privately by members of the archive chat
+type Bot struct {
+	api          *tgbotapi.BotAPI
+	db           *database.DB
+	chatID       int64                // The archive chat; its members may use the bot
+	allowedChats map[int64]bool       // Additional group chats the bot listens in
+	files        *telegram.Downloader // Downloads .torrent documents through the Local Bot API
+	torrents     *torrent.Downloader  // Fetches torrent metadata for previews; nil disables them
+	workDir      string
+	pending      pendingTorrents
+}
+
+// New creates a bot using the Local Bot API Server
+func New(db *database.DB, token string, chatID int64, apiURL string) (*Bot, error) {
+	if apiURL == "" {
+		return nil, fmt.Errorf("apiURL is required - Local Bot API Server must be configured")
+	}
+
+	apiURL = strings.TrimSuffix(apiURL, "/")
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(token, apiURL+"/bot%s/%s")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Telegram bot: %w", err)
+	}
+	api.Client = &http.Client{Timeout: 2 * time.Minute}
+
+	files, err := telegram.NewDownloader(token, chatID, apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Telegram file downloader: %w", err)
+	}
+
+	return &Bot{
+		api:          api,
+		db:           db,
+		chatID:       chatID,
+		allowedChats: make(map[int64]bool),
+		files:        files,
+		workDir:      os.TempDir(),
+	}, nil
+}
+
+// AllowChat lets the bot accept commands and torrents from another group chat
+func (b *Bot) AllowChat(chatID int64) {
+	b.allowedChats[chatID] = true
+}
+
+// SetTorrentDownloader enables torrent previews; .torrent documents are downloaded to workDir
+func (b *Bot) SetTorrentDownloader(d *torrent.Downloader, workDir string) error {
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return fmt.Errorf("failed to create work directory: %w", err)
+	}
+	b.torrents = d
+	b.workDir = workDir
+	return nil
+}
+
+// Run long-polls for updates and handles them until ctx is cancelled
+// Telegram allows only one getUpdates consumer per bot token, so run a single instance
+func (b *Bot) Run(ctx context.Context) error {
+	if _, err := b.api.Request(tgbotapi.NewSetMyCommands(botCommands...)); err != nil {
+		log.Printf("Warning: Failed to register bot commands: %v", err)
+	}
+
+	config := tgbotapi.NewUpdate(0)
+	config.Timeout = 60
+	updates := b.api.GetUpdatesChan(config)
+	log.Printf("Bot @%s is listening for commands", b.api.Self.UserName)
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.api.StopReceivingUpdates()
+			return nil
+		case update, ok := <-updates:
+			if !ok {
+				return fmt.Errorf("update channel closed")
+			}
+			b.handleUpdate(update)
+		}
+	}
+}
+
+// handleUpdate dispatches one update, recovering from panics so one bad message cannot stop the bot
+func (b *Bot) handleUpdate(update tgbotapi.Update) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Error: Panic while handling update %d: %v", update.UpdateID, r)
+		}
+	}()
+
+	if query := update.CallbackQuery; query != nil {
+		b.handleCallback(query)
+		return
+	}
+
+	msg := update.Message
+	if msg == nil || msg.From == nil {
+		return
+	}
+	if !b.isAllowed(msg) {
+		if msg.Chat.IsPrivate() {
+			b.reply(msg, "Sorry, only members of the archive chat can use this bot.")
+		}
+		return
+	}
+	if msg.IsCommand() {
+		b.handleCommand(msg)
+		return
+	}
+	b.handleTorrentMessage(msg)
+}
+
+// isAllowed reports whether a message comes from the archive chat, an allow-listed chat
+// or privately from a member of the archive chat
+func (b *Bot) isAllowed(msg *tgbotapi.Message) bool {
+	if msg.Chat.ID == b.chatID || b.allowedChats[msg.Chat.ID] {
+		return true
+	}
+	if !msg.Chat.IsPrivate() {
+		return false
+	}
+	member, err := b.chatMember(msg.From.ID)
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return false
+	}
+	return telegram.IsChatMember(member)
+}
+
+// isAdmin reports whether a Telegram user may run admin commands:
+// administrators of the archive chat and users linked to a web admin account
+func (b *Bot) isAdmin(userID int64) bool {
+	if member, err := b.chatMember(userID); err == nil && (member.IsCreator() || member.IsAdministrator()) {
+		return true
+	}
+	user, err := b.db.GetUserByTelegramID(userID)
+	if err != nil {
+		log.Printf("Warning: Failed to look up Telegram user %d: %v", userID, err)
+		return false
+	}
+	return user.IsAdmin()
+}
+
+// chatMember returns the membership of a user in the archive chat
+func (b *Bot) chatMember(userID int64) (tgbotapi.ChatMember, error) {
+	member, err := b.api.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: b.chatID, UserID: userID},
+	})
+	if err != nil {
+		return tgbotapi.ChatMember{}, fmt.Errorf("failed to get chat member %d: %w", userID, err)
+	}
+	return member, nil
+}
+
+// reply sends an HTML-formatted answer to a message, split into several messages if it is too long
+func (b *Bot) reply(msg *tgbotapi.Message, text string) {
+	for _, chunk := range splitMessage(text) {
+		answer := tgbotapi.NewMessage(msg.Chat.ID, chunk)
+		answer.ParseMode = tgbotapi.ModeHTML
+		answer.DisableWebPagePreview = true
+		answer.ReplyToMessageID = msg.MessageID
+		if _, err := b.api.Send(answer); err != nil {
+			log.Printf("Warning: Failed to reply in chat %d: %v", msg.Chat.ID, err)
+			return
+		}
+	}
+}
+
+// splitMessage splits text at line breaks into chunks Telegram accepts
+func splitMessage(text string) []string {
+	var chunks []string
+	for len(text) > maxMessageLength {
+		cut := strings.LastIndex(text[:maxMessageLength], "\n")
+		if cut <= 0 {
+			cut = maxMessageLength
+		}
+		chunks = append(chunks, text[:cut])
+		text = strings.TrimPrefix(text[cut:], "\n")
+	}
+	return append(chunks, text)
+}
+
+// requester describes who sent a message, for job and log records
+func requester(user *tgbotapi.User) string {
+	if user.UserName != "" {
+		return "telegram:@" + user.UserName
+	}
+	return fmt.Sprintf("telegram:%d", user.ID)
+}
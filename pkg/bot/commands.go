@@ -0,0 +1,224 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/telegram"
+)
+
+// searchLimit is the maximum number of episodes /search lists
+const searchLimit = 30
+
+// botCommands are registered with Telegram so clients can suggest them
+var botCommands = []tgbotapi.BotCommand{
+	{Command: "add", Description: "Queue a magnet link or .torrent URL for download"},
+	{Command: "status", Description: "Show queued and running downloads"},
+	{Command: "search", Description: "Find episodes of a show"},
+	{Command: "shows", Description: "List the shows in the archive"},
+	{Command: "delete", Description: "Delete an episode by ID (admins)"},
+	{Command: "help", Description: "List commands"},
+}
+
+// handleCommand runs a bot command
+func (b *Bot) handleCommand(msg *tgbotapi.Message) {
+	args := strings.TrimSpace(msg.CommandArguments())
+	log.Printf("Bot command /%s from %s in chat %d", msg.Command(), requester(msg.From), msg.Chat.ID)
+
+	switch msg.Command() {
+	case "add":
+		b.handleAdd(msg, args)
+	case "status":
+		b.handleStatus(msg)
+	case "search":
+		b.handleSearch(msg, args)
+	case "shows":
+		b.handleShows(msg)
+	case "delete":
+		b.handleDelete(msg, args)
+	case "start", "help":
+		b.handleHelp(msg)
+	}
+}
+
+// handleHelp lists the commands
+func (b *Bot) handleHelp(msg *tgbotapi.Message) {
+	var text strings.Builder
+	text.WriteString("<b>Commands</b>\n")
+	for _, command := range botCommands {
+		fmt.Fprintf(&text, "/%s – %s\n", command.Command, html.EscapeString(command.Description))
+	}
+	text.WriteString("\nSend a magnet link or a .torrent file to preview its files and choose what to download.")
+	b.reply(msg, text.String())
+}
+
+// handleAdd queues a torrent
+// Usage: /add <magnet link or .torrent URL>
+func (b *Bot) handleAdd(msg *tgbotapi.Message, args string) {
+	torrentURL := strings.TrimSpace(args)
+	if !isTorrentURL(torrentURL) {
+		b.reply(msg, "Usage: /add &lt;magnet link or .torrent URL&gt;")
+		return
+	}
+
+	job, err := b.db.EnqueueTorrentJob(torrentURL, torrentName(torrentURL), nil, requester(msg.From))
+	if err != nil {
+		log.Printf("Error queueing torrent from %s: %v", requester(msg.From), err)
+		b.reply(msg, "Failed to queue the torrent.")
+		return
+	}
+	b.reply(msg, fmt.Sprintf("Queued #%d %s", job.ID, html.EscapeString(job.Name)))
+}
+
+// handleStatus lists queued and running downloads
+func (b *Bot) handleStatus(msg *tgbotapi.Message) {
+	jobs, err := b.db.GetActiveTorrentJobs()
+	if err != nil {
+		log.Printf("Error loading torrent jobs: %v", err)
+		b.reply(msg, "Failed to load the download queue.")
+		return
+	}
+	if len(jobs) == 0 {
+		b.reply(msg, "Nothing is queued or downloading.")
+		return
+	}
+
+	var text strings.Builder
+	for _, job := range jobs {
+		name := job.Name
+		if name == "" {
+			name = job.TorrentURL
+		}
+		fmt.Fprintf(&text, "#%d <b>%s</b>\n", job.ID, html.EscapeString(name))
+		if job.Status == database.JobDownloading {
+			fmt.Fprintf(&text, "    downloading %.1f%%", job.Progress)
+			if job.CurrentFile != "" {
+				fmt.Fprintf(&text, " – %s", html.EscapeString(filepath.Base(job.CurrentFile)))
+			}
+			text.WriteString("\n")
+		} else {
+			fmt.Fprintf(&text, "    %s\n", job.Status)
+		}
+	}
+	b.reply(msg, text.String())
+}
+
+// handleSearch lists episodes of matching shows with links to their messages
+// Usage: /search <show>
+func (b *Bot) handleSearch(msg *tgbotapi.Message, query string) {
+	if query == "" {
+		b.reply(msg, "Usage: /search &lt;show&gt;")
+		return
+	}
+
+	episodes, err := b.db.SearchEpisodes(query, searchLimit+1)
+	if err != nil {
+		log.Printf("Error searching episodes for %q: %v", query, err)
+		b.reply(msg, "Search failed.")
+		return
+	}
+	if len(episodes) == 0 {
+		b.reply(msg, fmt.Sprintf("No episodes found for “%s”.", html.EscapeString(query)))
+		return
+	}
+
+	var text strings.Builder
+	for i, episode := range episodes {
+		if i == searchLimit {
+			text.WriteString("…more results, refine the search")
+			break
+		}
+		label := html.EscapeString(episode.ShowName)
+		if episode.SeasonNumber > 0 && episode.EpisodeNumber > 0 {
+			label += fmt.Sprintf(" S%02dE%02d", episode.SeasonNumber, episode.EpisodeNumber)
+		} else {
+			label += " – " + html.EscapeString(filepath.Base(episode.FilePath))
+		}
+		if link := telegram.MessageLink(b.chatID, episode.TelegramMessageID); link != "" {
+			label = fmt.Sprintf(`<a href="%s">%s</a>`, link, label)
+		}
+		fmt.Fprintf(&text, "#%d %s\n", episode.ID, label)
+	}
+	b.reply(msg, text.String())
+}
+
+// handleShows lists the shows in the archive
+func (b *Bot) handleShows(msg *tgbotapi.Message) {
+	shows, err := b.db.GetAllShows()
+	if err != nil {
+		log.Printf("Error loading shows: %v", err)
+		b.reply(msg, "Failed to load shows.")
+		return
+	}
+	if len(shows) == 0 {
+		b.reply(msg, "The archive is empty.")
+		return
+	}
+
+	var text strings.Builder
+	for _, show := range shows {
+		fmt.Fprintf(&text, "<b>%s</b> – %d seasons, %d episodes\n", html.EscapeString(show.Name), show.SeasonCount, show.EpisodeCount)
+	}
+	b.reply(msg, text.String())
+}
+
+// handleDelete deletes an episode and its Telegram message
+// Usage: /delete <episode id>, admins only
+func (b *Bot) handleDelete(msg *tgbotapi.Message, args string) {
+	if !b.isAdmin(msg.From.ID) {
+		b.reply(msg, "Only admins can delete episodes.")
+		return
+	}
+	videoID, err := strconv.ParseInt(strings.TrimPrefix(args, "#"), 10, 64)
+	if err != nil {
+		b.reply(msg, "Usage: /delete &lt;episode id&gt; (ids are shown by /search)")
+		return
+	}
+
+	video, err := b.db.DeleteVideo(videoID)
+	if err != nil {
+		b.reply(msg, fmt.Sprintf("Failed to delete #%d: %s", videoID, html.EscapeString(err.Error())))
+		return
+	}
+	if video.TelegramMessageID > 0 {
+		if _, err := b.api.Request(tgbotapi.NewDeleteMessage(b.chatID, video.TelegramMessageID)); err != nil {
+			log.Printf("Warning: Failed to delete Telegram message %d: %v", video.TelegramMessageID, err)
+		}
+	}
+	log.Printf("%s deleted video %d (%s)", requester(msg.From), videoID, video.FilePath)
+	b.reply(msg, fmt.Sprintf("Deleted #%d %s", videoID, html.EscapeString(filepath.Base(video.FilePath))))
+}
+
+// isTorrentURL reports whether s is a magnet link or an HTTP(S) URL
+func isTorrentURL(s string) bool {
+	if strings.HasPrefix(s, "magnet:?") {
+		return true
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// torrentName returns the display name ("dn") of a magnet link, or the file name of a .torrent URL
+func torrentName(torrentURL string) string {
+	if strings.HasPrefix(torrentURL, "magnet:?") {
+		if values, err := url.ParseQuery(strings.TrimPrefix(torrentURL, "magnet:?")); err == nil && values.Get("dn") != "" {
+			return values.Get("dn")
+		}
+		return ""
+	}
+	if u, err := url.Parse(torrentURL); err == nil {
+		// A URL without a file name ("https://host/") has no name to show
+		if name := strings.TrimSuffix(path.Base(u.Path), ".torrent"); name != "/" && name != "." {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,54 @@
+package bot
+
+import "testing"
+
+func TestIsTorrentURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&dn=Show", true},
+		{"https://tracker.example.com/download/123.torrent", true},
+		{"http://tracker.example.com/dl?id=123", true},
+		{"magnet:xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a", false},
+		{"ftp://tracker.example.com/123.torrent", false},
+		{"https://", false},
+		{"/downloads/show.torrent", false},
+		{"tracker.example.com/123.torrent", false},
+		{"Breaking Bad", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := isTorrentURL(tt.input); got != tt.expected {
+				t.Errorf("isTorrentURL(%q) = %v, want %v", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestTorrentName(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&dn=Breaking.Bad.S01.1080p", "Breaking.Bad.S01.1080p"},
+		{"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&dn=Breaking+Bad+S01", "Breaking Bad S01"},
+		{"magnet:?dn=The%20Wire%20S02&xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a", "The Wire S02"},
+		{"magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a", ""},
+		{"https://tracker.example.com/download/Breaking.Bad.S01.torrent", "Breaking.Bad.S01"},
+		{"https://tracker.example.com/download/The%20Wire.torrent?passkey=abc", "The Wire"},
+		{"https://tracker.example.com/dl/123", "123"},
+		{"https://tracker.example.com/", ""},
+		{"https://tracker.example.com", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := torrentName(tt.input); got != tt.expected {
+				t.Errorf("torrentName(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
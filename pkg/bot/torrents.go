@@ -0,0 +1,371 @@
+package bot
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rusik69/trtg/pkg/telegram"
+	"github.com/rusik69/trtg/pkg/torrent"
+)
+
+const (
+	// pendingTTL is how long a torrent preview waits for a decision
+	pendingTTL = time.Hour
+	// filesPerPage is how many files the "Pick files" keyboard shows at once
+	filesPerPage = 8
+	// previewFiles is how many files the preview message lists
+	previewFiles = 15
+)
+
+// magnetPattern finds magnet links in message text
+var magnetPattern = regexp.MustCompile(`magnet:\?[^\s<>"]+`)
+
+// pendingTorrent is a torrent preview waiting for the user to choose files
+type pendingTorrent struct {
+	id          int64
+	torrentURL  string
+	name        string
+	size        int64
+	files       []torrent.FileInfo
+	selected    map[int]bool
+	page        int
+	userID      int64 // Only the user who sent the torrent can answer
+	requestedBy string
+	chatID      int64
+	messageID   int // The preview message that is edited as the user chooses
+	created     time.Time
+}
+
+// pendingTorrents holds previews by ID
+type pendingTorrents struct {
+	mu     sync.Mutex
+	nextID int64
+	items  map[int64]*pendingTorrent
+}
+
+// add stores a preview and assigns its ID, dropping expired ones
+func (p *pendingTorrents) add(pt *pendingTorrent) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.items == nil {
+		p.items = make(map[int64]*pendingTorrent)
+	}
+	for id, item := range p.items {
+		if time.Since(item.created) > pendingTTL {
+			delete(p.items, id)
+		}
+	}
+	p.nextID++
+	pt.id = p.nextID
+	p.items[pt.id] = pt
+}
+
+// get returns a preview that has not expired
+func (p *pendingTorrents) get(id int64) *pendingTorrent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	pt, ok := p.items[id]
+	if !ok || time.Since(pt.created) > pendingTTL {
+		return nil
+	}
+	return pt
+}
+
+// remove forgets a preview
+func (p *pendingTorrents) remove(id int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.items, id)
+}
+
+// handleTorrentMessage previews magnet links and .torrent documents sent to the bot
+// Returns false if the message contains neither
+func (b *Bot) handleTorrentMessage(msg *tgbotapi.Message) bool {
+	if doc := msg.Document; doc != nil && isTorrentDocument(doc) {
+		go b.previewTorrentDocument(msg, doc)
+		return true
+	}
+
+	text := msg.Text
+	if text == "" {
+		text = msg.Caption
+	}
+	magnets := magnetPattern.FindAllString(text, -1)
+	for _, magnet := range magnets {
+		go b.previewTorrent(msg, magnet)
+	}
+	return len(magnets) > 0
+}
+
+// isTorrentDocument reports whether a document is a .torrent file
+func isTorrentDocument(doc *tgbotapi.Document) bool {
+	return doc.MimeType == "application/x-bittorrent" || strings.HasSuffix(strings.ToLower(doc.FileName), ".torrent")
+}
+
+// previewTorrentDocument downloads a .torrent document through the Local Bot API and previews it
+// The torrent is queued as a magnet link built from the file, so the daemon does not need the file itself
+func (b *Bot) previewTorrentDocument(msg *tgbotapi.Message, doc *tgbotapi.Document) {
+	if b.torrents == nil {
+		b.reply(msg, "Torrent previews are disabled on this bot.")
+		return
+	}
+
+	path := filepath.Join(b.workDir, fmt.Sprintf("%d-%d.torrent", msg.Chat.ID, msg.MessageID))
+	defer os.Remove(path)
+	if err := b.files.DownloadFile(doc.FileID, path); err != nil {
+		log.Printf("Error downloading torrent document %s: %v", doc.FileName, err)
+		b.reply(msg, "Failed to download the .torrent file.")
+		return
+	}
+
+	magnet, err := torrent.MagnetFromTorrentFile(path)
+	if err != nil {
+		log.Printf("Error reading torrent document %s: %v", doc.FileName, err)
+		b.reply(msg, "This does not look like a valid .torrent file.")
+		return
+	}
+	b.sendPreview(msg, magnet, path)
+}
+
+// previewTorrent fetches the metadata of a magnet link and previews it
+func (b *Bot) previewTorrent(msg *tgbotapi.Message, magnet string) {
+	if b.torrents == nil {
+		b.reply(msg, "Torrent previews are disabled on this bot.")
+		return
+	}
+	b.sendPreview(msg, magnet, magnet)
+}
+
+// sendPreview loads the file list of a torrent and replies with it and the inline buttons
+// torrentURL is what gets queued; source is what GetTorrentInfo reads (the same magnet or a local .torrent file)
+func (b *Bot) sendPreview(msg *tgbotapi.Message, torrentURL, source string) {
+	status := tgbotapi.NewMessage(msg.Chat.ID, "Fetching torrent info…")
+	status.ReplyToMessageID = msg.MessageID
+	sent, err := b.api.Send(status)
+	if err != nil {
+		log.Printf("Warning: Failed to send torrent preview: %v", err)
+		return
+	}
+
+	name, size, files, err := b.torrents.GetTorrentInfo(source)
+	if err != nil {
+		log.Printf("Error getting torrent info: %v", err)
+		b.edit(msg.Chat.ID, sent.MessageID, "Failed to get torrent info: "+html.EscapeString(err.Error()), nil)
+		return
+	}
+
+	pt := &pendingTorrent{
+		torrentURL:  torrentURL,
+		name:        name,
+		size:        size,
+		files:       files,
+		selected:    make(map[int]bool),
+		userID:      msg.From.ID,
+		requestedBy: requester(msg.From),
+		chatID:      msg.Chat.ID,
+		messageID:   sent.MessageID,
+		created:     time.Now(),
+	}
+	b.pending.add(pt)
+	b.showPreview(pt)
+}
+
+// showPreview renders the summary view with Download all / Pick files / Cancel
+func (b *Bot) showPreview(pt *pendingTorrent) {
+	var text strings.Builder
+	fmt.Fprintf(&text, "<b>%s</b>\n%d files, %s\n\n", html.EscapeString(pt.name), len(pt.files), telegram.FormatSize(pt.size))
+	for i, file := range pt.files {
+		if i == previewFiles {
+			fmt.Fprintf(&text, "…and %d more\n", len(pt.files)-previewFiles)
+			break
+		}
+		fmt.Fprintf(&text, "%s <i>(%s)</i>\n", html.EscapeString(file.Path), telegram.FormatSize(file.Size))
+	}
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("Download all", callbackData(pt.id, "all")),
+		tgbotapi.NewInlineKeyboardButtonData("Pick files", callbackData(pt.id, "pick")),
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", callbackData(pt.id, "cancel")),
+	))
+	b.edit(pt.chatID, pt.messageID, text.String(), &keyboard)
+}
+
+// showPicker renders one page of files with toggle buttons
+func (b *Bot) showPicker(pt *pendingTorrent) {
+	pages := (len(pt.files) + filesPerPage - 1) / filesPerPage
+	if pt.page >= pages {
+		pt.page = pages - 1
+	}
+	if pt.page < 0 {
+		pt.page = 0
+	}
+
+	var selectedSize int64
+	for index := range pt.selected {
+		selectedSize += pt.files[index].Size
+	}
+	text := fmt.Sprintf("<b>%s</b>\nSelected %d of %d files, %s\nPage %d/%d",
+		html.EscapeString(pt.name), len(pt.selected), len(pt.files), telegram.FormatSize(selectedSize), pt.page+1, pages)
+
+	var rows [][]tgbotapi.InlineKeyboardButton
+	start := pt.page * filesPerPage
+	for index := start; index < len(pt.files) && index < start+filesPerPage; index++ {
+		mark := "⬜"
+		if pt.selected[index] {
+			mark = "✅"
+		}
+		label := fmt.Sprintf("%s %s (%s)", mark, shorten(filepath.Base(pt.files[index].Path), 40), telegram.FormatSize(pt.files[index].Size))
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(label, callbackData(pt.id, "f"+strconv.Itoa(index))),
+		))
+	}
+
+	var nav []tgbotapi.InlineKeyboardButton
+	if pt.page > 0 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("◀", callbackData(pt.id, "p"+strconv.Itoa(pt.page-1))))
+	}
+	if pt.page < pages-1 {
+		nav = append(nav, tgbotapi.NewInlineKeyboardButtonData("▶", callbackData(pt.id, "p"+strconv.Itoa(pt.page+1))))
+	}
+	if len(nav) > 0 {
+		rows = append(rows, nav)
+	}
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData(fmt.Sprintf("Download selected (%d)", len(pt.selected)), callbackData(pt.id, "ok")),
+		tgbotapi.NewInlineKeyboardButtonData("Cancel", callbackData(pt.id, "cancel")),
+	))
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(rows...)
+	b.edit(pt.chatID, pt.messageID, text, &keyboard)
+}
+
+// handleCallback handles presses of the preview buttons
+// Callback data is "t:<preview id>:<action>" to stay within Telegram's 64 byte limit
+func (b *Bot) handleCallback(query *tgbotapi.CallbackQuery) {
+	parts := strings.SplitN(query.Data, ":", 3)
+	if len(parts) != 3 || parts[0] != "t" {
+		b.answer(query, "")
+		return
+	}
+	id, _ := strconv.ParseInt(parts[1], 10, 64)
+	action := parts[2]
+
+	pt := b.pending.get(id)
+	if pt == nil {
+		b.answer(query, "This preview has expired, send the torrent again")
+		return
+	}
+	if query.From.ID != pt.userID {
+		b.answer(query, "Only the person who sent the torrent can choose")
+		return
+	}
+
+	switch {
+	case action == "cancel":
+		b.pending.remove(id)
+		b.edit(pt.chatID, pt.messageID, fmt.Sprintf("Cancelled <b>%s</b>", html.EscapeString(pt.name)), nil)
+		b.answer(query, "Cancelled")
+
+	case action == "all":
+		b.queue(query, pt, nil)
+
+	case action == "ok":
+		if len(pt.selected) == 0 {
+			b.answer(query, "Select at least one file")
+			return
+		}
+		indexes := make([]int, 0, len(pt.selected))
+		for index := range pt.selected {
+			indexes = append(indexes, index)
+		}
+		sort.Ints(indexes)
+		b.queue(query, pt, indexes)
+
+	case action == "pick":
+		b.showPicker(pt)
+		b.answer(query, "")
+
+	case strings.HasPrefix(action, "p"):
+		pt.page, _ = strconv.Atoi(action[1:])
+		b.showPicker(pt)
+		b.answer(query, "")
+
+	case strings.HasPrefix(action, "f"):
+		index, err := strconv.Atoi(action[1:])
+		if err != nil || index < 0 || index >= len(pt.files) {
+			b.answer(query, "")
+			return
+		}
+		if pt.selected[index] {
+			delete(pt.selected, index)
+		} else {
+			pt.selected[index] = true
+		}
+		b.showPicker(pt)
+		b.answer(query, "")
+
+	default:
+		b.answer(query, "")
+	}
+}
+
+// queue enqueues a previewed torrent and replaces the preview with a confirmation
+func (b *Bot) queue(query *tgbotapi.CallbackQuery, pt *pendingTorrent, indexes []int) {
+	job, err := b.db.EnqueueTorrentJob(pt.torrentURL, pt.name, indexes, pt.requestedBy)
+	if err != nil {
+		log.Printf("Error queueing torrent %s: %v", pt.name, err)
+		b.answer(query, "Failed to queue the torrent")
+		return
+	}
+	b.pending.remove(pt.id)
+
+	files := "all files"
+	if len(indexes) > 0 {
+		files = fmt.Sprintf("%d of %d files", len(indexes), len(pt.files))
+	}
+	b.edit(pt.chatID, pt.messageID, fmt.Sprintf("Queued #%d <b>%s</b> (%s)", job.ID, html.EscapeString(pt.name), files), nil)
+	b.answer(query, "Queued")
+	log.Printf("%s queued torrent job %d: %s (%s)", pt.requestedBy, job.ID, pt.name, files)
+}
+
+// edit replaces the text and inline keyboard of a bot message
+func (b *Bot) edit(chatID int64, messageID int, text string, keyboard *tgbotapi.InlineKeyboardMarkup) {
+	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	edit.ParseMode = tgbotapi.ModeHTML
+	edit.DisableWebPagePreview = true
+	edit.ReplyMarkup = keyboard
+	if _, err := b.api.Send(edit); err != nil && !strings.Contains(err.Error(), "message is not modified") {
+		log.Printf("Warning: Failed to edit message %d in chat %d: %v", messageID, chatID, err)
+	}
+}
+
+// answer acknowledges a button press, optionally with a short notification
+func (b *Bot) answer(query *tgbotapi.CallbackQuery, text string) {
+	if _, err := b.api.Request(tgbotapi.NewCallback(query.ID, text)); err != nil {
+		log.Printf("Warning: Failed to answer callback: %v", err)
+	}
+}
+
+// callbackData builds the data of a preview button
+func callbackData(id int64, action string) string {
+	return fmt.Sprintf("t:%d:%s", id, action)
+}
+
+// shorten truncates s to at most n runes, keeping its end where episode numbers usually are
+func shorten(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return "…" + string(runes[len(runes)-n+1:])
+}
@@ -33,6 +33,7 @@ type Service struct {
 	maxBytes    int64
 	maxFiles    int
 	interval    time.Duration
+	onDelete    func(paths []string, freedBytes int64) // Called after a run that deleted files
 }
 
 // NewService creates a new cleanup service
@@ -45,6 +46,11 @@ func NewService(storagePath string) *Service {
 	}
 }
 
+// SetOnDelete sets a function called with the deleted files after each cleanup run that deleted any
+func (s *Service) SetOnDelete(fn func(paths []string, freedBytes int64)) {
+	s.onDelete = fn
+}
+
 // Start begins the cleanup service in a goroutine
 func (s *Service) Start() {
 	log.Printf("Starting cleanup service for %s (max: %d GB, %d files, interval: %v)",
@@ -113,6 +119,7 @@ func (s *Service) cleanup() {
 	// Delete oldest files until under limits
 	deletedCount := 0
 	var deletedSize int64
+	var deletedPaths []string
 
 	for _, file := range files {
 		// Check if we're now under limits
@@ -128,6 +135,7 @@ func (s *Service) cleanup() {
 
 		deletedCount++
 		deletedSize += file.Size
+		deletedPaths = append(deletedPaths, file.Path)
 		totalSize -= file.Size
 
 		log.Printf("Cleanup: Deleted %s (%.2f MB, modified: %s)",
@@ -141,6 +149,9 @@ func (s *Service) cleanup() {
 		deletedGB := float64(deletedSize) / (1024 * 1024 * 1024)
 		log.Printf("Cleanup: Deleted %d files (%.2f GB freed), remaining: %d files (%.2f GB)",
 			deletedCount, deletedGB, len(files)-deletedCount, remainingGB)
+		if s.onDelete != nil {
+			s.onDelete(deletedPaths, deletedSize)
+		}
 	}
 }
 
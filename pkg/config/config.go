@@ -16,9 +16,11 @@ type Config struct {
 	TelegramToken  string
 	TelegramChatID int64
 	TelegramAPIURL string
+	NotifyChatID   int64 // Chat for download status messages; 0 disables them
 	WebUsername    string
 	WebPassword    string
 	TRTGAPIURL     string // URL for trtg download API
+	ParserRules    string // JSON file with parser rules; empty for the built-in rules
 }
 
 // NewConfig creates a new configuration from environment variables
@@ -47,6 +49,13 @@ func NewConfig(skipTelegram bool) (*Config, error) {
 		apiURL = "http://localhost:8081" // Default for dry-run
 	}
 
+	var notifyChatID int64
+	if notifyChatIDStr := os.Getenv("TELEGRAM_NOTIFY_CHAT_ID"); notifyChatIDStr != "" {
+		if _, err := fmt.Sscanf(notifyChatIDStr, "%d", &notifyChatID); err != nil {
+			return nil, fmt.Errorf("invalid TELEGRAM_NOTIFY_CHAT_ID: %w", err)
+		}
+	}
+
 	torrentsFile := os.Getenv("TORRENTS_FILE")
 	if torrentsFile == "" {
 		torrentsFile = "torrents.txt"
@@ -85,9 +94,11 @@ func NewConfig(skipTelegram bool) (*Config, error) {
 		TelegramToken:  token,
 		TelegramChatID: chatID,
 		TelegramAPIURL: apiURL,
+		NotifyChatID:   notifyChatID,
 		WebUsername:    webUsername,
 		WebPassword:    webPassword,
 		TRTGAPIURL:     trtgAPIURL,
+		ParserRules:    os.Getenv("PARSER_RULES_FILE"),
 	}, nil
 }
 
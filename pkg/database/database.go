@@ -4,33 +4,51 @@ package database
 import (
 	"database/sql"
 	"fmt"
+	"log"
 	"strings"
 	"time"
 
 	_ "github.com/lib/pq"
+	"github.com/rusik69/trtg/pkg/parser"
 )
 
 // Video represents a downloaded file record
 // Note: Field names kept for backward compatibility with existing database schema
 type Video struct {
-	ID               int64
-	VideoID          string // Used as torrent URL/ID
-	ChannelURL       string // Used as torrent URL
-	Title            string
-	FilePath         string
-	DownloadedAt     time.Time
-	UploadedAt       *time.Time
-	TelegramFileID   string // Telegram file ID for downloading
-	TelegramFilePath string // Telegram file path for downloading (for large files)
-	TelegramMessageID int   // Telegram message ID for deleting messages
-	ShowName         string // Parsed show name
-	SeasonNumber     int    // Season number (0 for specials/unknown)
-	EpisodeNumber    int    // Episode number (0 if unknown)
+	ID                int64
+	VideoID           string // Used as torrent URL/ID
+	ChannelURL        string // Used as torrent URL
+	Title             string
+	FilePath          string
+	DownloadedAt      time.Time
+	UploadedAt        *time.Time
+	TelegramFileID    string         // Telegram file ID for downloading
+	TelegramFilePath  string         // Telegram file path for downloading (for large files)
+	TelegramMessageID int            // Telegram message ID for deleting messages
+	ShowName          string         // Parsed show name
+	SeasonNumber      int            // Season number (0 for specials/unknown)
+	EpisodeNumber     int            // Episode number (0 if unknown)
+	EpisodeEnd        int            // Last episode of a multi-episode file (0 for a single episode)
+	AirDate           *time.Time     // Air date of daily shows, nil if unknown
+	AbsoluteNumber    int            // Absolute episode number of anime releases (0 if unknown)
+	ParseConfidence   float64        // Parser confidence in the numbering, 0 to 1 (1 once corrected by hand)
+	ParseRule         string         // How the parser numbered the episode, see parser.VideoInfo.MatchedRule
+	EpisodeTitle      string         // Title set by hand, "" to show the file name
+	Release           parser.Release // Resolution, codecs, source, group and so on
+	MediaType         string         // parser.MediaEpisode, MediaMovie or MediaExtra
+	Movie             parser.Movie   // Title, year and edition of a movie
+}
+
+// Uploaded reports whether a video was uploaded to Telegram and can be played from there
+func (v Video) Uploaded() bool {
+	return v.UploadedAt != nil && v.TelegramFileID != ""
 }
 
 // DB wraps the PostgreSQL database connection
 type DB struct {
-	conn *sql.DB
+	conn    *sql.DB
+	url     string // Connection URL, for LISTEN connections
+	trigram bool   // The pg_trgm extension is available for ranking search results
 }
 
 // New creates a new database connection and initializes the schema
@@ -65,7 +83,7 @@ func New(dbURL string) (*DB, error) {
 		return nil, fmt.Errorf("failed to ping database after %d retries: %w", maxRetries, pingErr)
 	}
 
-	db := &DB{conn: conn}
+	db := &DB{conn: conn, url: dbURL}
 	if err := db.initSchema(); err != nil {
 		conn.Close()
 		return nil, err
@@ -115,6 +133,32 @@ func (db *DB) initSchema() error {
 	_, _ = db.conn.Exec("ALTER TABLE videos ADD COLUMN IF NOT EXISTS season_number INTEGER DEFAULT 0")
 	_, _ = db.conn.Exec("ALTER TABLE videos ADD COLUMN IF NOT EXISTS episode_number INTEGER DEFAULT 0")
 
+	// Add multi-episode, daily and absolute numbering columns
+	_, _ = db.conn.Exec("ALTER TABLE videos ADD COLUMN IF NOT EXISTS episode_end INTEGER DEFAULT 0")
+	_, _ = db.conn.Exec("ALTER TABLE videos ADD COLUMN IF NOT EXISTS air_date DATE")
+	_, _ = db.conn.Exec("ALTER TABLE videos ADD COLUMN IF NOT EXISTS absolute_number INTEGER DEFAULT 0")
+
+	// Add release metadata columns
+	for _, statement := range releaseSchema {
+		if _, err := db.conn.Exec(statement); err != nil {
+			return fmt.Errorf("failed to initialize release schema: %w", err)
+		}
+	}
+
+	// Add parse confidence columns and the corrections of the review queue
+	for _, statement := range reviewSchema {
+		if _, err := db.conn.Exec(statement); err != nil {
+			return fmt.Errorf("failed to initialize review schema: %w", err)
+		}
+	}
+
+	// Add the media type and movie columns
+	for _, statement := range movieSchema {
+		if _, err := db.conn.Exec(statement); err != nil {
+			return fmt.Errorf("failed to initialize movie schema: %w", err)
+		}
+	}
+
 	// Create indexes for season queries
 	_, _ = db.conn.Exec("CREATE INDEX IF NOT EXISTS idx_videos_show_name ON videos(show_name)")
 	_, _ = db.conn.Exec("CREATE INDEX IF NOT EXISTS idx_videos_season ON videos(show_name, season_number)")
@@ -137,6 +181,183 @@ func (db *DB) initSchema() error {
 	// This prevents duplicates in GetAllShows() grouping
 	_, _ = db.conn.Exec("UPDATE videos SET show_name = NULL WHERE show_name = ''")
 
+	// External subtitle files shipped with a video (e.g. .srt files in the torrent)
+	// Content is stored inline since subtitles are small and the source files are deleted after upload
+	subtitlesSchema := `
+	CREATE TABLE IF NOT EXISTS subtitles (
+		id SERIAL PRIMARY KEY,
+		video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+		language TEXT NOT NULL DEFAULT '',
+		label TEXT NOT NULL,
+		format TEXT NOT NULL,
+		content TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		UNIQUE(video_id, label)
+	);
+	CREATE INDEX IF NOT EXISTS idx_subtitles_video_id ON subtitles(video_id);
+	`
+	if _, err := db.conn.Exec(subtitlesSchema); err != nil {
+		return fmt.Errorf("failed to initialize subtitles schema: %w", err)
+	}
+
+	// Web users and their login sessions; only a hash of each session ID is stored
+	usersSchema := `
+	CREATE TABLE IF NOT EXISTS users (
+		id SERIAL PRIMARY KEY,
+		username TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		role TEXT NOT NULL DEFAULT 'viewer' CHECK (role IN ('admin', 'viewer')),
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE TABLE IF NOT EXISTS sessions (
+		id_hash TEXT PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		expires_at TIMESTAMP NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_sessions_expires_at ON sessions(expires_at);
+	`
+	if _, err := db.conn.Exec(usersSchema); err != nil {
+		return fmt.Errorf("failed to initialize users schema: %w", err)
+	}
+	// Users that log in with Telegram are matched by their Telegram user ID
+	_, _ = db.conn.Exec("ALTER TABLE users ADD COLUMN IF NOT EXISTS telegram_id BIGINT UNIQUE")
+
+	// Playback position per user and video for resuming and "Continue watching"
+	// Progress was first keyed by username; those rows move to the user's ID, and rows of
+	// users deleted or renamed since are dropped
+	progressSchema := `
+	DO $$
+	BEGIN
+		IF EXISTS (SELECT 1 FROM information_schema.columns
+			WHERE table_schema = current_schema() AND table_name = 'watch_progress' AND column_name = 'username') THEN
+			ALTER TABLE watch_progress ADD COLUMN IF NOT EXISTS user_id INTEGER REFERENCES users(id) ON DELETE CASCADE;
+			UPDATE watch_progress p SET user_id = u.id FROM users u WHERE u.username = p.username;
+			DELETE FROM watch_progress WHERE user_id IS NULL;
+			DROP INDEX IF EXISTS idx_watch_progress_recent;
+			ALTER TABLE watch_progress DROP CONSTRAINT IF EXISTS watch_progress_pkey;
+			ALTER TABLE watch_progress DROP COLUMN username;
+			ALTER TABLE watch_progress ALTER COLUMN user_id SET NOT NULL;
+			ALTER TABLE watch_progress ADD PRIMARY KEY (user_id, video_id);
+		END IF;
+	END $$;
+	CREATE TABLE IF NOT EXISTS watch_progress (
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		video_id INTEGER NOT NULL REFERENCES videos(id) ON DELETE CASCADE,
+		position_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+		duration_seconds DOUBLE PRECISION NOT NULL DEFAULT 0,
+		watched BOOLEAN NOT NULL DEFAULT FALSE,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		PRIMARY KEY (user_id, video_id)
+	);
+	CREATE INDEX IF NOT EXISTS idx_watch_progress_recent ON watch_progress(user_id, updated_at DESC);
+	`
+	if _, err := db.conn.Exec(progressSchema); err != nil {
+		return fmt.Errorf("failed to initialize watch progress schema: %w", err)
+	}
+
+	// Personal API tokens for scripts and clients; like sessions, only token hashes are stored
+	tokensSchema := `
+	CREATE TABLE IF NOT EXISTS api_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		name TEXT NOT NULL,
+		token_hash TEXT UNIQUE NOT NULL,
+		scope TEXT NOT NULL CHECK (scope IN ('read', 'admin')),
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		last_used_at TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_api_tokens_user_id ON api_tokens(user_id);
+	`
+	if _, err := db.conn.Exec(tokensSchema); err != nil {
+		return fmt.Errorf("failed to initialize API tokens schema: %w", err)
+	}
+
+	// Torrents queued from the bot or web UI for the download daemon
+	jobsSchema := `
+	CREATE TABLE IF NOT EXISTS torrent_jobs (
+		id SERIAL PRIMARY KEY,
+		torrent_url TEXT NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		file_indexes INTEGER[],
+		status TEXT NOT NULL DEFAULT 'queued',
+		progress DOUBLE PRECISION NOT NULL DEFAULT 0,
+		current_file TEXT NOT NULL DEFAULT '',
+		error TEXT NOT NULL DEFAULT '',
+		requested_by TEXT NOT NULL DEFAULT '',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_torrent_jobs_status ON torrent_jobs(status);
+	`
+	if _, err := db.conn.Exec(jobsSchema); err != nil {
+		return fmt.Errorf("failed to initialize torrent jobs schema: %w", err)
+	}
+
+	// Where to send notifications of events; a NULL show_name and empty events match everything
+	notificationsSchema := `
+	CREATE TABLE IF NOT EXISTS notification_subscriptions (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		kind TEXT NOT NULL CHECK (kind IN ('webhook', 'telegram', 'browser')),
+		target TEXT NOT NULL DEFAULT '',
+		secret TEXT NOT NULL DEFAULT '',
+		show_name TEXT,
+		events TEXT[] NOT NULL DEFAULT '{}',
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE INDEX IF NOT EXISTS idx_notification_subscriptions_user_id ON notification_subscriptions(user_id);
+	`
+	if _, err := db.conn.Exec(notificationsSchema); err != nil {
+		return fmt.Errorf("failed to initialize notifications schema: %w", err)
+	}
+
+	// Show names resolved per torrent name (see parser.CachedResolver)
+	nameResolutionsSchema := `
+	CREATE TABLE IF NOT EXISTS name_resolutions (
+		torrent_name TEXT PRIMARY KEY,
+		show_name TEXT NOT NULL,
+		source TEXT NOT NULL,
+		created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.conn.Exec(nameResolutionsSchema); err != nil {
+		return fmt.Errorf("failed to initialize name resolutions schema: %w", err)
+	}
+
+	// Responses of the show metadata provider (see metadata.Client)
+	metadataSchema := `
+	CREATE TABLE IF NOT EXISTS metadata_cache (
+		key TEXT PRIMARY KEY,
+		data JSONB NOT NULL,
+		fetched_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+	`
+	if _, err := db.conn.Exec(metadataSchema); err != nil {
+		return fmt.Errorf("failed to initialize metadata schema: %w", err)
+	}
+
+	// Trigram indexes for SearchVideos; pg_trgm is a trusted extension the database owner can create,
+	// and without it search still works, only unindexed and unranked
+	if _, err := db.conn.Exec("CREATE EXTENSION IF NOT EXISTS pg_trgm"); err != nil {
+		log.Printf("Warning: pg_trgm extension unavailable, search will not use indexes: %v", err)
+	} else {
+		db.trigram = true
+		for _, column := range []string{"show_name", "title", "file_path", "video_id"} {
+			_, _ = db.conn.Exec(fmt.Sprintf("CREATE INDEX IF NOT EXISTS idx_videos_%s_trgm ON videos USING GIN (%s gin_trgm_ops)", column, column))
+		}
+	}
+
+	// Show name normalization shared by the show, season, episode and gap queries
+	if _, err := db.conn.Exec(normalizeShowNameSchema); err != nil {
+		return fmt.Errorf("failed to initialize show name normalization: %w", err)
+	}
+
+	// Triggers announcing changes to other processes (see ListenChanges)
+	if _, err := db.conn.Exec(changeTriggersSchema); err != nil {
+		return fmt.Errorf("failed to initialize change triggers: %w", err)
+	}
+
 	return nil
 }
 
@@ -190,6 +411,42 @@ func (db *DB) AddVideo(videoID, channelURL, title, filePath, showName string, se
 	return nil
 }
 
+// AddParsedVideo adds a file with everything the parser found in its name and returns its ID
+// A file that is already tracked keeps its row, so corrections made by hand survive a re-download
+func (db *DB) AddParsedVideo(videoID, title, filePath string, info parser.VideoInfo) (int64, error) {
+	var id int64
+	err := db.conn.QueryRow("SELECT id FROM videos WHERE video_id = $1 AND file_path = $2", videoID, filePath).Scan(&id)
+	if err == nil {
+		return id, nil
+	}
+	if err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to check file: %w", err)
+	}
+
+	err = db.conn.QueryRow(
+		"INSERT INTO videos (video_id, channel_url, title, file_path, downloaded_at, show_name, season_number, episode_number) VALUES ($1, $1, $2, $3, $4, NULLIF($5, ''), $6, $7) RETURNING id",
+		videoID, title, filePath, time.Now(), info.ShowName, info.SeasonNumber, info.EpisodeNumber,
+	).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("failed to add file: %w", err)
+	}
+
+	var airDate *time.Time
+	if !info.AirDate.IsZero() {
+		airDate = &info.AirDate
+	}
+	if err := db.UpdateVideoNumbering(id, info.EpisodeEnd, airDate, info.AbsoluteNumber); err != nil {
+		return id, err
+	}
+	if err := db.UpdateVideoRelease(id, info.Release); err != nil {
+		return id, err
+	}
+	if err := db.UpdateVideoMedia(id, info.MediaType, info.Movie); err != nil {
+		return id, err
+	}
+	return id, db.UpdateVideoParse(id, info.Confidence, info.MatchedRule)
+}
+
 // UpdateTelegramFileID updates the Telegram file ID for a video
 func (db *DB) UpdateTelegramFileID(videoID, filePath, telegramFileID string) error {
 	_, err := db.conn.Exec(
@@ -276,7 +533,7 @@ func (db *DB) GetPendingUploads() ([]Video, error) {
 // GetAllVideos returns all downloaded files/torrents
 func (db *DB) GetAllVideos() ([]Video, error) {
 	rows, err := db.conn.Query(
-		"SELECT id, video_id, channel_url, title, file_path, downloaded_at, uploaded_at, telegram_file_id, telegram_file_path, COALESCE(telegram_message_id, 0), COALESCE(show_name, ''), COALESCE(season_number, 0), COALESCE(episode_number, 0) FROM videos ORDER BY downloaded_at DESC",
+		"SELECT id, video_id, channel_url, title, file_path, downloaded_at, uploaded_at, telegram_file_id, telegram_file_path, COALESCE(telegram_message_id, 0), COALESCE(show_name, ''), COALESCE(season_number, 0), COALESCE(episode_number, 0), " + metadataColumns + " FROM videos ORDER BY downloaded_at DESC",
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query files: %w", err)
@@ -289,7 +546,7 @@ func (db *DB) GetAllVideos() ([]Video, error) {
 		var uploadedAt sql.NullTime
 		var telegramFileID sql.NullString
 		var telegramFilePath sql.NullString
-		if err := rows.Scan(&v.ID, &v.VideoID, &v.ChannelURL, &v.Title, &v.FilePath, &v.DownloadedAt, &uploadedAt, &telegramFileID, &telegramFilePath, &v.TelegramMessageID, &v.ShowName, &v.SeasonNumber, &v.EpisodeNumber); err != nil {
+		if err := rows.Scan(append([]any{&v.ID, &v.VideoID, &v.ChannelURL, &v.Title, &v.FilePath, &v.DownloadedAt, &uploadedAt, &telegramFileID, &telegramFilePath, &v.TelegramMessageID, &v.ShowName, &v.SeasonNumber, &v.EpisodeNumber}, metadataFields(&v)...)...); err != nil {
 			return nil, fmt.Errorf("failed to scan file row: %w", err)
 		}
 		if uploadedAt.Valid {
@@ -315,9 +572,9 @@ func (db *DB) GetVideoByID(id int64) (*Video, error) {
 	var telegramFilePath sql.NullString
 
 	err := db.conn.QueryRow(
-		"SELECT id, video_id, channel_url, title, file_path, downloaded_at, uploaded_at, telegram_file_id, telegram_file_path, COALESCE(telegram_message_id, 0), COALESCE(show_name, ''), COALESCE(season_number, 0), COALESCE(episode_number, 0) FROM videos WHERE id = $1",
+		"SELECT id, video_id, channel_url, title, file_path, downloaded_at, uploaded_at, telegram_file_id, telegram_file_path, COALESCE(telegram_message_id, 0), COALESCE(show_name, ''), COALESCE(season_number, 0), COALESCE(episode_number, 0), "+metadataColumns+" FROM videos WHERE id = $1",
 		id,
-	).Scan(&v.ID, &v.VideoID, &v.ChannelURL, &v.Title, &v.FilePath, &v.DownloadedAt, &uploadedAt, &telegramFileID, &telegramFilePath, &v.TelegramMessageID, &v.ShowName, &v.SeasonNumber, &v.EpisodeNumber)
+	).Scan(append([]any{&v.ID, &v.VideoID, &v.ChannelURL, &v.Title, &v.FilePath, &v.DownloadedAt, &uploadedAt, &telegramFileID, &telegramFilePath, &v.TelegramMessageID, &v.ShowName, &v.SeasonNumber, &v.EpisodeNumber}, metadataFields(&v)...)...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -352,6 +609,45 @@ type Season struct {
 	EpisodeCount int `json:"episodeCount"`
 }
 
+// normalizeShowNameSchema creates normalize_show_name(raw_name), which strips version numbers, quality
+// tags and suffixes from show names so the episodes of one show group together. Every query that
+// groups or matches shows by name must use it, so that, say, deleting a show removes the episodes
+// listed under it
+const normalizeShowNameSchema = `
+CREATE OR REPLACE FUNCTION normalize_show_name(raw_name TEXT) RETURNS TEXT AS $$
+	SELECT TRIM(
+		REGEXP_REPLACE(
+			REGEXP_REPLACE(
+				REGEXP_REPLACE(
+					REGEXP_REPLACE(
+						REGEXP_REPLACE(
+							REGEXP_REPLACE(
+								REGEXP_REPLACE(
+									REGEXP_REPLACE(
+										REGEXP_REPLACE(
+											raw_name,
+											'\s+\d+\s*\([^)]*\)\s*$', '', 'g'  -- Remove " 13 (Mixed 10bit Mixed r00t)" - must be first
+										),
+										'\s+\d+\s+\d+.*$', '', 'g'  -- Remove " 9 10bit Silence)" - must be second
+									),
+									'\s+to \d+.*$', '', 'gi'  -- Remove "to 26 Mp4" type suffixes
+								),
+								'\s*\([^)]*\)\s*$', '', 'g'  -- Remove trailing parentheses like "(US)"
+							),
+							'\s*\[[^\]]*\]\s*$', '', 'g'  -- Remove trailing brackets
+						),
+						'\s+(US|UK|AU|CA)\s*$', '', 'gi'  -- Remove country suffixes
+					),
+					'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'  -- Remove "Complete", "5 Seasons", etc.
+				),
+				'\s+\d+\s*$', '', 'g'  -- Remove trailing numbers like "13" - must be last
+			),
+			'\s+', ' ', 'g'  -- Normalize multiple spaces
+		)
+	)
+$$ LANGUAGE SQL IMMUTABLE;
+`
+
 // GetAllShows returns all unique shows with their season and episode counts
 // Groups by show_name (parsed from torrents), falling back to title if show_name is not available
 // Normalizes show names to merge duplicates (e.g., "King of the Hill" and "King of the Hill 13 (Mixed 10bit Mixed r00t)")
@@ -366,41 +662,11 @@ func (db *DB) GetAllShows() ([]Show, error) {
 				season_number,
 				id
 			FROM videos
-			WHERE uploaded_at IS NOT NULL
+			WHERE uploaded_at IS NOT NULL AND media_type != 'movie'
 		),
 		cleaned_shows AS (
 			SELECT
-				-- Normalize show names by removing version numbers, quality tags, and common suffixes
-				TRIM(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												REGEXP_REPLACE(
-													REGEXP_REPLACE(
-														raw_name,
-														'\s+\d+\s*\([^)]*\)\s*$', '', 'g'  -- Remove " 13 (Mixed 10bit Mixed r00t)" - must be first
-													),
-													'\s+\d+\s+\d+.*$', '', 'g'  -- Remove " 9 10bit Silence)" - must be second
-												),
-												'\s+to \d+.*$', '', 'gi'  -- Remove "to 26 Mp4" type suffixes
-											),
-											'\s*\([^)]*\)\s*$', '', 'g'  -- Remove trailing parentheses like "(US)"
-										),
-										'\s*\[[^\]]*\]\s*$', '', 'g'  -- Remove trailing brackets
-									),
-									'\s+(US|UK|AU|CA)\s*$', '', 'gi'  -- Remove country suffixes
-								),
-								'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'  -- Remove "Complete", "5 Seasons", etc.
-							),
-							'\s+\d+\s*$', '', 'g'  -- Remove trailing numbers like "13" - must be last
-						),
-						'\s+', ' ', 'g'  -- Normalize multiple spaces
-					)
-				) as normalized_name,
+				normalize_show_name(raw_name) as normalized_name,
 				season_number,
 				id
 			FROM normalized_shows
@@ -451,39 +717,11 @@ func (db *DB) GetSeasonsByShow(showName string) ([]Season, error) {
 				season_number,
 				id
 			FROM videos
+			WHERE media_type != 'movie'
 		),
 		cleaned_videos AS (
 			SELECT
-				TRIM(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												REGEXP_REPLACE(
-													REGEXP_REPLACE(
-														raw_name,
-														'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-													),
-													'\s+\d+\s+\d+.*$', '', 'g'
-												),
-												'\s+to \d+.*$', '', 'gi'
-											),
-											'\s*\([^)]*\)\s*$', '', 'g'
-										),
-										'\s*\[[^\]]*\]\s*$', '', 'g'
-									),
-									'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-								),
-								'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-							),
-							'\s+\d+\s*$', '', 'g'
-						),
-						'\s+', ' ', 'g'
-					)
-				) as normalized_name,
+				normalize_show_name(raw_name) as normalized_name,
 				season_number,
 				id
 			FROM normalized_videos
@@ -492,36 +730,7 @@ func (db *DB) GetSeasonsByShow(showName string) ([]Season, error) {
 			season_number,
 			COUNT(*) as episode_count
 		FROM cleaned_videos
-		WHERE normalized_name = TRIM(
-			REGEXP_REPLACE(
-				REGEXP_REPLACE(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												$1,
-												'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-											),
-											'\s+\d+\s+\d+.*$', '', 'g'
-										),
-										'\s+to \d+.*$', '', 'gi'
-									),
-									'\s*\([^)]*\)\s*$', '', 'g'
-								),
-								'\s*\[[^\]]*\]\s*$', '', 'g'
-							),
-							'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-						),
-						'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-					),
-					'\s+\d+\s*$', '', 'g'
-				),
-				'\s+', ' ', 'g'
-			)
-		)
+		WHERE normalized_name = normalize_show_name($1)
 		GROUP BY season_number
 		ORDER BY season_number
 	`, showName)
@@ -555,82 +764,29 @@ func (db *DB) GetEpisodesByShowAndSeason(showName string, seasonNumber int) ([]V
 				END as raw_name,
 				COALESCE(show_name, '') as show_name,
 				COALESCE(season_number, 0) as season_number,
-				COALESCE(episode_number, 0) as episode_number
+				COALESCE(episode_number, 0) as episode_number,
+				COALESCE(episode_end, 0) as episode_end, air_date, COALESCE(absolute_number, 0) as absolute_number,
+				COALESCE(parse_confidence, 0) as parse_confidence, COALESCE(parse_rule, '') as parse_rule,
+				COALESCE(episode_title, '') as episode_title,
+				`+releaseColumns+`, `+movieColumns+`
 			FROM videos
+			WHERE media_type != 'movie'
 		),
 		cleaned_videos AS (
 			SELECT
 				id, video_id, channel_url, title, file_path, downloaded_at,
 				uploaded_at, telegram_file_id, telegram_file_path, telegram_message_id,
-				show_name, season_number, episode_number,
-				TRIM(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												REGEXP_REPLACE(
-													REGEXP_REPLACE(
-														raw_name,
-														'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-													),
-													'\s+\d+\s+\d+.*$', '', 'g'
-												),
-												'\s+to \d+.*$', '', 'gi'
-											),
-											'\s*\([^)]*\)\s*$', '', 'g'
-										),
-										'\s*\[[^\]]*\]\s*$', '', 'g'
-									),
-									'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-								),
-								'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-							),
-							'\s+\d+\s*$', '', 'g'
-						),
-						'\s+', ' ', 'g'
-					)
-				) as normalized_name
+				show_name, season_number, episode_number, episode_end, air_date, absolute_number, parse_confidence, parse_rule, episode_title, `+releaseColumns+`, `+movieColumns+`,
+				normalize_show_name(raw_name) as normalized_name
 			FROM normalized_videos
 		)
 		SELECT
 			id, video_id, channel_url, title, file_path, downloaded_at,
 			uploaded_at, telegram_file_id, telegram_file_path, telegram_message_id,
-			show_name, season_number, episode_number
+			show_name, season_number, episode_number, episode_end, air_date, absolute_number, parse_confidence, parse_rule, episode_title, `+releaseColumns+`, `+movieColumns+`
 		FROM cleaned_videos
-		WHERE normalized_name = TRIM(
-			REGEXP_REPLACE(
-				REGEXP_REPLACE(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												$1,
-												'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-											),
-											'\s+\d+\s+\d+.*$', '', 'g'
-										),
-										'\s+to \d+.*$', '', 'gi'
-									),
-									'\s*\([^)]*\)\s*$', '', 'g'
-								),
-								'\s*\[[^\]]*\]\s*$', '', 'g'
-							),
-							'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-						),
-						'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-					),
-					'\s+\d+\s*$', '', 'g'
-				),
-				'\s+', ' ', 'g'
-			)
-		) AND season_number = $2
-		ORDER BY episode_number, file_path
+		WHERE normalized_name = normalize_show_name($1) AND season_number = $2
+		ORDER BY episode_number, air_date, file_path
 	`, showName, seasonNumber)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query episodes: %w", err)
@@ -643,7 +799,7 @@ func (db *DB) GetEpisodesByShowAndSeason(showName string, seasonNumber int) ([]V
 		var uploadedAt sql.NullTime
 		var telegramFileID sql.NullString
 		var telegramFilePath sql.NullString
-		if err := rows.Scan(&v.ID, &v.VideoID, &v.ChannelURL, &v.Title, &v.FilePath, &v.DownloadedAt, &uploadedAt, &telegramFileID, &telegramFilePath, &v.TelegramMessageID, &v.ShowName, &v.SeasonNumber, &v.EpisodeNumber); err != nil {
+		if err := rows.Scan(append([]any{&v.ID, &v.VideoID, &v.ChannelURL, &v.Title, &v.FilePath, &v.DownloadedAt, &uploadedAt, &telegramFileID, &telegramFilePath, &v.TelegramMessageID, &v.ShowName, &v.SeasonNumber, &v.EpisodeNumber}, metadataFields(&v)...)...); err != nil {
 			return nil, fmt.Errorf("failed to scan episode row: %w", err)
 		}
 		if uploadedAt.Valid {
@@ -661,6 +817,35 @@ func (db *DB) GetEpisodesByShowAndSeason(showName string, seasonNumber int) ([]V
 	return videos, rows.Err()
 }
 
+// metadataColumns selects the numbering, parse result, release metadata and media type of a video, in the order metadataFields scans them
+const metadataColumns = numberingColumns + ", " + parseColumns + ", " + releaseColumns + ", " + movieColumns
+
+// metadataFields returns the scan destinations for metadataColumns
+func metadataFields(v *Video) []any {
+	fields := append(append(numberingFields(v), parseFields(v)...), releaseFields(&v.Release)...)
+	return append(fields, movieFields(v)...)
+}
+
+// numberingColumns selects the episode range, air date and absolute number, in the order numberingFields scans them
+const numberingColumns = "COALESCE(episode_end, 0), air_date, COALESCE(absolute_number, 0)"
+
+// numberingFields returns the scan destinations for numberingColumns
+func numberingFields(v *Video) []any {
+	return []any{&v.EpisodeEnd, &v.AirDate, &v.AbsoluteNumber}
+}
+
+// UpdateVideoNumbering stores the last episode of a multi-episode file, the air date and the absolute number of a video
+func (db *DB) UpdateVideoNumbering(id int64, episodeEnd int, airDate *time.Time, absoluteNumber int) error {
+	_, err := db.conn.Exec(
+		"UPDATE videos SET episode_end = $1, air_date = $2, absolute_number = $3 WHERE id = $4",
+		episodeEnd, airDate, absoluteNumber, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update video numbering: %w", err)
+	}
+	return nil
+}
+
 // UpdateVideoInfo updates the show name, season, and episode information for a video
 func (db *DB) UpdateVideoInfo(id int64, showName string, seasonNumber, episodeNumber int) error {
 	_, err := db.conn.Exec(
@@ -675,6 +860,7 @@ func (db *DB) UpdateVideoInfo(id int64, showName string, seasonNumber, episodeNu
 
 // MoveEpisodesWithoutEpisodeNumbersToExtras moves episodes that don't have episode numbers to season 0 (extras)
 // This is useful for shows like King of the Hill where some files are extras/specials without proper episode numbers
+// Daily episodes, which are numbered by air date instead, stay in their season
 func (db *DB) MoveEpisodesWithoutEpisodeNumbersToExtras(showName string) (int64, error) {
 	// Use normalized show name matching
 	result, err := db.conn.Exec(`
@@ -694,72 +880,14 @@ func (db *DB) MoveEpisodesWithoutEpisodeNumbersToExtras(showName string) (int64,
 				id,
 				season_number,
 				episode_number,
-				TRIM(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												REGEXP_REPLACE(
-													REGEXP_REPLACE(
-														raw_name,
-														'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-													),
-													'\s+\d+\s+\d+.*$', '', 'g'
-												),
-												'\s+to \d+.*$', '', 'gi'
-											),
-											'\s*\([^)]*\)\s*$', '', 'g'
-										),
-										'\s*\[[^\]]*\]\s*$', '', 'g'
-									),
-									'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-								),
-								'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-							),
-							'\s+\d+\s*$', '', 'g'
-						),
-						'\s+', ' ', 'g'
-					)
-				) as normalized_name
+				normalize_show_name(raw_name) as normalized_name
 			FROM normalized_videos
 		)
 		UPDATE videos
 		SET season_number = 0, episode_number = 0
-		WHERE id IN (
+		WHERE air_date IS NULL AND id IN (
 			SELECT id FROM cleaned_videos
-			WHERE normalized_name = TRIM(
-				REGEXP_REPLACE(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												REGEXP_REPLACE(
-													$1,
-													'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-												),
-												'\s+\d+\s+\d+.*$', '', 'g'
-											),
-											'\s+to \d+.*$', '', 'gi'
-										),
-										'\s*\([^)]*\)\s*$', '', 'g'
-									),
-									'\s*\[[^\]]*\]\s*$', '', 'g'
-								),
-								'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-							),
-							'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-						),
-						'\s+\d+\s*$', '', 'g'
-					),
-					'\s+', ' ', 'g'
-				)
-			)
+			WHERE normalized_name = normalize_show_name($1)
 			AND season_number > 0
 			AND episode_number = 0
 		)
@@ -832,36 +960,7 @@ func (db *DB) DeleteVideosByShow(showName string) ([]Video, error) {
 				id, video_id, channel_url, title, file_path, downloaded_at,
 				uploaded_at, telegram_file_id, telegram_file_path, telegram_message_id,
 				show_name, season_number, episode_number,
-				TRIM(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												REGEXP_REPLACE(
-													REGEXP_REPLACE(
-														raw_name,
-														'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-													),
-													'\s+\d+\s+\d+.*$', '', 'g'
-												),
-												'\s+to \d+.*$', '', 'gi'
-											),
-											'\s*\([^)]*\)\s*$', '', 'g'
-										),
-										'\s*\[[^\]]*\]\s*$', '', 'g'
-									),
-									'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-								),
-								'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-							),
-							'\s+\d+\s*$', '', 'g'
-						),
-						'\s+', ' ', 'g'
-					)
-				) as normalized_name
+				normalize_show_name(raw_name) as normalized_name
 			FROM normalized_videos
 		)
 		SELECT
@@ -869,36 +968,7 @@ func (db *DB) DeleteVideosByShow(showName string) ([]Video, error) {
 			uploaded_at, telegram_file_id, telegram_file_path, telegram_message_id,
 			show_name, season_number, episode_number
 		FROM cleaned_videos
-		WHERE normalized_name = TRIM(
-			REGEXP_REPLACE(
-				REGEXP_REPLACE(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												$1,
-												'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-											),
-											'\s+\d+\s+\d+.*$', '', 'g'
-										),
-										'\s+to \d+.*$', '', 'gi'
-									),
-									'\s*\([^)]*\)\s*$', '', 'g'
-								),
-								'\s*\[[^\]]*\]\s*$', '', 'g'
-							),
-							'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-						),
-						'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-					),
-					'\s+\d+\s*$', '', 'g'
-				),
-				'\s+', ' ', 'g'
-			)
-		)
+		WHERE normalized_name = normalize_show_name($1)
 	`, showName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query videos: %w", err)
@@ -940,65 +1010,13 @@ func (db *DB) DeleteVideosByShow(showName string) ([]Video, error) {
 		cleaned_videos AS (
 			SELECT
 				id,
-				TRIM(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												REGEXP_REPLACE(
-													REGEXP_REPLACE(
-														raw_name,
-														'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-													),
-													'\s+\d+\s+\d+.*$', '', 'g'
-												),
-												'\s+to \d+.*$', '', 'gi'
-											),
-											'\s*\([^)]*\)\s*$', '', 'g'
-										),
-										'\s*\[[^\]]*\]\s*$', '', 'g'
-									),
-									'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-								),
-								'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-							),
-							'\s+\d+\s*$', '', 'g'
-						),
-						'\s+', ' ', 'g'
-					)
-				) as normalized_name
+				normalize_show_name(raw_name) as normalized_name
 			FROM normalized_videos
 		)
 		DELETE FROM videos
 		WHERE id IN (
 			SELECT id FROM cleaned_videos
-			WHERE normalized_name = TRIM(
-				REGEXP_REPLACE(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											$1,
-											'\s+\d+\s*$', '', 'g'
-										),
-										'\s*\([^)]*\)\s*$', '', 'g'
-									),
-									'\s*\[[^\]]*\]\s*$', '', 'g'
-								),
-								'\s+(US|UK|AU|CA)$', '', 'gi'
-							),
-							'\s+(Complete|Collection|Box Set|Seasons? \d+.*)$', '', 'gi'
-						),
-						'\s+to \d+.*$', '', 'gi'
-					),
-					'\s+', ' ', 'g'
-				)
-			)
+			WHERE normalized_name = normalize_show_name($1)
 		)
 	`, showName)
 	if err != nil {
@@ -1029,36 +1047,7 @@ func (db *DB) DeleteVideosByShowAndSeason(showName string, seasonNumber int) ([]
 				id, video_id, channel_url, title, file_path, downloaded_at,
 				uploaded_at, telegram_file_id, telegram_file_path, telegram_message_id,
 				show_name, season_number, episode_number,
-				TRIM(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												REGEXP_REPLACE(
-													REGEXP_REPLACE(
-														raw_name,
-														'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-													),
-													'\s+\d+\s+\d+.*$', '', 'g'
-												),
-												'\s+to \d+.*$', '', 'gi'
-											),
-											'\s*\([^)]*\)\s*$', '', 'g'
-										),
-										'\s*\[[^\]]*\]\s*$', '', 'g'
-									),
-									'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-								),
-								'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-							),
-							'\s+\d+\s*$', '', 'g'
-						),
-						'\s+', ' ', 'g'
-					)
-				) as normalized_name
+				normalize_show_name(raw_name) as normalized_name
 			FROM normalized_videos
 		)
 		SELECT
@@ -1066,36 +1055,7 @@ func (db *DB) DeleteVideosByShowAndSeason(showName string, seasonNumber int) ([]
 			uploaded_at, telegram_file_id, telegram_file_path, telegram_message_id,
 			show_name, season_number, episode_number
 		FROM cleaned_videos
-		WHERE normalized_name = TRIM(
-			REGEXP_REPLACE(
-				REGEXP_REPLACE(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												$1,
-												'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-											),
-											'\s+\d+\s+\d+.*$', '', 'g'
-										),
-										'\s+to \d+.*$', '', 'gi'
-									),
-									'\s*\([^)]*\)\s*$', '', 'g'
-								),
-								'\s*\[[^\]]*\]\s*$', '', 'g'
-							),
-							'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-						),
-						'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-					),
-					'\s+\d+\s*$', '', 'g'
-				),
-				'\s+', ' ', 'g'
-			)
-		) AND season_number = $2
+		WHERE normalized_name = normalize_show_name($1) AND season_number = $2
 	`, showName, seasonNumber)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query videos: %w", err)
@@ -1139,65 +1099,13 @@ func (db *DB) DeleteVideosByShowAndSeason(showName string, seasonNumber int) ([]
 			SELECT
 				id,
 				season_number,
-				TRIM(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											REGEXP_REPLACE(
-												REGEXP_REPLACE(
-													REGEXP_REPLACE(
-														raw_name,
-														'\s+\d+\s*\([^)]*\)\s*$', '', 'g'
-													),
-													'\s+\d+\s+\d+.*$', '', 'g'
-												),
-												'\s+to \d+.*$', '', 'gi'
-											),
-											'\s*\([^)]*\)\s*$', '', 'g'
-										),
-										'\s*\[[^\]]*\]\s*$', '', 'g'
-									),
-									'\s+(US|UK|AU|CA)\s*$', '', 'gi'
-								),
-								'\s+(Complete|Collection|Box Set|Seasons? \d+.*)\s*$', '', 'gi'
-							),
-							'\s+\d+\s*$', '', 'g'
-						),
-						'\s+', ' ', 'g'
-					)
-				) as normalized_name
+				normalize_show_name(raw_name) as normalized_name
 			FROM normalized_videos
 		)
 		DELETE FROM videos
 		WHERE id IN (
 			SELECT id FROM cleaned_videos
-			WHERE normalized_name = TRIM(
-				REGEXP_REPLACE(
-					REGEXP_REPLACE(
-						REGEXP_REPLACE(
-							REGEXP_REPLACE(
-								REGEXP_REPLACE(
-									REGEXP_REPLACE(
-										REGEXP_REPLACE(
-											$1,
-											'\s+\d+\s*$', '', 'g'
-										),
-										'\s*\([^)]*\)\s*$', '', 'g'
-									),
-									'\s*\[[^\]]*\]\s*$', '', 'g'
-								),
-								'\s+(US|UK|AU|CA)$', '', 'gi'
-							),
-							'\s+(Complete|Collection|Box Set|Seasons? \d+.*)$', '', 'gi'
-						),
-						'\s+to \d+.*$', '', 'gi'
-					),
-					'\s+', ' ', 'g'
-				)
-			) AND season_number = $2
+			WHERE normalized_name = normalize_show_name($1) AND season_number = $2
 		)
 	`, showName, seasonNumber)
 	if err != nil {
@@ -1206,3 +1114,45 @@ func (db *DB) DeleteVideosByShowAndSeason(showName string, seasonNumber int) ([]
 
 	return videos, nil
 }
+
+// likeEscaper escapes LIKE wildcards so user input matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// SearchEpisodes returns uploaded episodes whose show name contains query (case-insensitive)
+// ordered by show, season and episode
+func (db *DB) SearchEpisodes(query string, limit int) ([]Video, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, video_id, channel_url, title, file_path, downloaded_at, uploaded_at, telegram_file_id, telegram_file_path, COALESCE(telegram_message_id, 0), COALESCE(show_name, ''), COALESCE(season_number, 0), COALESCE(episode_number, 0)
+		FROM videos
+		WHERE uploaded_at IS NOT NULL AND COALESCE(show_name, title) ILIKE '%' || $1 || '%'
+		ORDER BY COALESCE(show_name, title), season_number, episode_number, file_path
+		LIMIT $2
+	`, likeEscaper.Replace(query), limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search episodes: %w", err)
+	}
+	defer rows.Close()
+
+	var videos []Video
+	for rows.Next() {
+		var v Video
+		var uploadedAt sql.NullTime
+		var telegramFileID sql.NullString
+		var telegramFilePath sql.NullString
+		if err := rows.Scan(&v.ID, &v.VideoID, &v.ChannelURL, &v.Title, &v.FilePath, &v.DownloadedAt, &uploadedAt, &telegramFileID, &telegramFilePath, &v.TelegramMessageID, &v.ShowName, &v.SeasonNumber, &v.EpisodeNumber); err != nil {
+			return nil, fmt.Errorf("failed to scan file row: %w", err)
+		}
+		if uploadedAt.Valid {
+			v.UploadedAt = &uploadedAt.Time
+		}
+		if telegramFileID.Valid {
+			v.TelegramFileID = telegramFileID.String
+		}
+		if telegramFilePath.Valid {
+			v.TelegramFilePath = telegramFilePath.String
+		}
+		videos = append(videos, v)
+	}
+
+	return videos, rows.Err()
+}
@@ -0,0 +1,185 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ChangesChannel is the Postgres NOTIFY channel the change triggers publish on
+const ChangesChannel = "trtg_changes"
+
+// Change tables and operations
+const (
+	ChangeVideos      = "videos"
+	ChangeTorrentJobs = "torrent_jobs"
+	ChangeInsert      = "INSERT"
+	ChangeUpdate      = "UPDATE"
+	ChangeDelete      = "DELETE"
+	// ChangeResync is sent after the listener reconnected; notifications may have been missed
+	ChangeResync = "RESYNC"
+)
+
+// ChangeEvent describes a change to a video or torrent job, sent by database triggers
+type ChangeEvent struct {
+	Table string `json:"table"`
+	Op    string `json:"op"`
+	ID    int64  `json:"id"`
+
+	// Videos; the old values are set for updates so pages showing the previous location can refresh
+	Show        string `json:"show,omitempty"`
+	Season      int    `json:"season"`
+	Episode     int    `json:"episode"`
+	Uploaded    bool   `json:"uploaded"`
+	OldShow     string `json:"oldShow,omitempty"`
+	OldSeason   int    `json:"oldSeason"`
+	WasUploaded bool   `json:"wasUploaded"`
+
+	// Torrent jobs
+	Name      string `json:"name,omitempty"`
+	Status    string `json:"status,omitempty"`
+	OldStatus string `json:"oldStatus,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// changeTriggersSchema creates triggers that NOTIFY ChangesChannel with a ChangeEvent as JSON
+// Payloads stay small because NOTIFY is limited to 8000 bytes
+const changeTriggersSchema = `
+CREATE OR REPLACE FUNCTION trtg_notify_video_change() RETURNS trigger AS $$
+DECLARE
+	payload JSON;
+BEGIN
+	IF TG_OP = 'DELETE' THEN
+		payload := json_build_object('table', 'videos', 'op', TG_OP, 'id', OLD.id,
+			'show', COALESCE(OLD.show_name, ''), 'season', COALESCE(OLD.season_number, 0), 'episode', COALESCE(OLD.episode_number, 0),
+			'uploaded', OLD.uploaded_at IS NOT NULL);
+	ELSIF TG_OP = 'INSERT' THEN
+		payload := json_build_object('table', 'videos', 'op', TG_OP, 'id', NEW.id,
+			'show', COALESCE(NEW.show_name, ''), 'season', COALESCE(NEW.season_number, 0), 'episode', COALESCE(NEW.episode_number, 0),
+			'uploaded', NEW.uploaded_at IS NOT NULL);
+	ELSE
+		IF OLD IS NOT DISTINCT FROM NEW THEN
+			RETURN NULL;
+		END IF;
+		payload := json_build_object('table', 'videos', 'op', TG_OP, 'id', NEW.id,
+			'show', COALESCE(NEW.show_name, ''), 'season', COALESCE(NEW.season_number, 0), 'episode', COALESCE(NEW.episode_number, 0),
+			'uploaded', NEW.uploaded_at IS NOT NULL,
+			'oldShow', COALESCE(OLD.show_name, ''), 'oldSeason', COALESCE(OLD.season_number, 0), 'wasUploaded', OLD.uploaded_at IS NOT NULL);
+	END IF;
+	PERFORM pg_notify('trtg_changes', payload::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER trtg_videos_changes
+	AFTER INSERT OR UPDATE OR DELETE ON videos
+	FOR EACH ROW EXECUTE FUNCTION trtg_notify_video_change();
+
+CREATE OR REPLACE FUNCTION trtg_notify_job_change() RETURNS trigger AS $$
+DECLARE
+	old_status TEXT := '';
+BEGIN
+	IF TG_OP = 'UPDATE' THEN
+		-- Progress updates are too frequent to announce; only status changes are
+		IF OLD.status = NEW.status THEN
+			RETURN NULL;
+		END IF;
+		old_status := OLD.status;
+	END IF;
+	PERFORM pg_notify('trtg_changes', json_build_object('table', 'torrent_jobs', 'op', TG_OP, 'id', NEW.id,
+		'name', left(NEW.name, 500), 'status', NEW.status, 'oldStatus', old_status, 'error', left(NEW.error, 1000))::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER trtg_torrent_jobs_changes
+	AFTER INSERT OR UPDATE ON torrent_jobs
+	FOR EACH ROW EXECUTE FUNCTION trtg_notify_job_change();
+`
+
+// ListenChanges calls handle for every ChangeEvent until ctx is cancelled
+// It uses its own connection, which reconnects automatically; after a reconnect
+// handle receives an event with Op ChangeResync because changes may have been missed
+func (db *DB) ListenChanges(ctx context.Context, handle func(ChangeEvent)) error {
+	listener := pq.NewListener(db.url, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("Warning: Change listener: %v", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(ChangesChannel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", ChangesChannel, err)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case n := <-listener.Notify:
+			if n == nil {
+				// The connection was re-established
+				handle(ChangeEvent{Op: ChangeResync})
+				continue
+			}
+			var e ChangeEvent
+			if err := json.Unmarshal([]byte(n.Extra), &e); err != nil {
+				log.Printf("Warning: Invalid change notification %q: %v", n.Extra, err)
+				continue
+			}
+			handle(e)
+		case <-time.After(90 * time.Second):
+			// Detect dead connections that would otherwise go unnoticed
+			go listener.Ping()
+		}
+	}
+}
+
+// ChangesSince returns changes for the videos uploaded and the torrent jobs finished since a time,
+// so a listener can catch up on notifications it missed
+// Only the current state is known: videos are reported as uploaded updates, jobs with their status
+func (db *DB) ChangesSince(since time.Time) ([]ChangeEvent, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, COALESCE(show_name, ''), COALESCE(season_number, 0), COALESCE(episode_number, 0)
+		FROM videos WHERE uploaded_at > $1
+		ORDER BY uploaded_at
+	`, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query uploaded videos: %w", err)
+	}
+	var changes []ChangeEvent
+	for rows.Next() {
+		e := ChangeEvent{Table: ChangeVideos, Op: ChangeUpdate, Uploaded: true}
+		if err := rows.Scan(&e.ID, &e.Show, &e.Season, &e.Episode); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan uploaded video: %w", err)
+		}
+		changes = append(changes, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	jobs, err := db.conn.Query(`
+		SELECT id, name, status, error FROM torrent_jobs
+		WHERE updated_at > $1 AND status IN ($2, $3, $4)
+		ORDER BY updated_at
+	`, since, JobDone, JobFailed, JobCancelled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query finished torrent jobs: %w", err)
+	}
+	defer jobs.Close()
+	for jobs.Next() {
+		e := ChangeEvent{Table: ChangeTorrentJobs, Op: ChangeUpdate}
+		if err := jobs.Scan(&e.ID, &e.Name, &e.Status, &e.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan finished torrent job: %w", err)
+		}
+		changes = append(changes, e)
+	}
+	return changes, jobs.Err()
+}
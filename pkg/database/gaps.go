@@ -0,0 +1,135 @@
+package database
+
+import (
+	"fmt"
+	"sort"
+)
+
+// maxEpisodeRange bounds the episodes a multi-episode file spans, so a misparsed range such as
+// "E01-E2020" does not count as two thousand episodes
+const maxEpisodeRange = 50
+
+// SeasonGaps lists the episodes missing from a season
+type SeasonGaps struct {
+	SeasonNumber int   `json:"seasonNumber"`
+	Expected     int   `json:"expected"` // Number of the last episode of the season
+	Official     bool  `json:"official"` // Expected comes from the metadata provider, not the highest episode present
+	Present      int   `json:"present"`
+	Missing      []int `json:"missing"`
+}
+
+// GetShowEpisodeNumbers returns the episode numbers present in each season of a show (matches
+// normalized show names). Multi-episode files count for every episode they span; specials and
+// files without an episode number are left out
+func (db *DB) GetShowEpisodeNumbers(showName string) (map[int][]int, error) {
+	if showName == "" {
+		return map[int][]int{}, nil
+	}
+	shows, err := db.queryEpisodeNumbers(showName)
+	if err != nil {
+		return nil, err
+	}
+	seasons := map[int][]int{}
+	for _, show := range shows {
+		for season, episodes := range show {
+			seasons[season] = append(seasons[season], episodes...)
+		}
+	}
+	for season := range seasons {
+		sort.Ints(seasons[season])
+	}
+	return seasons, nil
+}
+
+// GetAllEpisodeNumbers returns the episode numbers present in each season of every show, by
+// normalized show name, as GetShowEpisodeNumbers does for one show
+func (db *DB) GetAllEpisodeNumbers() (map[string]map[int][]int, error) {
+	return db.queryEpisodeNumbers("")
+}
+
+// queryEpisodeNumbers returns the episode numbers per season of the shows whose normalized name
+// matches showName, or of all shows if it is empty
+func (db *DB) queryEpisodeNumbers(showName string) (map[string]map[int][]int, error) {
+	rows, err := db.conn.Query(`
+		WITH normalized_videos AS (
+			SELECT
+				CASE
+					WHEN show_name IS NOT NULL AND show_name != '' THEN show_name
+					ELSE title
+				END as raw_name,
+				season_number,
+				episode_number,
+				CASE
+					WHEN COALESCE(episode_end, 0) > episode_number AND episode_end - episode_number <= $2 THEN episode_end
+					ELSE episode_number
+				END as episode_end
+			FROM videos
+			WHERE season_number > 0 AND episode_number > 0
+		),
+		cleaned_videos AS (
+			SELECT
+				normalize_show_name(raw_name) as normalized_name,
+				season_number,
+				episode_number,
+				episode_end
+			FROM normalized_videos
+		)
+		SELECT DISTINCT
+			normalized_name,
+			season_number,
+			generate_series(episode_number, episode_end) as episode
+		FROM cleaned_videos
+		WHERE normalized_name != '' AND ($1 = '' OR normalized_name = normalize_show_name($1))
+		ORDER BY normalized_name, season_number, episode
+	`, showName, maxEpisodeRange)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query episode numbers: %w", err)
+	}
+	defer rows.Close()
+
+	shows := map[string]map[int][]int{}
+	for rows.Next() {
+		var name string
+		var season, episode int
+		if err := rows.Scan(&name, &season, &episode); err != nil {
+			return nil, fmt.Errorf("failed to scan episode number row: %w", err)
+		}
+		if shows[name] == nil {
+			shows[name] = map[int][]int{}
+		}
+		shows[name][season] = append(shows[name][season], episode)
+	}
+
+	return shows, rows.Err()
+}
+
+// FindGaps returns the missing episodes of each season from the episode numbers present, sorted
+// by season. official holds the number of the last aired episode of the seasons the metadata
+// provider knows, and may be nil; other seasons are checked against their highest episode present
+func FindGaps(seasons map[int][]int, official map[int]int) []SeasonGaps {
+	gaps := make([]SeasonGaps, 0, len(seasons))
+	for season, episodes := range seasons {
+		present := map[int]bool{}
+		highest := 0
+		for _, episode := range episodes {
+			present[episode] = true
+			if episode > highest {
+				highest = episode
+			}
+		}
+
+		g := SeasonGaps{SeasonNumber: season, Expected: highest, Present: len(present), Missing: []int{}}
+		if last, ok := official[season]; ok && last > 0 {
+			g.Expected = last
+			g.Official = true
+		}
+		for episode := 1; episode <= g.Expected; episode++ {
+			if !present[episode] {
+				g.Missing = append(g.Missing, episode)
+			}
+		}
+		gaps = append(gaps, g)
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i].SeasonNumber < gaps[j].SeasonNumber })
+	return gaps
+}
@@ -0,0 +1,113 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindGaps(t *testing.T) {
+	tests := []struct {
+		name     string
+		seasons  map[int][]int
+		official map[int]int
+		expected []SeasonGaps
+	}{
+		{
+			name:     "no seasons",
+			seasons:  map[int][]int{},
+			expected: []SeasonGaps{},
+		},
+		{
+			name:    "complete season",
+			seasons: map[int][]int{1: {1, 2, 3}},
+			expected: []SeasonGaps{
+				{SeasonNumber: 1, Expected: 3, Present: 3, Missing: []int{}},
+			},
+		},
+		{
+			name:    "gaps up to the highest episode present",
+			seasons: map[int][]int{1: {1, 4, 6}},
+			expected: []SeasonGaps{
+				{SeasonNumber: 1, Expected: 6, Present: 3, Missing: []int{2, 3, 5}},
+			},
+		},
+		{
+			// S01E01E02, S01E02-E04 and S01E06 as expanded by GetShowEpisodeNumbers; overlapping
+			// ranges repeat episodes, which count once
+			name:    "multi-episode ranges",
+			seasons: map[int][]int{1: {1, 2, 2, 3, 4, 6}},
+			expected: []SeasonGaps{
+				{SeasonNumber: 1, Expected: 6, Present: 5, Missing: []int{5}},
+			},
+		},
+		{
+			name:     "official count beyond the highest episode present",
+			seasons:  map[int][]int{2: {1, 2, 3}},
+			official: map[int]int{2: 5},
+			expected: []SeasonGaps{
+				{SeasonNumber: 2, Expected: 5, Official: true, Present: 3, Missing: []int{4, 5}},
+			},
+		},
+		{
+			// Episodes past the official count (e.g. a bonus numbered as an episode) are not missing
+			// and do not raise the expected count
+			name:     "official count below the highest episode present",
+			seasons:  map[int][]int{1: {1, 2, 3, 4}},
+			official: map[int]int{1: 3},
+			expected: []SeasonGaps{
+				{SeasonNumber: 1, Expected: 3, Official: true, Present: 4, Missing: []int{}},
+			},
+		},
+		{
+			name:     "official counts only for the seasons they cover",
+			seasons:  map[int][]int{1: {1, 3}, 2: {2}},
+			official: map[int]int{1: 4, 3: 10},
+			expected: []SeasonGaps{
+				{SeasonNumber: 1, Expected: 4, Official: true, Present: 2, Missing: []int{2, 4}},
+				{SeasonNumber: 2, Expected: 2, Present: 1, Missing: []int{1}},
+			},
+		},
+		{
+			name:     "zero official count falls back to the highest episode",
+			seasons:  map[int][]int{1: {2}},
+			official: map[int]int{1: 0},
+			expected: []SeasonGaps{
+				{SeasonNumber: 1, Expected: 2, Present: 1, Missing: []int{1}},
+			},
+		},
+		{
+			name:    "empty season",
+			seasons: map[int][]int{1: {1, 2}, 2: {}},
+			expected: []SeasonGaps{
+				{SeasonNumber: 1, Expected: 2, Present: 2, Missing: []int{}},
+				{SeasonNumber: 2, Expected: 0, Present: 0, Missing: []int{}},
+			},
+		},
+		{
+			name:     "empty season with an official count",
+			seasons:  map[int][]int{3: nil},
+			official: map[int]int{3: 3},
+			expected: []SeasonGaps{
+				{SeasonNumber: 3, Expected: 3, Official: true, Present: 0, Missing: []int{1, 2, 3}},
+			},
+		},
+		{
+			name:    "seasons sorted by number",
+			seasons: map[int][]int{10: {1}, 2: {1}, 1: {1}},
+			expected: []SeasonGaps{
+				{SeasonNumber: 1, Expected: 1, Present: 1, Missing: []int{}},
+				{SeasonNumber: 2, Expected: 1, Present: 1, Missing: []int{}},
+				{SeasonNumber: 10, Expected: 1, Present: 1, Missing: []int{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindGaps(tt.seasons, tt.official)
+			if !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("FindGaps() = %+v, expected %+v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Torrent job statuses
+const (
+	JobQueued      = "queued"
+	JobDownloading = "downloading"
+	JobDone        = "done"
+	JobFailed      = "failed"
+	JobCancelled   = "cancelled"
+)
+
+// TorrentJob is a torrent queued for download and upload
+type TorrentJob struct {
+	ID          int64     `json:"id"`
+	TorrentURL  string    `json:"torrentUrl"`
+	Name        string    `json:"name"`
+	FileIndexes []int     `json:"fileIndexes"` // Files to download; empty means all
+	Status      string    `json:"status"`
+	Progress    float64   `json:"progress"` // Percent of the selected files downloaded
+	CurrentFile string    `json:"currentFile"`
+	Error       string    `json:"error"`
+	RequestedBy string    `json:"requestedBy"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+const torrentJobColumns = "id, torrent_url, name, file_indexes, status, progress, current_file, error, requested_by, created_at, updated_at"
+
+// scanTorrentJob scans a row selected with torrentJobColumns
+func scanTorrentJob(scanner interface{ Scan(...interface{}) error }) (*TorrentJob, error) {
+	var job TorrentJob
+	var indexes pq.Int64Array
+	if err := scanner.Scan(&job.ID, &job.TorrentURL, &job.Name, &indexes, &job.Status, &job.Progress, &job.CurrentFile, &job.Error, &job.RequestedBy, &job.CreatedAt, &job.UpdatedAt); err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		job.FileIndexes = append(job.FileIndexes, int(index))
+	}
+	return &job, nil
+}
+
+// EnqueueTorrentJob queues a torrent for download
+// fileIndexes selects files of the torrent; nil or empty downloads all of them
+func (db *DB) EnqueueTorrentJob(torrentURL, name string, fileIndexes []int, requestedBy string) (*TorrentJob, error) {
+	var indexes interface{}
+	if len(fileIndexes) > 0 {
+		array := make(pq.Int64Array, len(fileIndexes))
+		for i, index := range fileIndexes {
+			array[i] = int64(index)
+		}
+		indexes = array
+	}
+
+	row := db.conn.QueryRow(
+		"INSERT INTO torrent_jobs (torrent_url, name, file_indexes, requested_by) VALUES ($1, $2, $3, $4) RETURNING "+torrentJobColumns,
+		torrentURL, name, indexes, requestedBy,
+	)
+	job, err := scanTorrentJob(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to queue torrent: %w", err)
+	}
+	return job, nil
+}
+
+// ClaimTorrentJob marks the oldest queued job as downloading and returns it, or nil if the queue is empty
+// Several workers can claim concurrently without getting the same job
+func (db *DB) ClaimTorrentJob() (*TorrentJob, error) {
+	row := db.conn.QueryRow(`
+		UPDATE torrent_jobs SET status = $1, updated_at = $2
+		WHERE id = (
+			SELECT id FROM torrent_jobs WHERE status = $3 ORDER BY id LIMIT 1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING `+torrentJobColumns,
+		JobDownloading, time.Now(), JobQueued,
+	)
+	job, err := scanTorrentJob(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to claim torrent job: %w", err)
+	}
+	return job, nil
+}
+
+// RequeueTorrentJobs queues the jobs left downloading by a worker that stopped, returning how many
+// Only safe to call when no other worker is running
+func (db *DB) RequeueTorrentJobs() (int64, error) {
+	result, err := db.conn.Exec(
+		"UPDATE torrent_jobs SET status = $1, progress = 0, current_file = '', updated_at = $2 WHERE status = $3",
+		JobQueued, time.Now(), JobDownloading,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue torrent jobs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// UpdateTorrentJobProgress records the download progress of a job
+func (db *DB) UpdateTorrentJobProgress(id int64, progress float64, currentFile string) error {
+	_, err := db.conn.Exec(
+		"UPDATE torrent_jobs SET progress = $1, current_file = $2, updated_at = $3 WHERE id = $4",
+		progress, currentFile, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update torrent job: %w", err)
+	}
+	return nil
+}
+
+// FinishTorrentJob sets the final status of a job, with an error message for failures
+func (db *DB) FinishTorrentJob(id int64, status, errMsg string) error {
+	_, err := db.conn.Exec(
+		"UPDATE torrent_jobs SET status = $1, error = $2, updated_at = $3 WHERE id = $4",
+		status, errMsg, time.Now(), id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish torrent job: %w", err)
+	}
+	return nil
+}
+
+// CancelTorrentJob cancels a job that has not started yet
+func (db *DB) CancelTorrentJob(id int64) error {
+	result, err := db.conn.Exec(
+		"UPDATE torrent_jobs SET status = $1, updated_at = $2 WHERE id = $3 AND status = $4",
+		JobCancelled, time.Now(), id, JobQueued,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cancel torrent job: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("torrent job %d is not queued", id)
+	}
+	return nil
+}
+
+// GetTorrentJob returns a job by ID
+func (db *DB) GetTorrentJob(id int64) (*TorrentJob, error) {
+	job, err := scanTorrentJob(db.conn.QueryRow("SELECT "+torrentJobColumns+" FROM torrent_jobs WHERE id = $1", id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("torrent job %d not found", id)
+		}
+		return nil, fmt.Errorf("failed to get torrent job: %w", err)
+	}
+	return job, nil
+}
+
+// GetActiveTorrentJobs returns queued and downloading jobs, oldest first
+func (db *DB) GetActiveTorrentJobs() ([]TorrentJob, error) {
+	rows, err := db.conn.Query(
+		"SELECT "+torrentJobColumns+" FROM torrent_jobs WHERE status IN ($1, $2) ORDER BY id",
+		JobQueued, JobDownloading,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query torrent jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []TorrentJob
+	for rows.Next() {
+		job, err := scanTorrentJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan torrent job: %w", err)
+		}
+		jobs = append(jobs, *job)
+	}
+	return jobs, rows.Err()
+}
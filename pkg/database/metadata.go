@@ -0,0 +1,48 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/metadata"
+)
+
+// DB caches the responses of the metadata provider
+var _ metadata.Cache = (*DB)(nil)
+
+// GetMetadata returns a cached metadata response and when it was fetched
+func (db *DB) GetMetadata(key string) ([]byte, time.Time, bool, error) {
+	var data []byte
+	var fetchedAt time.Time
+	err := db.conn.QueryRow("SELECT data, fetched_at FROM metadata_cache WHERE key = $1", key).Scan(&data, &fetchedAt)
+	if err == sql.ErrNoRows {
+		return nil, time.Time{}, false, nil
+	}
+	if err != nil {
+		return nil, time.Time{}, false, fmt.Errorf("failed to get cached metadata: %w", err)
+	}
+	return data, fetchedAt, true, nil
+}
+
+// SaveMetadata caches a metadata response
+func (db *DB) SaveMetadata(key string, data []byte) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO metadata_cache (key, data) VALUES ($1, $2)
+		ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, fetched_at = CURRENT_TIMESTAMP`,
+		key, string(data),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to cache metadata: %w", err)
+	}
+	return nil
+}
+
+// ClearMetadata forgets all cached metadata, so it is fetched again
+func (db *DB) ClearMetadata() (int64, error) {
+	result, err := db.conn.Exec("DELETE FROM metadata_cache")
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear cached metadata: %w", err)
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rusik69/trtg/pkg/parser"
+)
+
+// movieSchema adds the media type of videos and the title, year and edition of movies
+var movieSchema = []string{
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS media_type TEXT NOT NULL DEFAULT 'episode'",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS movie_title TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS movie_year INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS edition TEXT NOT NULL DEFAULT ''",
+	"CREATE INDEX IF NOT EXISTS idx_videos_media_type ON videos(media_type)",
+}
+
+// movieColumns selects the media type and movie metadata, in the order movieFields scans them
+const movieColumns = "media_type, movie_title, movie_year, edition"
+
+// movieFields returns the scan destinations for movieColumns
+func movieFields(v *Video) []any {
+	return []any{&v.MediaType, &v.Movie.Title, &v.Movie.Year, &v.Movie.Edition}
+}
+
+// IsMovie reports whether a video was classified as a movie
+func (v Video) IsMovie() bool {
+	return v.MediaType == parser.MediaMovie
+}
+
+// UpdateVideoMedia stores the media type of a video and, for movies, their title, year and edition
+func (db *DB) UpdateVideoMedia(id int64, mediaType string, movie parser.Movie) error {
+	_, err := db.conn.Exec(
+		"UPDATE videos SET media_type = $1, movie_title = $2, movie_year = $3, edition = $4 WHERE id = $5",
+		mediaType, movie.Title, movie.Year, movie.Edition, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update video media type: %w", err)
+	}
+	return nil
+}
+
+// GetMovies returns the videos classified as movies, ordered by title and year
+func (db *DB) GetMovies() ([]Video, error) {
+	rows, err := db.conn.Query(
+		"SELECT id, video_id, channel_url, title, file_path, downloaded_at, uploaded_at, telegram_file_id, telegram_file_path, COALESCE(telegram_message_id, 0), COALESCE(show_name, ''), COALESCE(season_number, 0), COALESCE(episode_number, 0), "+metadataColumns+" FROM videos WHERE media_type = $1 ORDER BY LOWER(movie_title), movie_year, file_path",
+		parser.MediaMovie,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query movies: %w", err)
+	}
+	defer rows.Close()
+
+	var videos []Video
+	for rows.Next() {
+		var v Video
+		var uploadedAt sql.NullTime
+		var telegramFileID sql.NullString
+		var telegramFilePath sql.NullString
+		if err := rows.Scan(append([]any{&v.ID, &v.VideoID, &v.ChannelURL, &v.Title, &v.FilePath, &v.DownloadedAt, &uploadedAt, &telegramFileID, &telegramFilePath, &v.TelegramMessageID, &v.ShowName, &v.SeasonNumber, &v.EpisodeNumber}, metadataFields(&v)...)...); err != nil {
+			return nil, fmt.Errorf("failed to scan movie row: %w", err)
+		}
+		if uploadedAt.Valid {
+			v.UploadedAt = &uploadedAt.Time
+		}
+		if telegramFileID.Valid {
+			v.TelegramFileID = telegramFileID.String
+		}
+		if telegramFilePath.Valid {
+			v.TelegramFilePath = telegramFilePath.String
+		}
+		videos = append(videos, v)
+	}
+
+	return videos, rows.Err()
+}
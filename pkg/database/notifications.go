@@ -0,0 +1,166 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Notification subscription kinds
+const (
+	NotifyWebhook  = "webhook"  // POST the event as JSON to Target, signed with Secret
+	NotifyTelegram = "telegram" // Send a message to the Telegram chat ID in Target
+	NotifyBrowser  = "browser"  // Push the event to the subscriber's open web UI pages
+)
+
+// NotificationSubscription routes events to a webhook, Telegram chat or the owner's browser
+type NotificationSubscription struct {
+	ID        int64     `json:"id"`
+	UserID    int64     `json:"userId"`
+	Kind      string    `json:"kind"`
+	Target    string    `json:"target,omitempty"`
+	Secret    string    `json:"-"`                  // HMAC key for webhooks; only shown when the subscription is created
+	ShowName  string    `json:"showName,omitempty"` // Empty for all shows
+	Events    []string  `json:"events"`             // Empty for all event types
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Matches reports whether the subscription wants an event of eventType about showName
+func (s *NotificationSubscription) Matches(eventType, showName string) bool {
+	if s.ShowName != "" && s.ShowName != showName {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// notificationColumns are the columns read by scanNotificationSubscription
+const notificationColumns = "id, user_id, kind, target, secret, COALESCE(show_name, ''), events, created_at"
+
+// scanNotificationSubscription scans a row of notificationColumns
+func scanNotificationSubscription(row interface{ Scan(...interface{}) error }) (*NotificationSubscription, error) {
+	var s NotificationSubscription
+	var events pq.StringArray
+	if err := row.Scan(&s.ID, &s.UserID, &s.Kind, &s.Target, &s.Secret, &s.ShowName, &events, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	s.Events = []string(events)
+	if s.Events == nil {
+		s.Events = []string{}
+	}
+	return &s, nil
+}
+
+// CreateNotificationSubscription validates and stores a subscription
+// Webhook subscriptions get a random secret if none is given
+func (db *DB) CreateNotificationSubscription(sub *NotificationSubscription) error {
+	sub.ShowName = strings.TrimSpace(sub.ShowName)
+	sub.Target = strings.TrimSpace(sub.Target)
+	switch sub.Kind {
+	case NotifyWebhook:
+		u, err := url.Parse(sub.Target)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook target must be an http or https URL")
+		}
+		if sub.Secret == "" {
+			secret, err := generateToken()
+			if err != nil {
+				return err
+			}
+			sub.Secret = secret
+		}
+	case NotifyTelegram:
+		if _, err := strconv.ParseInt(sub.Target, 10, 64); err != nil {
+			return fmt.Errorf("telegram target must be a chat ID")
+		}
+	case NotifyBrowser:
+		sub.Target = ""
+		sub.Secret = ""
+	default:
+		return fmt.Errorf("invalid kind %q (expected %s, %s or %s)", sub.Kind, NotifyWebhook, NotifyTelegram, NotifyBrowser)
+	}
+	if sub.Events == nil {
+		sub.Events = []string{}
+	}
+
+	var showName interface{}
+	if sub.ShowName != "" {
+		showName = sub.ShowName
+	}
+	err := db.conn.QueryRow(
+		"INSERT INTO notification_subscriptions (user_id, kind, target, secret, show_name, events) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at",
+		sub.UserID, sub.Kind, sub.Target, sub.Secret, showName, pq.StringArray(sub.Events),
+	).Scan(&sub.ID, &sub.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create notification subscription: %w", err)
+	}
+	return nil
+}
+
+// ListNotificationSubscriptions returns a user's subscriptions, newest first
+func (db *DB) ListNotificationSubscriptions(userID int64) ([]NotificationSubscription, error) {
+	rows, err := db.conn.Query("SELECT "+notificationColumns+" FROM notification_subscriptions WHERE user_id = $1 ORDER BY created_at DESC", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	subs := []NotificationSubscription{}
+	for rows.Next() {
+		sub, err := scanNotificationSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification subscription: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// GetMatchingSubscriptions returns all subscriptions that want an event of eventType about showName
+// showName is empty for events that are not about a show, which only match subscriptions to all shows
+// Show names are compared normalized, like the show list groups them (see normalizeShowNameSchema)
+func (db *DB) GetMatchingSubscriptions(eventType, showName string) ([]NotificationSubscription, error) {
+	rows, err := db.conn.Query(`
+		SELECT `+notificationColumns+`
+		FROM notification_subscriptions
+		WHERE (show_name IS NULL OR normalize_show_name(show_name) = normalize_show_name($2))
+		AND (cardinality(events) = 0 OR $1 = ANY(events))
+	`, eventType, showName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query notification subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var subs []NotificationSubscription
+	for rows.Next() {
+		sub, err := scanNotificationSubscription(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification subscription: %w", err)
+		}
+		subs = append(subs, *sub)
+	}
+	return subs, rows.Err()
+}
+
+// DeleteNotificationSubscription removes one of a user's subscriptions
+func (db *DB) DeleteNotificationSubscription(userID, id int64) error {
+	result, err := db.conn.Exec("DELETE FROM notification_subscriptions WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete notification subscription: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("notification subscription %d not found", id)
+	}
+	return nil
+}
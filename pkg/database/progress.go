@@ -0,0 +1,222 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WatchedThreshold is the fraction of a video that must be played for it to count as watched
+const WatchedThreshold = 0.9
+
+// WatchProgress is how far a user got in a video
+type WatchProgress struct {
+	VideoID   int64     `json:"videoId"`
+	Position  float64   `json:"position"` // Seconds
+	Duration  float64   `json:"duration"` // Seconds, 0 if unknown
+	Watched   bool      `json:"watched"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ContinueWatchingItem is an entry of the "Continue watching" row
+type ContinueWatchingItem struct {
+	Kind          string  `json:"kind"` // "continue" for a partly watched episode, "next" for the episode after a watched one
+	VideoID       int64   `json:"videoId"`
+	ShowName      string  `json:"showName"` // The movie title for movies
+	SeasonNumber  int     `json:"seasonNumber"`
+	EpisodeNumber int     `json:"episodeNumber"`
+	Title         string  `json:"title"`
+	Position      float64 `json:"position"`
+	Duration      float64 `json:"duration"`
+	Movie         bool    `json:"movie,omitempty"`
+}
+
+// SaveWatchProgress records the playback position of a video for a user
+// A video stays watched once it has been played past WatchedThreshold
+func (db *DB) SaveWatchProgress(userID int64, videoID int64, position, duration float64) error {
+	watched := isWatched(position, duration)
+	_, err := db.conn.Exec(`
+		INSERT INTO watch_progress (user_id, video_id, position_seconds, duration_seconds, watched, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, video_id) DO UPDATE SET
+			position_seconds = EXCLUDED.position_seconds,
+			duration_seconds = EXCLUDED.duration_seconds,
+			watched = watch_progress.watched OR EXCLUDED.watched,
+			updated_at = EXCLUDED.updated_at
+	`, userID, videoID, position, duration, watched, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to save watch progress: %w", err)
+	}
+	return nil
+}
+
+// isWatched reports whether playing up to position of a video of duration seconds counts as watching it
+// A video of unknown duration is never watched
+func isWatched(position, duration float64) bool {
+	return duration > 0 && position >= duration*WatchedThreshold
+}
+
+// GetWatchProgress returns a user's progress in a video, or nil if it was never played
+func (db *DB) GetWatchProgress(userID, videoID int64) (*WatchProgress, error) {
+	var p WatchProgress
+	err := db.conn.QueryRow(
+		"SELECT video_id, position_seconds, duration_seconds, watched, updated_at FROM watch_progress WHERE user_id = $1 AND video_id = $2",
+		userID, videoID,
+	).Scan(&p.VideoID, &p.Position, &p.Duration, &p.Watched, &p.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get watch progress: %w", err)
+	}
+	return &p, nil
+}
+
+// GetWatchProgressForVideos returns a user's progress for the given videos, keyed by video ID
+func (db *DB) GetWatchProgressForVideos(userID int64, videoIDs []int64) (map[int64]WatchProgress, error) {
+	progress := make(map[int64]WatchProgress)
+	if len(videoIDs) == 0 {
+		return progress, nil
+	}
+
+	rows, err := db.conn.Query(
+		"SELECT video_id, position_seconds, duration_seconds, watched, updated_at FROM watch_progress WHERE user_id = $1 AND video_id = ANY($2)",
+		userID, pq.Array(videoIDs),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query watch progress: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p WatchProgress
+		if err := rows.Scan(&p.VideoID, &p.Position, &p.Duration, &p.Watched, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan watch progress: %w", err)
+		}
+		progress[p.VideoID] = p
+	}
+	return progress, rows.Err()
+}
+
+// GetContinueWatching returns, per show, the most recently played episode if it is unfinished,
+// or the next uploaded episode if it was watched to the end
+func (db *DB) GetContinueWatching(userID int64, limit int) ([]ContinueWatchingItem, error) {
+	rows, err := db.conn.Query(`
+		SELECT v.id, v.file_path, COALESCE(v.show_name, v.title), COALESCE(v.season_number, 0), COALESCE(v.episode_number, 0),
+			p.position_seconds, p.duration_seconds, p.watched, v.media_type, v.movie_title
+		FROM watch_progress p
+		JOIN videos v ON v.id = p.video_id
+		WHERE p.user_id = $1
+		ORDER BY p.updated_at DESC
+		LIMIT 200
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent progress: %w", err)
+	}
+
+	type recent struct {
+		video    Video
+		position float64
+		duration float64
+		watched  bool
+	}
+	var recents []recent
+	for rows.Next() {
+		var r recent
+		if err := rows.Scan(&r.video.ID, &r.video.FilePath, &r.video.ShowName, &r.video.SeasonNumber, &r.video.EpisodeNumber, &r.position, &r.duration, &r.watched, &r.video.MediaType, &r.video.Movie.Title); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan recent progress: %w", err)
+		}
+		recents = append(recents, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	items := []ContinueWatchingItem{}
+	seenShows := make(map[string]bool)
+	for _, r := range recents {
+		if len(items) >= limit {
+			break
+		}
+		if seenShows[r.video.ShowName] {
+			continue
+		}
+		seenShows[r.video.ShowName] = true
+
+		// Movies have no next episode
+		if r.video.IsMovie() {
+			if !r.watched {
+				items = append(items, ContinueWatchingItem{
+					Kind:     "continue",
+					VideoID:  r.video.ID,
+					ShowName: r.video.Movie.Title,
+					Title:    filepath.Base(r.video.FilePath),
+					Position: r.position,
+					Duration: r.duration,
+					Movie:    true,
+				})
+			}
+			continue
+		}
+
+		if !r.watched {
+			items = append(items, ContinueWatchingItem{
+				Kind:          "continue",
+				VideoID:       r.video.ID,
+				ShowName:      r.video.ShowName,
+				SeasonNumber:  r.video.SeasonNumber,
+				EpisodeNumber: r.video.EpisodeNumber,
+				Title:         filepath.Base(r.video.FilePath),
+				Position:      r.position,
+				Duration:      r.duration,
+			})
+			continue
+		}
+
+		next, err := db.GetNextEpisode(r.video)
+		if err != nil {
+			return nil, err
+		}
+		if next != nil {
+			items = append(items, ContinueWatchingItem{
+				Kind:          "next",
+				VideoID:       next.ID,
+				ShowName:      r.video.ShowName,
+				SeasonNumber:  next.SeasonNumber,
+				EpisodeNumber: next.EpisodeNumber,
+				Title:         filepath.Base(next.FilePath),
+			})
+		}
+	}
+
+	return items, nil
+}
+
+// GetNextEpisode returns the uploaded episode following a video in its season, or the first one of the next season
+// Returns nil if there is none (or the video has no episode number to order by)
+func (db *DB) GetNextEpisode(video Video) (*Video, error) {
+	if video.SeasonNumber == 0 || video.EpisodeNumber == 0 {
+		return nil, nil
+	}
+
+	for _, season := range []int{video.SeasonNumber, video.SeasonNumber + 1} {
+		episodes, err := db.GetEpisodesByShowAndSeason(video.ShowName, season)
+		if err != nil {
+			return nil, err
+		}
+		for _, episode := range episodes {
+			if episode.TelegramFileID == "" || episode.EpisodeNumber == 0 {
+				continue
+			}
+			if season > video.SeasonNumber || episode.EpisodeNumber > video.EpisodeNumber {
+				return &episode, nil
+			}
+		}
+	}
+	return nil, nil
+}
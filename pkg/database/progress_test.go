@@ -0,0 +1,30 @@
+package database
+
+import "testing"
+
+func TestIsWatched(t *testing.T) {
+	tests := []struct {
+		name     string
+		position float64
+		duration float64
+		expected bool
+	}{
+		{"not started", 0, 2700, false},
+		{"halfway", 1350, 2700, false},
+		{"just before the threshold", 2429.9, 2700, false},
+		{"at the threshold", 2430, 2700, true},
+		{"credits", 2650, 2700, true},
+		{"finished", 2700, 2700, true},
+		{"past the end", 2710, 2700, true},
+		{"unknown duration", 2700, 0, false},
+		{"nothing played of unknown duration", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isWatched(tt.position, tt.duration); got != tt.expected {
+				t.Errorf("isWatched(%v, %v) = %v, want %v", tt.position, tt.duration, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/rusik69/trtg/pkg/parser"
+)
+
+// releaseSchema adds the release metadata columns parsed from file and torrent names
+var releaseSchema = []string{
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS resolution TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS video_codec TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS audio_codec TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS source TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS release_group TEXT NOT NULL DEFAULT ''",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS languages TEXT[] NOT NULL DEFAULT '{}'",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS proper BOOLEAN NOT NULL DEFAULT false",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS repack BOOLEAN NOT NULL DEFAULT false",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS release_year INTEGER NOT NULL DEFAULT 0",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS ten_bit BOOLEAN NOT NULL DEFAULT false",
+}
+
+// releaseColumns selects the release metadata, in the order releaseFields scans it
+const releaseColumns = "resolution, video_codec, audio_codec, source, release_group, languages, proper, repack, release_year, ten_bit"
+
+// releaseFields returns the scan destinations for releaseColumns
+func releaseFields(r *parser.Release) []any {
+	return []any{
+		&r.Resolution, &r.VideoCodec, &r.AudioCodec, &r.Source, &r.ReleaseGroup,
+		(*pq.StringArray)(&r.Languages), &r.Proper, &r.Repack, &r.Year, &r.TenBit,
+	}
+}
+
+// UpdateVideoRelease stores the release metadata of a video
+func (db *DB) UpdateVideoRelease(id int64, r parser.Release) error {
+	languages := r.Languages
+	if languages == nil {
+		languages = []string{}
+	}
+	_, err := db.conn.Exec(`
+		UPDATE videos SET resolution = $1, video_codec = $2, audio_codec = $3, source = $4, release_group = $5,
+			languages = $6, proper = $7, repack = $8, release_year = $9, ten_bit = $10
+		WHERE id = $11`,
+		r.Resolution, r.VideoCodec, r.AudioCodec, r.Source, r.ReleaseGroup,
+		pq.StringArray(languages), r.Proper, r.Repack, r.Year, r.TenBit, id,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update video release: %w", err)
+	}
+	return nil
+}
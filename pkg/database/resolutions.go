@@ -0,0 +1,65 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/rusik69/trtg/pkg/parser"
+)
+
+// DB caches show names for the parser's resolvers
+var _ parser.ResolverStore = (*DB)(nil)
+
+// GetNameResolution returns the cached show name of a torrent
+func (db *DB) GetNameResolution(torrentName string) (string, bool, error) {
+	var showName string
+	err := db.conn.QueryRow("SELECT show_name FROM name_resolutions WHERE torrent_name = $1", torrentName).Scan(&showName)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get name resolution: %w", err)
+	}
+	return showName, true, nil
+}
+
+// SaveNameResolution caches the show name of a torrent and which resolver found it
+func (db *DB) SaveNameResolution(torrentName, showName, source string) error {
+	_, err := db.conn.Exec(`
+		INSERT INTO name_resolutions (torrent_name, show_name, source) VALUES ($1, $2, $3)
+		ON CONFLICT (torrent_name) DO UPDATE SET show_name = EXCLUDED.show_name, source = EXCLUDED.source, created_at = CURRENT_TIMESTAMP`,
+		torrentName, showName, source,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to save name resolution: %w", err)
+	}
+	return nil
+}
+
+// ClearNameResolutions forgets all cached show names, so they are resolved again
+func (db *DB) ClearNameResolutions() (int64, error) {
+	result, err := db.conn.Exec("DELETE FROM name_resolutions")
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear name resolutions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// ShowNames returns the distinct show names in the archive
+func (db *DB) ShowNames() ([]string, error) {
+	rows, err := db.conn.Query("SELECT DISTINCT show_name FROM videos WHERE show_name IS NOT NULL AND show_name != ''")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query show names: %w", err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan show name: %w", err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
@@ -0,0 +1,251 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/parser"
+)
+
+// OverrideRule is stored as the parse rule of videos whose numbering was corrected by hand
+const OverrideRule = "override"
+
+// reviewSchema adds the parser's confidence to videos and the corrections made in the review queue
+// and the season view's editor
+var reviewSchema = []string{
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS parse_confidence DOUBLE PRECISION",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS parse_rule TEXT",
+	"ALTER TABLE videos ADD COLUMN IF NOT EXISTS episode_title TEXT",
+	"CREATE INDEX IF NOT EXISTS idx_videos_parse_confidence ON videos(parse_confidence)",
+	`CREATE TABLE IF NOT EXISTS video_overrides (
+		video_id INTEGER PRIMARY KEY REFERENCES videos(id) ON DELETE CASCADE,
+		show_name TEXT,
+		season_number INTEGER,
+		episode_number INTEGER,
+		title TEXT,
+		updated_by TEXT NOT NULL DEFAULT '',
+		updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+	)`,
+	"ALTER TABLE video_overrides ADD COLUMN IF NOT EXISTS title TEXT",
+}
+
+// parseColumns selects the parser's confidence and matched rule and the title set by hand, in the order
+// parseFields scans them. Videos not parsed since the columns were added have confidence 0 and no rule
+const parseColumns = "COALESCE(parse_confidence, 0), COALESCE(parse_rule, ''), COALESCE(episode_title, '')"
+
+// parseFields returns the scan destinations for parseColumns
+func parseFields(v *Video) []any {
+	return []any{&v.ParseConfidence, &v.ParseRule, &v.EpisodeTitle}
+}
+
+// VideoOverride is a correction of the parsed show, season or episode of a video, or its title.
+// Nil fields are not overridden and stay with the parser
+type VideoOverride struct {
+	VideoID       int64
+	ShowName      *string
+	SeasonNumber  *int
+	EpisodeNumber *int
+	Title         *string // Episode title shown instead of the file name, "" to show the file name again
+	UpdatedBy     string
+	UpdatedAt     time.Time
+}
+
+// Locked reports whether a video's numbering was set by hand and is kept by reparse
+func (v Video) Locked() bool {
+	return v.ParseRule == OverrideRule
+}
+
+// Apply replaces parse results with the overridden fields
+func (o VideoOverride) Apply(info *parser.VideoInfo) {
+	if o.ShowName != nil {
+		info.ShowName = *o.ShowName
+	}
+	if o.SeasonNumber != nil {
+		info.SeasonNumber = *o.SeasonNumber
+		// Numbered into a season, a file taken for a movie is an episode
+		if info.SeasonNumber > 0 && info.MediaType == parser.MediaMovie {
+			info.MediaType = parser.MediaEpisode
+			info.Movie = parser.Movie{}
+		}
+	}
+	if o.EpisodeNumber != nil {
+		// The parsed range and absolute number belong to the parsed episode, not the one set by hand
+		info.EpisodeNumber = *o.EpisodeNumber
+		info.EpisodeEnd = 0
+		info.AbsoluteNumber = 0
+	}
+	info.Confidence = 1
+	info.MatchedRule = OverrideRule
+}
+
+// UpdateVideoParse stores how confident the parser is about a video and which rule numbered it
+func (db *DB) UpdateVideoParse(id int64, confidence float64, rule string) error {
+	_, err := db.conn.Exec("UPDATE videos SET parse_confidence = $1, parse_rule = $2 WHERE id = $3", confidence, rule, id)
+	if err != nil {
+		return fmt.Errorf("failed to update video parse result: %w", err)
+	}
+	return nil
+}
+
+// GetReviewQueue returns the videos parsed with a confidence below threshold that were not
+// corrected yet, least confident first. Only the parsed fields and the parse result are filled in
+func (db *DB) GetReviewQueue(threshold float64, limit int) ([]Video, error) {
+	rows, err := db.conn.Query(`
+		SELECT id, title, file_path, COALESCE(show_name, ''), COALESCE(season_number, 0), COALESCE(episode_number, 0), `+parseColumns+`
+		FROM videos
+		WHERE parse_confidence < $1
+			AND NOT EXISTS (SELECT 1 FROM video_overrides o WHERE o.video_id = videos.id)
+		ORDER BY parse_confidence, title, file_path
+		LIMIT $2`,
+		threshold, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query review queue: %w", err)
+	}
+	defer rows.Close()
+
+	var videos []Video
+	for rows.Next() {
+		var v Video
+		if err := rows.Scan(append([]any{&v.ID, &v.Title, &v.FilePath, &v.ShowName, &v.SeasonNumber, &v.EpisodeNumber}, parseFields(&v)...)...); err != nil {
+			return nil, fmt.Errorf("failed to scan review queue row: %w", err)
+		}
+		videos = append(videos, v)
+	}
+	return videos, rows.Err()
+}
+
+// CountReviewQueue returns how many videos GetReviewQueue would return without a limit
+func (db *DB) CountReviewQueue(threshold float64) (int, error) {
+	var count int
+	err := db.conn.QueryRow(`
+		SELECT COUNT(*) FROM videos
+		WHERE parse_confidence < $1
+			AND NOT EXISTS (SELECT 1 FROM video_overrides o WHERE o.video_id = videos.id)`,
+		threshold,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count review queue: %w", err)
+	}
+	return count, nil
+}
+
+// SaveVideoOverride stores a correction and applies it to the video. Fields left nil keep
+// an earlier override of the same video. Setting the episode makes the video a single episode
+// without an absolute number
+func (db *DB) SaveVideoOverride(o VideoOverride) error {
+	return db.SaveVideoOverrides([]VideoOverride{o})
+}
+
+// SaveVideoOverrides stores the corrections of several videos at once, see SaveVideoOverride.
+// Either all are saved or, if a video does not exist, none
+func (db *DB) SaveVideoOverrides(overrides []VideoOverride) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, o := range overrides {
+		_, err = tx.Exec(`
+			INSERT INTO video_overrides (video_id, show_name, season_number, episode_number, title, updated_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (video_id) DO UPDATE SET
+				show_name = COALESCE(EXCLUDED.show_name, video_overrides.show_name),
+				season_number = COALESCE(EXCLUDED.season_number, video_overrides.season_number),
+				episode_number = COALESCE(EXCLUDED.episode_number, video_overrides.episode_number),
+				title = COALESCE(EXCLUDED.title, video_overrides.title),
+				updated_by = EXCLUDED.updated_by,
+				updated_at = CURRENT_TIMESTAMP`,
+			o.VideoID, o.ShowName, o.SeasonNumber, o.EpisodeNumber, o.Title, o.UpdatedBy,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to save override of video %d: %w", o.VideoID, err)
+		}
+
+		result, err := tx.Exec(`
+			UPDATE videos SET
+				show_name = COALESCE($1, show_name),
+				season_number = COALESCE($2, season_number),
+				episode_number = COALESCE($3, episode_number),
+				episode_end = CASE WHEN $3 IS NULL THEN episode_end ELSE 0 END,
+				absolute_number = CASE WHEN $3 IS NULL THEN absolute_number ELSE 0 END,
+				episode_title = COALESCE($4, episode_title),
+				media_type = CASE WHEN media_type = $7 AND COALESCE($2, 0) > 0 THEN $8 ELSE media_type END,
+				parse_confidence = 1,
+				parse_rule = $5
+			WHERE id = $6`,
+			o.ShowName, o.SeasonNumber, o.EpisodeNumber, o.Title, OverrideRule, o.VideoID, parser.MediaMovie, parser.MediaEpisode,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to apply override of video %d: %w", o.VideoID, err)
+		}
+		if n, _ := result.RowsAffected(); n == 0 {
+			return fmt.Errorf("video %d not found", o.VideoID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit video overrides: %w", err)
+	}
+	return nil
+}
+
+// GetVideoOverrides returns the corrections of all videos, by video ID
+func (db *DB) GetVideoOverrides() (map[int64]VideoOverride, error) {
+	rows, err := db.conn.Query("SELECT video_id, show_name, season_number, episode_number, title, updated_by, updated_at FROM video_overrides")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query video overrides: %w", err)
+	}
+	defer rows.Close()
+
+	overrides := make(map[int64]VideoOverride)
+	for rows.Next() {
+		var o VideoOverride
+		var showName, title sql.NullString
+		var seasonNumber, episodeNumber sql.NullInt64
+		if err := rows.Scan(&o.VideoID, &showName, &seasonNumber, &episodeNumber, &title, &o.UpdatedBy, &o.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan video override: %w", err)
+		}
+		if showName.Valid {
+			o.ShowName = &showName.String
+		}
+		if seasonNumber.Valid {
+			season := int(seasonNumber.Int64)
+			o.SeasonNumber = &season
+		}
+		if episodeNumber.Valid {
+			episode := int(episodeNumber.Int64)
+			o.EpisodeNumber = &episode
+		}
+		if title.Valid {
+			o.Title = &title.String
+		}
+		overrides[o.VideoID] = o
+	}
+	return overrides, rows.Err()
+}
+
+// DeleteVideoOverride removes the correction of a video and unlocks it; the next reparse numbers it again.
+// A title set by hand stays
+func (db *DB) DeleteVideoOverride(videoID int64) error {
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM video_overrides WHERE video_id = $1", videoID); err != nil {
+		return fmt.Errorf("failed to delete video override: %w", err)
+	}
+	// Not parsed since the correction; reparse scores it again
+	if _, err := tx.Exec("UPDATE videos SET parse_confidence = NULL, parse_rule = NULL WHERE id = $1 AND parse_rule = $2", videoID, OverrideRule); err != nil {
+		return fmt.Errorf("failed to unlock video: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit video override removal: %w", err)
+	}
+	return nil
+}
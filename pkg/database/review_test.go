@@ -0,0 +1,50 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rusik69/trtg/pkg/parser"
+)
+
+func TestVideoOverrideApply(t *testing.T) {
+	episode, season := 5, 2
+
+	tests := []struct {
+		name     string
+		override VideoOverride
+		info     parser.VideoInfo
+		expected parser.VideoInfo
+	}{
+		{
+			name:     "episode of a multi-episode file",
+			override: VideoOverride{EpisodeNumber: &episode},
+			info:     parser.VideoInfo{ShowName: "Show", SeasonNumber: 1, EpisodeNumber: 1, EpisodeEnd: 3, AbsoluteNumber: 137},
+			expected: parser.VideoInfo{ShowName: "Show", SeasonNumber: 1, EpisodeNumber: 5},
+		},
+		{
+			name:     "season keeps the range",
+			override: VideoOverride{SeasonNumber: &season},
+			info:     parser.VideoInfo{ShowName: "Show", SeasonNumber: 1, EpisodeNumber: 1, EpisodeEnd: 3},
+			expected: parser.VideoInfo{ShowName: "Show", SeasonNumber: 2, EpisodeNumber: 1, EpisodeEnd: 3},
+		},
+		{
+			name:     "movie numbered into a season",
+			override: VideoOverride{SeasonNumber: &season, EpisodeNumber: &episode},
+			info:     parser.VideoInfo{MediaType: parser.MediaMovie, Movie: parser.Movie{Title: "Film", Year: 2001}},
+			expected: parser.VideoInfo{MediaType: parser.MediaEpisode, SeasonNumber: 2, EpisodeNumber: 5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := tt.info
+			tt.override.Apply(&info)
+			tt.expected.Confidence = 1
+			tt.expected.MatchedRule = OverrideRule
+			if !reflect.DeepEqual(info, tt.expected) {
+				t.Errorf("Apply() = %+v, want %+v", info, tt.expected)
+			}
+		})
+	}
+}
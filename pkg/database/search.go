@@ -0,0 +1,85 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// maxSearchWords limits how many words of a query are matched
+const maxSearchWords = 5
+
+// SearchVideos returns videos where every word of query appears in the show name, movie title, title,
+// file path or torrent URL (whose magnet dn= carries the torrent name), best matches first
+func (db *DB) SearchVideos(query string, limit int) ([]Video, error) {
+	words, patterns := searchPatterns(query)
+	if len(words) == 0 {
+		return nil, nil
+	}
+
+	args := []interface{}{strings.Join(words, " ")}
+	var conditions []string
+	for _, pattern := range patterns {
+		args = append(args, pattern)
+		n := len(args)
+		conditions = append(conditions, fmt.Sprintf(
+			"(COALESCE(show_name, '') ILIKE $%d OR movie_title ILIKE $%d OR title ILIKE $%d OR file_path ILIKE $%d OR video_id ILIKE $%d)", n, n, n, n, n))
+	}
+
+	order := "COALESCE(show_name, title), season_number, episode_number, file_path"
+	if db.trigram {
+		order = "GREATEST(similarity(COALESCE(show_name, ''), $1), similarity(title, $1)) DESC, " + order
+	} else {
+		// Keep $1 referenced so the parameter types can be inferred
+		conditions = append(conditions, "$1::text IS NOT NULL")
+	}
+	args = append(args, limit)
+
+	rows, err := db.conn.Query(fmt.Sprintf(`
+		SELECT id, video_id, channel_url, title, file_path, downloaded_at, uploaded_at, telegram_file_id, telegram_file_path, COALESCE(telegram_message_id, 0), COALESCE(show_name, ''), COALESCE(season_number, 0), COALESCE(episode_number, 0), `+movieColumns+`
+		FROM videos
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d
+	`, strings.Join(conditions, " AND "), order, len(args)), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search videos: %w", err)
+	}
+	defer rows.Close()
+
+	var videos []Video
+	for rows.Next() {
+		var v Video
+		var uploadedAt sql.NullTime
+		var telegramFileID sql.NullString
+		var telegramFilePath sql.NullString
+		if err := rows.Scan(&v.ID, &v.VideoID, &v.ChannelURL, &v.Title, &v.FilePath, &v.DownloadedAt, &uploadedAt, &telegramFileID, &telegramFilePath, &v.TelegramMessageID, &v.ShowName, &v.SeasonNumber, &v.EpisodeNumber, &v.MediaType, &v.Movie.Title, &v.Movie.Year, &v.Movie.Edition); err != nil {
+			return nil, fmt.Errorf("failed to scan file row: %w", err)
+		}
+		if uploadedAt.Valid {
+			v.UploadedAt = &uploadedAt.Time
+		}
+		if telegramFileID.Valid {
+			v.TelegramFileID = telegramFileID.String
+		}
+		if telegramFilePath.Valid {
+			v.TelegramFilePath = telegramFilePath.String
+		}
+		videos = append(videos, v)
+	}
+
+	return videos, rows.Err()
+}
+
+// searchPatterns splits a query into at most maxSearchWords words and returns them with the
+// ILIKE pattern matching each one literally anywhere in a value
+func searchPatterns(query string) (words, patterns []string) {
+	words = strings.Fields(query)
+	if len(words) > maxSearchWords {
+		words = words[:maxSearchWords]
+	}
+	for _, word := range words {
+		patterns = append(patterns, "%"+likeEscaper.Replace(word)+"%")
+	}
+	return words, patterns
+}
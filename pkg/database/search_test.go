@@ -0,0 +1,38 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSearchPatterns(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		words    []string
+		patterns []string
+	}{
+		{"empty", "", []string{}, nil},
+		{"whitespace only", " \t\n", []string{}, nil},
+		{"one word", "office", []string{"office"}, []string{"%office%"}},
+		{"extra whitespace", "  the   office\t", []string{"the", "office"}, []string{"%the%", "%office%"}},
+		{"percent", "100%", []string{"100%"}, []string{`%100\%%`}},
+		{"underscore", "s01_e02", []string{"s01_e02"}, []string{`%s01\_e02%`}},
+		{"backslash", `a\b`, []string{`a\b`}, []string{`%a\\b%`}},
+		{
+			name:     "too many words",
+			query:    "a b c d e f g",
+			words:    []string{"a", "b", "c", "d", "e"},
+			patterns: []string{"%a%", "%b%", "%c%", "%d%", "%e%"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			words, patterns := searchPatterns(tt.query)
+			if !reflect.DeepEqual(words, tt.words) || !reflect.DeepEqual(patterns, tt.patterns) {
+				t.Errorf("searchPatterns(%q) = %q, %q, want %q, %q", tt.query, words, patterns, tt.words, tt.patterns)
+			}
+		})
+	}
+}
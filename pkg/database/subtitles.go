@@ -0,0 +1,71 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Subtitle is an external subtitle track stored for a video
+type Subtitle struct {
+	ID       int64  `json:"id"`
+	VideoID  int64  `json:"videoId"`
+	Language string `json:"language"`
+	Label    string `json:"label"`
+	Format   string `json:"format"` // srt, vtt, ass or ssa
+	Content  string `json:"-"`
+}
+
+// AddSubtitle stores a subtitle for the video identified by its torrent URL and file path
+// Re-adding a subtitle with the same label replaces its content
+func (db *DB) AddSubtitle(videoID, filePath, language, label, format, content string) error {
+	result, err := db.conn.Exec(`
+		INSERT INTO subtitles (video_id, language, label, format, content)
+		SELECT id, $3, $4, $5, $6 FROM videos WHERE video_id = $1 AND file_path = $2
+		ON CONFLICT (video_id, label) DO UPDATE SET language = EXCLUDED.language, format = EXCLUDED.format, content = EXCLUDED.content
+	`, videoID, filePath, language, label, format, content)
+	if err != nil {
+		return fmt.Errorf("failed to add subtitle: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("video not found: %s", filePath)
+	}
+	return nil
+}
+
+// GetSubtitlesByVideo returns the subtitles of a video without their content
+func (db *DB) GetSubtitlesByVideo(videoID int64) ([]Subtitle, error) {
+	rows, err := db.conn.Query(
+		"SELECT id, video_id, language, label, format FROM subtitles WHERE video_id = $1 ORDER BY language, label",
+		videoID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query subtitles: %w", err)
+	}
+	defer rows.Close()
+
+	var subtitles []Subtitle
+	for rows.Next() {
+		var s Subtitle
+		if err := rows.Scan(&s.ID, &s.VideoID, &s.Language, &s.Label, &s.Format); err != nil {
+			return nil, fmt.Errorf("failed to scan subtitle: %w", err)
+		}
+		subtitles = append(subtitles, s)
+	}
+	return subtitles, rows.Err()
+}
+
+// GetSubtitle returns a subtitle with its content
+func (db *DB) GetSubtitle(id int64) (*Subtitle, error) {
+	var s Subtitle
+	err := db.conn.QueryRow(
+		"SELECT id, video_id, language, label, format, content FROM subtitles WHERE id = $1",
+		id,
+	).Scan(&s.ID, &s.VideoID, &s.Language, &s.Label, &s.Format, &s.Content)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("subtitle not found")
+		}
+		return nil, fmt.Errorf("failed to get subtitle: %w", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,135 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// API token scopes
+const (
+	TokenScopeRead  = "read"  // GET and HEAD requests only
+	TokenScopeAdmin = "admin" // Everything the owning admin can do
+)
+
+// apiTokenPrefix marks trtg API tokens so they are recognizable in configs and secret scanners
+const apiTokenPrefix = "trtg_"
+
+// APIToken is a personal token for programmatic access to the web API
+type APIToken struct {
+	ID         int64      `json:"id"`
+	UserID     int64      `json:"userId"`
+	Name       string     `json:"name"`
+	Scope      string     `json:"scope"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+}
+
+// ValidTokenScope reports whether scope is a known API token scope
+func ValidTokenScope(scope string) bool {
+	return scope == TokenScopeRead || scope == TokenScopeAdmin
+}
+
+// CreateAPIToken creates a token for a user and returns its secret, which is not stored and cannot be shown again
+// Only admins can create admin-scoped tokens
+func (db *DB) CreateAPIToken(user *User, name, scope string) (string, *APIToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("token name is required")
+	}
+	if !ValidTokenScope(scope) {
+		return "", nil, fmt.Errorf("invalid scope %q (expected %s or %s)", scope, TokenScopeRead, TokenScopeAdmin)
+	}
+	if scope == TokenScopeAdmin && !user.IsAdmin() {
+		return "", nil, fmt.Errorf("only admins can create %s tokens", TokenScopeAdmin)
+	}
+
+	secret, err := generateToken()
+	if err != nil {
+		return "", nil, err
+	}
+	secret = apiTokenPrefix + secret
+
+	token := APIToken{UserID: user.ID, Name: name, Scope: scope}
+	err = db.conn.QueryRow(
+		"INSERT INTO api_tokens (user_id, name, token_hash, scope) VALUES ($1, $2, $3, $4) RETURNING id, created_at",
+		user.ID, name, hashToken(secret), scope,
+	).Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create API token: %w", err)
+	}
+	return secret, &token, nil
+}
+
+// GetAPITokenUser returns the token and owning user for a token secret, or nils if the token is unknown
+// It also records when the token was last used
+func (db *DB) GetAPITokenUser(secret string) (*User, *APIToken, error) {
+	if !strings.HasPrefix(secret, apiTokenPrefix) {
+		return nil, nil, nil
+	}
+
+	var u User
+	var t APIToken
+	var lastUsed sql.NullTime
+	err := db.conn.QueryRow(`
+		SELECT u.id, u.username, u.role, u.created_at, t.id, t.name, t.scope, t.created_at, t.last_used_at
+		FROM api_tokens t
+		JOIN users u ON u.id = t.user_id
+		WHERE t.token_hash = $1
+	`, hashToken(secret)).Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt, &t.ID, &t.Name, &t.Scope, &t.CreatedAt, &lastUsed)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, nil
+		}
+		return nil, nil, fmt.Errorf("failed to get API token: %w", err)
+	}
+	t.UserID = u.ID
+	if lastUsed.Valid {
+		t.LastUsedAt = &lastUsed.Time
+	}
+
+	// Avoid a write per request; minute precision is enough for "last used"
+	if t.LastUsedAt == nil || time.Since(*t.LastUsedAt) > time.Minute {
+		_, _ = db.conn.Exec("UPDATE api_tokens SET last_used_at = $1 WHERE id = $2", time.Now(), t.ID)
+	}
+	return &u, &t, nil
+}
+
+// ListAPITokens returns a user's tokens, newest first
+func (db *DB) ListAPITokens(userID int64) ([]APIToken, error) {
+	rows, err := db.conn.Query(
+		"SELECT id, user_id, name, scope, created_at, last_used_at FROM api_tokens WHERE user_id = $1 ORDER BY created_at DESC",
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	tokens := []APIToken{}
+	for rows.Next() {
+		var t APIToken
+		var lastUsed sql.NullTime
+		if err := rows.Scan(&t.ID, &t.UserID, &t.Name, &t.Scope, &t.CreatedAt, &lastUsed); err != nil {
+			return nil, fmt.Errorf("failed to scan API token: %w", err)
+		}
+		if lastUsed.Valid {
+			t.LastUsedAt = &lastUsed.Time
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+// RevokeAPIToken deletes one of a user's tokens
+func (db *DB) RevokeAPIToken(userID, tokenID int64) error {
+	result, err := db.conn.Exec("DELETE FROM api_tokens WHERE id = $1 AND user_id = $2", tokenID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke API token: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("API token %d not found", tokenID)
+	}
+	return nil
+}
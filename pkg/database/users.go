@@ -0,0 +1,328 @@
+package database
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// User roles
+const (
+	RoleAdmin  = "admin"  // Can delete shows and episodes and edit metadata
+	RoleViewer = "viewer" // Can browse and watch
+)
+
+// User is a web interface account
+type User struct {
+	ID        int64     `json:"id"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// IsAdmin reports whether the user has the admin role
+func (u *User) IsAdmin() bool {
+	return u != nil && u.Role == RoleAdmin
+}
+
+// ValidRole reports whether role is a known user role
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleViewer
+}
+
+// CreateUser adds a user with a bcrypt-hashed password
+func (db *DB) CreateUser(username, password, role string) (*User, error) {
+	username = strings.TrimSpace(username)
+	if username == "" || password == "" {
+		return nil, fmt.Errorf("username and password are required")
+	}
+	if !ValidRole(role) {
+		return nil, fmt.Errorf("invalid role %q (expected %s or %s)", role, RoleAdmin, RoleViewer)
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	u := User{Username: username, Role: role}
+	err = db.conn.QueryRow(
+		"INSERT INTO users (username, password_hash, role) VALUES ($1, $2, $3) RETURNING id, created_at",
+		username, string(hash), role,
+	).Scan(&u.ID, &u.CreatedAt)
+	if err != nil {
+		if strings.Contains(err.Error(), "unique constraint") || strings.Contains(err.Error(), "duplicate key") {
+			return nil, fmt.Errorf("user %s already exists", username)
+		}
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return &u, nil
+}
+
+// SetUserPassword changes a user's password and logs out all of their sessions
+func (db *DB) SetUserPassword(username, password string) error {
+	if password == "" {
+		return fmt.Errorf("password is required")
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var userID int64
+	err = db.conn.QueryRow("UPDATE users SET password_hash = $1 WHERE username = $2 RETURNING id", string(hash), username).Scan(&userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("user %s not found", username)
+		}
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if _, err := db.conn.Exec("DELETE FROM sessions WHERE user_id = $1", userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// SetUserRole changes a user's role
+func (db *DB) SetUserRole(username, role string) error {
+	if !ValidRole(role) {
+		return fmt.Errorf("invalid role %q (expected %s or %s)", role, RoleAdmin, RoleViewer)
+	}
+	result, err := db.conn.Exec("UPDATE users SET role = $1 WHERE username = $2", role, username)
+	if err != nil {
+		return fmt.Errorf("failed to update role: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("user %s not found", username)
+	}
+	return nil
+}
+
+// DeleteUser removes a user; their sessions, tokens and watch progress are deleted with them
+func (db *DB) DeleteUser(username string) error {
+	result, err := db.conn.Exec("DELETE FROM users WHERE username = $1", username)
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("user %s not found", username)
+	}
+	return nil
+}
+
+// ListUsers returns all users ordered by username
+func (db *DB) ListUsers() ([]User, error) {
+	rows, err := db.conn.Query("SELECT id, username, role, created_at FROM users ORDER BY username")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// GetUserByUsername returns a user by name
+func (db *DB) GetUserByUsername(username string) (*User, error) {
+	var u User
+	err := db.conn.QueryRow(
+		"SELECT id, username, role, created_at FROM users WHERE username = $1",
+		username,
+	).Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("user %s not found", username)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &u, nil
+}
+
+// GetUserByTelegramID returns the user linked to a Telegram account, or nil if there is none
+func (db *DB) GetUserByTelegramID(telegramID int64) (*User, error) {
+	var u User
+	err := db.conn.QueryRow(
+		"SELECT id, username, role, created_at FROM users WHERE telegram_id = $1",
+		telegramID,
+	).Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &u, nil
+}
+
+// CreateTelegramUser adds a user that can only log in with Telegram
+// The Telegram username is used if free, otherwise a name derived from the Telegram ID
+func (db *DB) CreateTelegramUser(telegramID int64, telegramUsername, role string) (*User, error) {
+	if !ValidRole(role) {
+		return nil, fmt.Errorf("invalid role %q (expected %s or %s)", role, RoleAdmin, RoleViewer)
+	}
+
+	fallback := fmt.Sprintf("tg%d", telegramID)
+	candidates := []string{fallback}
+	if telegramUsername != "" {
+		candidates = []string{telegramUsername, telegramUsername + "_" + fallback}
+	}
+
+	for _, username := range candidates {
+		// An empty password hash never matches, so password logins are impossible
+		u := User{Username: username, Role: role}
+		err := db.conn.QueryRow(
+			"INSERT INTO users (username, password_hash, role, telegram_id) VALUES ($1, '', $2, $3) ON CONFLICT (username) DO NOTHING RETURNING id, created_at",
+			username, role, telegramID,
+		).Scan(&u.ID, &u.CreatedAt)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to create user: %w", err)
+		}
+		return &u, nil
+	}
+	return nil, fmt.Errorf("no free username for Telegram user %d", telegramID)
+}
+
+// LinkTelegramUser allows an existing user to log in with a Telegram account
+func (db *DB) LinkTelegramUser(username string, telegramID int64) error {
+	result, err := db.conn.Exec("UPDATE users SET telegram_id = $1 WHERE username = $2", telegramID, username)
+	if err != nil {
+		return fmt.Errorf("failed to link Telegram account: %w", err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return fmt.Errorf("user %s not found", username)
+	}
+	return nil
+}
+
+// CountUsers returns the number of users
+func (db *DB) CountUsers() (int, error) {
+	var count int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	return count, nil
+}
+
+// AuthenticateUser checks a username and password
+// Returns nil without an error if the credentials are wrong
+func (db *DB) AuthenticateUser(username, password string) (*User, error) {
+	var u User
+	var hash string
+	err := db.conn.QueryRow(
+		"SELECT id, username, role, created_at, password_hash FROM users WHERE username = $1",
+		username,
+	).Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt, &hash)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			// Spend the same time as a real check so usernames cannot be probed by timing
+			_ = bcrypt.CompareHashAndPassword([]byte("$2a$10$7EqJtq98hPqEX7fNZaFWoOhi5BWX4Z3b8Vx6Ur7FGGq3bYZqkNvTm"), []byte(password))
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if hash == "" || bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return nil, nil
+	}
+	return &u, nil
+}
+
+// hashToken returns the hex SHA-256 of a secret token; only hashes are stored in the database
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// generateToken returns a random URL-safe token
+func generateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CreateSession starts a login session for a user and returns its secret ID
+func (db *DB) CreateSession(userID int64, ttl time.Duration) (string, error) {
+	sessionID, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.conn.Exec(
+		"INSERT INTO sessions (id_hash, user_id, expires_at) VALUES ($1, $2, $3)",
+		hashToken(sessionID), userID, time.Now().Add(ttl),
+	)
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	return sessionID, nil
+}
+
+// GetSessionUser returns the user of an unexpired session, or nil if the session is unknown or expired
+func (db *DB) GetSessionUser(sessionID string) (*User, error) {
+	var u User
+	err := db.conn.QueryRow(`
+		SELECT u.id, u.username, u.role, u.created_at
+		FROM sessions s
+		JOIN users u ON u.id = s.user_id
+		WHERE s.id_hash = $1 AND s.expires_at > $2
+	`, hashToken(sessionID), time.Now()).Scan(&u.ID, &u.Username, &u.Role, &u.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &u, nil
+}
+
+// DeleteSession ends a login session
+func (db *DB) DeleteSession(sessionID string) error {
+	if _, err := db.conn.Exec("DELETE FROM sessions WHERE id_hash = $1", hashToken(sessionID)); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// DeleteExpiredSessions removes sessions past their expiry
+func (db *DB) DeleteExpiredSessions() (int64, error) {
+	result, err := db.conn.Exec("DELETE FROM sessions WHERE expires_at <= $1", time.Now())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+// BootstrapAdmin creates an admin user when the users table is empty
+// Returns true if the user was created
+func (db *DB) BootstrapAdmin(username, password string) (bool, error) {
+	count, err := db.CountUsers()
+	if err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return false, nil
+	}
+	if _, err := db.CreateUser(username, password, RoleAdmin); err != nil {
+		return false, err
+	}
+	return true, nil
+}
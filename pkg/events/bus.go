@@ -0,0 +1,85 @@
+// Package events fans database change notifications out to in-process subscribers
+package events
+
+import (
+	"log"
+	"sync"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+// subscriberBuffer is how many events a subscriber may lag behind before events are dropped
+const subscriberBuffer = 256
+
+// Bus delivers every published change to all subscribers
+type Bus struct {
+	mu      sync.Mutex
+	nextID  int
+	subs    map[int]*subscriber
+	dropped uint64
+}
+
+// subscriber is the channel of one subscriber
+type subscriber struct {
+	ch     chan database.ChangeEvent
+	missed int // Changes dropped since the subscriber last kept up; it is owed a ChangeResync
+}
+
+// NewBus creates an empty bus
+func NewBus() *Bus {
+	return &Bus{subs: make(map[int]*subscriber)}
+}
+
+// Subscribe returns a channel receiving published changes; call the returned function to unsubscribe
+func (b *Bus) Subscribe() (<-chan database.ChangeEvent, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	sub := &subscriber{ch: make(chan database.ChangeEvent, subscriberBuffer)}
+	b.subs[id] = sub
+
+	return sub.ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[id]; ok {
+			delete(b.subs, id)
+			close(sub.ch)
+		}
+	}
+}
+
+// Publish sends a change to all subscribers
+// Subscribers that are not keeping up miss changes rather than blocking the publisher; once they
+// catch up they receive a ChangeResync first, so they know to reload what they show
+func (b *Bus) Publish(e database.ChangeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, sub := range b.subs {
+		if sub.missed > 0 {
+			select {
+			case sub.ch <- database.ChangeEvent{Op: database.ChangeResync}:
+				log.Printf("Change subscriber %d caught up after missing %d changes", id, sub.missed)
+				sub.missed = 0
+			default:
+				sub.missed++
+				b.dropped++
+				continue
+			}
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			sub.missed++
+			b.dropped++
+			log.Printf("Warning: Change subscriber %d is %d changes behind, dropping changes until it catches up (%d dropped in total)", id, subscriberBuffer, b.dropped)
+		}
+	}
+}
+
+// Dropped returns how many changes subscribers have missed since the bus was created
+func (b *Bus) Dropped() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dropped
+}
@@ -0,0 +1,44 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+func TestBusDropsAndResyncs(t *testing.T) {
+	bus := NewBus()
+	slow, _ := bus.Subscribe()
+	fast, unsubscribe := bus.Subscribe()
+	defer unsubscribe()
+
+	// The slow subscriber reads nothing while the buffer fills up and overflows
+	for i := 1; i <= subscriberBuffer+10; i++ {
+		bus.Publish(database.ChangeEvent{Table: database.ChangeVideos, ID: int64(i)})
+		<-fast
+	}
+	if got := bus.Dropped(); got != 10 {
+		t.Fatalf("Dropped() = %d, expected 10", got)
+	}
+
+	for i := 1; i <= subscriberBuffer; i++ {
+		if e := <-slow; e.ID != int64(i) {
+			t.Fatalf("event %d has ID %d", i, e.ID)
+		}
+	}
+
+	// Once there is room again the slow subscriber is told to resync before new changes
+	bus.Publish(database.ChangeEvent{Table: database.ChangeVideos, ID: 1000})
+	if e := <-slow; e.Op != database.ChangeResync {
+		t.Fatalf("expected a resync after dropped changes, got %+v", e)
+	}
+	if e := <-slow; e.ID != 1000 {
+		t.Fatalf("expected the next change after the resync, got %+v", e)
+	}
+	if e := <-fast; e.ID != 1000 {
+		t.Fatalf("fast subscriber got %+v", e)
+	}
+	if got := bus.Dropped(); got != 10 {
+		t.Errorf("Dropped() = %d after catching up, expected 10", got)
+	}
+}
@@ -0,0 +1,255 @@
+// Package ingest downloads queued torrents and archives their files in the Telegram chat
+package ingest
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/parser"
+	"github.com/rusik69/trtg/pkg/telegram"
+	"github.com/rusik69/trtg/pkg/torrent"
+)
+
+const (
+	// pollInterval is how often an idle worker checks the queue
+	pollInterval = 30 * time.Second
+	// torrentFileTimeout bounds fetching a .torrent file queued by URL
+	torrentFileTimeout = time.Minute
+	// maxSubtitleSize skips files too large to be text subtitles
+	maxSubtitleSize = 5 << 20
+)
+
+// Worker claims jobs from the torrent_jobs queue, downloads their files and uploads them
+type Worker struct {
+	db         *database.DB
+	downloader *torrent.Downloader
+	uploader   *telegram.Uploader
+	notifier   *telegram.Notifier // Posts a status message per job; nil disables them
+	workDir    string             // .torrent files fetched for jobs queued by URL
+}
+
+// NewWorker creates a worker; .torrent files of jobs queued by URL are fetched to workDir
+// notifier may be nil, in which case progress is only recorded on the job
+func NewWorker(db *database.DB, downloader *torrent.Downloader, uploader *telegram.Uploader, notifier *telegram.Notifier, workDir string) (*Worker, error) {
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create work directory: %w", err)
+	}
+	return &Worker{db: db, downloader: downloader, uploader: uploader, notifier: notifier, workDir: workDir}, nil
+}
+
+// Run processes queued jobs one at a time until ctx is cancelled
+// Jobs left downloading by an earlier run are queued again first, so run a single worker
+func (w *Worker) Run(ctx context.Context) {
+	if count, err := w.db.RequeueTorrentJobs(); err != nil {
+		log.Printf("Warning: %v", err)
+	} else if count > 0 {
+		log.Printf("Requeued %d interrupted torrent jobs", count)
+	}
+
+	for {
+		job, err := w.db.ClaimTorrentJob()
+		if err != nil {
+			log.Printf("Error claiming torrent job: %v", err)
+		}
+		if job != nil {
+			w.process(job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// process downloads and uploads the files of a claimed job and records how it ended
+func (w *Worker) process(job *database.TorrentJob) {
+	log.Printf("Starting torrent job %d: %s", job.ID, job.Name)
+	name := job.Name
+	if name == "" {
+		name = fmt.Sprintf("Torrent job #%d", job.ID)
+	}
+	status := w.notifier.Start(name)
+	uploaded, err := w.ingest(job, status)
+	status.Finish(err)
+
+	result, message := database.JobDone, ""
+	if err != nil {
+		result, message = database.JobFailed, err.Error()
+		log.Printf("Torrent job %d failed: %v", job.ID, err)
+	} else {
+		log.Printf("Torrent job %d finished: %d files uploaded", job.ID, uploaded)
+	}
+	if err := w.db.FinishTorrentJob(job.ID, result, message); err != nil {
+		log.Printf("Error: %v", err)
+	}
+}
+
+// ingest downloads a job's torrent, or the files picked for it, and uploads them, returning how many were uploaded
+// Failing files are reported and skipped; an error means the torrent itself failed
+func (w *Worker) ingest(job *database.TorrentJob, status *telegram.StatusMessage) (int, error) {
+	torrentURL, cleanup, err := w.torrentSource(job)
+	if err != nil {
+		return 0, err
+	}
+	defer cleanup()
+
+	download, err := w.downloader.DownloadTorrentFiles(torrentURL, job.FileIndexes, &jobReporter{db: w.db, jobID: job.ID, status: status})
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err := w.downloader.RemoveData(download.Name); err != nil {
+			log.Printf("Warning: Failed to delete downloaded files of %s: %v", download.Name, err)
+		}
+	}()
+
+	uploaded := 0
+	for _, file := range download.Files {
+		if err := w.archive(job.TorrentURL, download.Name, file); err != nil {
+			log.Printf("Error archiving %s: %v", file.Path, err)
+			status.Failed(file.Path, err)
+			continue
+		}
+		status.Uploaded(file.Path)
+		uploaded++
+	}
+	if uploaded == 0 {
+		return 0, fmt.Errorf("none of the %d downloaded files could be uploaded", len(download.Files))
+	}
+	return uploaded, nil
+}
+
+// archive records a downloaded file with its parsed metadata and uploads it to the archive chat
+// Files already uploaded from the same torrent are not uploaded again
+func (w *Worker) archive(torrentURL, torrentName string, file torrent.DownloadedFile) error {
+	info := parser.ParseVideoInfo(torrentName, file.Path)
+	id, err := w.db.AddParsedVideo(torrentURL, torrentName, file.Path, info)
+	if err != nil {
+		return err
+	}
+	w.addSubtitles(torrentURL, file)
+
+	video, err := w.db.GetVideoByID(id)
+	if err != nil {
+		return err
+	}
+	if video.Uploaded() {
+		log.Printf("Skipping %s: already uploaded as video %d", file.Path, id)
+		return nil
+	}
+
+	result, err := w.uploader.UploadDocumentWithPath(file.LocalPath, filepath.Base(file.Path))
+	if err != nil {
+		return err
+	}
+	if err := w.db.UpdateVideoTelegramInfoWithMessageID(id, result.FileID, result.FilePath, result.MessageID); err != nil {
+		return err
+	}
+	return w.db.MarkUploaded(torrentURL, file.Path)
+}
+
+// addSubtitles stores the subtitle files downloaded next to a video, so the player can offer
+// them after the local files are deleted. Failures are logged; the video is archived regardless
+func (w *Worker) addSubtitles(torrentURL string, file torrent.DownloadedFile) {
+	for _, sub := range torrent.FindSubtitles(file.LocalPath) {
+		info, err := os.Stat(sub.Path)
+		if err != nil {
+			log.Printf("Warning: Failed to read subtitle %s: %v", sub.Path, err)
+			continue
+		}
+		if info.Size() > maxSubtitleSize {
+			log.Printf("Warning: Skipping subtitle %s: %s is too large", sub.Path, telegram.FormatSize(info.Size()))
+			continue
+		}
+		content, err := os.ReadFile(sub.Path)
+		if err != nil {
+			log.Printf("Warning: Failed to read subtitle %s: %v", sub.Path, err)
+			continue
+		}
+		if !utf8.Valid(content) {
+			log.Printf("Warning: Skipping subtitle %s: not UTF-8 text", sub.Path)
+			continue
+		}
+		text := strings.TrimPrefix(string(content), "\uFEFF")
+		if err := w.db.AddSubtitle(torrentURL, file.Path, sub.Language, sub.Label, sub.Format, text); err != nil {
+			log.Printf("Warning: Failed to store subtitle %s: %v", sub.Path, err)
+			continue
+		}
+		log.Printf("Stored %s subtitle %q for %s", sub.Format, sub.Label, file.Path)
+	}
+}
+
+// torrentSource returns what the torrent client can add for a job: the magnet link or a local
+// .torrent file, fetched first if the job was queued by URL. cleanup deletes the fetched file
+func (w *Worker) torrentSource(job *database.TorrentJob) (string, func(), error) {
+	if !strings.HasPrefix(job.TorrentURL, "http://") && !strings.HasPrefix(job.TorrentURL, "https://") {
+		return job.TorrentURL, func() {}, nil
+	}
+
+	path := filepath.Join(w.workDir, fmt.Sprintf("job-%d.torrent", job.ID))
+	if err := fetchFile(job.TorrentURL, path); err != nil {
+		return "", nil, err
+	}
+	return path, func() { os.Remove(path) }, nil
+}
+
+// fetchFile downloads url to path
+func fetchFile(url, path string) error {
+	client := &http.Client{Timeout: torrentFileTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("failed to fetch torrent file: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch torrent file: status %d", resp.StatusCode)
+	}
+
+	out, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create torrent file: %w", err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		return fmt.Errorf("failed to save torrent file: %w", err)
+	}
+	return nil
+}
+
+// jobReporter records download progress on the job for /status and the web UI, and passes it on
+// to the job's status message
+type jobReporter struct {
+	db     *database.DB
+	jobID  int64
+	status *telegram.StatusMessage // nil without a notification chat
+}
+
+// Progress implements torrent.Reporter; the job's progress covers all of its files
+func (r *jobReporter) Progress(p torrent.Progress) {
+	progress := p.Percent()
+	if p.FileCount > 0 {
+		progress = (float64(p.FileIndex) + p.Percent()/100) / float64(p.FileCount) * 100
+	}
+	if err := r.db.UpdateTorrentJobProgress(r.jobID, progress, p.File); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	r.status.Progress(p)
+}
+
+// Skipped implements torrent.Reporter
+func (r *jobReporter) Skipped(path, reason string) {
+	log.Printf("Torrent job %d: skipping %s (%s)", r.jobID, path, reason)
+	r.status.Skipped(path, reason)
+}
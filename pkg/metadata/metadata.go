@@ -0,0 +1,420 @@
+// Package metadata looks up show and episode titles, air dates, overviews and artwork
+// from TVmaze or TMDB compatible APIs, cached in the database
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Provider names, as used in METADATA_PROVIDER
+const (
+	ProviderTVmaze = "tvmaze"
+	ProviderTMDB   = "tmdb"
+)
+
+// Cache lifetimes
+const (
+	DefaultTTL      = 7 * 24 * time.Hour // Show details and matches
+	DefaultMissTTL  = 24 * time.Hour     // Shows the provider does not know
+	DefaultTimeout  = 10 * time.Second
+	requestCacheTTL = time.Minute // In-memory copy, so one page load does not hit the database per request
+)
+
+// ErrNotFound is returned when the provider knows no show of that name
+var ErrNotFound = errors.New("show not found")
+
+// Show is a show as the provider knows it
+type Show struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Year      int    `json:"year,omitempty"`      // Year of the premiere
+	Premiered string `json:"premiered,omitempty"` // YYYY-MM-DD
+	Overview  string `json:"overview,omitempty"`
+	Poster    string `json:"poster,omitempty"` // Image URL
+}
+
+// Season is a season of a show
+type Season struct {
+	Number   int    `json:"number"`
+	Name     string `json:"name,omitempty"`
+	Overview string `json:"overview,omitempty"`
+	Poster   string `json:"poster,omitempty"`
+}
+
+// Episode is an episode of a show
+type Episode struct {
+	Season   int    `json:"season"`
+	Number   int    `json:"number"`
+	Title    string `json:"title"`
+	AirDate  string `json:"airDate,omitempty"` // YYYY-MM-DD
+	Overview string `json:"overview,omitempty"`
+	Image    string `json:"image,omitempty"`
+}
+
+// ShowDetails is a show with its seasons and episodes
+type ShowDetails struct {
+	Show
+	Seasons  []Season  `json:"seasons"`
+	Episodes []Episode `json:"episodes"`
+}
+
+// Season returns the season with the given number, or nil
+func (d *ShowDetails) Season(number int) *Season {
+	for i := range d.Seasons {
+		if d.Seasons[i].Number == number {
+			return &d.Seasons[i]
+		}
+	}
+	return nil
+}
+
+// Episode returns the episode with the given season and number, or nil
+func (d *ShowDetails) Episode(season, number int) *Episode {
+	for i := range d.Episodes {
+		if d.Episodes[i].Season == season && d.Episodes[i].Number == number {
+			return &d.Episodes[i]
+		}
+	}
+	return nil
+}
+
+// EpisodeByAirDate returns the episode that aired on a day (YYYY-MM-DD), for daily shows, or nil
+func (d *ShowDetails) EpisodeByAirDate(airDate string) *Episode {
+	for i := range d.Episodes {
+		if d.Episodes[i].AirDate == airDate {
+			return &d.Episodes[i]
+		}
+	}
+	return nil
+}
+
+// LastAired returns the number of the last episode of each season that aired by a day (YYYY-MM-DD);
+// episodes without an air date count as aired
+func (d *ShowDetails) LastAired(day string) map[int]int {
+	last := make(map[int]int)
+	for _, episode := range d.Episodes {
+		if episode.AirDate != "" && episode.AirDate > day {
+			continue
+		}
+		if episode.Number > last[episode.Season] {
+			last[episode.Season] = episode.Number
+		}
+	}
+	return last
+}
+
+// Provider is a metadata API
+type Provider interface {
+	Name() string
+	SearchShows(ctx context.Context, query string) ([]Show, error)
+	ShowDetails(ctx context.Context, id string) (*ShowDetails, error)
+}
+
+// Cache stores provider responses; implemented by database.DB
+type Cache interface {
+	GetMetadata(key string) (data []byte, fetchedAt time.Time, ok bool, err error)
+	SaveMetadata(key string, data []byte) error
+}
+
+// Config selects and configures the metadata provider
+type Config struct {
+	Provider string // ProviderTVmaze or ProviderTMDB; empty disables metadata
+	BaseURL  string // API URL; point it at a fixture server in tests
+	ImageURL string // TMDB image URL prefix
+	APIKey   string // TMDB API key (v3) or read access token (v4)
+	Timeout  time.Duration
+	TTL      time.Duration
+}
+
+// ConfigFromEnv reads the configuration from METADATA_PROVIDER, METADATA_URL,
+// METADATA_IMAGE_URL and METADATA_API_KEY
+func ConfigFromEnv() Config {
+	return Config{
+		Provider: strings.ToLower(strings.TrimSpace(os.Getenv("METADATA_PROVIDER"))),
+		BaseURL:  os.Getenv("METADATA_URL"),
+		ImageURL: os.Getenv("METADATA_IMAGE_URL"),
+		APIKey:   os.Getenv("METADATA_API_KEY"),
+	}
+}
+
+// NewProvider returns the provider of cfg, or nil if metadata is disabled
+func NewProvider(cfg Config) (Provider, error) {
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+	if cfg.Timeout <= 0 {
+		httpClient.Timeout = DefaultTimeout
+	}
+	switch cfg.Provider {
+	case "":
+		return nil, nil
+	case ProviderTVmaze:
+		return NewTVmaze(cfg.BaseURL, httpClient), nil
+	case ProviderTMDB:
+		if cfg.APIKey == "" {
+			return nil, fmt.Errorf("the %s metadata provider needs METADATA_API_KEY", cfg.Provider)
+		}
+		return NewTMDB(cfg.BaseURL, cfg.ImageURL, cfg.APIKey, httpClient), nil
+	default:
+		return nil, fmt.Errorf("unknown metadata provider %q", cfg.Provider)
+	}
+}
+
+// Client matches shows of the archive to the provider and caches what it finds
+type Client struct {
+	provider Provider
+	cache    Cache // May be nil
+	ttl      time.Duration
+	missTTL  time.Duration
+
+	mu     sync.Mutex
+	recent map[string]recentEntry // Recently used cache entries, by key
+}
+
+type recentEntry struct {
+	data    []byte
+	expires time.Time
+}
+
+// New returns a client for the provider of cfg, caching in cache if it is not nil.
+// It returns nil if metadata is disabled
+func New(cfg Config, cache Cache) (*Client, error) {
+	provider, err := NewProvider(cfg)
+	if err != nil || provider == nil {
+		return nil, err
+	}
+	client := NewClient(provider, cache)
+	if cfg.TTL > 0 {
+		client.ttl = cfg.TTL
+	}
+	return client, nil
+}
+
+// NewClient returns a client for a provider
+func NewClient(provider Provider, cache Cache) *Client {
+	return &Client{
+		provider: provider,
+		cache:    cache,
+		ttl:      DefaultTTL,
+		missTTL:  DefaultMissTTL,
+		recent:   make(map[string]recentEntry),
+	}
+}
+
+// Lookup finds a show by the name the parser gave it, plus the year of its premiere if known
+// (0 otherwise), and returns its details. It returns ErrNotFound if the provider does not know it
+func (c *Client) Lookup(ctx context.Context, showName string, year int) (*ShowDetails, error) {
+	name, nameYear := SplitYear(showName)
+	if year == 0 {
+		year = nameYear
+	}
+	if name == "" {
+		return nil, ErrNotFound
+	}
+
+	// The match is cached separately from the details, so shows with several names share them
+	matchKey := fmt.Sprintf("%s:match:%s:%d", c.provider.Name(), strings.ToLower(name), year)
+	var showID string
+	if err := c.cached(matchKey, &showID, func() (any, error) {
+		shows, err := c.provider.SearchShows(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if show := Match(shows, name, year); show != nil {
+			return show.ID, nil
+		}
+		return "", nil
+	}); err != nil {
+		return nil, err
+	}
+	if showID == "" {
+		return nil, ErrNotFound
+	}
+
+	details := &ShowDetails{}
+	showKey := fmt.Sprintf("%s:show:%s", c.provider.Name(), showID)
+	if err := c.cached(showKey, details, func() (any, error) {
+		return c.provider.ShowDetails(ctx, showID)
+	}); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
+
+// cached decodes the cache entry of key into v, fetching and storing it when it is missing or
+// expired. Empty results (a show not found) expire after the shorter miss TTL
+func (c *Client) cached(key string, v any, fetch func() (any, error)) error {
+	c.mu.Lock()
+	entry, ok := c.recent[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return json.Unmarshal(entry.data, v)
+	}
+
+	if c.cache != nil {
+		data, fetchedAt, ok, err := c.cache.GetMetadata(key)
+		if err != nil {
+			return err
+		}
+		ttl := c.ttl
+		if string(data) == `""` {
+			ttl = c.missTTL
+		}
+		if ok && time.Since(fetchedAt) < ttl {
+			c.remember(key, data)
+			return json.Unmarshal(data, v)
+		}
+	}
+
+	result, err := fetch()
+	if err != nil {
+		return fmt.Errorf("%s: %w", c.provider.Name(), err)
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	if c.cache != nil {
+		if err := c.cache.SaveMetadata(key, data); err != nil {
+			return err
+		}
+	}
+	c.remember(key, data)
+	return json.Unmarshal(data, v)
+}
+
+// remember keeps a cache entry in memory for a minute
+func (c *Client) remember(key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, entry := range c.recent {
+		if now.After(entry.expires) {
+			delete(c.recent, k)
+		}
+	}
+	c.recent[key] = recentEntry{data: data, expires: now.Add(requestCacheTTL)}
+}
+
+// yearSuffix matches a year at the end of a show name: "Doctor Who (2005)", "Doctor Who 2005"
+var yearSuffix = regexp.MustCompile(`^(.*?)[\s.]*[\(\[]?((?:19|20)\d\d)[\)\]]?$`)
+
+// SplitYear splits a year off the end of a show name, returning 0 if there is none
+func SplitYear(showName string) (string, int) {
+	showName = strings.TrimSpace(showName)
+	if matches := yearSuffix.FindStringSubmatch(showName); matches != nil && strings.TrimSpace(matches[1]) != "" {
+		year, _ := strconv.Atoi(matches[2])
+		return strings.TrimSpace(matches[1]), year
+	}
+	return showName, 0
+}
+
+// Match picks the search result for a show name and year. Results are in the provider's order
+// of relevance; one whose name and year match wins, then one whose name matches, then a similar
+// name ("The Office" for "The Office US") of the right year, then the first similar name.
+// Results with unrelated names never match
+func Match(shows []Show, name string, year int) *Show {
+	words := nameWords(name)
+	key := strings.Join(words, "")
+	var nameMatch, similarYear, similar *Show
+	for i := range shows {
+		show := &shows[i]
+		showWords := nameWords(show.Name)
+		showKey := strings.Join(showWords, "")
+		sameYear := year != 0 && show.Year == year
+		switch {
+		case showKey == key && (sameYear || year == 0):
+			return show
+		case showKey == key:
+			if nameMatch == nil {
+				nameMatch = show
+			}
+		case containsWords(showWords, words) || containsWords(words, showWords):
+			if sameYear && similarYear == nil {
+				similarYear = show
+			}
+			if similar == nil {
+				similar = show
+			}
+		}
+	}
+	switch {
+	case nameMatch != nil:
+		return nameMatch
+	case similarYear != nil:
+		return similarYear
+	default:
+		return similar
+	}
+}
+
+// nameWords lower-cases a name and splits it into words for comparison, dropping punctuation
+// and a leading "the". Apostrophes are dropped within words, so "Grey's" is "greys"
+func nameWords(name string) []string {
+	var words []string
+	var b strings.Builder
+	flush := func() {
+		if b.Len() > 0 {
+			words = append(words, b.String())
+			b.Reset()
+		}
+	}
+	for _, r := range strings.ToLower(name) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '\'', r == '’':
+		case r == '&':
+			flush()
+			words = append(words, "and")
+		default:
+			flush()
+		}
+	}
+	flush()
+	if len(words) > 1 && words[0] == "the" {
+		words = words[1:]
+	}
+	return words
+}
+
+// containsWords reports whether sub appears in words as a run of whole words
+func containsWords(words, sub []string) bool {
+	if len(sub) == 0 {
+		return false
+	}
+	for i := 0; i+len(sub) <= len(words); i++ {
+		if slices.Equal(words[i:i+len(sub)], sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// htmlTags matches the markup of TVmaze summaries
+var htmlTags = regexp.MustCompile(`<[^>]*>`)
+
+// plainText strips markup from an overview
+func plainText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(htmlTags.ReplaceAllString(s, "")))
+}
+
+// yearOf returns the year of a YYYY-MM-DD date, 0 if there is none
+func yearOf(date string) int {
+	if len(date) < 4 {
+		return 0
+	}
+	year, _ := strconv.Atoi(date[:4])
+	return year
+}
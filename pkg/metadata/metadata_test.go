@@ -0,0 +1,203 @@
+package metadata
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// memoryCache is a Cache in memory
+type memoryCache struct {
+	entries map[string][]byte
+}
+
+func (m *memoryCache) GetMetadata(key string) ([]byte, time.Time, bool, error) {
+	data, ok := m.entries[key]
+	return data, time.Now(), ok, nil
+}
+
+func (m *memoryCache) SaveMetadata(key string, data []byte) error {
+	m.entries[key] = data
+	return nil
+}
+
+// fixtureServer serves canned responses by request path, counting requests
+func fixtureServer(t *testing.T, responses map[string]string) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		response, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestTVmaze(t *testing.T) {
+	server, requests := fixtureServer(t, map[string]string{
+		"/search/shows": `[
+			{"score": 0.9, "show": {"id": 1, "name": "Doctor Who", "premiered": "1963-11-23"}},
+			{"score": 0.8, "show": {"id": 210, "name": "Doctor Who", "premiered": "2005-03-26",
+				"summary": "<p>The <b>Doctor</b> travels in time.</p>", "image": {"medium": "http://img/210.jpg"}}}
+		]`,
+		"/shows/210": `{"id": 210, "name": "Doctor Who", "premiered": "2005-03-26",
+			"summary": "<p>The <b>Doctor</b> travels in time.</p>", "image": {"medium": "http://img/210.jpg"}, "_embedded": {
+			"seasons": [{"number": 1, "name": "", "image": {"original": "http://img/s1.jpg"}}],
+			"episodes": [
+				{"season": 1, "number": 1, "name": "Rose", "airdate": "2005-03-26", "summary": "<p>Rose meets the Doctor.</p>"},
+				{"season": 1, "number": null, "name": "Special"}
+			]}}`,
+	})
+
+	cache := &memoryCache{entries: make(map[string][]byte)}
+	client, err := New(Config{Provider: ProviderTVmaze, BaseURL: server.URL}, cache)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	details, err := client.Lookup(context.Background(), "Doctor Who (2005)", 0)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if details.ID != "210" || details.Year != 2005 || details.Overview != "The Doctor travels in time." || details.Poster != "http://img/210.jpg" {
+		t.Errorf("show = %+v", details.Show)
+	}
+	if season := details.Season(1); season == nil || season.Poster != "http://img/s1.jpg" {
+		t.Errorf("Season(1) = %+v", season)
+	}
+	if episode := details.Episode(1, 1); episode == nil || episode.Title != "Rose" || episode.Overview != "Rose meets the Doctor." {
+		t.Errorf("Episode(1, 1) = %+v", episode)
+	}
+	if last := details.LastAired("2005-03-25"); last[1] != 0 {
+		t.Errorf("LastAired() before the premiere = %v, want no episodes", last)
+	}
+	if last := details.LastAired("2005-03-26"); last[1] != 1 {
+		t.Errorf("LastAired() = %v, want episode 1 of season 1", last)
+	}
+	if len(details.Episodes) != 1 {
+		t.Errorf("got %d episodes, want specials without a number skipped", len(details.Episodes))
+	}
+	if *requests != 2 {
+		t.Errorf("got %d requests, want 2", *requests)
+	}
+
+	// Cached in the database: a new client makes no requests
+	client = NewClient(NewTVmaze(server.URL, http.DefaultClient), cache)
+	if _, err := client.Lookup(context.Background(), "Doctor Who", 2005); err != nil {
+		t.Fatalf("cached Lookup() error = %v", err)
+	}
+	if *requests != 2 {
+		t.Errorf("got %d requests after a cached lookup, want 2", *requests)
+	}
+
+	if _, err := client.Lookup(context.Background(), "Unknown Show", 0); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Lookup() of an unknown show error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestTMDB(t *testing.T) {
+	server, _ := fixtureServer(t, map[string]string{
+		"/search/tv": `{"results": [{"id": 2316, "name": "The Office", "first_air_date": "2005-03-24", "poster_path": "/office.jpg"}]}`,
+		"/tv/2316": `{"id": 2316, "name": "The Office", "first_air_date": "2005-03-24", "overview": "A mockumentary.", "poster_path": "/office.jpg",
+			"seasons": [{"season_number": 2, "name": "Season 2", "poster_path": "/s2.jpg"}]}`,
+		"/tv/2316/season/2": `{"episodes": [{"season_number": 2, "episode_number": 1, "name": "The Dundies", "air_date": "2005-09-20", "still_path": "/e1.jpg"}]}`,
+	})
+
+	client, err := New(Config{Provider: ProviderTMDB, BaseURL: server.URL, ImageURL: "http://img", APIKey: "key"}, nil)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	details, err := client.Lookup(context.Background(), "The Office US", 0)
+	if err != nil {
+		t.Fatalf("Lookup() error = %v", err)
+	}
+	if details.Poster != "http://img/office.jpg" || details.Overview != "A mockumentary." {
+		t.Errorf("show = %+v", details.Show)
+	}
+	if episode := details.EpisodeByAirDate("2005-09-20"); episode == nil || episode.Title != "The Dundies" || episode.Image != "http://img/e1.jpg" {
+		t.Errorf("EpisodeByAirDate() = %+v", episode)
+	}
+
+	if _, err := New(Config{Provider: ProviderTMDB}, nil); err == nil {
+		t.Error("New() accepted TMDB without an API key")
+	}
+	if client, err := New(Config{}, nil); client != nil || err != nil {
+		t.Errorf("New() without a provider = %v, %v, want nil, nil", client, err)
+	}
+}
+
+func TestMatch(t *testing.T) {
+	shows := []Show{
+		{ID: "1", Name: "Shameless", Year: 2004},
+		{ID: "2", Name: "Shameless", Year: 2011},
+		{ID: "3", Name: "Shameless Hall of Shame", Year: 2020},
+	}
+	tests := []struct {
+		name     string
+		year     int
+		expected string
+	}{
+		{"Shameless", 2011, "2"},
+		{"Shameless", 0, "1"},
+		{"shameless", 1999, "1"},
+		{"Shameless US", 2011, "2"},
+		{"Fargo", 0, ""},
+		{"Sham", 0, ""},
+		{"Hall of Shame", 2020, "3"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if show := Match(shows, tt.name, tt.year); show != nil {
+			got = show.ID
+		}
+		if got != tt.expected {
+			t.Errorf("Match(%q, %d) = %q, want %q", tt.name, tt.year, got, tt.expected)
+		}
+	}
+
+	for name, expected := range map[string]int{"Doctor Who (2005)": 2005, "Doctor Who 2005": 2005, "Doctor Who": 0, "1923": 0} {
+		if _, year := SplitYear(name); year != expected {
+			t.Errorf("SplitYear(%q) year = %d, want %d", name, year, expected)
+		}
+	}
+}
+
+func TestMatchWords(t *testing.T) {
+	tests := []struct {
+		name     string
+		shows    []Show
+		query    string
+		expected string
+	}{
+		{"leading the", []Show{{ID: "1", Name: "The Office"}}, "Office", "1"},
+		{"the within a word", []Show{{ID: "1", Name: "Theodore"}}, "odore", ""},
+		{"the as the whole name", []Show{{ID: "1", Name: "The"}}, "the", "1"},
+		{"word prefix", []Show{{ID: "1", Name: "Lost Girl"}, {ID: "2", Name: "Lostgirl"}}, "Lost", "1"},
+		{"name within a word", []Show{{ID: "1", Name: "Lostgirl"}}, "Lost", ""},
+		{"punctuation", []Show{{ID: "1", Name: "Mr. Robot"}}, "Mr Robot", "1"},
+		{"apostrophe", []Show{{ID: "1", Name: "Grey's Anatomy"}}, "Greys Anatomy", "1"},
+		{"ampersand", []Show{{ID: "1", Name: "Law & Order"}}, "Law and Order", "1"},
+		{"extra words", []Show{{ID: "1", Name: "The Office"}}, "The Office US", "1"},
+		{"empty name", []Show{{ID: "1", Name: "Fargo"}}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if show := Match(tt.shows, tt.query, 0); show != nil {
+				got = show.ID
+			}
+			if got != tt.expected {
+				t.Errorf("Match(%q) = %q, want %q", tt.query, got, tt.expected)
+			}
+		})
+	}
+}
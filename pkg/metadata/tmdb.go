@@ -0,0 +1,157 @@
+package metadata
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// TMDB defaults
+const (
+	DefaultTMDBURL      = "https://api.themoviedb.org/3"
+	DefaultTMDBImageURL = "https://image.tmdb.org/t/p/w342"
+)
+
+// TMDB is a Provider for The Movie Database API (v3)
+type TMDB struct {
+	baseURL  string
+	imageURL string
+	apiKey   string // v3 API key, or a v4 read access token sent as bearer token
+	client   *http.Client
+}
+
+// NewTMDB returns a TMDB provider; baseURL and imageURL default to DefaultTMDBURL and DefaultTMDBImageURL
+func NewTMDB(baseURL, imageURL, apiKey string, client *http.Client) *TMDB {
+	if baseURL == "" {
+		baseURL = DefaultTMDBURL
+	}
+	if imageURL == "" {
+		imageURL = DefaultTMDBImageURL
+	}
+	return &TMDB{
+		baseURL:  strings.TrimRight(baseURL, "/"),
+		imageURL: strings.TrimRight(imageURL, "/"),
+		apiKey:   apiKey,
+		client:   client,
+	}
+}
+
+// Name returns the provider name
+func (t *TMDB) Name() string {
+	return ProviderTMDB
+}
+
+type tmdbShow struct {
+	ID           int    `json:"id"`
+	Name         string `json:"name"`
+	FirstAirDate string `json:"first_air_date"`
+	Overview     string `json:"overview"`
+	PosterPath   string `json:"poster_path"`
+	Seasons      []struct {
+		SeasonNumber int    `json:"season_number"`
+		Name         string `json:"name"`
+		Overview     string `json:"overview"`
+		PosterPath   string `json:"poster_path"`
+	} `json:"seasons"`
+}
+
+func (t *TMDB) show(s tmdbShow) Show {
+	return Show{
+		ID:        strconv.Itoa(s.ID),
+		Name:      s.Name,
+		Year:      yearOf(s.FirstAirDate),
+		Premiered: s.FirstAirDate,
+		Overview:  s.Overview,
+		Poster:    t.image(s.PosterPath),
+	}
+}
+
+// image returns the URL of an image path, "" if there is none
+func (t *TMDB) image(path string) string {
+	if path == "" {
+		return ""
+	}
+	return t.imageURL + path
+}
+
+// SearchShows searches TV shows by name
+func (t *TMDB) SearchShows(ctx context.Context, query string) ([]Show, error) {
+	var response struct {
+		Results []tmdbShow `json:"results"`
+	}
+	if err := t.get(ctx, "/search/tv", url.Values{"query": {query}}, &response); err != nil {
+		return nil, err
+	}
+	shows := make([]Show, 0, len(response.Results))
+	for _, result := range response.Results {
+		shows = append(shows, t.show(result))
+	}
+	return shows, nil
+}
+
+// ShowDetails returns a show with its seasons and, one request per season, their episodes
+func (t *TMDB) ShowDetails(ctx context.Context, id string) (*ShowDetails, error) {
+	var show tmdbShow
+	if err := t.get(ctx, "/tv/"+url.PathEscape(id), nil, &show); err != nil {
+		return nil, err
+	}
+
+	details := &ShowDetails{Show: t.show(show), Seasons: []Season{}, Episodes: []Episode{}}
+	for _, season := range show.Seasons {
+		details.Seasons = append(details.Seasons, Season{
+			Number:   season.SeasonNumber,
+			Name:     season.Name,
+			Overview: season.Overview,
+			Poster:   t.image(season.PosterPath),
+		})
+
+		var episodes struct {
+			Episodes []struct {
+				SeasonNumber  int    `json:"season_number"`
+				EpisodeNumber int    `json:"episode_number"`
+				Name          string `json:"name"`
+				AirDate       string `json:"air_date"`
+				Overview      string `json:"overview"`
+				StillPath     string `json:"still_path"`
+			} `json:"episodes"`
+		}
+		path := fmt.Sprintf("/tv/%s/season/%d", url.PathEscape(id), season.SeasonNumber)
+		if err := t.get(ctx, path, nil, &episodes); err != nil {
+			return nil, err
+		}
+		for _, episode := range episodes.Episodes {
+			details.Episodes = append(details.Episodes, Episode{
+				Season:   episode.SeasonNumber,
+				Number:   episode.EpisodeNumber,
+				Title:    episode.Name,
+				AirDate:  episode.AirDate,
+				Overview: episode.Overview,
+				Image:    t.image(episode.StillPath),
+			})
+		}
+	}
+	return details, nil
+}
+
+// get decodes the JSON response of a GET request, authenticated with the API key
+func (t *TMDB) get(ctx context.Context, path string, query url.Values, v any) error {
+	if query == nil {
+		query = url.Values{}
+	}
+	// v4 read access tokens are JWTs; v3 keys go in the query
+	bearer := strings.Count(t.apiKey, ".") == 2
+	if !bearer {
+		query.Set("api_key", t.apiKey)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", t.baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	if bearer {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+	return doJSON(t.client, req, v)
+}
@@ -0,0 +1,167 @@
+package metadata
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// DefaultTVmazeURL is the public TVmaze API, which needs no key
+const DefaultTVmazeURL = "https://api.tvmaze.com"
+
+// TVmaze is a Provider for the TVmaze API
+type TVmaze struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewTVmaze returns a TVmaze provider; baseURL defaults to DefaultTVmazeURL
+func NewTVmaze(baseURL string, client *http.Client) *TVmaze {
+	if baseURL == "" {
+		baseURL = DefaultTVmazeURL
+	}
+	return &TVmaze{baseURL: strings.TrimRight(baseURL, "/"), client: client}
+}
+
+// Name returns the provider name
+func (t *TVmaze) Name() string {
+	return ProviderTVmaze
+}
+
+type tvmazeImage struct {
+	Medium   string `json:"medium"`
+	Original string `json:"original"`
+}
+
+// url prefers the medium size, which is plenty for cards
+func (i *tvmazeImage) url() string {
+	if i == nil {
+		return ""
+	}
+	if i.Medium != "" {
+		return i.Medium
+	}
+	return i.Original
+}
+
+type tvmazeShow struct {
+	ID        int          `json:"id"`
+	Name      string       `json:"name"`
+	Premiered string       `json:"premiered"`
+	Summary   string       `json:"summary"`
+	Image     *tvmazeImage `json:"image"`
+	Embedded  struct {
+		Seasons []struct {
+			Number  int          `json:"number"`
+			Name    string       `json:"name"`
+			Summary string       `json:"summary"`
+			Image   *tvmazeImage `json:"image"`
+		} `json:"seasons"`
+		Episodes []struct {
+			Season  int          `json:"season"`
+			Number  *int         `json:"number"` // null for specials
+			Name    string       `json:"name"`
+			Airdate string       `json:"airdate"`
+			Summary string       `json:"summary"`
+			Image   *tvmazeImage `json:"image"`
+		} `json:"episodes"`
+	} `json:"_embedded"`
+}
+
+func (s tvmazeShow) show() Show {
+	return Show{
+		ID:        strconv.Itoa(s.ID),
+		Name:      s.Name,
+		Year:      yearOf(s.Premiered),
+		Premiered: s.Premiered,
+		Overview:  plainText(s.Summary),
+		Poster:    s.Image.url(),
+	}
+}
+
+// SearchShows searches shows by name
+func (t *TVmaze) SearchShows(ctx context.Context, query string) ([]Show, error) {
+	var results []struct {
+		Show tvmazeShow `json:"show"`
+	}
+	if err := t.get(ctx, "/search/shows?q="+url.QueryEscape(query), &results); err != nil {
+		return nil, err
+	}
+	shows := make([]Show, 0, len(results))
+	for _, result := range results {
+		shows = append(shows, result.Show.show())
+	}
+	return shows, nil
+}
+
+// ShowDetails returns a show with its seasons and episodes
+func (t *TVmaze) ShowDetails(ctx context.Context, id string) (*ShowDetails, error) {
+	var show tvmazeShow
+	if err := t.get(ctx, "/shows/"+url.PathEscape(id)+"?embed[]=seasons&embed[]=episodes", &show); err != nil {
+		return nil, err
+	}
+
+	details := &ShowDetails{Show: show.show(), Seasons: []Season{}, Episodes: []Episode{}}
+	for _, season := range show.Embedded.Seasons {
+		details.Seasons = append(details.Seasons, Season{
+			Number:   season.Number,
+			Name:     season.Name,
+			Overview: plainText(season.Summary),
+			Poster:   season.Image.url(),
+		})
+	}
+	for _, episode := range show.Embedded.Episodes {
+		if episode.Number == nil {
+			continue
+		}
+		details.Episodes = append(details.Episodes, Episode{
+			Season:   episode.Season,
+			Number:   *episode.Number,
+			Title:    episode.Name,
+			AirDate:  episode.Airdate,
+			Overview: plainText(episode.Summary),
+			Image:    episode.Image.url(),
+		})
+	}
+	return details, nil
+}
+
+// get decodes the JSON response of a GET request
+func (t *TVmaze) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", t.baseURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	return doJSON(t.client, req, v)
+}
+
+// doJSON sends a request and decodes its JSON response
+func doJSON(client *http.Client, req *http.Request, v any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		// Without the URL, which may carry an API key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("request to %s failed: %w", req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", req.URL.Path, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package notify
+
+import "sync"
+
+// subscriberBuffer is how many events a slow subscriber may lag behind before events are dropped
+const subscriberBuffer = 16
+
+// Broker fans events out to the open browser feeds of users
+type Broker struct {
+	mu     sync.Mutex
+	nextID int
+	subs   map[int]*subscriber
+}
+
+// subscriber is one open feed
+type subscriber struct {
+	userID int64
+	events chan Event
+}
+
+// NewBroker creates an empty broker
+func NewBroker() *Broker {
+	return &Broker{subs: make(map[int]*subscriber)}
+}
+
+// Subscribe opens a feed for a user; call the returned function to close it
+func (b *Broker) Subscribe(userID int64) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	id := b.nextID
+	sub := &subscriber{userID: userID, events: make(chan Event, subscriberBuffer)}
+	b.subs[id] = sub
+
+	return sub.events, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[id]; ok {
+			delete(b.subs, id)
+			close(sub.events)
+		}
+	}
+}
+
+// Publish sends an event to the open feeds of the given users
+// Feeds that are not keeping up miss the event rather than blocking the publisher
+func (b *Broker) Publish(e Event, userIDs []int64) {
+	recipients := make(map[int64]bool, len(userIDs))
+	for _, id := range userIDs {
+		recipients[id] = true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, sub := range b.subs {
+		if !recipients[sub.userID] {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package notify
+
+import "testing"
+
+func TestBrokerPublish(t *testing.T) {
+	b := NewBroker()
+	alice, closeAlice := b.Subscribe(1)
+	aliceTab, closeAliceTab := b.Subscribe(1)
+	bob, closeBob := b.Subscribe(2)
+	defer closeAlice()
+	defer closeAliceTab()
+	defer closeBob()
+
+	b.Publish(Event{Type: EventEpisodeUploaded, VideoID: 7}, []int64{1, 3})
+
+	tests := []struct {
+		name     string
+		feed     <-chan Event
+		expected int
+	}{
+		{"recipient", alice, 1},
+		{"second feed of a recipient", aliceTab, 1},
+		{"other user", bob, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := len(tt.feed); got != tt.expected {
+				t.Errorf("feed has %d events, want %d", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBrokerSlowSubscriber(t *testing.T) {
+	b := NewBroker()
+	feed, unsubscribe := b.Subscribe(1)
+	defer unsubscribe()
+
+	// Publish must not block on a feed nobody reads
+	for i := 0; i < subscriberBuffer+5; i++ {
+		b.Publish(Event{Type: EventEpisodeUploaded, VideoID: int64(i)}, []int64{1})
+	}
+	if got := len(feed); got != subscriberBuffer {
+		t.Fatalf("feed has %d events, want %d", got, subscriberBuffer)
+	}
+	if e := <-feed; e.VideoID != 0 {
+		t.Errorf("first event is video %d, want the oldest, 0", e.VideoID)
+	}
+}
+
+func TestBrokerUnsubscribe(t *testing.T) {
+	b := NewBroker()
+	feed, unsubscribe := b.Subscribe(1)
+	unsubscribe()
+	unsubscribe()
+
+	if _, ok := <-feed; ok {
+		t.Error("feed still open after unsubscribing")
+	}
+	b.Publish(Event{Type: EventEpisodeUploaded}, []int64{1})
+	if len(b.subs) != 0 {
+		t.Errorf("%d subscribers left, want 0", len(b.subs))
+	}
+}
@@ -0,0 +1,309 @@
+// Package notify delivers archive events to webhooks, Telegram chats and browsers
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+// Event types
+const (
+	EventEpisodeUploaded = "episode.uploaded"
+	EventTorrentFailed   = "torrent.failed"
+	EventCleanupDeleted  = "cleanup.deleted"
+)
+
+// EventTypes lists the event types subscriptions can filter on
+var EventTypes = []string{EventEpisodeUploaded, EventTorrentFailed, EventCleanupDeleted}
+
+// ValidEventType reports whether t is a known event type
+func ValidEventType(t string) bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is something that happened in the archive; it is also the JSON payload of webhooks
+type Event struct {
+	Type       string    `json:"type"`
+	Time       time.Time `json:"time"`
+	Show       string    `json:"show,omitempty"`
+	Season     int       `json:"season,omitempty"`
+	Episode    int       `json:"episode,omitempty"`
+	VideoID    int64     `json:"videoId,omitempty"`
+	Title      string    `json:"title,omitempty"`
+	Torrent    string    `json:"torrent,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Files      []string  `json:"files,omitempty"`
+	FreedBytes int64     `json:"freedBytes,omitempty"`
+}
+
+// EpisodeUploaded returns the event for a video uploaded to the archive chat
+func EpisodeUploaded(v database.Video) Event {
+	return Event{
+		Type:    EventEpisodeUploaded,
+		Time:    time.Now(),
+		Show:    v.ShowName,
+		Season:  v.SeasonNumber,
+		Episode: v.EpisodeNumber,
+		VideoID: v.ID,
+		Title:   v.Title,
+	}
+}
+
+// TorrentFailed returns the event for a torrent that could not be downloaded
+func TorrentFailed(torrentName string, err error) Event {
+	return Event{
+		Type:    EventTorrentFailed,
+		Time:    time.Now(),
+		Torrent: torrentName,
+		Error:   err.Error(),
+	}
+}
+
+// CleanupDeleted returns the event for files removed by the storage cleanup
+func CleanupDeleted(files []string, freedBytes int64) Event {
+	return Event{
+		Type:       EventCleanupDeleted,
+		Time:       time.Now(),
+		Files:      files,
+		FreedBytes: freedBytes,
+	}
+}
+
+// Text returns a one-line plain text description of the event
+func (e Event) Text() string {
+	switch e.Type {
+	case EventEpisodeUploaded:
+		if e.Show == "" {
+			return "New video: " + e.Title
+		}
+		if e.Season > 0 || e.Episode > 0 {
+			return fmt.Sprintf("New episode: %s S%02dE%02d", e.Show, e.Season, e.Episode)
+		}
+		return fmt.Sprintf("New episode: %s – %s", e.Show, e.Title)
+	case EventTorrentFailed:
+		return fmt.Sprintf("Torrent failed: %s: %s", e.Torrent, e.Error)
+	case EventCleanupDeleted:
+		return fmt.Sprintf("Storage cleanup deleted %d files (%.2f GB freed)", len(e.Files), float64(e.FreedBytes)/(1024*1024*1024))
+	}
+	return e.Type
+}
+
+const (
+	// catchUpOverlap is how far before the last handled change catch-up looks, to cover changes
+	// committed out of order and small clock differences between trtg-bot and this process
+	catchUpOverlap = time.Minute
+	// notifiedTTL is how long notified changes are remembered so catch-up does not repeat them
+	notifiedTTL = time.Hour
+)
+
+// TelegramSender sends HTML messages to Telegram chats
+type TelegramSender interface {
+	SendHTML(chatID int64, text string) error
+}
+
+// Dispatcher sends events to the subscriptions that match them
+type Dispatcher struct {
+	db       *database.DB
+	broker   *Broker
+	telegram TelegramSender
+	client   *http.Client
+
+	mu       sync.Mutex
+	handled  time.Time            // When the last change was handled; catch-up starts there
+	notified map[string]time.Time // Changes already notified, by notifiedKey
+}
+
+// NewDispatcher creates a dispatcher; browser subscribers receive events through Broker
+func NewDispatcher(db *database.DB) *Dispatcher {
+	return &Dispatcher{
+		db:       db,
+		broker:   NewBroker(),
+		client:   &http.Client{Timeout: 15 * time.Second},
+		handled:  time.Now(),
+		notified: make(map[string]time.Time),
+	}
+}
+
+// SetTelegram enables Telegram subscriptions
+func (d *Dispatcher) SetTelegram(sender TelegramSender) {
+	d.telegram = sender
+}
+
+// Broker returns the broker browser subscribers listen on
+func (d *Dispatcher) Broker() *Broker {
+	return d.broker
+}
+
+// Publish delivers an event to all matching subscriptions
+// Webhooks and Telegram messages are sent in the background; a nil Dispatcher drops the event
+func (d *Dispatcher) Publish(e Event) {
+	if d == nil {
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	subs, err := d.db.GetMatchingSubscriptions(e.Type, e.Show)
+	if err != nil {
+		log.Printf("Warning: Failed to look up subscriptions for %s: %v", e.Type, err)
+		return
+	}
+
+	var browserUsers []int64
+	for _, sub := range subs {
+		switch sub.Kind {
+		case database.NotifyBrowser:
+			browserUsers = append(browserUsers, sub.UserID)
+		case database.NotifyWebhook:
+			go d.sendWebhook(sub, e)
+		case database.NotifyTelegram:
+			go d.sendTelegram(sub, e)
+		}
+	}
+	if len(browserUsers) > 0 {
+		d.broker.Publish(e, browserUsers)
+	}
+}
+
+// HandleChange publishes the events implied by a database change:
+// a video becoming uploaded and a torrent job failing
+// This lets the download worker trigger notifications without talking to this process.
+// On a ChangeResync, changes made since the last handled one are read from the database,
+// so notifications dropped by the listener or the event bus are still sent
+func (d *Dispatcher) HandleChange(c database.ChangeEvent) {
+	if d == nil {
+		return
+	}
+	if c.Op == database.ChangeResync {
+		d.catchUp()
+		return
+	}
+	d.mu.Lock()
+	d.handled = time.Now()
+	d.mu.Unlock()
+	d.handleChange(c)
+}
+
+// catchUp handles the changes made since shortly before the last handled change
+// Changes notified before are skipped
+func (d *Dispatcher) catchUp() {
+	d.mu.Lock()
+	since := d.handled.Add(-catchUpOverlap)
+	d.mu.Unlock()
+
+	now := time.Now()
+	changes, err := d.db.ChangesSince(since)
+	if err != nil {
+		log.Printf("Warning: Failed to catch up on missed notifications: %v", err)
+		return
+	}
+	d.mu.Lock()
+	d.handled = now
+	d.mu.Unlock()
+
+	log.Printf("Catching up on notifications for %d changes since %s", len(changes), since.Format(time.RFC3339))
+	for _, c := range changes {
+		d.handleChange(c)
+	}
+}
+
+// handleChange publishes the event of a change unless it was already notified
+func (d *Dispatcher) handleChange(c database.ChangeEvent) {
+	switch c.Table {
+	case database.ChangeVideos:
+		if !c.Uploaded || c.Op == database.ChangeDelete || (c.Op == database.ChangeUpdate && c.WasUploaded) {
+			return
+		}
+		if !d.markNotified(c) {
+			return
+		}
+		v, err := d.db.GetVideoByID(c.ID)
+		if err != nil {
+			log.Printf("Warning: Failed to load video %d for notifications: %v", c.ID, err)
+			return
+		}
+		d.Publish(EpisodeUploaded(*v))
+
+	case database.ChangeTorrentJobs:
+		if c.Status != database.JobFailed || c.OldStatus == database.JobFailed {
+			return
+		}
+		if !d.markNotified(c) {
+			return
+		}
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("job #%d", c.ID)
+		}
+		d.Publish(TorrentFailed(name, errors.New(c.Error)))
+	}
+}
+
+// markNotified records that the event of a change is being published
+// Returns false if it already was within notifiedTTL
+func (d *Dispatcher) markNotified(c database.ChangeEvent) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	now := time.Now()
+	for key, at := range d.notified {
+		if now.Sub(at) > notifiedTTL {
+			delete(d.notified, key)
+		}
+	}
+
+	key := fmt.Sprintf("%s:%d:%s", c.Table, c.ID, c.Status)
+	if _, ok := d.notified[key]; ok {
+		return false
+	}
+	d.notified[key] = now
+	return true
+}
+
+// sendTelegram posts an event to the subscription's chat
+func (d *Dispatcher) sendTelegram(sub database.NotificationSubscription, e Event) {
+	if d.telegram == nil {
+		log.Printf("Warning: Telegram notifications are not configured, dropping %s for subscription %d", e.Type, sub.ID)
+		return
+	}
+	chatID, err := strconv.ParseInt(sub.Target, 10, 64)
+	if err != nil {
+		log.Printf("Warning: Invalid chat ID in subscription %d: %v", sub.ID, err)
+		return
+	}
+	if err := d.telegram.SendHTML(chatID, telegramText(e)); err != nil {
+		log.Printf("Warning: Failed to send %s notification for subscription %d: %v", e.Type, sub.ID, err)
+	}
+}
+
+// telegramText formats an event as a Telegram HTML message
+func telegramText(e Event) string {
+	text := html.EscapeString(e.Text())
+	if e.Type == EventCleanupDeleted && len(e.Files) > 0 {
+		var names []string
+		for i, file := range e.Files {
+			if i == 10 {
+				names = append(names, fmt.Sprintf("…and %d more", len(e.Files)-10))
+				break
+			}
+			names = append(names, html.EscapeString(filepath.Base(file)))
+		}
+		text += "\n" + strings.Join(names, "\n")
+	}
+	return text
+}
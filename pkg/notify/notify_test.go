@@ -0,0 +1,55 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+func TestMarkNotified(t *testing.T) {
+	uploaded := database.ChangeEvent{Table: database.ChangeVideos, Op: database.ChangeInsert, ID: 1, Uploaded: true}
+	failed := database.ChangeEvent{Table: database.ChangeTorrentJobs, Op: database.ChangeUpdate, ID: 1, Status: database.JobFailed}
+
+	tests := []struct {
+		name     string
+		previous []database.ChangeEvent
+		change   database.ChangeEvent
+		expected bool
+	}{
+		{"first time", nil, uploaded, true},
+		{"again", []database.ChangeEvent{uploaded}, uploaded, false},
+		{"same change delivered by catch-up", []database.ChangeEvent{failed}, failed, false},
+		{"same ID in another table", []database.ChangeEvent{uploaded}, failed, true},
+		{"other video", []database.ChangeEvent{uploaded}, database.ChangeEvent{Table: database.ChangeVideos, ID: 2, Uploaded: true}, true},
+		{"other status of the same job", []database.ChangeEvent{{Table: database.ChangeTorrentJobs, ID: 1, Status: database.JobQueued}}, failed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Dispatcher{notified: make(map[string]time.Time)}
+			for _, c := range tt.previous {
+				d.markNotified(c)
+			}
+			if got := d.markNotified(tt.change); got != tt.expected {
+				t.Errorf("markNotified() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMarkNotifiedExpires(t *testing.T) {
+	c := database.ChangeEvent{Table: database.ChangeVideos, ID: 1, Uploaded: true}
+	d := &Dispatcher{notified: make(map[string]time.Time)}
+	d.markNotified(c)
+	for key := range d.notified {
+		d.notified[key] = time.Now().Add(-notifiedTTL - time.Minute)
+	}
+
+	if !d.markNotified(c) {
+		t.Error("markNotified() = false for a change notified longer than notifiedTTL ago")
+	}
+	if len(d.notified) != 1 {
+		t.Errorf("%d notified changes remembered, want 1", len(d.notified))
+	}
+}
@@ -0,0 +1,84 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+// Webhook request headers
+const (
+	// SignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the body keyed with the subscription secret
+	SignatureHeader = "X-Trtg-Signature"
+	// EventHeader carries the event type
+	EventHeader = "X-Trtg-Event"
+)
+
+// webhookAttempts is how many times a webhook is tried before giving up
+const webhookAttempts = 3
+
+// Sign returns the SignatureHeader value for a webhook body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a SignatureHeader value; receivers in Go can use it directly
+func VerifySignature(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// sendWebhook POSTs an event to the subscription's URL, retrying on errors and 5xx responses
+func (d *Dispatcher) sendWebhook(sub database.NotificationSubscription, e Event) {
+	body, err := json.Marshal(e)
+	if err != nil {
+		log.Printf("Warning: Failed to encode %s event: %v", e.Type, err)
+		return
+	}
+
+	for attempt := 1; attempt <= webhookAttempts; attempt++ {
+		err = d.postWebhook(sub, e.Type, body)
+		if err == nil {
+			return
+		}
+		if attempt < webhookAttempts {
+			time.Sleep(time.Duration(attempt*attempt) * 5 * time.Second)
+		}
+	}
+	log.Printf("Warning: Webhook for subscription %d failed after %d attempts: %v", sub.ID, webhookAttempts, err)
+}
+
+// postWebhook makes one webhook request
+func (d *Dispatcher) postWebhook(sub database.NotificationSubscription, eventType string, body []byte) error {
+	req, err := http.NewRequest("POST", sub.Target, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "trtg-webhook")
+	req.Header.Set(EventHeader, eventType)
+	req.Header.Set(SignatureHeader, Sign(sub.Secret, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send webhook: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	if resp.StatusCode >= 300 {
+		// Client errors will not go away by retrying
+		log.Printf("Warning: Webhook for subscription %d returned status %d", sub.ID, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte("The quick brown fox jumps over the lazy dog")
+	expected := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got := Sign("key", body); got != expected {
+		t.Errorf("Sign() = %q, want %q", got, expected)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"type":"episode.uploaded"}`)
+	signature := Sign("secret", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		body      []byte
+		signature string
+		expected  bool
+	}{
+		{"valid", "secret", body, signature, true},
+		{"wrong secret", "other", body, signature, false},
+		{"modified body", "secret", []byte(`{"type":"torrent.failed"}`), signature, false},
+		{"missing prefix", "secret", body, signature[len("sha256="):], false},
+		{"uppercase hex", "secret", body, "sha256=" + strings.ToUpper(signature[len("sha256="):]), false},
+		{"empty", "secret", body, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, tt.body, tt.signature); got != tt.expected {
+				t.Errorf("VerifySignature(%q, %s, %q) = %v, want %v", tt.secret, tt.body, tt.signature, got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,234 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// goldenFile is the corpus of torrent names and file paths with the results the parser should give
+const goldenFile = "testdata/golden.json"
+
+var update = flag.Bool("update", false, "fill in the expectations of new entries in "+goldenFile+" with the parser's current results")
+
+// goldenMinAccuracy is the share of the corpus, in percent, each field must get right. The known
+// failures of the corpus count as wrong, so adding one can push a field below its minimum.
+var goldenMinAccuracy = map[string]float64{
+	"showName":     89,
+	"season":       93,
+	"episode":      93,
+	"episodeEnd":   100,
+	"airDate":      100,
+	"absolute":     100,
+	"mediaType":    100,
+	"movie":        99,
+	"matchedRule":  92,
+	"resolution":   100,
+	"videoCodec":   100,
+	"source":       100,
+	"releaseGroup": 99,
+}
+
+// TestMain makes runs deterministic: show names come from the regex resolver whatever
+// SHOW_NAME_RESOLVERS and ANTHROPIC_API_KEY say, and the built-in rules apply
+func TestMain(m *testing.M) {
+	SetShowNameResolver(RegexResolver{})
+	if err := SetRules(DefaultRules()); err != nil {
+		fmt.Fprintf(os.Stderr, "SetRules() error = %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(m.Run())
+}
+
+// goldenCase is an entry of the corpus. Expected holds the hand-verified right results, not
+// necessarily the parser's; KnownFailures names the fields the parser currently gets wrong.
+// New entries may leave out expected and get a draft of it with -update.
+type goldenCase struct {
+	TorrentName   string        `json:"torrentName"`
+	FilePath      string        `json:"filePath"`
+	Expected      *goldenResult `json:"expected,omitempty"`
+	KnownFailures []string      `json:"knownFailures,omitempty"`
+}
+
+// goldenResult is the part of a VideoInfo the corpus checks. Show names are the show's title
+// with the words and capitalization of the official one and the punctuation of the release,
+// hyphens as spaces; movies are grouped by title and year, so they leave the show name out.
+type goldenResult struct {
+	ShowName       string `json:"showName,omitempty"`
+	SeasonNumber   int    `json:"season"`
+	EpisodeNumber  int    `json:"episode"`
+	EpisodeEnd     int    `json:"episodeEnd,omitempty"`
+	AirDate        string `json:"airDate,omitempty"`
+	AbsoluteNumber int    `json:"absolute,omitempty"`
+	MediaType      string `json:"mediaType"`
+	Movie          *Movie `json:"movie,omitempty"`
+	MatchedRule    string `json:"matchedRule"`
+	Resolution     string `json:"resolution,omitempty"`
+	VideoCodec     string `json:"videoCodec,omitempty"`
+	Source         string `json:"source,omitempty"`
+	ReleaseGroup   string `json:"releaseGroup,omitempty"`
+}
+
+// goldenFields are the fields accuracy is reported for, in report order
+var goldenFields = []string{
+	"showName", "season", "episode", "episodeEnd", "airDate", "absolute", "mediaType", "movie",
+	"matchedRule", "resolution", "videoCodec", "source", "releaseGroup",
+}
+
+func newGoldenResult(info VideoInfo) *goldenResult {
+	result := &goldenResult{
+		SeasonNumber:   info.SeasonNumber,
+		EpisodeNumber:  info.EpisodeNumber,
+		EpisodeEnd:     info.EpisodeEnd,
+		AbsoluteNumber: info.AbsoluteNumber,
+		MediaType:      info.MediaType,
+		MatchedRule:    info.MatchedRule,
+		Resolution:     info.Release.Resolution,
+		VideoCodec:     info.Release.VideoCodec,
+		Source:         info.Release.Source,
+		ReleaseGroup:   info.Release.ReleaseGroup,
+	}
+	if !info.AirDate.IsZero() {
+		result.AirDate = info.AirDate.Format("2006-01-02")
+	}
+	if info.MediaType == MediaMovie {
+		movie := info.Movie
+		result.Movie = &movie
+	} else {
+		result.ShowName = info.ShowName
+	}
+	return result
+}
+
+// field returns a field of the result by its name in goldenFields, formatted for comparison
+func (g *goldenResult) field(name string) string {
+	switch name {
+	case "showName":
+		return g.ShowName
+	case "season":
+		return strconv.Itoa(g.SeasonNumber)
+	case "episode":
+		return strconv.Itoa(g.EpisodeNumber)
+	case "episodeEnd":
+		return strconv.Itoa(g.EpisodeEnd)
+	case "airDate":
+		return g.AirDate
+	case "absolute":
+		return strconv.Itoa(g.AbsoluteNumber)
+	case "mediaType":
+		return g.MediaType
+	case "movie":
+		if g.Movie == nil {
+			return ""
+		}
+		return fmt.Sprintf("%s (%d) %s", g.Movie.Title, g.Movie.Year, g.Movie.Edition)
+	case "matchedRule":
+		return g.MatchedRule
+	case "resolution":
+		return g.Resolution
+	case "videoCodec":
+		return g.VideoCodec
+	case "source":
+		return g.Source
+	case "releaseGroup":
+		return g.ReleaseGroup
+	}
+	panic("unknown golden field " + name)
+}
+
+// TestGoldenCorpus parses every entry of the corpus and reports how many of them each field
+// gets right. A field that differs from the expectation fails the test unless the entry lists
+// it as a known failure, and so does a known failure the parser now gets right, so the list
+// stays current. -update only drafts the expectations of new entries: check every field by
+// hand before committing them, and list the ones the parser gets wrong as known failures.
+func TestGoldenCorpus(t *testing.T) {
+	data, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", goldenFile, err)
+	}
+	var cases []goldenCase
+	if err := json.Unmarshal(data, &cases); err != nil {
+		t.Fatalf("failed to parse %s: %v", goldenFile, err)
+	}
+
+	if *update {
+		added := 0
+		for i := range cases {
+			if cases[i].Expected == nil {
+				cases[i].Expected = newGoldenResult(ParseVideoInfo(cases[i].TorrentName, cases[i].FilePath))
+				t.Logf("%s | %s: review the drafted expectation", cases[i].TorrentName, cases[i].FilePath)
+				added++
+			}
+		}
+		var buf bytes.Buffer
+		encoder := json.NewEncoder(&buf)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "\t")
+		if err := encoder.Encode(cases); err != nil {
+			t.Fatalf("failed to encode %s: %v", goldenFile, err)
+		}
+		if err := os.WriteFile(goldenFile, buf.Bytes(), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", goldenFile, err)
+		}
+		t.Logf("drafted %d expectations in %s", added, goldenFile)
+		return
+	}
+
+	correct := make(map[string]int)
+	known := 0
+	checked := 0
+	for _, c := range cases {
+		if c.Expected == nil {
+			t.Errorf("%s | %s: no expectation, run with -update", c.TorrentName, c.FilePath)
+			continue
+		}
+		knownFailures := make(map[string]bool)
+		for _, name := range c.KnownFailures {
+			if _, ok := goldenMinAccuracy[name]; !ok {
+				t.Errorf("%s | %s: unknown field %q in knownFailures", c.TorrentName, c.FilePath, name)
+			}
+			knownFailures[name] = true
+		}
+
+		checked++
+		got := newGoldenResult(ParseVideoInfo(c.TorrentName, c.FilePath))
+		var diffs, fixed []string
+		for _, name := range goldenFields {
+			want, have := c.Expected.field(name), got.field(name)
+			switch {
+			case want == have && knownFailures[name]:
+				correct[name]++
+				fixed = append(fixed, name)
+			case want == have:
+				correct[name]++
+			case knownFailures[name]:
+				known++
+			default:
+				diffs = append(diffs, fmt.Sprintf("%s = %q, want %q", name, have, want))
+			}
+		}
+		if len(diffs) > 0 {
+			t.Errorf("%s | %s: %s", c.TorrentName, c.FilePath, strings.Join(diffs, ", "))
+		}
+		if len(fixed) > 0 {
+			t.Errorf("%s | %s: %s now right, remove from knownFailures", c.TorrentName, c.FilePath, strings.Join(fixed, ", "))
+		}
+	}
+
+	if checked == 0 {
+		return
+	}
+	t.Logf("accuracy over %d files, %d known failures:", checked, known)
+	for _, name := range goldenFields {
+		accuracy := float64(correct[name]) * 100 / float64(checked)
+		t.Logf("  %-13s %4d/%d  %6.2f%%  (minimum %.0f%%)", name, correct[name], checked, accuracy, goldenMinAccuracy[name])
+		if accuracy < goldenMinAccuracy[name] {
+			t.Errorf("%s accuracy %.2f%% is below the minimum of %.0f%%", name, accuracy, goldenMinAccuracy[name])
+		}
+	}
+}
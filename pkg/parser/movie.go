@@ -0,0 +1,114 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Media types of a file, reported in VideoInfo.MediaType
+const (
+	MediaEpisode = "episode"
+	MediaMovie   = "movie"
+	MediaExtra   = "extra"
+)
+
+// Movie is the title, year and edition found in the name of a movie file
+type Movie struct {
+	Title   string `json:"title"`
+	Year    int    `json:"year"`
+	Edition string `json:"edition,omitempty"` // Director's Cut, Extended, ...
+}
+
+var (
+	// A release year as a whole token: "Heat (1995)", "Heat.1995.1080p"
+	movieYearPattern = regexp.MustCompile(`(?:^|[\s._\(\[-])((?:19|20)\d\d)(?:$|[\s._\)\]-])`)
+
+	// Torrent and folder names of TV releases, whose files are never movies
+	tvNamePattern = regexp.MustCompile(`(?i)(?:^|[\s._\[\(-])(?:seasons?[\s._-]*\d{1,2}|s\d{1,2}(?:e\d{1,3})?)(?:$|[\s._\]\)-])`)
+
+	// Checked in order, the first match wins
+	editionTags = []releaseTag{
+		{tag(`director'?s[\s._-]?cut`), "Director's Cut"},
+		{tag(`extended(?:[\s._-](?:cut|edition))?`), "Extended"},
+		{tag(`final[\s._-]cut`), "Final Cut"},
+		{tag(`ultimate(?:[\s._-](?:cut|edition))?`), "Ultimate"},
+		{tag(`special[\s._-]edition`), "Special Edition"},
+		{tag(`(?:\d+th[\s._-])?anniversary(?:[\s._-]edition)?`), "Anniversary Edition"},
+		{tag(`theatrical(?:[\s._-](?:cut|edition))?`), "Theatrical"},
+		{tag(`unrated`), "Unrated"},
+		{tag(`uncut`), "Uncut"},
+		{tag(`remastered`), "Remastered"},
+		{tag(`criterion`), "Criterion"},
+		{tag(`imax`), "IMAX"},
+	}
+)
+
+// parseMovie recognizes a movie by a title followed by its year in the file name, the name of
+// its folder or the torrent name, in that order. Files of TV releases are never movies
+func parseMovie(torrentName, filePath string) (Movie, bool) {
+	dir := filepath.Dir(filePath)
+	if tvNamePattern.MatchString(torrentName) || tvNamePattern.MatchString(dir) {
+		return Movie{}, false
+	}
+
+	fileName := filepath.Base(filePath)
+	names := []string{strings.TrimSuffix(fileName, filepath.Ext(fileName))}
+	if dir != "." && dir != "/" {
+		names = append(names, filepath.Base(dir))
+	}
+	names = append(names, torrentName)
+
+	var movie Movie
+	found := false
+	for _, name := range names {
+		m, ok := parseMovieName(name)
+		if !ok {
+			continue
+		}
+		if !found {
+			movie, found = m, true
+		}
+		// Editions are often only tagged on the torrent
+		if movie.Edition == "" && m.Year == movie.Year {
+			movie.Edition = m.Edition
+		}
+	}
+	return movie, found
+}
+
+// parseMovieName splits a single file, folder or torrent name into a movie title, year and edition.
+// The last year wins, so the year in "Blade Runner 2049 (2017)" is 2017
+func parseMovieName(name string) (Movie, bool) {
+	name = groupPrefixPattern.ReplaceAllString(name, "")
+	maxYear := time.Now().Year() + 1
+
+	var movie Movie
+	end := -1
+	for _, match := range movieYearPattern.FindAllStringSubmatchIndex(name, -1) {
+		year, _ := strconv.Atoi(name[match[2]:match[3]])
+		if match[2] == 0 || year > maxYear {
+			continue
+		}
+		title := cleanMovieTitle(name[:match[2]])
+		if title == "" {
+			continue
+		}
+		movie.Title, movie.Year, end = title, year, match[3]
+	}
+	if end < 0 {
+		return Movie{}, false
+	}
+
+	movie.Edition = firstTag(editionTags, name[end:])
+	return movie, true
+}
+
+// cleanMovieTitle turns the part of a release name before the year into a title
+func cleanMovieTitle(title string) string {
+	title = strings.NewReplacer(".", " ", "_", " ").Replace(title)
+	title = strings.Join(strings.Fields(title), " ")
+	return strings.TrimRight(title, " -([")
+}
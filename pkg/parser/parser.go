@@ -2,12 +2,6 @@
 package parser
 
 import (
-	"bytes"
-	"encoding/json"
-	"fmt"
-	"io"
-	"net/http"
-	"os"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -17,9 +11,52 @@ import (
 
 // VideoInfo contains parsed metadata from a file path
 type VideoInfo struct {
-	ShowName      string
-	SeasonNumber  int
-	EpisodeNumber int
+	ShowName       string
+	SeasonNumber   int
+	EpisodeNumber  int
+	EpisodeEnd     int       // Last episode of a multi-episode file (S01E01-E03), 0 for a single episode
+	AirDate        time.Time // Air date of daily shows (Show.2024.03.15), zero if unknown
+	AbsoluteNumber int       // Absolute episode number of anime releases (Show - 137), 0 if unknown
+	Release        Release   // Resolution, codecs, source, group and so on
+	Confidence     float64   // How likely the season and episode are right, from 0 to 1; below ReviewThreshold needs review
+	MatchedRule    string    // How the episode was numbered, one of the Match constants or "rule: <pattern>"
+	MediaType      string    // MediaEpisode, MediaMovie or MediaExtra
+	Movie          Movie     // Title, year and edition of a movie, empty for episodes and extras
+}
+
+// Ways ParseVideoInfo numbers an episode, reported in VideoInfo.MatchedRule
+const (
+	MatchRule     = "rule"     // An episode rule, reported with its pattern
+	MatchSxxExx   = "SxxExx"   // S01E05 in the file name
+	MatchAirDate  = "air date" // 2024.03.15 in the file name of a daily show
+	MatchNxM      = "NxM"      // 1x05 in the file name
+	MatchAbsolute = "absolute" // "Show - 137" anime numbering
+	MatchExtra    = "extra"    // An extras folder or keyword
+	MatchMovie    = "movie"    // No episode numbering, but a movie title and year
+	MatchEpisode  = "episode"  // "Episode 5" or "Ep 5", with the season from a folder
+	MatchSeason   = "season"   // Only a season was found
+	MatchNone     = "none"     // Nothing was found, the file went to season 0
+)
+
+// ReviewThreshold is the confidence below which parse results should be checked by a person
+const ReviewThreshold = 0.6
+
+// productionCodeMaxEpisode is the highest episode a production code like "301" (season 3,
+// episode 1) is taken to contain; see parseAbsoluteNumber
+const productionCodeMaxEpisode = 30
+
+// matchConfidence is how much each way of numbering an episode is trusted
+var matchConfidence = map[string]float64{
+	MatchRule:     1,
+	MatchSxxExx:   0.95,
+	MatchAirDate:  0.9,
+	MatchNxM:      0.8,
+	MatchAbsolute: 0.7,
+	MatchExtra:    0.6,
+	MatchMovie:    0.8,
+	MatchEpisode:  0.5,
+	MatchSeason:   0.3,
+	MatchNone:     0.1,
 }
 
 var (
@@ -36,6 +73,17 @@ var (
 	// Episode 1, episode.1, ep01, e01 (but not embedded in other numbers)
 	episodePattern = regexp.MustCompile(`(?i)(?:episode|ep|e)[\s._-]+(\d{1,3})\b`)
 
+	// Further episodes after S01E01: S01E01E02, S01E01-E03, S01E01-03
+	multiEpisodePattern  = regexp.MustCompile(`(?i)[sS]\d{1,2}[eE]\d{1,3}((?:[\s._]*-?[\s._]*[eE]\d{1,3}|-\d{1,3}\b)+)`)
+	episodeNumberPattern = regexp.MustCompile(`\d{1,3}`)
+
+	// Air date of daily shows: 2024.03.15, 2024-03-15, 2024 03 15
+	airDatePattern = regexp.MustCompile(`(?:^|[^\d])((?:19|20)\d\d)[\s._-](0[1-9]|1[0-2])[\s._-](0[1-9]|[12]\d|3[01])(?:$|[^\d])`)
+
+	// Absolute numbering of anime releases: "Show - 137 [1080p]", "[Group] Show - 05v2 (720p)",
+	// "Show - 366 - Title". The number ends the name or is followed by tags or an episode title
+	absolutePattern = regexp.MustCompile(`\s-\s+(\d{1,4})(?:v\d)?(\s*(?:$|[\[\(])|\s+-\s)`)
+
 	// Folder pattern: /Season 1/, /S01/
 	folderSeasonPattern = regexp.MustCompile(`(?i)(?:season|s)[\s._-]*(\d{1,2})`)
 
@@ -55,50 +103,27 @@ func ParseVideoInfo(torrentName, filePath string) VideoInfo {
 	fileName := filepath.Base(filePath)
 	dirPath := filepath.Dir(filePath)
 
-	// Check if this is an Extra/Special by looking for:
-	// - Special folders: /extras/, /specials/, /deleted scenes/, /bloopers/, /gag reel/
-	// - Special keywords in filename: extra, special, bonus, deleted scene, blooper, gag reel, behind the scenes, featurette
-	lowerPath := strings.ToLower(filePath)
-	lowerFileName := strings.ToLower(fileName)
-
-	// Known special episode titles that should be marked as extras
-	knownSpecials := []string{
-		"faces of scranton",
-		"the accountants",
-		"the mentor",
-		"blackmail",
-		"subtle sexuality",
-		"the 3rd floor",
-		"the podcast",
-	}
-
-	isKnownSpecial := false
-	for _, special := range knownSpecials {
-		if strings.Contains(lowerFileName, special) {
-			isKnownSpecial = true
-			break
-		}
+	// Rules come first: a show override, then its and the global episode patterns
+	rules := activeRules.Load()
+	showRule := rules.showRule(torrentName, filePath)
+	if season, episode, pattern, ok := rules.matchEpisode(showRule, fileName); ok {
+		info.SeasonNumber = season
+		info.EpisodeNumber = episode
+		info.MatchedRule = MatchRule + ": " + pattern
+		info.Confidence = matchConfidence[MatchRule]
+		info.MediaType = MediaEpisode
+		info.ShowName = ruleShowName(rules, showRule, torrentName, filePath)
+		info.Release = ParseRelease(torrentName, filePath)
+		return info
 	}
 
-	isExtra := isKnownSpecial ||
-	           strings.Contains(lowerPath, "/extras/") ||
-	           strings.Contains(lowerPath, "/specials/") ||
-	           strings.Contains(lowerPath, "/deleted scenes/") ||
-	           strings.Contains(lowerPath, "/bloopers/") ||
-	           strings.Contains(lowerPath, "/blooper/") ||
-	           strings.Contains(lowerPath, "/gag reel/") ||
-	           strings.Contains(lowerFileName, "extra") ||
-	           strings.Contains(lowerFileName, "special") ||
-	           strings.Contains(lowerFileName, "bonus") ||
-	           strings.Contains(lowerFileName, "deleted scene") ||
-	           strings.Contains(lowerFileName, "blooper") ||
-	           strings.Contains(lowerFileName, "gag reel") ||
-	           strings.Contains(lowerFileName, "behind the scenes") ||
-	           strings.Contains(lowerFileName, "featurette")
+	// Check if this is an Extra/Special by its folder or file name (see Rules.ExtraFolders and ExtraKeywords)
+	isExtra := rules.isExtra(showRule, filePath)
 
 	if isExtra {
 		// Mark as Season 0 (Specials/Extras)
 		info.SeasonNumber = 0
+		info.MatchedRule = MatchExtra
 		// Try to extract episode number from "Extra N" or "Special N" pattern
 		extraPattern := regexp.MustCompile(`(?i)(?:extra|special|bonus)[s]?\s+(\d+)`)
 		if matches := extraPattern.FindStringSubmatch(fileName); len(matches) >= 2 {
@@ -118,6 +143,17 @@ func ParseVideoInfo(torrentName, filePath string) VideoInfo {
 			if episode, err := strconv.Atoi(matches[2]); err == nil {
 				info.EpisodeNumber = episode
 			}
+			info.EpisodeEnd = parseEpisodeEnd(fileName, info.EpisodeNumber)
+			info.MatchedRule = MatchSxxExx
+		}
+	}
+
+	// Daily shows are numbered by air date; the year becomes the season
+	if !isExtra && info.EpisodeNumber == 0 {
+		if airDate, ok := parseAirDate(fileName); ok {
+			info.AirDate = airDate
+			info.SeasonNumber = airDate.Year()
+			info.MatchedRule = MatchAirDate
 		}
 	}
 
@@ -134,7 +170,7 @@ func ParseVideoInfo(torrentName, filePath string) VideoInfo {
 	}
 
 	// Try XxY format if SxxExx didn't match (e.g., 10x05) - skip for extras
-	if !isExtra && info.EpisodeNumber == 0 {
+	if !isExtra && info.EpisodeNumber == 0 && info.AirDate.IsZero() {
 		if matches := seXepPattern.FindStringSubmatch(fileName); len(matches) >= 3 {
 			if season, err := strconv.Atoi(matches[1]); err == nil {
 				// Sanity check: season should be reasonable (1-99)
@@ -145,6 +181,7 @@ func ParseVideoInfo(torrentName, filePath string) VideoInfo {
 			}
 			if episode, err := strconv.Atoi(matches[2]); err == nil {
 				info.EpisodeNumber = episode
+				info.MatchedRule = MatchNxM
 			}
 		}
 	}
@@ -168,31 +205,123 @@ func ParseVideoInfo(torrentName, filePath string) VideoInfo {
 	}
 
 	// If no episode found, try episode pattern (but avoid matching years like 2020) - skip for extras
-	if !isExtra && info.EpisodeNumber == 0 {
+	if !isExtra && info.EpisodeNumber == 0 && info.AirDate.IsZero() {
 		if matches := episodePattern.FindStringSubmatch(fileName); len(matches) >= 2 {
 			if episode, err := strconv.Atoi(matches[1]); err == nil {
 				// Ignore if it looks like a year (4 digits >= 1900)
 				if episode < 1900 {
 					info.EpisodeNumber = episode
+					info.MatchedRule = MatchEpisode
 				}
 			}
 		}
 	}
 
+	// Anime releases count episodes across seasons ("Show - 137"); without a
+	// season folder they go to season 1 so they still sort in order
+	if !isExtra && info.EpisodeNumber == 0 && info.AirDate.IsZero() {
+		if absolute := parseAbsoluteNumber(fileName); absolute > 0 {
+			info.AbsoluteNumber = absolute
+			info.EpisodeNumber = absolute
+			info.MatchedRule = MatchAbsolute
+			if info.SeasonNumber == 0 {
+				info.SeasonNumber = 1
+			}
+		}
+	}
+
 	// If no season info was found, treat as extra/special
 	// This catches files that don't have clear season numbering
 	if info.SeasonNumber == 0 && !isExtra {
 		// Mark as Season 0 (Specials/Extras) and reset episode to 0
 		// since we can't reliably determine episode numbers without season context
 		info.EpisodeNumber = 0
+		info.MatchedRule = MatchNone
+	} else if info.MatchedRule == "" {
+		info.MatchedRule = MatchSeason
 	}
 
-	// Extract show name from torrent name using LLM for better parsing
-	info.ShowName = extractShowNameWithLLM(torrentName, filePath)
+	// Classify the file: files without episode numbering are movies when they carry a title and
+	// year, unless a show rule says they belong to a show
+	info.MediaType = MediaEpisode
+	if isExtra {
+		info.MediaType = MediaExtra
+	} else if info.MatchedRule == MatchNone && showRule == nil {
+		if movie, ok := parseMovie(torrentName, filePath); ok {
+			info.MediaType = MediaMovie
+			info.Movie = movie
+			info.MatchedRule = MatchMovie
+		}
+	}
+	info.Confidence = matchConfidence[info.MatchedRule]
+
+	info.ShowName = ruleShowName(rules, showRule, torrentName, filePath)
+	info.Release = ParseRelease(torrentName, filePath)
 
 	return info
 }
 
+// ruleShowName returns the show name set by a show rule, or resolves it with the
+// configured ShowNameResolver and renames aliases
+func ruleShowName(rules *compiledRules, showRule *compiledShowRule, torrentName, filePath string) string {
+	if showRule != nil && showRule.Name != "" {
+		return showRule.Name
+	}
+	return rules.resolveAlias(resolveShowName(torrentName, filePath))
+}
+
+// parseEpisodeEnd returns the last episode of a multi-episode file name, or 0 if it has a single episode
+func parseEpisodeEnd(fileName string, first int) int {
+	matches := multiEpisodePattern.FindStringSubmatch(fileName)
+	if len(matches) < 2 {
+		return 0
+	}
+	last := 0
+	for _, number := range episodeNumberPattern.FindAllString(matches[1], -1) {
+		if episode, err := strconv.Atoi(number); err == nil && episode > last {
+			last = episode
+		}
+	}
+	if last <= first {
+		return 0
+	}
+	return last
+}
+
+// parseAirDate finds the air date in a daily show's file name
+func parseAirDate(fileName string) (time.Time, bool) {
+	matches := airDatePattern.FindStringSubmatch(fileName)
+	if len(matches) < 4 {
+		return time.Time{}, false
+	}
+	airDate, err := time.Parse("2006-01-02", matches[1]+"-"+matches[2]+"-"+matches[3])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return airDate, true
+}
+
+// parseAbsoluteNumber finds the absolute episode number in an anime style file name
+func parseAbsoluteNumber(fileName string) int {
+	name := strings.TrimSuffix(fileName, filepath.Ext(fileName))
+	matches := absolutePattern.FindStringSubmatch(name)
+	if len(matches) < 3 {
+		return 0
+	}
+	episode, err := strconv.Atoi(matches[1])
+	// Ignore years like "Show - 2019"
+	if err != nil || episode >= 1900 {
+		return 0
+	}
+	// Before a title, 3 and 4 digit numbers may be production codes: "Simpsons - 301 - Title" is
+	// season 3 episode 1. Only numbers whose last two digits cannot be an episode are absolute
+	titled := strings.TrimSpace(matches[2]) == "-"
+	if titled && len(matches[1]) >= 3 && episode%100 >= 1 && episode%100 <= productionCodeMaxEpisode {
+		return 0
+	}
+	return episode
+}
+
 // extractShowName cleans up the torrent name to get a proper show name
 func extractShowName(torrentName, filePath string) string {
 	showName := torrentName
@@ -259,107 +388,3 @@ func cleanShowName(name string) string {
 
 	return name
 }
-
-// extractShowNameWithLLM uses Claude Haiku to intelligently extract show names
-func extractShowNameWithLLM(torrentName, filePath string) string {
-	// Try LLM extraction first if API key is available
-	if apiKey := os.Getenv("ANTHROPIC_API_KEY"); apiKey != "" {
-		if showName := callClaudeForShowName(torrentName, filePath, apiKey); showName != "" {
-			return showName
-		}
-	}
-
-	// Fall back to regex-based extraction
-	return extractShowName(torrentName, filePath)
-}
-
-type claudeMessage struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-type claudeRequest struct {
-	Model     string          `json:"model"`
-	MaxTokens int             `json:"max_tokens"`
-	Messages  []claudeMessage `json:"messages"`
-}
-
-type claudeResponse struct {
-	Content []struct {
-		Text string `json:"text"`
-	} `json:"content"`
-}
-
-// callClaudeForShowName calls Claude Haiku API to extract clean show name
-func callClaudeForShowName(torrentName, filePath, apiKey string) string {
-	prompt := fmt.Sprintf(`Extract the TV show name from this torrent/file information. Return ONLY the clean show name without any metadata.
-
-Torrent name: %s
-File path: %s
-
-Rules:
-- Remove metadata like "5 Seasons", "Complete", "Season 1-5", etc.
-- Remove quality info like "720p", "WEB-DL", "DVDRip", etc.
-- Remove year if present
-- Return just the show name, properly capitalized
-- If it's "Futurama 5 Seasons", return "Futurama"
-- If it's "The Simpsons Complete", return "The Simpsons"
-
-Return ONLY the show name, nothing else:`, torrentName, filePath)
-
-	reqBody := claudeRequest{
-		Model:     "claude-haiku-4-20250129",
-		MaxTokens: 50,
-		Messages: []claudeMessage{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return ""
-	}
-
-	req, err := http.NewRequest("POST", "https://api.anthropic.com/v1/messages", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return ""
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return ""
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return ""
-	}
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return ""
-	}
-
-	var claudeResp claudeResponse
-	if err := json.Unmarshal(body, &claudeResp); err != nil {
-		return ""
-	}
-
-	if len(claudeResp.Content) > 0 {
-		showName := strings.TrimSpace(claudeResp.Content[0].Text)
-		// Basic validation - make sure it's not empty and not too long
-		if len(showName) > 0 && len(showName) < 100 {
-			return showName
-		}
-	}
-
-	return ""
-}
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -140,3 +142,338 @@ func TestCleanShowName(t *testing.T) {
 		})
 	}
 }
+
+func TestParseRelease(t *testing.T) {
+	tests := []struct {
+		torrentName string
+		filePath    string
+		expected    Release
+	}{
+		{
+			torrentName: "Breaking Bad Season 1",
+			filePath:    "Breaking.Bad.S01E01.720p.WEB-DL.mkv",
+			expected:    Release{Resolution: "720p", Source: "WEB-DL"},
+		},
+		{
+			torrentName: "The Office",
+			filePath:    "The.Office.s02e10.HDTV.x264.mp4",
+			expected:    Release{Source: "HDTV", VideoCodec: "H.264"},
+		},
+		{
+			torrentName: "Severance.S02.1080p.ATVP.WEB-DL.DDP5.1.H.264-NTb",
+			filePath:    "Severance.S02.1080p.ATVP.WEB-DL.DDP5.1.H.264-NTb/Severance.S02E01.1080p.ATVP.WEB-DL.DDP5.1.H.264-NTb.mkv",
+			expected:    Release{Resolution: "1080p", Source: "WEB-DL", VideoCodec: "H.264", AudioCodec: "E-AC-3", ReleaseGroup: "NTb"},
+		},
+		{
+			torrentName: "Dark.S01.2160p.BluRay.REMUX.HEVC.10bit.TrueHD.Atmos.MULTi-FraMeSToR",
+			filePath:    "Dark.S01E03.mkv",
+			expected:    Release{Resolution: "2160p", Source: "Remux", VideoCodec: "H.265", AudioCodec: "TrueHD", ReleaseGroup: "FraMeSToR", Languages: []string{"multi"}, TenBit: true},
+		},
+		{
+			torrentName: "The Expanse",
+			filePath:    "The.Expanse.S03E05.PROPER.REPACK.720p.HDTV.x265.AAC.RUS.ENG-GROUP.mkv",
+			expected:    Release{Resolution: "720p", Source: "HDTV", VideoCodec: "H.265", AudioCodec: "AAC", ReleaseGroup: "GROUP", Languages: []string{"en", "ru"}, Proper: true, Repack: true},
+		},
+		{
+			torrentName: "[SubsPlease] Frieren - 01 (1080p) [ABCD1234]",
+			filePath:    "[SubsPlease] Frieren - 01 (1080p) [ABCD1234].mkv",
+			expected:    Release{Resolution: "1080p", ReleaseGroup: "SubsPlease"},
+		},
+		{
+			torrentName: "Doctor Who (2005) Complete",
+			filePath:    "Season 1/Doctor Who - 1x01 - Rose.avi",
+			expected:    Release{Year: 2005},
+		},
+		{
+			torrentName: "Spider-Man The Animated Series",
+			filePath:    "Spider-Man - 1x01.avi",
+			expected:    Release{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			result := ParseRelease(tt.torrentName, tt.filePath)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("ParseRelease(%q, %q) = %+v, want %+v", tt.torrentName, tt.filePath, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseEpisodeNumbering(t *testing.T) {
+	tests := []struct {
+		torrentName      string
+		filePath         string
+		expectedSeason   int
+		expectedEpisode  int
+		expectedEnd      int
+		expectedAirDate  string
+		expectedAbsolute int
+	}{
+		{
+			torrentName:     "Friends Season 1",
+			filePath:        "Friends.S01E16E17.720p.mkv",
+			expectedSeason:  1,
+			expectedEpisode: 16,
+			expectedEnd:     17,
+		},
+		{
+			torrentName:     "Lost",
+			filePath:        "Lost.S01E01-E03.1080p.BluRay.x264.mkv",
+			expectedSeason:  1,
+			expectedEpisode: 1,
+			expectedEnd:     3,
+		},
+		{
+			torrentName:     "Lost",
+			filePath:        "Lost.S02E01-02.HDTV.mkv",
+			expectedSeason:  2,
+			expectedEpisode: 1,
+			expectedEnd:     2,
+		},
+		{
+			torrentName:     "The Office",
+			filePath:        "The.Office.S05E01-720p.mkv",
+			expectedSeason:  5,
+			expectedEpisode: 1,
+		},
+		{
+			torrentName:     "The Daily Show 2024",
+			filePath:        "The.Daily.Show.2024.03.15.Guest.Name.720p.WEB.h264.mkv",
+			expectedSeason:  2024,
+			expectedAirDate: "2024-03-15",
+		},
+		{
+			torrentName:      "One Piece",
+			filePath:         "[SubsPlease] One Piece - 1087 (1080p) [ABCD1234].mkv",
+			expectedSeason:   1,
+			expectedEpisode:  1087,
+			expectedAbsolute: 1087,
+		},
+		{
+			torrentName:      "Naruto Shippuden",
+			filePath:         "Season 3/Naruto Shippuden - 054v2 [720p].mkv",
+			expectedSeason:   3,
+			expectedEpisode:  54,
+			expectedAbsolute: 54,
+		},
+		{
+			torrentName:      "Bleach",
+			filePath:         "Bleach - 366 - The Blade Is Me.mkv",
+			expectedSeason:   1,
+			expectedEpisode:  366,
+			expectedAbsolute: 366,
+		},
+		{
+			torrentName:      "Cowboy Bebop",
+			filePath:         "Cowboy Bebop - 01 - Asteroid Blues.mkv",
+			expectedSeason:   1,
+			expectedEpisode:  1,
+			expectedAbsolute: 1,
+		},
+		{
+			// A production code (season 3, episode 1), not absolute numbering
+			torrentName: "The Simpsons",
+			filePath:    "Simpsons - 301 - Homer Goes to College.mkv",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			result := ParseVideoInfo(tt.torrentName, tt.filePath)
+			if result.SeasonNumber != tt.expectedSeason {
+				t.Errorf("SeasonNumber = %d, want %d", result.SeasonNumber, tt.expectedSeason)
+			}
+			if result.EpisodeNumber != tt.expectedEpisode {
+				t.Errorf("EpisodeNumber = %d, want %d", result.EpisodeNumber, tt.expectedEpisode)
+			}
+			if result.EpisodeEnd != tt.expectedEnd {
+				t.Errorf("EpisodeEnd = %d, want %d", result.EpisodeEnd, tt.expectedEnd)
+			}
+			airDate := ""
+			if !result.AirDate.IsZero() {
+				airDate = result.AirDate.Format("2006-01-02")
+			}
+			if airDate != tt.expectedAirDate {
+				t.Errorf("AirDate = %q, want %q", airDate, tt.expectedAirDate)
+			}
+			if result.AbsoluteNumber != tt.expectedAbsolute {
+				t.Errorf("AbsoluteNumber = %d, want %d", result.AbsoluteNumber, tt.expectedAbsolute)
+			}
+		})
+	}
+}
+
+func TestParseExtraFolders(t *testing.T) {
+	tests := []struct {
+		torrentName string
+		filePath    string
+		extra       bool
+	}{
+		{"Lost", "Lost/Extras/Making Of.mkv", true},
+		{"Lost Season 1", "Extras/Making Of.mkv", true},
+		{"Lost Season 1", "extras/Making Of.mkv", true},
+		{"Lost Season 1", "Featurettes/The Island.mkv", true},
+		{"Lost Season 1", "Bonus/Audition Tapes.mkv", true},
+		{"Lost Season 1", "Behind the Scenes/Day 1.mkv", true},
+		{"Lost Season 1", "Season 1/Deleted Scenes/Scene 3.mkv", true},
+		{"Lost Season 1", "Season 1/Lost.S01E01.mkv", false},
+		{"Lost Season 1", "Lost.S01E02.mkv", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			result := ParseVideoInfo(tt.torrentName, tt.filePath)
+			if extra := result.MediaType == MediaExtra; extra != tt.extra {
+				t.Errorf("MediaType = %q, extra = %v, want %v", result.MediaType, extra, tt.extra)
+			}
+			if tt.extra && (result.SeasonNumber != 0 || result.MatchedRule != MatchExtra) {
+				t.Errorf("SeasonNumber = %d, MatchedRule = %q, want season 0 from %q", result.SeasonNumber, result.MatchedRule, MatchExtra)
+			}
+		})
+	}
+}
+
+func TestParseConfidence(t *testing.T) {
+	tests := []struct {
+		torrentName    string
+		filePath       string
+		expectedRule   string
+		expectedReview bool
+	}{
+		{"Breaking Bad", "Breaking.Bad.S01E01.720p.mkv", MatchSxxExx, false},
+		{"The Daily Show", "The.Daily.Show.2024.03.15.Guest.mkv", MatchAirDate, false},
+		{"Doctor Who", "Doctor Who 10x05 Oxygen.mkv", MatchNxM, false},
+		{"Naruto", "Naruto - 137 [720p].mkv", MatchAbsolute, false},
+		{"Lost", "Lost/Extras/Making Of.mkv", MatchExtra, false},
+		{"Heat (1995)", "Heat.1995.1080p.mkv", MatchMovie, false},
+		{"Lost", "Season 1/Lost Episode 5.mkv", MatchEpisode, true},
+		{"Lost", "Season 1/Pilot.mkv", MatchSeason, true},
+		{"Lost", "Pilot.mkv", MatchNone, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			result := ParseVideoInfo(tt.torrentName, tt.filePath)
+			if result.MatchedRule != tt.expectedRule {
+				t.Errorf("MatchedRule = %q, want %q", result.MatchedRule, tt.expectedRule)
+			}
+			if review := result.Confidence < ReviewThreshold; review != tt.expectedReview {
+				t.Errorf("Confidence = %v, needs review = %v, want %v", result.Confidence, review, tt.expectedReview)
+			}
+		})
+	}
+}
+
+func TestParseMovie(t *testing.T) {
+	tests := []struct {
+		torrentName string
+		filePath    string
+		mediaType   string
+		movie       Movie
+	}{
+		{"Heat (1995)", "Heat (1995)/Heat.1995.1080p.BluRay.x264.mkv", MediaMovie, Movie{Title: "Heat", Year: 1995}},
+		{"Blade Runner 2049 (2017) 2160p", "Blade.Runner.2049.2017.2160p.mkv", MediaMovie, Movie{Title: "Blade Runner 2049", Year: 2017}},
+		{"Aliens.1986.Special.Edition.1080p", "aliens.mkv", MediaMovie, Movie{Title: "Aliens", Year: 1986, Edition: "Special Edition"}},
+		{"Apocalypse Now 1979 Final Cut", "Apocalypse.Now.1979.1080p.mkv", MediaMovie, Movie{Title: "Apocalypse Now", Year: 1979, Edition: "Final Cut"}},
+		{"[Group] Akira (1988)", "[Group] Akira (1988) [BD 1080p].mkv", MediaMovie, Movie{Title: "Akira", Year: 1988}},
+		{"Season of the Witch (2011)", "Season.of.the.Witch.2011.Extended.mkv", MediaMovie, Movie{Title: "Season of the Witch", Year: 2011, Edition: "Extended"}},
+		{"Lost Season 1", "Lost.2004.Pilot.mkv", MediaEpisode, Movie{}},
+		{"Heat (1995)", "Heat (1995)/Extras/Making Of.mkv", MediaExtra, Movie{}},
+		{"Breaking Bad", "Breaking.Bad.S01E01.2008.mkv", MediaEpisode, Movie{}},
+		{"Random Show", "Random Video File.mkv", MediaEpisode, Movie{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			result := ParseVideoInfo(tt.torrentName, tt.filePath)
+			if result.MediaType != tt.mediaType {
+				t.Errorf("MediaType = %q, want %q", result.MediaType, tt.mediaType)
+			}
+			if result.Movie != tt.movie {
+				t.Errorf("Movie = %+v, want %+v", result.Movie, tt.movie)
+			}
+		})
+	}
+}
+
+func TestParserRules(t *testing.T) {
+	rules, err := ParseRules([]byte(`{
+		"extraFolders": ["extras"],
+		"extraKeywords": ["featurette"],
+		"episodes": [{"pattern": "(?i)^Webisode (?P<episode>\\d+)", "season": 0}],
+		"shows": [
+			{"match": "(?i)simpsons", "name": "The Simpsons", "episodes": [{"pattern": "- (?P<season>\\d)(?P<episode>\\d{2}) -"}]},
+			{"name": "Doctor Who", "aliases": ["Doctor Who 2005", "Dr Who"]}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseRules() error = %v", err)
+	}
+	if err := SetRules(rules); err != nil {
+		t.Fatalf("SetRules() error = %v", err)
+	}
+	defer SetRules(DefaultRules())
+
+	tests := []struct {
+		torrentName     string
+		filePath        string
+		expectedShow    string
+		expectedSeason  int
+		expectedEpisode int
+	}{
+		{
+			torrentName:     "Simpsons Seasons 1-10",
+			filePath:        "Simpsons - 301 - Homer Goes to College.mkv",
+			expectedShow:    "The Simpsons",
+			expectedSeason:  3,
+			expectedEpisode: 1,
+		},
+		{
+			torrentName:     "Dr Who",
+			filePath:        "Dr.Who.S01E02.mkv",
+			expectedShow:    "Doctor Who",
+			expectedSeason:  1,
+			expectedEpisode: 2,
+		},
+		{
+			torrentName:     "Lost",
+			filePath:        "Webisode 3.mkv",
+			expectedShow:    "Lost",
+			expectedSeason:  0,
+			expectedEpisode: 3,
+		},
+		{
+			// Keywords of the default rules no longer apply
+			torrentName:     "The Office",
+			filePath:        "Season 2/Faces of Scranton.mkv",
+			expectedShow:    "The Office",
+			expectedSeason:  2,
+			expectedEpisode: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filePath, func(t *testing.T) {
+			result := ParseVideoInfo(tt.torrentName, tt.filePath)
+			if result.ShowName != tt.expectedShow {
+				t.Errorf("ShowName = %q, want %q", result.ShowName, tt.expectedShow)
+			}
+			if result.SeasonNumber != tt.expectedSeason {
+				t.Errorf("SeasonNumber = %d, want %d", result.SeasonNumber, tt.expectedSeason)
+			}
+			if result.EpisodeNumber != tt.expectedEpisode {
+				t.Errorf("EpisodeNumber = %d, want %d", result.EpisodeNumber, tt.expectedEpisode)
+			}
+		})
+	}
+
+	if result := ParseVideoInfo("Simpsons", "Simpsons - 301 - Homer Goes to College.mkv"); result.Confidence != 1 || !strings.HasPrefix(result.MatchedRule, MatchRule+": ") {
+		t.Errorf("rule match: Confidence = %v, MatchedRule = %q", result.Confidence, result.MatchedRule)
+	}
+
+	if _, err := ParseRules([]byte(`{"shows": [{"match": "("}]}`)); err == nil {
+		t.Error("ParseRules() accepted an invalid pattern")
+	}
+}
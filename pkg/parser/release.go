@@ -0,0 +1,229 @@
+package parser
+
+import (
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Release contains the release metadata found in a file or torrent name
+type Release struct {
+	Resolution   string   `json:"resolution,omitempty"`   // 480p, 720p, 1080p, 2160p
+	VideoCodec   string   `json:"videoCodec,omitempty"`   // H.264, H.265, AV1, XviD
+	AudioCodec   string   `json:"audioCodec,omitempty"`   // AAC, AC-3, E-AC-3, DTS, TrueHD, ...
+	Source       string   `json:"source,omitempty"`       // WEB-DL, WEBRip, BluRay, Remux, HDTV, DVD
+	ReleaseGroup string   `json:"releaseGroup,omitempty"` // NTb, RARBG, ...
+	Languages    []string `json:"languages,omitempty"`    // ISO 639-1 codes, or "multi"
+	Proper       bool     `json:"proper,omitempty"`
+	Repack       bool     `json:"repack,omitempty"`
+	Year         int      `json:"year,omitempty"`
+	TenBit       bool     `json:"tenBit,omitempty"`
+}
+
+// releaseTag maps a pattern to the normalized value it stands for
+type releaseTag struct {
+	pattern *regexp.Regexp
+	value   string
+}
+
+// tag builds a pattern matching name as a whole token of a release name
+func tag(name string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:^|[\s._\[\(+-])(?:` + name + `)(?:$|[\s._\]\)+-])`)
+}
+
+var (
+	// 1080p, 720i, 4K, UHD
+	resolutionPattern = regexp.MustCompile(`(?i)(?:^|[\s._\[\(-])(2160|1080|720|576|480)[pi](?:$|[\s._\]\)-])`)
+	uhdPattern        = tag(`4k|uhd`)
+
+	// Checked in order, the first match wins
+	videoCodecTags = []releaseTag{
+		{tag(`x\.?265|h\.?265|hevc`), "H.265"},
+		{tag(`x\.?264|h\.?264|avc`), "H.264"},
+		{tag(`av1`), "AV1"},
+		{tag(`xvid`), "XviD"},
+		{tag(`divx`), "DivX"},
+	}
+	audioCodecTags = []releaseTag{
+		{tag(`truehd`), "TrueHD"},
+		{tag(`dts-?hd(?:[\s._-]?ma)?|dts-?x`), "DTS-HD"},
+		{tag(`dts`), "DTS"},
+		{tag(`ddp(?:[\s._]?[257][\s._]?[01])?|dd\+|e-?ac-?3`), "E-AC-3"},
+		{tag(`dd(?:[\s._]?[257][\s._]?[01])?|ac-?3`), "AC-3"},
+		{tag(`aac(?:[\s._]?[257][\s._]?[01])?`), "AAC"},
+		{tag(`flac`), "FLAC"},
+		{tag(`opus`), "Opus"},
+		{tag(`mp3`), "MP3"},
+	}
+	sourceTags = []releaseTag{
+		{tag(`remux`), "Remux"},
+		{tag(`web-?dl`), "WEB-DL"},
+		{tag(`web-?rip`), "WEBRip"},
+		{tag(`blu-?ray|bd-?rip|br-?rip|bdremux`), "BluRay"},
+		{tag(`hdtv|hdtvrip`), "HDTV"},
+		{tag(`dvd-?rip|dvd(?:-?[59])?`), "DVD"},
+		{tag(`web`), "WEB"},
+	}
+	languageTags = []releaseTag{
+		{tag(`multi|multi-?subs?`), "multi"},
+		{tag(`eng|english`), "en"},
+		{tag(`rus|russian`), "ru"},
+		{tag(`ukr|ukrainian`), "uk"},
+		{tag(`french|vff|vostfr|truefrench`), "fr"},
+		{tag(`ger|german`), "de"},
+		{tag(`ita|italian`), "it"},
+		{tag(`spa|spanish|esp|castellano`), "es"},
+		{tag(`jap|japanese`), "ja"},
+	}
+
+	properPattern = tag(`proper`)
+	repackPattern = tag(`repack|rerip`)
+	tenBitPattern = tag(`10[\s._-]?bits?|hi10p?`)
+
+	// A year between 1930 and 2099, not glued to other digits
+	yearPattern = regexp.MustCompile(`(?:^|[^\d])(19[3-9]\d|20\d\d)(?:$|[^\dpPiI])`)
+
+	// Group after the last dash (Show.S01E01.1080p.WEB-DL-NTb) or anime style group prefix ([SubsPlease] Show - 01)
+	groupSuffixPattern = regexp.MustCompile(`-([A-Za-z0-9]+)(?:\s*\[[^\]]*\])?$`)
+	groupPrefixPattern = regexp.MustCompile(`^\[([^\]]+)\]`)
+
+	// Dash-separated words that are part of a tag, not a group
+	notGroups = map[string]bool{"dl": true, "rip": true, "hd": true, "ray": true, "x": true, "ma": true, "3": true, "5": true, "9": true}
+
+	// Video file extensions stripped before looking for a release group
+	videoExtensions = map[string]bool{".mkv": true, ".mp4": true, ".avi": true, ".m4v": true, ".mov": true, ".wmv": true, ".ts": true, ".webm": true}
+)
+
+// ParseRelease extracts release metadata from a file path, filling in what the
+// file name lacks from the torrent name (season packs often tag only the torrent)
+func ParseRelease(torrentName, filePath string) Release {
+	release := parseReleaseName(filepath.Base(filePath))
+	fromTorrent := parseReleaseName(torrentName)
+
+	if release.Resolution == "" {
+		release.Resolution = fromTorrent.Resolution
+	}
+	if release.VideoCodec == "" {
+		release.VideoCodec = fromTorrent.VideoCodec
+	}
+	if release.AudioCodec == "" {
+		release.AudioCodec = fromTorrent.AudioCodec
+	}
+	if release.Source == "" {
+		release.Source = fromTorrent.Source
+	}
+	if release.ReleaseGroup == "" {
+		release.ReleaseGroup = fromTorrent.ReleaseGroup
+	}
+	if len(release.Languages) == 0 {
+		release.Languages = fromTorrent.Languages
+	}
+	if release.Year == 0 {
+		release.Year = fromTorrent.Year
+	}
+	release.Proper = release.Proper || fromTorrent.Proper
+	release.Repack = release.Repack || fromTorrent.Repack
+	release.TenBit = release.TenBit || fromTorrent.TenBit
+
+	return release
+}
+
+// parseReleaseName extracts release metadata from a single file or torrent name
+func parseReleaseName(name string) Release {
+	if ext := strings.ToLower(filepath.Ext(name)); videoExtensions[ext] {
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+	}
+	name = strings.TrimSpace(name)
+
+	var release Release
+	if m := resolutionPattern.FindStringSubmatch(name); m != nil {
+		release.Resolution = m[1] + "p"
+	} else if uhdPattern.MatchString(name) {
+		release.Resolution = "2160p"
+	}
+	release.VideoCodec = firstTag(videoCodecTags, name)
+	release.AudioCodec = firstTag(audioCodecTags, name)
+	release.Source = firstTag(sourceTags, name)
+	for _, t := range languageTags {
+		if t.pattern.MatchString(name) {
+			release.Languages = append(release.Languages, t.value)
+		}
+	}
+	release.Proper = properPattern.MatchString(name)
+	release.Repack = repackPattern.MatchString(name)
+	release.TenBit = tenBitPattern.MatchString(name)
+
+	if m := yearPattern.FindStringSubmatch(name); m != nil {
+		release.Year, _ = strconv.Atoi(m[1])
+	}
+
+	// Only trust a group when the name looks like a release name at all,
+	// otherwise "Spider-Man" would be released by "Man"
+	tagged := release.Resolution != "" || release.VideoCodec != "" || release.Source != ""
+	if m := groupPrefixPattern.FindStringSubmatch(name); m != nil && !resolutionPattern.MatchString(m[0]) {
+		release.ReleaseGroup = strings.TrimSpace(m[1])
+	} else if m := groupSuffixPattern.FindStringSubmatch(name); m != nil && tagged && !notGroups[strings.ToLower(m[1])] {
+		release.ReleaseGroup = m[1]
+	}
+
+	return release
+}
+
+// firstTag returns the value of the first tag found in name
+func firstTag(tags []releaseTag, name string) string {
+	for _, t := range tags {
+		if t.pattern.MatchString(name) {
+			return t.value
+		}
+	}
+	return ""
+}
+
+// Equal reports whether two releases have the same metadata
+func (r Release) Equal(other Release) bool {
+	return r.Resolution == other.Resolution &&
+		r.VideoCodec == other.VideoCodec &&
+		r.AudioCodec == other.AudioCodec &&
+		r.Source == other.Source &&
+		r.ReleaseGroup == other.ReleaseGroup &&
+		slices.Equal(r.Languages, other.Languages) &&
+		r.Proper == other.Proper &&
+		r.Repack == other.Repack &&
+		r.Year == other.Year &&
+		r.TenBit == other.TenBit
+}
+
+// Badges returns the release metadata as short labels, most important first
+func (r Release) Badges() []string {
+	var badges []string
+	if r.Resolution != "" {
+		badges = append(badges, r.Resolution)
+	}
+	if r.Source != "" {
+		badges = append(badges, r.Source)
+	}
+	if r.VideoCodec != "" {
+		badges = append(badges, r.VideoCodec)
+	}
+	if r.TenBit {
+		badges = append(badges, "10-bit")
+	}
+	if r.AudioCodec != "" {
+		badges = append(badges, r.AudioCodec)
+	}
+	for _, lang := range r.Languages {
+		badges = append(badges, strings.ToUpper(lang))
+	}
+	if r.Proper {
+		badges = append(badges, "PROPER")
+	}
+	if r.Repack {
+		badges = append(badges, "REPACK")
+	}
+	if r.ReleaseGroup != "" {
+		badges = append(badges, r.ReleaseGroup)
+	}
+	return badges
+}
@@ -0,0 +1,438 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Show name resolver names, as used in SHOW_NAME_RESOLVERS
+const (
+	ResolverLookup = "lookup" // Known show names from the archive
+	ResolverLLM    = "llm"    // Anthropic API
+	ResolverRegex  = "regex"  // Cleaning up the torrent or file name
+)
+
+// LLM resolver defaults
+const (
+	DefaultLLMModel    = "claude-haiku-4-20250129"
+	DefaultLLMEndpoint = "https://api.anthropic.com/v1/messages"
+	DefaultLLMTimeout  = 10 * time.Second
+)
+
+// lookupRefreshInterval is how long the lookup resolver keeps the known show names
+const lookupRefreshInterval = 5 * time.Minute
+
+// ErrNotResolved is returned by resolvers that found no show name
+var ErrNotResolved = errors.New("show name not resolved")
+
+// ShowNameResolver finds the show name of a file in a torrent
+type ShowNameResolver interface {
+	Name() string
+	ResolveShowName(torrentName, filePath string) (string, error)
+}
+
+// ResolverStore caches resolved show names and lists the shows in the archive; implemented by database.DB
+type ResolverStore interface {
+	GetNameResolution(torrentName string) (showName string, ok bool, err error)
+	SaveNameResolution(torrentName, showName, source string) error
+	ShowNames() ([]string, error)
+}
+
+// ResolverConfig selects and configures the show name resolvers
+type ResolverConfig struct {
+	Chain       []string // Resolvers to try in order, see ResolverLookup, ResolverLLM and ResolverRegex
+	APIKey      string   // Anthropic API key for the LLM resolver
+	LLMModel    string
+	LLMEndpoint string // Messages API URL; point it at a stub server in tests
+	LLMTimeout  time.Duration
+}
+
+// ResolverConfigFromEnv reads the resolver configuration from SHOW_NAME_RESOLVERS
+// (comma-separated, default "llm,regex"), ANTHROPIC_API_KEY, PARSER_LLM_MODEL and PARSER_LLM_ENDPOINT
+func ResolverConfigFromEnv() ResolverConfig {
+	cfg := ResolverConfig{
+		Chain:       []string{ResolverLLM, ResolverRegex},
+		APIKey:      os.Getenv("ANTHROPIC_API_KEY"),
+		LLMModel:    os.Getenv("PARSER_LLM_MODEL"),
+		LLMEndpoint: os.Getenv("PARSER_LLM_ENDPOINT"),
+	}
+	if chain := os.Getenv("SHOW_NAME_RESOLVERS"); chain != "" {
+		cfg.Chain = nil
+		for _, name := range strings.Split(chain, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				cfg.Chain = append(cfg.Chain, name)
+			}
+		}
+	}
+	return cfg
+}
+
+// NewShowNameResolver builds the resolver chain of cfg. With a store, the lookup
+// resolver is available and results are cached by torrent name. The LLM resolver
+// is skipped without an API key, and a regex resolver always ends the chain
+func NewShowNameResolver(cfg ResolverConfig, store ResolverStore) (ShowNameResolver, error) {
+	chain := &ChainResolver{}
+	hasRegex := false
+	for _, name := range cfg.Chain {
+		switch name {
+		case ResolverLookup:
+			if store == nil {
+				return nil, fmt.Errorf("the %s resolver needs a database", name)
+			}
+			chain.Resolvers = append(chain.Resolvers, NewLookupResolver(store.ShowNames))
+		case ResolverLLM:
+			if cfg.APIKey == "" {
+				continue
+			}
+			llm := NewLLMResolver(cfg.APIKey)
+			if cfg.LLMModel != "" {
+				llm.Model = cfg.LLMModel
+			}
+			if cfg.LLMEndpoint != "" {
+				llm.Endpoint = cfg.LLMEndpoint
+			}
+			if cfg.LLMTimeout > 0 {
+				llm.client.Timeout = cfg.LLMTimeout
+			}
+			chain.Resolvers = append(chain.Resolvers, llm)
+		case ResolverRegex:
+			hasRegex = true
+			chain.Resolvers = append(chain.Resolvers, RegexResolver{})
+		default:
+			return nil, fmt.Errorf("unknown show name resolver %q", name)
+		}
+	}
+	if !hasRegex {
+		chain.Resolvers = append(chain.Resolvers, RegexResolver{})
+	}
+
+	if store == nil {
+		return chain, nil
+	}
+	return NewCachedResolver(chain, store), nil
+}
+
+// activeResolver resolves show names for ParseVideoInfo
+var activeResolver atomic.Pointer[ShowNameResolver]
+
+func init() {
+	resolver, err := NewShowNameResolver(ResolverConfigFromEnv(), nil)
+	if err != nil {
+		log.Printf("Warning: Invalid show name resolvers, using regex only: %v", err)
+		resolver = RegexResolver{}
+	}
+	SetShowNameResolver(resolver)
+}
+
+// SetShowNameResolver makes ParseVideoInfo use resolver for show names
+func SetShowNameResolver(resolver ShowNameResolver) {
+	activeResolver.Store(&resolver)
+}
+
+// resolveShowName resolves a show name with the active resolver, cleaning up the
+// torrent name if it fails
+func resolveShowName(torrentName, filePath string) string {
+	showName, err := (*activeResolver.Load()).ResolveShowName(torrentName, filePath)
+	if err != nil || showName == "" {
+		return extractShowName(torrentName, filePath)
+	}
+	return showName
+}
+
+// RegexResolver cleans up the torrent or file name; it always finds a name
+type RegexResolver struct{}
+
+// Name returns "regex"
+func (RegexResolver) Name() string { return ResolverRegex }
+
+// ResolveShowName extracts the show name with regular expressions
+func (RegexResolver) ResolveShowName(torrentName, filePath string) (string, error) {
+	return extractShowName(torrentName, filePath), nil
+}
+
+// ChainResolver tries resolvers in order until one finds a name
+type ChainResolver struct {
+	Resolvers []ShowNameResolver
+}
+
+// Name returns "chain"
+func (c *ChainResolver) Name() string { return "chain" }
+
+// ResolveShowName returns the first name found by the resolvers
+func (c *ChainResolver) ResolveShowName(torrentName, filePath string) (string, error) {
+	showName, _, err := c.Resolve(torrentName, filePath)
+	return showName, err
+}
+
+// Resolve returns the first name found by the resolvers and the name of the resolver that found it
+func (c *ChainResolver) Resolve(torrentName, filePath string) (showName, source string, err error) {
+	for _, resolver := range c.Resolvers {
+		showName, err := resolver.ResolveShowName(torrentName, filePath)
+		if err != nil {
+			if !errors.Is(err, ErrNotResolved) {
+				log.Printf("Warning: %s show name resolver failed for %q: %v", resolver.Name(), torrentName, err)
+			}
+			continue
+		}
+		if showName != "" {
+			return showName, resolver.Name(), nil
+		}
+	}
+	return "", "", ErrNotResolved
+}
+
+// CachedResolver remembers the names a chain resolves, keyed by torrent name,
+// in memory and in the store. Regex results are only kept in memory so a failed
+// LLM call is retried by the next process
+type CachedResolver struct {
+	chain *ChainResolver
+	store ResolverStore
+	mu    sync.Mutex
+	names map[string]string // Torrent name to show name
+}
+
+// NewCachedResolver creates a resolver caching the results of chain in store
+func NewCachedResolver(chain *ChainResolver, store ResolverStore) *CachedResolver {
+	return &CachedResolver{chain: chain, store: store, names: make(map[string]string)}
+}
+
+// Name returns "cache"
+func (c *CachedResolver) Name() string { return "cache" }
+
+// ResolveShowName returns the cached name of the torrent, or resolves and caches it
+func (c *CachedResolver) ResolveShowName(torrentName, filePath string) (string, error) {
+	// Without a torrent name there is nothing shared between files to cache
+	if strings.TrimSpace(torrentName) == "" {
+		return c.chain.ResolveShowName(torrentName, filePath)
+	}
+
+	c.mu.Lock()
+	showName, ok := c.names[torrentName]
+	c.mu.Unlock()
+	if ok {
+		return showName, nil
+	}
+
+	showName, ok, err := c.store.GetNameResolution(torrentName)
+	if err != nil {
+		log.Printf("Warning: Failed to look up cached show name of %q: %v", torrentName, err)
+	}
+	if !ok {
+		var source string
+		if showName, source, err = c.chain.Resolve(torrentName, filePath); err != nil {
+			return "", err
+		}
+		if source != ResolverRegex {
+			if err := c.store.SaveNameResolution(torrentName, showName, source); err != nil {
+				log.Printf("Warning: Failed to cache show name of %q: %v", torrentName, err)
+			}
+		}
+	}
+
+	c.mu.Lock()
+	c.names[torrentName] = showName
+	c.mu.Unlock()
+	return showName, nil
+}
+
+// LookupResolver matches torrent and file names against the shows already in the archive
+type LookupResolver struct {
+	load     func() ([]string, error)
+	mu       sync.Mutex
+	shows    map[string]string // Normalized name to show name
+	keys     []string          // Normalized names, longest first
+	loadedAt time.Time
+}
+
+// NewLookupResolver creates a resolver matching against the show names load returns
+func NewLookupResolver(load func() ([]string, error)) *LookupResolver {
+	return &LookupResolver{load: load}
+}
+
+// Name returns "lookup"
+func (l *LookupResolver) Name() string { return ResolverLookup }
+
+// ResolveShowName returns the longest known show name the torrent or file name starts with
+func (l *LookupResolver) ResolveShowName(torrentName, filePath string) (string, error) {
+	if err := l.refresh(); err != nil {
+		return "", err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	candidates := []string{normalizeName(cleanShowName(torrentName)), normalizeName(fileBaseName(filePath))}
+	for _, key := range l.keys {
+		for _, candidate := range candidates {
+			if candidate == key || strings.HasPrefix(candidate, key+" ") {
+				return l.shows[key], nil
+			}
+		}
+	}
+	return "", ErrNotResolved
+}
+
+// refresh reloads the known show names when they are older than lookupRefreshInterval
+func (l *LookupResolver) refresh() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.shows != nil && time.Since(l.loadedAt) < lookupRefreshInterval {
+		return nil
+	}
+
+	names, err := l.load()
+	if err != nil {
+		return fmt.Errorf("failed to load show names: %w", err)
+	}
+	l.shows = make(map[string]string, len(names))
+	l.keys = l.keys[:0]
+	for _, name := range names {
+		key := normalizeName(name)
+		// Very short names would match far too many torrents
+		if len(key) < 4 {
+			continue
+		}
+		if _, ok := l.shows[key]; !ok {
+			l.keys = append(l.keys, key)
+		}
+		l.shows[key] = name
+	}
+	sort.Slice(l.keys, func(i, j int) bool { return len(l.keys[i]) > len(l.keys[j]) })
+	l.loadedAt = time.Now()
+	return nil
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^a-z0-9]+`)
+
+// normalizeName lower-cases a name and reduces punctuation to single spaces
+func normalizeName(name string) string {
+	return strings.TrimSpace(nonAlphanumeric.ReplaceAllString(strings.ToLower(name), " "))
+}
+
+// fileBaseName returns the file name of a path without its extension
+func fileBaseName(filePath string) string {
+	name := filepath.Base(filePath)
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// LLMResolver asks the Anthropic Messages API for the show name
+type LLMResolver struct {
+	APIKey   string
+	Model    string
+	Endpoint string
+	client   *http.Client
+}
+
+// NewLLMResolver creates an LLM resolver with the default model, endpoint and timeout
+func NewLLMResolver(apiKey string) *LLMResolver {
+	return &LLMResolver{
+		APIKey:   apiKey,
+		Model:    DefaultLLMModel,
+		Endpoint: DefaultLLMEndpoint,
+		client:   &http.Client{Timeout: DefaultLLMTimeout},
+	}
+}
+
+// Name returns "llm"
+func (l *LLMResolver) Name() string { return ResolverLLM }
+
+type claudeMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type claudeRequest struct {
+	Model     string          `json:"model"`
+	MaxTokens int             `json:"max_tokens"`
+	Messages  []claudeMessage `json:"messages"`
+}
+
+type claudeResponse struct {
+	Content []struct {
+		Text string `json:"text"`
+	} `json:"content"`
+}
+
+// ResolveShowName asks the model to extract the clean show name
+func (l *LLMResolver) ResolveShowName(torrentName, filePath string) (string, error) {
+	prompt := fmt.Sprintf(`Extract the TV show name from this torrent/file information. Return ONLY the clean show name without any metadata.
+
+Torrent name: %s
+File path: %s
+
+Rules:
+- Remove metadata like "5 Seasons", "Complete", "Season 1-5", etc.
+- Remove quality info like "720p", "WEB-DL", "DVDRip", etc.
+- Remove year if present
+- Return just the show name, properly capitalized
+- If it's "Futurama 5 Seasons", return "Futurama"
+- If it's "The Simpsons Complete", return "The Simpsons"
+
+Return ONLY the show name, nothing else:`, torrentName, filePath)
+
+	reqBody := claudeRequest{
+		Model:     l.Model,
+		MaxTokens: 50,
+		Messages: []claudeMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+	}
+
+	jsonData, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", l.Endpoint, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", l.APIKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("API returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var claudeResp claudeResponse
+	if err := json.Unmarshal(body, &claudeResp); err != nil {
+		return "", fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	if len(claudeResp.Content) > 0 {
+		showName := strings.TrimSpace(claudeResp.Content[0].Text)
+		// Basic validation - make sure it's not empty and not too long
+		if len(showName) > 0 && len(showName) < 100 {
+			return showName, nil
+		}
+	}
+
+	return "", fmt.Errorf("unusable response: %s", strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// memoryStore is a ResolverStore in memory
+type memoryStore struct {
+	names map[string]string
+	shows []string
+	saves int
+}
+
+func (m *memoryStore) GetNameResolution(torrentName string) (string, bool, error) {
+	name, ok := m.names[torrentName]
+	return name, ok, nil
+}
+
+func (m *memoryStore) SaveNameResolution(torrentName, showName, source string) error {
+	m.names[torrentName] = showName
+	m.saves++
+	return nil
+}
+
+func (m *memoryStore) ShowNames() ([]string, error) {
+	return m.shows, nil
+}
+
+// stubLLM serves the Messages API, answering every request with answer
+func stubLLM(t *testing.T, status int, answer string) (*httptest.Server, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		var req claudeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("invalid request: %v", err)
+		}
+		if req.Model != "test-model" || r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("request model = %q, key = %q", req.Model, r.Header.Get("x-api-key"))
+		}
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"content": []map[string]string{{"type": "text", "text": answer}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func TestShowNameResolvers(t *testing.T) {
+	server, calls := stubLLM(t, http.StatusOK, " Futurama \n")
+	cfg := ResolverConfig{
+		Chain:       []string{ResolverLookup, ResolverLLM},
+		APIKey:      "test-key",
+		LLMModel:    "test-model",
+		LLMEndpoint: server.URL,
+	}
+	store := &memoryStore{names: map[string]string{}, shows: []string{"Breaking Bad", "The Office"}}
+	resolver, err := NewShowNameResolver(cfg, store)
+	if err != nil {
+		t.Fatalf("NewShowNameResolver() error = %v", err)
+	}
+
+	tests := []struct {
+		torrentName  string
+		filePath     string
+		expectedShow string
+	}{
+		{"Breaking.Bad.S01.1080p.BluRay", "Breaking.Bad.S01E01.mkv", "Breaking Bad"}, // Known show
+		{"Futurama 5 Seasons", "Season 1/Futurama.S01E01.mkv", "Futurama"},           // Asks the LLM
+		{"Futurama 5 Seasons", "Season 1/Futurama.S01E02.mkv", "Futurama"},           // Cached
+	}
+	for _, tt := range tests {
+		showName, err := resolver.ResolveShowName(tt.torrentName, tt.filePath)
+		if err != nil || showName != tt.expectedShow {
+			t.Errorf("ResolveShowName(%q) = %q, %v, want %q", tt.torrentName, showName, err, tt.expectedShow)
+		}
+	}
+	if *calls != 1 {
+		t.Errorf("LLM called %d times, want 1", *calls)
+	}
+	if store.names["Futurama 5 Seasons"] != "Futurama" {
+		t.Errorf("LLM result not stored: %v", store.names)
+	}
+}
+
+func TestShowNameResolverFallback(t *testing.T) {
+	server, calls := stubLLM(t, http.StatusInternalServerError, "")
+	cfg := ResolverConfig{Chain: []string{ResolverLLM}, APIKey: "test-key", LLMModel: "test-model", LLMEndpoint: server.URL}
+	store := &memoryStore{names: map[string]string{}}
+	resolver, err := NewShowNameResolver(cfg, store)
+	if err != nil {
+		t.Fatalf("NewShowNameResolver() error = %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		showName, err := resolver.ResolveShowName("Game of Thrones Season 3", "Game.of.Thrones.S03E05.mkv")
+		if err != nil || showName != "Game of Thrones" {
+			t.Errorf("ResolveShowName() = %q, %v, want regex fallback", showName, err)
+		}
+	}
+	// The regex fallback is remembered by the process but not stored, so the LLM is asked again later
+	if *calls != 1 || store.saves != 0 {
+		t.Errorf("LLM calls = %d, saves = %d, want 1 and 0", *calls, store.saves)
+	}
+
+	if _, err := NewShowNameResolver(ResolverConfig{Chain: []string{"oracle"}}, nil); err == nil {
+		t.Error("NewShowNameResolver() accepted an unknown resolver")
+	}
+}
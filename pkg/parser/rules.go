@@ -0,0 +1,310 @@
+package parser
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Rules configure the parser without code changes. They are evaluated before
+// the generic heuristics and can be loaded from a JSON file, see LoadRules
+type Rules struct {
+	ExtraFolders  []string      `json:"extraFolders"`       // Folders holding extras, e.g. "deleted scenes"
+	ExtraKeywords []string      `json:"extraKeywords"`      // Words in file names marking extras, e.g. "featurette"
+	Episodes      []EpisodeRule `json:"episodes,omitempty"` // Season/episode patterns for every show
+	Shows         []ShowRule    `json:"shows,omitempty"`    // Per-show overrides
+}
+
+// ShowRule overrides the parser for the files of one show
+type ShowRule struct {
+	Match    string        `json:"match,omitempty"`    // Regular expression matched against the torrent name and file path
+	Name     string        `json:"name,omitempty"`     // Show name to use for matching files, skipping name extraction
+	Aliases  []string      `json:"aliases,omitempty"`  // Extracted show names that are renamed to Name
+	Specials []string      `json:"specials,omitempty"` // Words in file names marking extras of this show
+	Episodes []EpisodeRule `json:"episodes,omitempty"` // Season/episode patterns, tried before the global ones
+}
+
+// EpisodeRule maps file names matching a pattern to a season and episode
+type EpisodeRule struct {
+	Pattern string `json:"pattern"`          // Regular expression matched against the file name; named groups "season" and "episode" supply the numbers
+	Season  int    `json:"season,omitempty"` // Season for patterns without a "season" group; 0 makes matching files extras
+}
+
+// DefaultRules are used when no rules file is loaded
+func DefaultRules() Rules {
+	return Rules{
+		ExtraFolders: []string{
+			"extras", "specials", "featurettes", "bonus", "behind the scenes", "deleted scenes", "bloopers", "blooper", "gag reel",
+		},
+		ExtraKeywords: []string{
+			"extra", "special", "bonus", "deleted scene", "blooper", "gag reel", "behind the scenes", "featurette",
+		},
+		Shows: []ShowRule{
+			{
+				// Webisodes shipped in The Office season packs
+				Match: `(?i)\boffice\b`,
+				Specials: []string{
+					"faces of scranton", "the accountants", "the mentor", "blackmail",
+					"subtle sexuality", "the 3rd floor", "the podcast",
+				},
+			},
+		},
+	}
+}
+
+// compiledRules are Rules with their patterns compiled, ready for the parser
+type compiledRules struct {
+	rules    Rules
+	episodes []compiledEpisodeRule
+	shows    []compiledShowRule
+	aliases  map[string]string // Lower-case alias to show name
+}
+
+type compiledShowRule struct {
+	ShowRule
+	match    *regexp.Regexp
+	episodes []compiledEpisodeRule
+}
+
+type compiledEpisodeRule struct {
+	EpisodeRule
+	pattern *regexp.Regexp
+}
+
+// activeRules are the rules ParseVideoInfo evaluates; swapped atomically on reload
+var activeRules atomic.Pointer[compiledRules]
+
+func init() {
+	compiled, err := compileRules(DefaultRules())
+	if err != nil {
+		panic(fmt.Sprintf("invalid default parser rules: %v", err))
+	}
+	activeRules.Store(compiled)
+}
+
+// compileRules validates rules and compiles their patterns
+func compileRules(rules Rules) (*compiledRules, error) {
+	compiled := &compiledRules{rules: rules, aliases: make(map[string]string)}
+
+	var err error
+	if compiled.episodes, err = compileEpisodeRules(rules.Episodes); err != nil {
+		return nil, err
+	}
+
+	for i, show := range rules.Shows {
+		if show.Match == "" && len(show.Aliases) == 0 {
+			return nil, fmt.Errorf("show rule %d needs a match pattern or aliases", i+1)
+		}
+		if len(show.Aliases) > 0 && show.Name == "" {
+			return nil, fmt.Errorf("show rule %d has aliases but no name", i+1)
+		}
+		c := compiledShowRule{ShowRule: show}
+		if show.Match != "" {
+			if c.match, err = regexp.Compile(show.Match); err != nil {
+				return nil, fmt.Errorf("invalid match pattern of show rule %d: %w", i+1, err)
+			}
+		}
+		if c.episodes, err = compileEpisodeRules(show.Episodes); err != nil {
+			return nil, fmt.Errorf("show rule %d: %w", i+1, err)
+		}
+		for _, alias := range show.Aliases {
+			compiled.aliases[strings.ToLower(strings.TrimSpace(alias))] = show.Name
+		}
+		compiled.shows = append(compiled.shows, c)
+	}
+
+	return compiled, nil
+}
+
+// compileEpisodeRules compiles the patterns of episode rules
+func compileEpisodeRules(rules []EpisodeRule) ([]compiledEpisodeRule, error) {
+	var compiled []compiledEpisodeRule
+	for i, rule := range rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern of episode rule %d: %w", i+1, err)
+		}
+		if pattern.SubexpIndex("episode") < 0 && pattern.SubexpIndex("season") < 0 && rule.Season == 0 {
+			return nil, fmt.Errorf("episode rule %d needs a season, or a \"season\" or \"episode\" group", i+1)
+		}
+		compiled = append(compiled, compiledEpisodeRule{EpisodeRule: rule, pattern: pattern})
+	}
+	return compiled, nil
+}
+
+// showRule returns the first show rule matching the torrent name or file path
+func (c *compiledRules) showRule(torrentName, filePath string) *compiledShowRule {
+	for i := range c.shows {
+		rule := &c.shows[i]
+		if rule.match != nil && (rule.match.MatchString(torrentName) || rule.match.MatchString(filePath)) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// isExtra reports whether a file is an extra according to the extras folders and keywords
+// Folders match anywhere in the path, including the top level of the torrent ("Extras/...")
+func (c *compiledRules) isExtra(show *compiledShowRule, filePath string) bool {
+	lowerPath := "/" + strings.ToLower(filepath.ToSlash(filePath))
+	lowerFileName := strings.ToLower(filepath.Base(filePath))
+
+	for _, folder := range c.rules.ExtraFolders {
+		if strings.Contains(lowerPath, "/"+strings.ToLower(folder)+"/") {
+			return true
+		}
+	}
+	keywords := c.rules.ExtraKeywords
+	if show != nil {
+		keywords = append(append([]string{}, keywords...), show.Specials...)
+	}
+	for _, keyword := range keywords {
+		if strings.Contains(lowerFileName, strings.ToLower(keyword)) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchEpisode applies the show's and then the global episode rules to a file name
+// and returns the pattern of the rule that matched
+func (c *compiledRules) matchEpisode(show *compiledShowRule, fileName string) (season, episode int, pattern string, ok bool) {
+	rules := c.episodes
+	if show != nil {
+		rules = append(append([]compiledEpisodeRule{}, show.episodes...), rules...)
+	}
+	for _, rule := range rules {
+		matches := rule.pattern.FindStringSubmatch(fileName)
+		if matches == nil {
+			continue
+		}
+		season = rule.Season
+		if i := rule.pattern.SubexpIndex("season"); i >= 0 {
+			season, _ = strconv.Atoi(matches[i])
+		}
+		if i := rule.pattern.SubexpIndex("episode"); i >= 0 {
+			episode, _ = strconv.Atoi(matches[i])
+		}
+		return season, episode, rule.Pattern, true
+	}
+	return 0, 0, "", false
+}
+
+// resolveAlias renames a show to the name its alias maps to
+func (c *compiledRules) resolveAlias(showName string) string {
+	if name, ok := c.aliases[strings.ToLower(strings.TrimSpace(showName))]; ok {
+		return name
+	}
+	return showName
+}
+
+// CurrentRules returns the rules the parser is using
+func CurrentRules() Rules {
+	return activeRules.Load().rules
+}
+
+// SetRules validates rules and makes the parser use them
+func SetRules(rules Rules) error {
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return err
+	}
+	activeRules.Store(compiled)
+	return nil
+}
+
+// ParseRules decodes and validates rules in JSON
+func ParseRules(data []byte) (Rules, error) {
+	var rules Rules
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&rules); err != nil {
+		return Rules{}, fmt.Errorf("failed to decode parser rules: %w", err)
+	}
+	if _, err := compileRules(rules); err != nil {
+		return Rules{}, err
+	}
+	return rules, nil
+}
+
+// LoadRules makes the parser use the rules in a JSON file
+func LoadRules(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read parser rules: %w", err)
+	}
+	rules, err := ParseRules(data)
+	if err != nil {
+		return err
+	}
+	return SetRules(rules)
+}
+
+// SaveRules validates rules, writes them to a JSON file and makes the parser use them
+func SaveRules(path string, rules Rules) error {
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode parser rules: %w", err)
+	}
+
+	// Write to a temporary file first so watchers never read a partial file
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write parser rules: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write parser rules: %w", err)
+	}
+	activeRules.Store(compiled)
+	return nil
+}
+
+// WatchRules loads the rules file and reloads it whenever it changes, until ctx is done.
+// A missing file keeps the default rules; an invalid one keeps the rules loaded before
+func WatchRules(ctx context.Context, path string, interval time.Duration) {
+	var loaded time.Time
+	check := func() {
+		stat, err := os.Stat(path)
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Printf("Warning: Failed to check parser rules %s: %v", path, err)
+			}
+			return
+		}
+		if stat.ModTime().Equal(loaded) {
+			return
+		}
+		loaded = stat.ModTime()
+		if err := LoadRules(path); err != nil {
+			log.Printf("Warning: Keeping previous parser rules, %s is invalid: %v", path, err)
+			return
+		}
+		log.Printf("Loaded parser rules from %s", path)
+	}
+
+	check()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			check()
+		}
+	}
+}
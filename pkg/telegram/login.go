@@ -0,0 +1,103 @@
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// LoginData is a Telegram user authenticated by the Login Widget
+type LoginData struct {
+	ID        int64
+	FirstName string
+	LastName  string
+	Username  string
+	PhotoURL  string
+	AuthDate  time.Time
+}
+
+// VerifyLogin checks the hash of Telegram Login Widget callback parameters
+// The hash is an HMAC-SHA256 of the sorted "key=value" lines of every other received field, keyed
+// with SHA256(bot token), so values must only contain the widget's fields; logins older than
+// maxAge are rejected to limit replay of leaked callback URLs
+// See https://core.telegram.org/widgets/login#checking-authorization
+func VerifyLogin(botToken string, values url.Values, maxAge time.Duration) (*LoginData, error) {
+	hash := values.Get("hash")
+	if hash == "" {
+		return nil, fmt.Errorf("missing hash")
+	}
+
+	var lines []string
+	for key := range values {
+		if key != "hash" {
+			lines = append(lines, key+"="+values.Get(key))
+		}
+	}
+	sort.Strings(lines)
+
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	expected := hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(hash))) {
+		return nil, fmt.Errorf("invalid hash")
+	}
+
+	id, err := strconv.ParseInt(values.Get("id"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid user id: %w", err)
+	}
+	authUnix, err := strconv.ParseInt(values.Get("auth_date"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth_date: %w", err)
+	}
+	authDate := time.Unix(authUnix, 0)
+	if time.Since(authDate) > maxAge {
+		return nil, fmt.Errorf("login expired (authorized %s ago)", time.Since(authDate).Round(time.Second))
+	}
+
+	return &LoginData{
+		ID:        id,
+		FirstName: values.Get("first_name"),
+		LastName:  values.Get("last_name"),
+		Username:  values.Get("username"),
+		PhotoURL:  values.Get("photo_url"),
+		AuthDate:  authDate,
+	}, nil
+}
+
+// BotUsername returns the bot's username, as needed by the Login Widget
+func (d *Downloader) BotUsername() string {
+	return d.bot.Self.UserName
+}
+
+// ChatMember returns the membership of a user in the downloader's chat
+func (d *Downloader) ChatMember(userID int64) (tgbotapi.ChatMember, error) {
+	member, err := d.bot.GetChatMember(tgbotapi.GetChatMemberConfig{
+		ChatConfigWithUser: tgbotapi.ChatConfigWithUser{ChatID: d.chatID, UserID: userID},
+	})
+	if err != nil {
+		return tgbotapi.ChatMember{}, fmt.Errorf("failed to get chat member %d: %w", userID, err)
+	}
+	return member, nil
+}
+
+// IsChatMember reports whether a chat membership grants access
+// Restricted users count only while they are still in the chat
+func IsChatMember(member tgbotapi.ChatMember) bool {
+	switch member.Status {
+	case "creator", "administrator", "member":
+		return true
+	case "restricted":
+		return member.IsMember
+	}
+	return false
+}
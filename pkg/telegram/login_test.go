@@ -0,0 +1,144 @@
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testBotToken = "123456:test-bot-token"
+
+// signLogin signs fields the way the Telegram Login Widget does
+func signLogin(botToken string, fields url.Values) url.Values {
+	var lines []string
+	for key := range fields {
+		lines = append(lines, key+"="+fields.Get(key))
+	}
+	sort.Strings(lines)
+
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+
+	signed := url.Values{}
+	for key, values := range fields {
+		signed[key] = values
+	}
+	signed.Set("hash", hex.EncodeToString(mac.Sum(nil)))
+	return signed
+}
+
+// loginFields returns widget fields of a login authorized at authDate
+func loginFields(authDate time.Time) url.Values {
+	return url.Values{
+		"id":         {"42"},
+		"first_name": {"Alice"},
+		"username":   {"alice"},
+		"photo_url":  {"https://t.me/i/userpic/320/alice.jpg"},
+		"auth_date":  {strconv.FormatInt(authDate.Unix(), 10)},
+	}
+}
+
+func TestVerifyLogin(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name    string
+		values  func() url.Values
+		wantErr string
+	}{
+		{
+			name:   "valid",
+			values: func() url.Values { return signLogin(testBotToken, loginFields(now)) },
+		},
+		{
+			name: "valid without optional fields",
+			values: func() url.Values {
+				return signLogin(testBotToken, url.Values{"id": {"42"}, "first_name": {"Alice"}, "auth_date": {strconv.FormatInt(now.Unix(), 10)}})
+			},
+		},
+		{
+			name: "valid with a field added by a newer widget",
+			values: func() url.Values {
+				fields := loginFields(now)
+				fields.Set("allows_write_to_pm", "true")
+				return signLogin(testBotToken, fields)
+			},
+		},
+		{
+			name: "uppercase hash",
+			values: func() url.Values {
+				values := signLogin(testBotToken, loginFields(now))
+				values.Set("hash", strings.ToUpper(values.Get("hash")))
+				return values
+			},
+		},
+		{
+			name: "tampered field",
+			values: func() url.Values {
+				values := signLogin(testBotToken, loginFields(now))
+				values.Set("id", "1")
+				return values
+			},
+			wantErr: "invalid hash",
+		},
+		{
+			name: "unsigned field added",
+			values: func() url.Values {
+				values := signLogin(testBotToken, loginFields(now))
+				values.Set("last_name", "Admin")
+				return values
+			},
+			wantErr: "invalid hash",
+		},
+		{
+			name: "signed field removed",
+			values: func() url.Values {
+				values := signLogin(testBotToken, loginFields(now))
+				values.Del("photo_url")
+				return values
+			},
+			wantErr: "invalid hash",
+		},
+		{
+			name:    "expired auth_date",
+			values:  func() url.Values { return signLogin(testBotToken, loginFields(now.Add(-25*time.Hour))) },
+			wantErr: "login expired",
+		},
+		{
+			name:    "wrong bot token",
+			values:  func() url.Values { return signLogin("654321:other-bot-token", loginFields(now)) },
+			wantErr: "invalid hash",
+		},
+		{
+			name: "missing hash",
+			values: func() url.Values {
+				return loginFields(now)
+			},
+			wantErr: "missing hash",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login, err := VerifyLogin(testBotToken, tt.values(), 24*time.Hour)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("VerifyLogin() error = %v, expected %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("VerifyLogin() error = %v", err)
+			}
+			if login.ID != 42 || login.FirstName != "Alice" || login.AuthDate.Unix() != now.Unix() {
+				t.Errorf("VerifyLogin() = %+v", login)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package telegram
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/rusik69/trtg/pkg/torrent"
+)
+
+const (
+	// minEditInterval keeps status message edits well below Telegram's rate limits
+	minEditInterval = 5 * time.Second
+	// maxListedFiles is how many skipped or failed files the summary lists
+	maxListedFiles = 10
+)
+
+// Notifier posts download status messages to a notification chat
+type Notifier struct {
+	bot    *tgbotapi.BotAPI
+	chatID int64
+}
+
+// NewNotifier creates a notifier posting to chatID with the uploader's bot
+// Returns nil if chatID is 0; a nil Notifier starts no status messages
+func NewNotifier(uploader *Uploader, chatID int64) *Notifier {
+	if chatID == 0 {
+		return nil
+	}
+	return &Notifier{bot: uploader.bot, chatID: chatID}
+}
+
+// fileNote is a file with the reason it was skipped or failed
+type fileNote struct {
+	path   string
+	reason string
+}
+
+// StatusMessage is one torrent's status message, edited in place as the download progresses
+// It implements torrent.Reporter; a nil StatusMessage ignores all calls
+type StatusMessage struct {
+	mu        sync.Mutex
+	n         *Notifier
+	messageID int
+	name      string
+	progress  *torrent.Progress
+	lastEdit  time.Time
+	uploaded  int
+	skipped   []fileNote
+	failed    []fileNote
+	done      bool
+}
+
+// Start posts the status message of a torrent
+// Returns nil if the notifier is nil or the message cannot be sent
+func (n *Notifier) Start(name string) *StatusMessage {
+	if n == nil {
+		return nil
+	}
+	s := &StatusMessage{n: n, name: name}
+	msg := tgbotapi.NewMessage(n.chatID, s.render())
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.DisableWebPagePreview = true
+	msg.DisableNotification = true
+	sent, err := n.bot.Send(msg)
+	if err != nil {
+		log.Printf("Warning: Failed to send status message for %s: %v", name, err)
+		return nil
+	}
+	s.messageID = sent.MessageID
+	s.lastEdit = time.Now()
+	return s
+}
+
+// Progress implements torrent.Reporter
+func (s *StatusMessage) Progress(p torrent.Progress) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.Torrent != "" {
+		s.name = p.Torrent
+	}
+	s.progress = &p
+	s.edit(false)
+}
+
+// Skipped implements torrent.Reporter
+func (s *StatusMessage) Skipped(path, reason string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.skipped = append(s.skipped, fileNote{path, reason})
+}
+
+// Uploaded records a file uploaded to the archive chat
+func (s *StatusMessage) Uploaded(path string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploaded++
+	s.edit(false)
+}
+
+// Failed records a file that could not be uploaded
+func (s *StatusMessage) Failed(path string, err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failed = append(s.failed, fileNote{path, err.Error()})
+	s.edit(false)
+}
+
+// Finish replaces the progress with the summary; err is the error that stopped the torrent, if any
+func (s *StatusMessage) Finish(err error) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failed = append(s.failed, fileNote{"", err.Error()})
+	}
+	s.done = true
+	s.progress = nil
+	s.edit(true)
+}
+
+// edit updates the message unless it was edited less than minEditInterval ago
+// Must be called with s.mu held
+func (s *StatusMessage) edit(force bool) {
+	if !force && time.Since(s.lastEdit) < minEditInterval {
+		return
+	}
+	s.lastEdit = time.Now()
+
+	edit := tgbotapi.NewEditMessageText(s.n.chatID, s.messageID, s.render())
+	edit.ParseMode = tgbotapi.ModeHTML
+	edit.DisableWebPagePreview = true
+	if _, err := s.n.bot.Send(edit); err != nil && !strings.Contains(err.Error(), "message is not modified") {
+		log.Printf("Warning: Failed to update status message for %s: %v", s.name, err)
+	}
+}
+
+// render formats the status message
+// Must be called with s.mu held
+func (s *StatusMessage) render() string {
+	var text strings.Builder
+	icon := "⬇️"
+	if s.done {
+		icon = "✅"
+		if len(s.failed) > 0 {
+			icon = "⚠️"
+		}
+	}
+	fmt.Fprintf(&text, "%s <b>%s</b>\n", icon, html.EscapeString(s.name))
+
+	if p := s.progress; p != nil {
+		fmt.Fprintf(&text, "File %d/%d: %s\n", p.FileIndex+1, p.FileCount, html.EscapeString(p.File))
+		fmt.Fprintf(&text, "%.1f%% of %s · %s/s", p.Percent(), FormatSize(p.FileSize), FormatSize(int64(p.Speed)))
+		if p.ETA > 0 {
+			fmt.Fprintf(&text, " · ETA %s", p.ETA.Round(time.Second))
+		}
+		fmt.Fprintf(&text, " · %d peers\n", p.Peers)
+	} else if !s.done {
+		text.WriteString("Starting…\n")
+	}
+
+	if !s.done {
+		if s.uploaded > 0 || len(s.failed) > 0 {
+			fmt.Fprintf(&text, "Uploaded %d, failed %d\n", s.uploaded, len(s.failed))
+		}
+		return text.String()
+	}
+
+	fmt.Fprintf(&text, "Uploaded %d, skipped %d, failed %d\n", s.uploaded, len(s.skipped), len(s.failed))
+	writeNotes(&text, "Failed", s.failed)
+	writeNotes(&text, "Skipped", s.skipped)
+	return text.String()
+}
+
+// writeNotes lists up to maxListedFiles files with their reasons
+func writeNotes(text *strings.Builder, title string, notes []fileNote) {
+	if len(notes) == 0 {
+		return
+	}
+	fmt.Fprintf(text, "\n<b>%s:</b>\n", title)
+	for i, note := range notes {
+		if i == maxListedFiles {
+			fmt.Fprintf(text, "…and %d more\n", len(notes)-maxListedFiles)
+			break
+		}
+		if note.path == "" {
+			fmt.Fprintf(text, "• %s\n", html.EscapeString(note.reason))
+		} else {
+			fmt.Fprintf(text, "• %s: %s\n", html.EscapeString(note.path), html.EscapeString(note.reason))
+		}
+	}
+}
+
+// FormatSize formats a byte count for humans
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -153,6 +153,17 @@ func (u *Uploader) SendMessage(text string) error {
 	return err
 }
 
+// SendHTML sends an HTML-formatted message to any chat the bot can write to
+func (u *Uploader) SendHTML(chatID int64, text string) error {
+	msg := tgbotapi.NewMessage(chatID, text)
+	msg.ParseMode = tgbotapi.ModeHTML
+	msg.DisableWebPagePreview = true
+	if _, err := u.bot.Send(msg); err != nil {
+		return fmt.Errorf("failed to send message to chat %d: %w", chatID, err)
+	}
+	return nil
+}
+
 // DeleteMessage deletes a message from Telegram by message ID
 func (u *Uploader) DeleteMessage(messageID int) error {
 	deleteMsg := tgbotapi.NewDeleteMessage(u.chatID, messageID)
@@ -332,3 +343,13 @@ func NewDownloaderFromUploader(uploader *Uploader) (*Downloader, error) {
 		apiURL: uploader.apiURL,
 	}, nil
 }
+
+// MessageLink returns a t.me link to a message in a supergroup or channel
+// Returns an empty string for other chats, whose messages cannot be linked
+func MessageLink(chatID int64, messageID int) string {
+	const channelPrefix = -1000000000000
+	if chatID > channelPrefix || messageID == 0 {
+		return ""
+	}
+	return fmt.Sprintf("https://t.me/c/%d/%d", channelPrefix-chatID, messageID)
+}
@@ -0,0 +1,64 @@
+package torrent
+
+import (
+	"fmt"
+	"time"
+)
+
+// progressInterval is how often download progress is reported
+const progressInterval = 10 * time.Second
+
+// Progress is a snapshot of the download of one file
+type Progress struct {
+	Torrent       string
+	File          string
+	FileIndex     int // Zero-based index among the files being downloaded
+	FileCount     int
+	FileCompleted int64
+	FileSize      int64
+	Speed         float64       // Bytes per second since the previous report
+	ETA           time.Duration // Time left for the current file; 0 if unknown
+	Peers         int
+}
+
+// Percent returns how much of the current file is downloaded
+func (p Progress) Percent() float64 {
+	if p.FileSize <= 0 {
+		return 0
+	}
+	return float64(p.FileCompleted) / float64(p.FileSize) * 100
+}
+
+// Reporter receives progress and the files DownloadTorrentFiles and DownloadTorrentWithReporter skip
+// Methods are called from the downloading goroutine
+type Reporter interface {
+	Progress(p Progress)
+	Skipped(path, reason string)
+}
+
+// stdoutReporter prints progress to stdout, at most every 5% or 10MB
+type stdoutReporter struct {
+	lastProgress int64
+	lastPercent  float64
+	lastFile     string
+}
+
+// Progress implements Reporter
+func (r *stdoutReporter) Progress(p Progress) {
+	if p.File != r.lastFile {
+		r.lastFile = p.File
+		r.lastProgress = 0
+		r.lastPercent = 0
+	}
+	percent := p.Percent()
+	if percent-r.lastPercent >= 5.0 || (p.FileCompleted != r.lastProgress && p.FileCompleted-r.lastProgress > 10*1024*1024) {
+		fmt.Printf("  Progress: %.1f%% (%d/%d bytes)\n", percent, p.FileCompleted, p.FileSize)
+		r.lastPercent = percent
+		r.lastProgress = p.FileCompleted
+	}
+}
+
+// Skipped implements Reporter
+func (r *stdoutReporter) Skipped(path, reason string) {
+	fmt.Printf("Skipping %s (%s)\n", path, reason)
+}
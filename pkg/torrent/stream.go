@@ -0,0 +1,215 @@
+package torrent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/metainfo"
+)
+
+// StreamReadahead is how many bytes ahead of the playback position are prioritized when streaming
+const StreamReadahead = 16 * 1024 * 1024
+
+// ErrFileNotFound is returned when a torrent has no file at the requested path
+var ErrFileNotFound = errors.New("file not found in torrent")
+
+// InfoHashFromURL extracts the lowercase hex info hash from a magnet link
+// Returns an empty string for .torrent file paths or malformed links
+func InfoHashFromURL(torrentURL string) string {
+	if !strings.HasPrefix(torrentURL, "magnet:") {
+		return ""
+	}
+	parts := strings.SplitN(torrentURL, "btih:", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	infoHash := strings.ToLower(strings.Split(parts[1], "&")[0])
+	if !IsInfoHash(infoHash) {
+		return ""
+	}
+	return infoHash
+}
+
+// IsInfoHash reports whether s is a 40 character hex info hash
+func IsInfoHash(s string) bool {
+	if len(s) != 40 {
+		return false
+	}
+	for _, c := range strings.ToLower(s) {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// MagnetFromInfoHash builds a minimal magnet link for an info hash (peers are found via DHT)
+func MagnetFromInfoHash(infoHash string) string {
+	return "magnet:?xt=urn:btih:" + strings.ToLower(infoHash)
+}
+
+// MagnetFromTorrentFile builds a magnet link with the info hash, name and trackers of a .torrent file
+// so the torrent can be queued without sharing the file with the downloader
+func MagnetFromTorrentFile(path string) (string, error) {
+	mi, err := metainfo.LoadFromFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to load torrent file: %w", err)
+	}
+	info, err := mi.UnmarshalInfo()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse torrent info: %w", err)
+	}
+	return mi.Magnet(nil, &info).String(), nil
+}
+
+// waitForInfo waits until torrent metadata is available or the context is done
+func waitForInfo(ctx context.Context, t *torrent.Torrent) error {
+	select {
+	case <-t.GotInfo():
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("timeout waiting for torrent metadata: %w", ctx.Err())
+	}
+}
+
+// Streams tracks the torrents opened for streaming, dropping them and deleting their pieces once
+// nobody has watched them for a while
+type Streams struct {
+	downloader *Downloader
+	idle       time.Duration
+
+	mu       sync.Mutex
+	torrents map[metainfo.Hash]*streamedTorrent
+	stop     chan struct{}
+}
+
+type streamedTorrent struct {
+	t        *torrent.Torrent
+	readers  int
+	lastUsed time.Time
+}
+
+// NewStreams returns a Streams dropping torrents without readers for idle
+func NewStreams(d *Downloader, idle time.Duration) *Streams {
+	return &Streams{
+		downloader: d,
+		idle:       idle,
+		torrents:   make(map[metainfo.Hash]*streamedTorrent),
+		stop:       make(chan struct{}),
+	}
+}
+
+// Open adds a torrent if needed and opens a prioritized reader on filePath, which must be closed.
+// Reads block until the swarm delivers the data or ctx is done (e.g. the HTTP client went away);
+// waiting for the torrent metadata is bounded by metadataCtx
+func (s *Streams) Open(ctx, metadataCtx context.Context, torrentURL, filePath string) (io.ReadSeekCloser, *torrent.File, error) {
+	t, err := s.downloader.GetOrAddTorrent(torrentURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.touch(t, 0)
+	if err := waitForInfo(metadataCtx, t); err != nil {
+		return nil, nil, err
+	}
+
+	for _, file := range t.Files() {
+		if file.Path() == filePath {
+			s.touch(t, 1)
+			reader := file.NewReader()
+			reader.SetReadahead(StreamReadahead)
+			reader.SetResponsive()
+			return &streamReader{ctx: ctx, Reader: reader, done: func() { s.touch(t, -1) }}, file, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%w: %s", ErrFileNotFound, filePath)
+}
+
+// touch marks a torrent as used now, adding delta to its open readers
+func (s *Streams) touch(t *torrent.Torrent, delta int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.torrents[t.InfoHash()]
+	if !ok {
+		entry = &streamedTorrent{t: t}
+		s.torrents[t.InfoHash()] = entry
+	}
+	entry.readers += delta
+	entry.lastUsed = time.Now()
+}
+
+// Start drops idle torrents periodically until Stop is called
+func (s *Streams) Start() {
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.dropIdle(time.Now())
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops dropping idle torrents
+func (s *Streams) Stop() {
+	close(s.stop)
+}
+
+// dropIdle drops the torrents without readers that were last used before now-idle and deletes their pieces
+func (s *Streams) dropIdle(now time.Time) {
+	s.mu.Lock()
+	var idle []*torrent.Torrent
+	for hash, entry := range s.torrents {
+		if entry.readers <= 0 && now.Sub(entry.lastUsed) >= s.idle {
+			idle = append(idle, entry.t)
+			delete(s.torrents, hash)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, t := range idle {
+		name := ""
+		if t.Info() != nil {
+			name = t.Name()
+		}
+		t.Drop()
+		log.Printf("Dropped idle torrent stream %s (%s)", t.InfoHash().HexString(), name)
+		if name == "" {
+			continue // Never got metadata, so nothing was written
+		}
+		if err := s.downloader.RemoveData(name); err != nil {
+			log.Printf("Warning: Failed to delete pieces of torrent %s: %v", name, err)
+		}
+	}
+}
+
+// streamReader adapts a torrent.Reader so blocking reads are cancelled with the context
+type streamReader struct {
+	ctx context.Context
+	torrent.Reader
+	done func() // Called once on Close
+}
+
+// Read reads from the torrent, giving up when the context is done
+func (r *streamReader) Read(b []byte) (int, error) {
+	return r.Reader.ReadContext(r.ctx, b)
+}
+
+// Close closes the reader
+func (r *streamReader) Close() error {
+	if r.done != nil {
+		r.done()
+		r.done = nil
+	}
+	return r.Reader.Close()
+}
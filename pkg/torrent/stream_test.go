@@ -0,0 +1,101 @@
+package torrent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/anacrolix/torrent"
+	"github.com/anacrolix/torrent/storage"
+)
+
+func TestInfoHashFromURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		torrentURL string
+		expected   string
+	}{
+		{"magnet link", "magnet:?xt=urn:btih:C12FE1C06BBA254A9DC9F519B335AA7C1367A88A&dn=Show", "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"},
+		{"magnet link without parameters", "magnet:?xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a", "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"},
+		{"hash after other parameters", "magnet:?dn=Show&xt=urn:btih:c12fe1c06bba254a9dc9f519b335aa7c1367a88a&tr=udp://tracker", "c12fe1c06bba254a9dc9f519b335aa7c1367a88a"},
+		{"short hash", "magnet:?xt=urn:btih:c12fe1c06bba254a", ""},
+		{"base32 hash", "magnet:?xt=urn:btih:YEX6DQDLXISUVHOJ6UM3GNNKPQJWPKEK", ""},
+		{"no info hash", "magnet:?dn=Show", ""},
+		{"torrent file", "/torrents/c12fe1c06bba254a9dc9f519b335aa7c1367a88a.torrent", ""},
+		{"empty", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InfoHashFromURL(tt.torrentURL); got != tt.expected {
+				t.Errorf("InfoHashFromURL(%q) = %q, want %q", tt.torrentURL, got, tt.expected)
+			}
+		})
+	}
+}
+
+// newTestDownloader returns a Downloader with a client that stays off the network
+func newTestDownloader(t *testing.T) *Downloader {
+	t.Helper()
+	dir := t.TempDir()
+	cfg := torrent.NewDefaultClientConfig()
+	cfg.DataDir = dir
+	cfg.DefaultStorage = storage.NewMMap(dir)
+	cfg.ListenPort = 0
+	cfg.NoDHT = true
+	cfg.DisableTrackers = true
+	cfg.DisableTCP = true
+	cfg.DisableUTP = true
+	client, err := torrent.NewClient(cfg)
+	if err != nil {
+		t.Fatalf("NewClient() error = %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return &Downloader{client: client, downloadDir: dir}
+}
+
+func TestStreamsDropIdle(t *testing.T) {
+	const idle = 10 * time.Minute
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		readers  int
+		lastUsed time.Time
+		dropped  bool
+	}{
+		{"idle without readers", 0, now.Add(-idle), true},
+		{"recently used", 0, now.Add(-idle + time.Second), false},
+		{"idle with an open reader", 1, now.Add(-2 * idle), false},
+	}
+
+	d := newTestDownloader(t)
+	s := NewStreams(d, idle)
+	hashes := []string{
+		"c12fe1c06bba254a9dc9f519b335aa7c1367a88a",
+		"08ada5a7a6183aae1e09d831df6748d566095a10",
+		"5bd1d9c08b2c2c2d71c1e5b7b0d1c9d0e4a3b2c1",
+	}
+	torrents := make([]*torrent.Torrent, len(tests))
+	for i, tt := range tests {
+		tor, err := d.GetOrAddTorrent(MagnetFromInfoHash(hashes[i]))
+		if err != nil {
+			t.Fatalf("GetOrAddTorrent() error = %v", err)
+		}
+		s.touch(tor, tt.readers)
+		s.torrents[tor.InfoHash()].lastUsed = tt.lastUsed
+		torrents[i] = tor
+	}
+
+	s.dropIdle(now)
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash := torrents[i].InfoHash()
+			_, tracked := s.torrents[hash]
+			_, added := d.client.Torrent(hash)
+			if tracked == tt.dropped || added == tt.dropped {
+				t.Errorf("tracked = %v, in client = %v, want dropped = %v", tracked, added, tt.dropped)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package torrent
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// subtitleExts lists text subtitle formats kept alongside downloaded videos
+var subtitleExts = map[string]bool{
+	".srt": true, ".vtt": true, ".ass": true, ".ssa": true,
+}
+
+// subtitleLanguages maps common language tokens found in subtitle file names to ISO 639-1 codes
+var subtitleLanguages = map[string]string{
+	"en": "en", "eng": "en", "english": "en",
+	"ru": "ru", "rus": "ru", "russian": "ru",
+	"uk": "uk", "ukr": "uk", "ukrainian": "uk",
+	"de": "de", "ger": "de", "deu": "de", "german": "de",
+	"fr": "fr", "fre": "fr", "fra": "fr", "french": "fr",
+	"es": "es", "spa": "es", "spanish": "es",
+	"it": "it", "ita": "it", "italian": "it",
+	"pt": "pt", "por": "pt", "portuguese": "pt",
+	"nl": "nl", "dut": "nl", "nld": "nl", "dutch": "nl",
+	"pl": "pl", "pol": "pl", "polish": "pl",
+	"ja": "ja", "jpn": "ja", "japanese": "ja",
+	"zh": "zh", "chi": "zh", "zho": "zh", "chinese": "zh",
+	"ko": "ko", "kor": "ko", "korean": "ko",
+}
+
+// Subtitle is an external subtitle file that belongs to a video
+type Subtitle struct {
+	Path     string // Local path of the subtitle file
+	Language string // ISO 639-1 code, empty if unknown
+	Label    string // Human readable name derived from the file name
+	Format   string // File extension without the dot (srt, vtt, ass, ssa)
+}
+
+// IsSubtitleFile reports whether a path is a text subtitle file
+func IsSubtitleFile(path string) bool {
+	return subtitleExts[strings.ToLower(filepath.Ext(path))]
+}
+
+// FindSubtitles returns the subtitle files downloaded next to a video
+// It matches "<video name>*.srt" in the same directory and the common
+// "Subs/<video name>/*.srt" and "Subs/*.srt" layouts of release groups
+func FindSubtitles(videoPath string) []Subtitle {
+	dir := filepath.Dir(videoPath)
+	base := strings.TrimSuffix(filepath.Base(videoPath), filepath.Ext(videoPath))
+
+	var subtitles []Subtitle
+	seen := make(map[string]bool)
+	add := func(path, name string) {
+		if seen[path] || !IsSubtitleFile(path) {
+			return
+		}
+		seen[path] = true
+		subtitles = append(subtitles, newSubtitle(path, name))
+	}
+
+	// Same directory: Show.S01E01.en.srt, Show.S01E01.English.forced.srt
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, entry := range entries {
+			if !entry.IsDir() && strings.HasPrefix(entry.Name(), base) {
+				name := strings.TrimSuffix(strings.TrimPrefix(entry.Name(), base), filepath.Ext(entry.Name()))
+				add(filepath.Join(dir, entry.Name()), strings.Trim(name, ". _-"))
+			}
+		}
+	}
+
+	// Per-episode subtitle folder: Subs/Show.S01E01/2_English.srt
+	for _, subsDir := range []string{"Subs", "subs", "Subtitles", "subtitles"} {
+		episodeDir := filepath.Join(dir, subsDir, base)
+		if entries, err := os.ReadDir(episodeDir); err == nil {
+			for _, entry := range entries {
+				if !entry.IsDir() {
+					add(filepath.Join(episodeDir, entry.Name()), strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+				}
+			}
+		}
+
+		// Flat subtitle folder: only files that mention the episode belong to it
+		if entries, err := os.ReadDir(filepath.Join(dir, subsDir)); err == nil {
+			for _, entry := range entries {
+				if !entry.IsDir() && strings.HasPrefix(entry.Name(), base) {
+					name := strings.TrimSuffix(strings.TrimPrefix(entry.Name(), base), filepath.Ext(entry.Name()))
+					add(filepath.Join(dir, subsDir, entry.Name()), strings.Trim(name, ". _-"))
+				}
+			}
+		}
+	}
+
+	sort.Slice(subtitles, func(i, j int) bool {
+		return subtitles[i].Path < subtitles[j].Path
+	})
+	return subtitles
+}
+
+// newSubtitle builds a Subtitle, guessing the language from tokens of its name
+func newSubtitle(path, name string) Subtitle {
+	subtitle := Subtitle{
+		Path:   path,
+		Label:  name,
+		Format: strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), "."),
+	}
+
+	tokens := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '.' || r == '_' || r == '-' || r == ' ' || (r >= '0' && r <= '9')
+	})
+	for _, token := range tokens {
+		if lang, ok := subtitleLanguages[token]; ok {
+			subtitle.Language = lang
+			break
+		}
+	}
+
+	if subtitle.Label == "" {
+		subtitle.Label = subtitle.Language
+	}
+	if subtitle.Label == "" {
+		subtitle.Label = "Subtitles"
+	}
+	return subtitle
+}
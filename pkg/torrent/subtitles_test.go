@@ -0,0 +1,127 @@
+package torrent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindSubtitles(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    []string // Created in the torrent directory next to the video
+		expected []Subtitle
+	}{
+		{
+			name:  "same directory",
+			files: []string{"Show.S01E01.en.srt", "Show.S01E01.English.forced.srt", "Show.S01E02.en.srt", "Show.S01E01.nfo"},
+			expected: []Subtitle{
+				{Path: "Show.S01E01.English.forced.srt", Language: "en", Label: "English.forced", Format: "srt"},
+				{Path: "Show.S01E01.en.srt", Language: "en", Label: "en", Format: "srt"},
+			},
+		},
+		{
+			name:  "per-episode folder",
+			files: []string{"Subs/Show.S01E01/2_English.srt", "Subs/Show.S01E01/3_Russian.ass", "Subs/Show.S01E02/2_English.srt"},
+			expected: []Subtitle{
+				{Path: "Subs/Show.S01E01/2_English.srt", Language: "en", Label: "2_English", Format: "srt"},
+				{Path: "Subs/Show.S01E01/3_Russian.ass", Language: "ru", Label: "3_Russian", Format: "ass"},
+			},
+		},
+		{
+			name:  "flat folder",
+			files: []string{"subs/Show.S01E01.rus.srt", "subs/Show.S01E02.rus.srt", "subs/readme.txt"},
+			expected: []Subtitle{
+				{Path: "subs/Show.S01E01.rus.srt", Language: "ru", Label: "rus", Format: "srt"},
+			},
+		},
+		{
+			name:  "unknown language",
+			files: []string{"Show.S01E01.vtt", "Subtitles/Show.S01E01.Commentary.vtt"},
+			expected: []Subtitle{
+				{Path: "Show.S01E01.vtt", Language: "", Label: "Subtitles", Format: "vtt"},
+				{Path: "Subtitles/Show.S01E01.Commentary.vtt", Language: "", Label: "Commentary", Format: "vtt"},
+			},
+		},
+		{
+			name:     "no subtitles",
+			files:    []string{"Show.S01E02.en.srt", "Sample/Show.S01E01.srt"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			video := filepath.Join(dir, "Show.S01E01.mkv")
+			for _, name := range append(tt.files, "Show.S01E01.mkv") {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, nil, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var expected []Subtitle
+			for _, sub := range tt.expected {
+				sub.Path = filepath.Join(dir, sub.Path)
+				expected = append(expected, sub)
+			}
+
+			got := FindSubtitles(video)
+			if !reflect.DeepEqual(got, expected) {
+				t.Errorf("FindSubtitles() = %+v, expected %+v", got, expected)
+			}
+		})
+	}
+}
+
+func TestNewSubtitleLanguage(t *testing.T) {
+	tests := []struct {
+		path          string
+		name          string
+		expectedLang  string
+		expectedLabel string
+	}{
+		{"a.srt", "en", "en", "en"},
+		{"a.srt", "English", "en", "English"},
+		{"a.srt", "English.forced", "en", "English.forced"},
+		{"a.srt", "forced.eng", "en", "forced.eng"},
+		{"a.ass", "2_Russian", "ru", "2_Russian"},
+		{"a.srt", "rus", "ru", "rus"},
+		{"a.srt", "ukr", "uk", "ukr"},
+		{"a.srt", "Italian SDH", "it", "Italian SDH"},
+		{"a.srt", "pt-BR", "pt", "pt-BR"},
+		{"a.srt", "jpn-chi", "ja", "jpn-chi"}, // First known token wins
+		{"a.srt", "Signs", "", "Signs"},
+		{"a.srt", "", "", "Subtitles"},
+	}
+
+	for _, tt := range tests {
+		got := newSubtitle(tt.path, tt.name)
+		if got.Language != tt.expectedLang {
+			t.Errorf("newSubtitle(%q).Language = %q, expected %q", tt.name, got.Language, tt.expectedLang)
+		}
+		if got.Label != tt.expectedLabel {
+			t.Errorf("newSubtitle(%q).Label = %q, expected %q", tt.name, got.Label, tt.expectedLabel)
+		}
+	}
+}
+
+func TestNewSubtitleFormat(t *testing.T) {
+	tests := map[string]string{
+		"a.srt": "srt",
+		"a.SRT": "srt",
+		"a.vtt": "vtt",
+		"a.Ass": "ass",
+		"a.ssa": "ssa",
+	}
+	for path, expected := range tests {
+		if got := newSubtitle(path, "en").Format; got != expected {
+			t.Errorf("newSubtitle(%q).Format = %q, expected %q", path, got, expected)
+		}
+	}
+}
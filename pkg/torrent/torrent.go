@@ -21,6 +21,12 @@ type Downloader struct {
 
 // NewDownloader creates a new torrent downloader
 func NewDownloader(downloadDir string) (*Downloader, error) {
+	return NewDownloaderWithPort(downloadDir, 0)
+}
+
+// NewDownloaderWithPort creates a new torrent downloader listening for peers on the given port
+// A port of 0 keeps the library default; processes sharing a host need distinct ports
+func NewDownloaderWithPort(downloadDir string, listenPort int) (*Downloader, error) {
 	if err := os.MkdirAll(downloadDir, 0755); err != nil {
 		return nil, fmt.Errorf("failed to create download directory: %w", err)
 	}
@@ -32,6 +38,9 @@ func NewDownloader(downloadDir string) (*Downloader, error) {
 	cfg.NoUpload = true
 	cfg.DisableAggressiveUpload = true
 	cfg.Seed = false
+	if listenPort > 0 {
+		cfg.ListenPort = listenPort
+	}
 
 	client, err := torrent.NewClient(cfg)
 	if err != nil {
@@ -84,6 +93,15 @@ func (d *Downloader) StopTorrent(torrentURL string) error {
 	return nil
 }
 
+// RemoveData deletes the files of a torrent named name from the download directory
+func (d *Downloader) RemoveData(name string) error {
+	path := filepath.Join(d.downloadDir, name)
+	if name == "" || filepath.Dir(path) != filepath.Clean(d.downloadDir) {
+		return fmt.Errorf("refusing to delete %q outside the download directory", name)
+	}
+	return os.RemoveAll(path)
+}
+
 // GetOrAddTorrent gets an existing torrent or adds a new one
 func (d *Downloader) GetOrAddTorrent(torrentURL string) (*torrent.Torrent, error) {
 	// Check if torrent already exists (for magnet links, check by info hash)
@@ -126,51 +144,16 @@ func (d *Downloader) DownloadSingleFile(t *torrent.Torrent, filePath, torrentNam
 	}
 
 	if targetFile == nil {
-		return "", fmt.Errorf("file not found in torrent: %s", filePath)
+		return "", fmt.Errorf("%w: %s", ErrFileNotFound, filePath)
 	}
 
 	fileSizeGB := float64(targetFile.Length()) / (1024 * 1024 * 1024)
 	fmt.Printf("Downloading file %d/%d: %s (%.2f GB)\n", fileIndex+1, totalFiles, filePath, fileSizeGB)
 
-	// Download this file
+	// Download this file and wait for it to complete
 	targetFile.Download()
-
-	// Wait for this file to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 24*time.Hour)
-	ticker := time.NewTicker(30 * time.Second)
-	var lastProgress int64
-	var lastPercent float64
-
-	fileDownloaded := false
-	for {
-		select {
-		case <-ctx.Done():
-			cancel()
-			ticker.Stop()
-			return "", fmt.Errorf("download timeout for file %s", filePath)
-		case <-ticker.C:
-			progress := targetFile.BytesCompleted()
-			fileSize := targetFile.Length()
-
-			if fileSize > 0 && progress >= fileSize {
-				fileDownloaded = true
-				cancel()
-				ticker.Stop()
-				break
-			}
-
-			if fileSize > 0 {
-				percent := float64(progress) / float64(fileSize) * 100
-				if percent-lastPercent >= 5.0 || (progress != lastProgress && progress-lastProgress > 10*1024*1024) {
-					fmt.Printf("  Progress: %.1f%% (%d/%d bytes)\n", percent, progress, fileSize)
-					lastPercent = percent
-					lastProgress = progress
-				}
-			}
-		}
-		if fileDownloaded {
-			break
-		}
+	if err := waitForFile(t, targetFile, torrentName, fileIndex, totalFiles, &stdoutReporter{}); err != nil {
+		return "", err
 	}
 
 	// Find the downloaded file path
@@ -202,10 +185,49 @@ func (d *Downloader) DownloadSingleFile(t *torrent.Torrent, filePath, torrentNam
 	return downloadedPath, nil
 }
 
-// DownloadTorrent downloads a torrent file or magnet link
+// DownloadTorrent downloads a torrent file or magnet link, printing progress to stdout
 // Returns the paths to the downloaded files and the torrent name
 // Note: Caller should call StopTorrent after upload to prevent seeding
 func (d *Downloader) DownloadTorrent(torrentURL string) ([]string, string, error) {
+	return d.DownloadTorrentWithReporter(torrentURL, nil)
+}
+
+// DownloadTorrentWithReporter is DownloadTorrent with progress and skipped files sent to reporter
+// A nil reporter prints to stdout
+func (d *Downloader) DownloadTorrentWithReporter(torrentURL string, reporter Reporter) ([]string, string, error) {
+	download, err := d.DownloadTorrentFiles(torrentURL, nil, reporter)
+	if err != nil {
+		return nil, "", err
+	}
+	filePaths := make([]string, 0, len(download.Files))
+	for _, file := range download.Files {
+		filePaths = append(filePaths, file.LocalPath)
+	}
+	return filePaths, download.Name, nil
+}
+
+// DownloadedFile is a file of a torrent downloaded to local disk
+type DownloadedFile struct {
+	Path      string // Path of the file within the torrent, as stored in the database
+	LocalPath string
+}
+
+// Download is the result of DownloadTorrentFiles
+type Download struct {
+	Name  string // Torrent name
+	Files []DownloadedFile
+}
+
+// DownloadTorrentFiles downloads the video files of a torrent like DownloadTorrentWithReporter,
+// returning their paths within the torrent along with the local ones. fileIndexes selects files
+// by their index in the torrent; nil or empty downloads all of them. Subtitle files are always
+// downloaded, since they are matched to the videos afterwards
+// Note: Caller should delete the files with RemoveData after upload
+func (d *Downloader) DownloadTorrentFiles(torrentURL string, fileIndexes []int, reporter Reporter) (*Download, error) {
+	if reporter == nil {
+		reporter = &stdoutReporter{}
+	}
+
 	var t *torrent.Torrent
 	var err error
 
@@ -213,13 +235,13 @@ func (d *Downloader) DownloadTorrent(torrentURL string) ([]string, string, error
 	if strings.HasPrefix(torrentURL, "magnet:") {
 		t, err = d.client.AddMagnet(torrentURL)
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to add magnet link: %w", err)
+			return nil, fmt.Errorf("failed to add magnet link: %w", err)
 		}
 	} else {
 		// Assume it's a .torrent file path
 		t, err = d.client.AddTorrentFromFile(torrentURL)
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to add torrent file: %w", err)
+			return nil, fmt.Errorf("failed to add torrent file: %w", err)
 		}
 	}
 
@@ -238,6 +260,7 @@ func (d *Downloader) DownloadTorrent(torrentURL string) ([]string, string, error
 	maxFileSize := int64(2 * 1024 * 1024 * 1024)
 	files := t.Files()
 	var filesToDownload []*torrent.File
+	var subtitleFiles []*torrent.File
 	var skippedCount int
 
 	// Video file extensions
@@ -247,19 +270,38 @@ func (d *Downloader) DownloadTorrent(torrentURL string) ([]string, string, error
 		".3gp": true, ".ogv": true, ".ts": true, ".m2ts": true, ".mts": true,
 	}
 
-	for _, file := range files {
+	selected := make(map[int]bool, len(fileIndexes))
+	for _, index := range fileIndexes {
+		selected[index] = true
+	}
+
+	for i, file := range files {
 		filePath := file.Path()
 		ext := strings.ToLower(filepath.Ext(filePath))
 
+		// Keep subtitle files so they can be associated with their episodes (see FindSubtitles)
+		// They are tiny, so they are fetched alongside the first video
+		if IsSubtitleFile(filePath) {
+			subtitleFiles = append(subtitleFiles, file)
+			file.Download()
+			continue
+		}
+
+		// Skip files the user did not pick
+		if len(selected) > 0 && !selected[i] {
+			skippedCount++
+			continue
+		}
+
 		// Skip non-video files
 		if !videoExts[ext] {
-			fmt.Printf("Skipping non-video file: %s\n", filePath)
+			reporter.Skipped(filePath, "not a video file")
 			skippedCount++
 			continue
 		}
 
 		if file.Length() > maxFileSize {
-			fmt.Printf("Skipping file %s (%.2f GB > 2GB)\n", filePath, float64(file.Length())/(1024*1024*1024))
+			reporter.Skipped(filePath, fmt.Sprintf("%.2f GB > 2GB", float64(file.Length())/(1024*1024*1024)))
 			skippedCount++
 		} else {
 			filesToDownload = append(filesToDownload, file)
@@ -267,58 +309,21 @@ func (d *Downloader) DownloadTorrent(torrentURL string) ([]string, string, error
 	}
 
 	if len(filesToDownload) == 0 {
-		return nil, "", fmt.Errorf("no video files to download (all files exceed 2GB limit or are not video files)")
+		return nil, fmt.Errorf("no video files to download (all files exceed 2GB limit or are not video files)")
 	}
 
-	fmt.Printf("Downloading %d files one by one (skipping %d files > 2GB)\n", len(filesToDownload), skippedCount)
+	fmt.Printf("Downloading %d files one by one (skipping %d files)\n", len(filesToDownload), skippedCount)
 
 	// Download files one by one sequentially
-	var filePaths []string
+	download := &Download{Name: torrentName}
 	for i, file := range filesToDownload {
 		fileSizeGB := float64(file.Length()) / (1024 * 1024 * 1024)
 		fmt.Printf("Downloading file %d/%d: %s (%.2f GB)\n", i+1, len(filesToDownload), file.Path(), fileSizeGB)
 
-		// Download this file
+		// Download this file and wait for it to complete
 		file.Download()
-
-		// Wait for this file to complete
-		ctx, cancel := context.WithTimeout(context.Background(), 24*time.Hour)
-		ticker := time.NewTicker(30 * time.Second) // Check every 30 seconds
-		var lastProgress int64
-		var lastPercent float64
-
-		fileDownloaded := false
-		for {
-			select {
-			case <-ctx.Done():
-				cancel()
-				ticker.Stop()
-				return nil, "", fmt.Errorf("download timeout for file %s", file.Path())
-			case <-ticker.C:
-				progress := file.BytesCompleted()
-				fileSize := file.Length()
-
-				if fileSize > 0 && progress >= fileSize {
-					// File download complete
-					fileDownloaded = true
-					cancel()
-					ticker.Stop()
-					break
-				}
-
-				// Only log if progress changed by at least 5% or significant bytes
-				if fileSize > 0 {
-					percent := float64(progress) / float64(fileSize) * 100
-					if percent-lastPercent >= 5.0 || (progress != lastProgress && progress-lastProgress > 10*1024*1024) {
-						fmt.Printf("  Progress: %.1f%% (%d/%d bytes)\n", percent, progress, fileSize)
-						lastPercent = percent
-						lastProgress = progress
-					}
-				}
-			}
-			if fileDownloaded {
-				break
-			}
+		if err := waitForFile(t, file, torrentName, i, len(filesToDownload), reporter); err != nil {
+			return nil, err
 		}
 
 		// Verify file exists and add to list
@@ -347,21 +352,93 @@ func (d *Downloader) DownloadTorrent(torrentURL string) ([]string, string, error
 		}
 
 		if found {
-			filePaths = append(filePaths, filePath)
+			download.Files = append(download.Files, DownloadedFile{Path: file.Path(), LocalPath: filePath})
 			fmt.Printf("  Completed: %s\n", file.Path())
 		} else {
 			fmt.Printf("  Warning: File not found after download: %s (tried: %s)\n", file.Path(), filePath)
+			reporter.Skipped(file.Path(), "not found after download")
 		}
 	}
 
-	if len(filePaths) == 0 {
-		return nil, "", fmt.Errorf("no files found after download completion")
+	if len(download.Files) == 0 {
+		return nil, fmt.Errorf("no files found after download completion")
+	}
+
+	if len(subtitleFiles) > 0 {
+		fmt.Printf("Waiting for %d subtitle files\n", len(subtitleFiles))
+		waitForFiles(subtitleFiles, 5*time.Minute)
 	}
 
 	// Stop the torrent to prevent seeding (will be removed after upload)
 	t.Drop()
 
-	return filePaths, torrentName, nil
+	return download, nil
+}
+
+// waitForFile waits up to 24 hours for a file to download, reporting progress every progressInterval
+func waitForFile(t *torrent.Torrent, file *torrent.File, torrentName string, index, count int, reporter Reporter) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 24*time.Hour)
+	defer cancel()
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+	lastBytes, lastTime := file.BytesCompleted(), time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("download timeout for file %s", file.Path())
+		case now := <-ticker.C:
+			progress := file.BytesCompleted()
+			fileSize := file.Length()
+			if fileSize > 0 && progress >= fileSize {
+				return nil
+			}
+			if fileSize <= 0 {
+				continue
+			}
+
+			var speed float64
+			if elapsed := now.Sub(lastTime).Seconds(); elapsed > 0 {
+				speed = float64(progress-lastBytes) / elapsed
+			}
+			var eta time.Duration
+			if speed > 0 {
+				eta = time.Duration(float64(fileSize-progress) / speed * float64(time.Second))
+			}
+			lastBytes, lastTime = progress, now
+
+			reporter.Progress(Progress{
+				Torrent:       torrentName,
+				File:          file.Path(),
+				FileIndex:     index,
+				FileCount:     count,
+				FileCompleted: progress,
+				FileSize:      fileSize,
+				Speed:         speed,
+				ETA:           eta,
+				Peers:         t.Stats().ActivePeers,
+			})
+		}
+	}
+}
+
+// waitForFiles waits until all files are fully downloaded or the timeout expires
+func waitForFiles(files []*torrent.File, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		complete := true
+		for _, file := range files {
+			if file.BytesCompleted() < file.Length() {
+				complete = false
+				break
+			}
+		}
+		if complete {
+			return
+		}
+		time.Sleep(time.Second)
+	}
+	fmt.Printf("  Warning: Timed out waiting for %d files\n", len(files))
 }
 
 // FileInfo represents information about a file in a torrent
@@ -0,0 +1,330 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+// sessionTTL is how long a login session stays valid
+const sessionTTL = 7 * 24 * time.Hour
+
+// contextKey is the type of request context keys set by this package
+type contextKey string
+
+// Request context keys
+const (
+	userContextKey  contextKey = "user"  // Authenticated *database.User
+	tokenContextKey contextKey = "token" // *database.APIToken when authenticated with a bearer token
+)
+
+// authStore looks up the users of sessions and API tokens; *database.DB implements it
+type authStore interface {
+	GetSessionUser(sessionID string) (*database.User, error)
+	GetAPITokenUser(secret string) (*database.User, *database.APIToken, error)
+}
+
+// requireAuth wraps a handler to require authentication by session cookie or
+// "Authorization: Bearer <token>" header
+// The user (and token) are stored in the request context for currentUser and checkAdmin
+func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var user *database.User
+		var token *database.APIToken
+		var err error
+		if secret, ok := bearerToken(r); ok {
+			user, token, err = s.auth.GetAPITokenUser(secret)
+			if err == nil && user == nil {
+				writeAuthError(w, r, http.StatusUnauthorized, "Invalid API token")
+				return
+			}
+		} else {
+			user, err = s.sessionUser(r)
+			// Browsers send cookies automatically, so cookie-authenticated changes need a CSRF token
+			if err == nil && user != nil {
+				sessionID := s.getSessionID(r)
+				if !checkCSRF(r, sessionID) {
+					writeAuthError(w, r, http.StatusForbidden, "Invalid or missing CSRF token")
+					return
+				}
+				s.ensureCSRFCookie(w, r, sessionID)
+			}
+		}
+		if err != nil {
+			log.Printf("Error checking authentication: %v", err)
+			http.Error(w, "Failed to check authentication", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			writeAuthError(w, r, http.StatusUnauthorized, "Authentication required")
+			return
+		}
+		if token != nil && token.Scope == database.TokenScopeRead && r.Method != "GET" && r.Method != "HEAD" {
+			writeAuthError(w, r, http.StatusForbidden, "API token is read-only")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userContextKey, user)
+		if token != nil {
+			ctx = context.WithValue(ctx, tokenContextKey, token)
+		}
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// bearerToken returns the token of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// writeAuthError rejects a request: API routes get a JSON error, pages redirect to the login form
+func writeAuthError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		if status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="trtg"`)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]interface{}{"error": message})
+		return
+	}
+	if status == http.StatusUnauthorized {
+		http.Redirect(w, r, "/login?redirect="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+		return
+	}
+	http.Error(w, message, status)
+}
+
+// requireAdmin wraps a handler to require an authenticated admin
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return s.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+		if !s.checkAdmin(w, r) {
+			return
+		}
+		next(w, r)
+	})
+}
+
+// checkAdmin writes a 403 response and returns false unless the request's user is an admin
+// Requests authenticated with a token additionally need an admin-scoped token
+func (s *Server) checkAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if s.isAdmin(r) {
+		return true
+	}
+	writeAuthError(w, r, http.StatusForbidden, "Admin role required")
+	return false
+}
+
+// isAdmin reports whether a request may perform admin actions
+func (s *Server) isAdmin(r *http.Request) bool {
+	if token := s.currentToken(r); token != nil && token.Scope != database.TokenScopeAdmin {
+		return false
+	}
+	return s.currentUser(r).IsAdmin()
+}
+
+// currentToken returns the API token a request was authenticated with, or nil for session logins
+func (s *Server) currentToken(r *http.Request) *database.APIToken {
+	token, _ := r.Context().Value(tokenContextKey).(*database.APIToken)
+	return token
+}
+
+// currentUser returns the authenticated user of a request wrapped by requireAuth
+func (s *Server) currentUser(r *http.Request) *database.User {
+	user, _ := r.Context().Value(userContextKey).(*database.User)
+	return user
+}
+
+// sessionUser looks up the user of the request's session cookie
+// Returns nil without an error if there is no valid session
+func (s *Server) sessionUser(r *http.Request) (*database.User, error) {
+	sessionID := s.getSessionID(r)
+	if sessionID == "" {
+		return nil, nil
+	}
+	return s.auth.GetSessionUser(sessionID)
+}
+
+// handleAPIMe returns the current user
+func (s *Server) handleAPIMe(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.currentUser(r))
+}
+
+// handleLogin handles login requests
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "GET" {
+		// Show login page
+		tmpl := `<!DOCTYPE html>
+<html>
+<head>
+	<title>Login</title>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<style>
+		* { margin: 0; padding: 0; box-sizing: border-box; }
+		body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #1a1a1a; color: #fff; display: flex; align-items: center; justify-content: center; min-height: 100vh; }
+		.login-container { background: #2a2a2a; border-radius: 8px; padding: 40px; width: 100%; max-width: 400px; }
+		h1 { margin-bottom: 30px; text-align: center; }
+		.form-group { margin-bottom: 20px; }
+		label { display: block; margin-bottom: 8px; }
+		input { width: 100%; padding: 12px; border: 1px solid #444; border-radius: 4px; background: #1a1a1a; color: #fff; font-size: 16px; }
+		input:focus { outline: none; border-color: #4a9eff; }
+		button { width: 100%; padding: 12px; background: #4a9eff; color: white; border: none; border-radius: 4px; cursor: pointer; font-size: 16px; }
+		button:hover { background: #5aaeff; }
+		.error { color: #dc3545; margin-top: 10px; text-align: center; }
+		.telegram-login { margin-top: 20px; text-align: center; }
+		.separator { color: #aaa; margin-bottom: 15px; }
+	</style>
+</head>
+<body>
+	<div class="login-container">
+		<h1>Login</h1>
+		<form method="POST" action="/login">
+			<input type="hidden" name="redirect" value="{{.Redirect}}">
+			<div class="form-group">
+				<label for="username">Username</label>
+				<input type="text" id="username" name="username" required autofocus>
+			</div>
+			<div class="form-group">
+				<label for="password">Password</label>
+				<input type="password" id="password" name="password" required>
+			</div>
+			<button type="submit">Login</button>
+			{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
+		</form>
+		{{if .TelegramBot}}
+		<div class="telegram-login">
+			<div class="separator">or</div>
+			<script async src="https://telegram.org/js/telegram-widget.js?22" data-telegram-login="{{.TelegramBot}}" data-size="large" data-auth-url="{{.TelegramAuthURL}}"></script>
+		</div>
+		{{end}}
+	</div>
+</body>
+</html>`
+
+		// Only known messages are shown so the page cannot be made to display arbitrary text
+		var errorMessage string
+		switch r.URL.Query().Get("error") {
+		case "invalid":
+			errorMessage = "Invalid username or password"
+		case "locked":
+			errorMessage = "Too many failed logins, try again later"
+		case "telegram":
+			errorMessage = "Telegram login failed"
+		case "notmember":
+			errorMessage = "Your Telegram account is not a member of the chat"
+		}
+
+		t, _ := template.New("login").Parse(tmpl)
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		redirect := safeRedirect(r.URL.Query().Get("redirect"))
+		data := map[string]string{
+			"Redirect": redirect,
+			"Error":    errorMessage,
+		}
+		if s.telegramLogin && s.downloader != nil {
+			data["TelegramBot"] = s.downloader.BotUsername()
+			scheme := "http"
+			if s.isSecureRequest(r) {
+				scheme = "https"
+			}
+			data["TelegramAuthURL"] = scheme + "://" + r.Host + "/login/telegram?redirect=" + url.QueryEscape(redirect)
+		}
+		t.Execute(w, data)
+		return
+	}
+
+	// Handle POST login
+	if r.Method == "POST" {
+		username := r.FormValue("username")
+		password := r.FormValue("password")
+		redirect := safeRedirect(r.FormValue("redirect"))
+		ip := s.clientIP(r)
+
+		if allowed, wait := s.logins.Allowed(ip); !allowed {
+			log.Printf("Refusing login of %s from locked out address %s", username, ip)
+			w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())+1))
+			http.Redirect(w, r, "/login?redirect="+url.QueryEscape(redirect)+"&error=locked", http.StatusFound)
+			return
+		}
+
+		user, err := s.db.AuthenticateUser(username, password)
+		if err != nil {
+			log.Printf("Error authenticating %s: %v", username, err)
+			http.Error(w, "Login failed", http.StatusInternalServerError)
+			return
+		}
+		if user != nil {
+			s.logins.Success(ip)
+			s.startSession(w, r, user, redirect)
+			return
+		}
+
+		// Invalid credentials
+		s.logins.Failure(ip)
+		log.Printf("Failed login of %s from %s", username, ip)
+		http.Redirect(w, r, "/login?redirect="+url.QueryEscape(redirect)+"&error=invalid", http.StatusFound)
+		return
+	}
+
+	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+}
+
+// startSession logs a user in by creating a session and redirects to target
+func (s *Server) startSession(w http.ResponseWriter, r *http.Request, user *database.User, target string) {
+	sessionID, err := s.db.CreateSession(user.ID, sessionTTL)
+	if err != nil {
+		log.Printf("Error creating session for %s: %v", user.Username, err)
+		http.Error(w, "Login failed", http.StatusInternalServerError)
+		return
+	}
+	s.setCookie(w, r, "session", sessionID, int(sessionTTL.Seconds()), true)
+	s.setCookie(w, r, csrfCookieName, csrfToken(sessionID), int(sessionTTL.Seconds()), false)
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// handleLogout handles logout requests
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if sessionID := s.getSessionID(r); sessionID != "" {
+		if err := s.db.DeleteSession(sessionID); err != nil {
+			log.Printf("Warning: Failed to delete session: %v", err)
+		}
+	}
+	s.setCookie(w, r, "session", "", -1, true)
+	s.setCookie(w, r, csrfCookieName, "", -1, false)
+	http.Redirect(w, r, "/login", http.StatusFound)
+}
+
+// getSessionID retrieves session ID from cookie
+func (s *Server) getSessionID(r *http.Request) string {
+	cookie, err := r.Cookie("session")
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// cleanupSessions removes expired sessions and stale login throttling entries periodically
+func (s *Server) cleanupSessions() {
+	ticker := time.NewTicker(1 * time.Hour)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := s.db.DeleteExpiredSessions(); err != nil {
+			log.Printf("Warning: Failed to clean up sessions: %v", err)
+		}
+		s.logins.Cleanup()
+	}
+}
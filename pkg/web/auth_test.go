@@ -0,0 +1,107 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+// fakeAuthStore authenticates fixed API tokens
+type fakeAuthStore struct {
+	tokens map[string]*database.APIToken
+	users  map[int64]*database.User
+}
+
+func (f *fakeAuthStore) GetSessionUser(sessionID string) (*database.User, error) {
+	return nil, nil
+}
+
+func (f *fakeAuthStore) GetAPITokenUser(secret string) (*database.User, *database.APIToken, error) {
+	token, ok := f.tokens[secret]
+	if !ok {
+		return nil, nil, nil
+	}
+	return f.users[token.UserID], token, nil
+}
+
+func newTokenTestServer() *Server {
+	admin := &database.User{ID: 1, Username: "admin", Role: database.RoleAdmin}
+	return &Server{auth: &fakeAuthStore{
+		tokens: map[string]*database.APIToken{
+			"read-secret":  {ID: 1, UserID: admin.ID, Name: "read", Scope: database.TokenScopeRead},
+			"admin-secret": {ID: 2, UserID: admin.ID, Name: "admin", Scope: database.TokenScopeAdmin},
+		},
+		users: map[int64]*database.User{admin.ID: admin},
+	}}
+}
+
+func TestRequireAuthTokenScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		token    string
+		expected int
+	}{
+		{"read token GET", "GET", "read-secret", http.StatusOK},
+		{"read token HEAD", "HEAD", "read-secret", http.StatusOK},
+		{"read token POST", "POST", "read-secret", http.StatusForbidden},
+		{"read token PUT", "PUT", "read-secret", http.StatusForbidden},
+		{"read token DELETE", "DELETE", "read-secret", http.StatusForbidden},
+		{"admin token GET", "GET", "admin-secret", http.StatusOK},
+		{"admin token POST", "POST", "admin-secret", http.StatusOK},
+		{"admin token DELETE", "DELETE", "admin-secret", http.StatusOK},
+		{"unknown token", "GET", "wrong-secret", http.StatusUnauthorized},
+		{"no credentials", "GET", "", http.StatusUnauthorized},
+	}
+
+	s := newTokenTestServer()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			handler := s.requireAuth(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+				if s.currentUser(r) == nil || s.currentToken(r) == nil {
+					t.Error("user and token not set on the request context")
+				}
+			})
+
+			req := httptest.NewRequest(tt.method, "/api/progress/1", nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			rec := httptest.NewRecorder()
+			handler(rec, req)
+
+			if rec.Code != tt.expected {
+				t.Errorf("%s with %q: status %d, expected %d", tt.method, tt.token, rec.Code, tt.expected)
+			}
+			if called != (tt.expected == http.StatusOK) {
+				t.Errorf("%s with %q: handler called = %v", tt.method, tt.token, called)
+			}
+		})
+	}
+}
+
+func TestRequireAdminTokenScope(t *testing.T) {
+	tests := []struct {
+		token    string
+		expected int
+	}{
+		{"read-secret", http.StatusForbidden}, // The owner is an admin, but the token is read-only
+		{"admin-secret", http.StatusOK},
+	}
+
+	s := newTokenTestServer()
+	handler := s.requireAdmin(func(w http.ResponseWriter, r *http.Request) {})
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		req.Header.Set("Authorization", "Bearer "+tt.token)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		if rec.Code != tt.expected {
+			t.Errorf("GET /api/users with %q: status %d, expected %d", tt.token, rec.Code, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+// maxBulkEdit limits how many episodes one bulk edit changes
+const maxBulkEdit = 500
+
+// episodeEdit is the body of episode edits; omitted fields are left alone
+type episodeEdit struct {
+	ShowName      *string `json:"showName"`
+	SeasonNumber  *int    `json:"seasonNumber"`
+	EpisodeNumber *int    `json:"episodeNumber"`
+	Title         *string `json:"title"`
+}
+
+// validate trims the show name and title and checks the numbers
+func (e *episodeEdit) validate() error {
+	if e.ShowName != nil {
+		name := strings.TrimSpace(*e.ShowName)
+		if name == "" {
+			return fmt.Errorf("showName must not be empty")
+		}
+		e.ShowName = &name
+	}
+	if e.Title != nil {
+		title := strings.TrimSpace(*e.Title)
+		e.Title = &title
+	}
+	if (e.SeasonNumber != nil && *e.SeasonNumber < 0) || (e.EpisodeNumber != nil && *e.EpisodeNumber < 0) {
+		return fmt.Errorf("seasonNumber and episodeNumber must not be negative")
+	}
+	return nil
+}
+
+// override turns the edit of a video into an override, which locks it against reparse
+func (e episodeEdit) override(videoID int64, username string) database.VideoOverride {
+	return database.VideoOverride{
+		VideoID:       videoID,
+		ShowName:      e.ShowName,
+		SeasonNumber:  e.SeasonNumber,
+		EpisodeNumber: e.EpisodeNumber,
+		Title:         e.Title,
+		UpdatedBy:     username,
+	}
+}
+
+// handleAPIEpisode edits (PATCH) or deletes (DELETE) an episode
+func (s *Server) handleAPIEpisode(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "PATCH" {
+		s.handleAPIEditEpisode(w, r)
+		return
+	}
+	s.handleAPIDeleteEpisode(w, r)
+}
+
+// handleAPIEditEpisode changes the show, season, episode number or title of an episode
+// PATCH body: {"showName": "...", "seasonNumber": 1, "episodeNumber": 2, "title": "..."}
+func (s *Server) handleAPIEditEpisode(w http.ResponseWriter, r *http.Request) {
+	videoID := parseVideoID(strings.TrimPrefix(r.URL.Path, "/api/episode/"))
+	if videoID == 0 {
+		http.Error(w, "Video ID required", http.StatusBadRequest)
+		return
+	}
+
+	var edit episodeEdit
+	if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if err := edit.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user := s.currentUser(r)
+	if err := s.db.SaveVideoOverride(edit.override(videoID, user.Username)); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to edit episode: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("User %s edited video %d", user.Username, videoID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
+
+// handleAPIBulkEditEpisodes edits several episodes at once
+// POST body: {"ids": [1, 2], "showName": "...", "seasonNumber": 3, "renumberFrom": 1}
+// renumberFrom numbers the episodes sequentially in the order of their file names; multi-episode
+// files keep their numbers
+func (s *Server) handleAPIBulkEditEpisodes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body struct {
+		IDs          []int64 `json:"ids"`
+		ShowName     *string `json:"showName"`
+		SeasonNumber *int    `json:"seasonNumber"`
+		RenumberFrom *int    `json:"renumberFrom"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if len(body.IDs) == 0 || len(body.IDs) > maxBulkEdit {
+		http.Error(w, fmt.Sprintf("Between 1 and %d ids required", maxBulkEdit), http.StatusBadRequest)
+		return
+	}
+	if body.ShowName == nil && body.SeasonNumber == nil && body.RenumberFrom == nil {
+		http.Error(w, "Nothing to change", http.StatusBadRequest)
+		return
+	}
+	edit := episodeEdit{ShowName: body.ShowName, SeasonNumber: body.SeasonNumber, EpisodeNumber: body.RenumberFrom}
+	if err := edit.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	videos := make([]*database.Video, 0, len(body.IDs))
+	for _, id := range body.IDs {
+		video, err := s.db.GetVideoByID(id)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Video %d not found", id), http.StatusNotFound)
+			return
+		}
+		videos = append(videos, video)
+	}
+	var episodes map[int64]int
+	if body.RenumberFrom != nil {
+		episodes = renumberEpisodes(videos, *body.RenumberFrom)
+	}
+
+	user := s.currentUser(r)
+	overrides := make([]database.VideoOverride, 0, len(videos))
+	for _, video := range videos {
+		o := edit.override(video.ID, user.Username)
+		o.EpisodeNumber = nil // renumberFrom is only where numbering starts
+		if episode, ok := episodes[video.ID]; ok {
+			o.EpisodeNumber = &episode
+		}
+		overrides = append(overrides, o)
+	}
+	if err := s.db.SaveVideoOverrides(overrides); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to edit episodes: %v", err), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("User %s edited %d videos", user.Username, len(overrides))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success":        true,
+		"episodesEdited": len(overrides),
+	})
+}
+
+// renumberEpisodes numbers videos sequentially from first in the order of their file names.
+// Multi-episode files keep their range and are left out: an override sets a single episode
+func renumberEpisodes(videos []*database.Video, first int) map[int64]int {
+	sorted := make([]*database.Video, 0, len(videos))
+	for _, video := range videos {
+		if video.EpisodeEnd <= video.EpisodeNumber {
+			sorted = append(sorted, video)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return naturalLess(filepath.Base(sorted[i].FilePath), filepath.Base(sorted[j].FilePath))
+	})
+
+	episodes := make(map[int64]int, len(sorted))
+	for i, video := range sorted {
+		episodes[video.ID] = first + i
+	}
+	return episodes
+}
+
+// naturalLess compares file names case-insensitively with runs of digits compared as numbers,
+// so "Episode 2" sorts before "Episode 10"
+func naturalLess(a, b string) bool {
+	a, b = strings.ToLower(a), strings.ToLower(b)
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, restA := splitDigits(a)
+			nb, restB := splitDigits(b)
+			if na != nb {
+				// Compare by length first: both runs have their leading zeros trimmed
+				if len(na) != len(nb) {
+					return len(na) < len(nb)
+				}
+				return na < nb
+			}
+			a, b = restA, restB
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// splitDigits splits the leading run of digits off s, without leading zeros
+func splitDigits(s string) (digits, rest string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	digits = strings.TrimLeft(s[:i], "0")
+	return digits, s[i:]
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
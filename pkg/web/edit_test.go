@@ -0,0 +1,87 @@
+package web
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{"Episode 2.mkv", "Episode 10.mkv", true},
+		{"Episode 10.mkv", "Episode 2.mkv", false},
+		{"Show.S01E09.mkv", "Show.S01E10.mkv", true},
+		{"Show.S02E01.mkv", "Show.S01E10.mkv", false},
+		{"episode 1.mkv", "Episode 2.mkv", true},
+		{"Episode 02.mkv", "Episode 3.mkv", true},
+		{"Episode 007.mkv", "Episode 7.mkv", false},
+		{"Episode 7.mkv", "Episode 007.mkv", false},
+		{"Episode 1", "Episode 1.mkv", true},
+		{"a.mkv", "a.mkv", false},
+		{"Part 99999999999999999999.mkv", "Part 100000000000000000000.mkv", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+" < "+tt.b, func(t *testing.T) {
+			if got := naturalLess(tt.a, tt.b); got != tt.expected {
+				t.Errorf("naturalLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRenumberEpisodes(t *testing.T) {
+	tests := []struct {
+		name     string
+		videos   []*database.Video
+		first    int
+		expected map[int64]int
+	}{
+		{
+			name: "file name order",
+			videos: []*database.Video{
+				{ID: 1, FilePath: "/downloads/Show/Episode 10.mkv"},
+				{ID: 2, FilePath: "/downloads/Show/Episode 2.mkv"},
+				{ID: 3, FilePath: "/downloads/Show/Episode 1.mkv"},
+			},
+			first:    1,
+			expected: map[int64]int{3: 1, 2: 2, 1: 3},
+		},
+		{
+			name: "directories are ignored",
+			videos: []*database.Video{
+				{ID: 1, FilePath: "/downloads/b/Episode 2.mkv"},
+				{ID: 2, FilePath: "/downloads/a/Episode 3.mkv"},
+			},
+			first:    5,
+			expected: map[int64]int{1: 5, 2: 6},
+		},
+		{
+			name: "multi-episode files are left out",
+			videos: []*database.Video{
+				{ID: 1, FilePath: "Show.S01E01-E02.mkv", EpisodeNumber: 1, EpisodeEnd: 2},
+				{ID: 2, FilePath: "Show.S01E03.mkv", EpisodeNumber: 3},
+				{ID: 3, FilePath: "Show.S01E04.mkv", EpisodeNumber: 4, EpisodeEnd: 4},
+			},
+			first:    3,
+			expected: map[int64]int{2: 3, 3: 4},
+		},
+		{
+			name:     "no videos",
+			first:    1,
+			expected: map[int64]int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renumberEpisodes(tt.videos, tt.first); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("renumberEpisodes() = %v, want %v", got, tt.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/events"
+	"github.com/rusik69/trtg/pkg/notify"
+)
+
+// sseHeartbeat is how often an idle event stream sends a comment to keep proxies from closing it
+const sseHeartbeat = 30 * time.Second
+
+// eventsScript opens the page's /api/events stream
+// Notifications the user subscribed to are shown as toasts, or as desktop notifications when the tab is hidden;
+// pages call onArchiveChange(filter, reload) to refresh when matching episodes change
+const eventsScript = `<script>
+		const archiveEvents = window.EventSource ? new EventSource('/api/events') : null;
+
+		// onArchiveChange calls reload, at most once per second, for changes accepted by filter and after reconnects
+		function onArchiveChange(filter, reload) {
+			if (!archiveEvents) return;
+			let timer = null;
+			const schedule = function() {
+				clearTimeout(timer);
+				timer = setTimeout(reload, 1000);
+			};
+			archiveEvents.addEventListener('change', function(msg) {
+				if (filter(JSON.parse(msg.data))) schedule();
+			});
+			archiveEvents.addEventListener('resync', schedule);
+		}
+
+		document.addEventListener('DOMContentLoaded', function() {
+			if (!archiveEvents) return;
+			archiveEvents.addEventListener('notification', function(msg) {
+				const event = JSON.parse(msg.data);
+				if (window.Notification && Notification.permission === 'granted' && document.hidden) {
+					new Notification('trtg', { body: event.text });
+					return;
+				}
+				const toast = document.createElement('div');
+				toast.textContent = event.text;
+				toast.style.cssText = 'position:fixed;right:20px;bottom:20px;z-index:1000;background:#2a2a2a;color:#fff;border-left:4px solid #4a9eff;padding:12px 16px;border-radius:4px;box-shadow:0 2px 8px rgba(0,0,0,.5);max-width:400px;';
+				if (event.show) {
+					toast.style.cursor = 'pointer';
+					toast.onclick = function() { window.location.href = '/show/' + encodeURIComponent(event.show); };
+				}
+				document.body.appendChild(toast);
+				setTimeout(function() { toast.remove(); }, 8000);
+			});
+		});
+	</script>`
+
+// SetEventBus enables live updates on /api/events from database changes published on bus
+func (s *Server) SetEventBus(bus *events.Bus) {
+	s.events = bus
+}
+
+// handleAPIEvents streams server-sent events to the web UI:
+// "change" for changed videos and torrent jobs, "resync" when changes may have been missed,
+// and "notification" for the current user's browser notifications
+func (s *Server) handleAPIEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusNotImplemented)
+		return
+	}
+
+	// A nil channel blocks forever, so a missing bus or notifier just sends nothing
+	var changes <-chan database.ChangeEvent
+	if s.events != nil {
+		ch, unsubscribe := s.events.Subscribe()
+		defer unsubscribe()
+		changes = ch
+	}
+	var notifications <-chan notify.Event
+	if s.notifier != nil {
+		ch, unsubscribe := s.notifier.Broker().Subscribe(s.currentUser(r).ID)
+		defer unsubscribe()
+		notifications = ch
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // Keep nginx from buffering the stream
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case c, ok := <-changes:
+			if !ok {
+				return
+			}
+			if c.Op == database.ChangeResync {
+				fmt.Fprint(w, "event: resync\ndata: {}\n\n")
+			} else {
+				writeEvent(w, "change", c)
+			}
+		case e, ok := <-notifications:
+			if !ok {
+				return
+			}
+			writeEvent(w, "notification", struct {
+				notify.Event
+				Text string `json:"text"`
+			}{e, e.Text()})
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes one server-sent event with a JSON payload
+func writeEvent(w http.ResponseWriter, name string, payload interface{}) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+}
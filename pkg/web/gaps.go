@@ -0,0 +1,44 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+// handleAPIShowGaps returns the missing episodes of each season of a show: against the episodes
+// the metadata provider lists as aired when it knows the show, otherwise up to the highest
+// episode present
+// GET /api/show/{showName}/gaps
+func (s *Server) handleAPIShowGaps(w http.ResponseWriter, r *http.Request, showName string) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	seasons, err := s.db.GetShowEpisodeNumbers(showName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var official map[int]int
+	if details := s.showMetadata(r, showName); details != nil {
+		official = details.LastAired(time.Now().Format("2006-01-02"))
+	}
+	gaps := database.FindGaps(seasons, official)
+
+	missing := 0
+	for _, season := range gaps {
+		missing += len(season.Missing)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"showName": showName,
+		"missing":  missing,
+		"seasons":  gaps,
+	})
+}
@@ -0,0 +1,411 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+// hlsSegmentSeconds is the target duration of each on-the-fly transcoded HLS segment
+const hlsSegmentSeconds = 6
+
+// hlsJSVersion is the hls.js release the player pages load from /static/
+// Dockerfile.web downloads the same release (HLS_JS_VERSION), so change both together
+const hlsJSVersion = "1.5.17"
+
+// hlsScript loads hls.js from the static directory rather than a CDN, so the pages run no
+// third-party code
+const hlsScript = `<script src="/static/hls-` + hlsJSVersion + `.min.js"></script>`
+
+// CacheDir returns the directory holding re-downloaded sources and transcoded segments
+func (s *Server) CacheDir() string {
+	return filepath.Join(s.downloadDir, "web-cache")
+}
+
+// hlsDir returns the segment cache directory for a video
+func (s *Server) hlsDir(videoID int64) string {
+	return filepath.Join(s.CacheDir(), fmt.Sprintf("hls-%d", videoID))
+}
+
+// keyedMutex serializes work identified by a key (source downloads, segment transcodes)
+// A key's mutex lives only while someone holds or waits for it, so the map stays small
+// The zero value is ready to use
+type keyedMutex struct {
+	mu    sync.Mutex
+	locks map[string]*keyedLock
+}
+
+type keyedLock struct {
+	sync.Mutex
+	refs int // Holders and waiters
+}
+
+// Lock locks key and returns the function that unlocks it
+func (k *keyedMutex) Lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyedLock)
+	}
+	lock, ok := k.locks[key]
+	if !ok {
+		lock = &keyedLock{}
+		k.locks[key] = lock
+	}
+	lock.refs++
+	k.mu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+		k.mu.Lock()
+		if lock.refs--; lock.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+// cachedSourcePath returns the web cache path of a video re-downloaded from Telegram
+func (s *Server) cachedSourcePath(video *database.Video) string {
+	return filepath.Join(s.CacheDir(), fmt.Sprintf("source-%d%s", video.ID, filepath.Ext(video.FilePath)))
+}
+
+// localSourcePath returns the original file of a video if it is on local disk, without downloading it
+func (s *Server) localSourcePath(video *database.Video) string {
+	if s.token != "" && video.TelegramFilePath != "" {
+		localPath := filepath.Join("/var/lib/telegram-bot-api", s.token, video.TelegramFilePath)
+		if _, err := os.Stat(localPath); err == nil {
+			return localPath
+		}
+	}
+	if _, err := os.Stat(s.cachedSourcePath(video)); err == nil {
+		return s.cachedSourcePath(video)
+	}
+	return ""
+}
+
+// probeSource returns a local path or URL ffprobe can read the headers of a video from
+// Unlike sourcePath it never downloads the whole file
+func (s *Server) probeSource(video *database.Video) (string, error) {
+	if src := s.localSourcePath(video); src != "" {
+		return src, nil
+	}
+	if s.downloader == nil || video.TelegramFileID == "" {
+		return "", fmt.Errorf("video %d is not on local disk and cannot be fetched from Telegram", video.ID)
+	}
+	return s.downloader.GetDownloadURL(video.TelegramFileID, video.TelegramFilePath)
+}
+
+// sourcePath returns a local path to the original file of a video
+// Files evicted from the telegram-bot-api storage are re-downloaded once into the web cache
+func (s *Server) sourcePath(video *database.Video) (string, error) {
+	if s.token != "" && video.TelegramFilePath != "" {
+		localPath := filepath.Join("/var/lib/telegram-bot-api", s.token, video.TelegramFilePath)
+		if _, err := os.Stat(localPath); err == nil {
+			return localPath, nil
+		}
+		log.Printf("Local file not found at %s (cleaned from cache)", localPath)
+	}
+
+	if s.downloader == nil {
+		return "", fmt.Errorf("video %d is not on local disk and no Telegram downloader is configured", video.ID)
+	}
+	if video.TelegramFileID == "" {
+		return "", fmt.Errorf("video %d has no Telegram file ID", video.ID)
+	}
+
+	cachedPath := s.cachedSourcePath(video)
+
+	// Only one request downloads a given video; the others wait and reuse the result
+	unlock := s.cacheLocks.Lock(fmt.Sprintf("source-%d", video.ID))
+	defer unlock()
+
+	if _, err := os.Stat(cachedPath); err == nil {
+		// Bump the modification time so the cache cleanup treats it as recently used
+		now := time.Now()
+		_ = os.Chtimes(cachedPath, now, now)
+		return cachedPath, nil
+	}
+
+	if err := os.MkdirAll(s.CacheDir(), 0755); err != nil {
+		return "", fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	log.Printf("Re-downloading video %d from Telegram (not in cache)", video.ID)
+	tmpPath := cachedPath + ".part"
+	if err := s.downloader.DownloadFileWithPath(video.TelegramFileID, video.TelegramFilePath, tmpPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to download video from Telegram: %w", err)
+	}
+	if err := os.Rename(tmpPath, cachedPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to move downloaded video into cache: %w", err)
+	}
+
+	log.Printf("Successfully re-downloaded video %d to %s", video.ID, cachedPath)
+	return cachedPath, nil
+}
+
+// removeVideoCache deletes every cached artifact of a video (full transcode, re-downloaded source, HLS segments, subtitles)
+func (s *Server) removeVideoCache(videoID int64) {
+	s.transcodes.Forget(videoID)
+	transcodedPath := s.transcodes.OutputPath(videoID)
+	if err := os.Remove(transcodedPath); err != nil && !os.IsNotExist(err) {
+		log.Printf("Warning: Failed to delete transcoded cache file %s: %v", transcodedPath, err)
+	}
+
+	sources, _ := filepath.Glob(filepath.Join(s.CacheDir(), fmt.Sprintf("source-%d.*", videoID)))
+	subtitles, _ := filepath.Glob(filepath.Join(s.CacheDir(), fmt.Sprintf("subs-%d-*.vtt", videoID)))
+	for _, cached := range append(sources, subtitles...) {
+		if err := os.Remove(cached); err != nil && !os.IsNotExist(err) {
+			log.Printf("Warning: Failed to delete cached file %s: %v", cached, err)
+		}
+	}
+
+	if err := os.RemoveAll(s.hlsDir(videoID)); err != nil {
+		log.Printf("Warning: Failed to delete HLS cache for video %d: %v", videoID, err)
+	}
+}
+
+// probeDuration returns the duration of a media file in seconds using ffprobe
+func probeDuration(filePath string) (float64, error) {
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-show_entries", "format=duration",
+		"-of", "default=noprint_wrappers=1:nokey=1",
+		filePath,
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("failed to probe duration: %w", err)
+	}
+
+	duration, err := strconv.ParseFloat(strings.TrimSpace(string(output)), 64)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("invalid duration %q", strings.TrimSpace(string(output)))
+	}
+	return duration, nil
+}
+
+// needsTranscodingByRelease guesses from the release metadata and container of a video whether
+// browsers can play it, for files that are not on local disk to probe. Unknown codecs are assumed
+// to play; the stream endpoint still transcodes them if they turn out not to
+func needsTranscodingByRelease(video *database.Video) bool {
+	switch strings.ToLower(filepath.Ext(video.FilePath)) {
+	case ".avi", ".wmv", ".flv", ".mpg", ".mpeg", ".vob":
+		return true
+	}
+	switch video.Release.VideoCodec {
+	case "H.265", "XviD", "DivX":
+		return true
+	}
+	switch video.Release.AudioCodec {
+	case "AC-3", "E-AC-3", "DTS", "DTS-HD", "TrueHD":
+		return true
+	}
+	return false
+}
+
+// handleAPIPlayback tells the player how to play a video: directly or via HLS
+// URL: /api/playback/{id}
+func (s *Server) handleAPIPlayback(w http.ResponseWriter, r *http.Request) {
+	videoID := parseVideoID(strings.TrimPrefix(r.URL.Path, "/api/playback/"))
+	video, err := s.db.GetVideoByID(videoID)
+	if err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	streamURL := fmt.Sprintf("/api/stream/%d", videoID)
+	response := map[string]interface{}{
+		"mode":        "direct",
+		"url":         streamURL,
+		"fallbackUrl": streamURL,
+	}
+
+	// Browser-incompatible codecs are transcoded segment by segment instead of all at once,
+	// unless a full transcode has already finished in the background. This answers right away:
+	// files that are not on local disk are judged by their release metadata, and the HLS
+	// segment handler fetches them when the first segment is requested
+	// Without a Telegram file the stream endpoint proxies to trtg, which cannot be segmented
+	if job, ok := s.transcodes.Status(videoID); ok && job.Status == TranscodeDone {
+		response["transcoded"] = true
+	} else if src := s.localSourcePath(video); src != "" {
+		if needsTranscodingByCodec(src) {
+			response["mode"] = "hls"
+		}
+	} else if s.downloader == nil || video.TelegramFileID == "" {
+		log.Printf("Warning: No local source for video %d, using direct stream", videoID)
+	} else if needsTranscodingByRelease(video) {
+		response["mode"] = "hls"
+	}
+	if response["mode"] == "hls" {
+		response["url"] = fmt.Sprintf("/api/hls/%d/index.m3u8", videoID)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleAPIHLS serves HLS playlists and on-the-fly transcoded segments
+// URL: /api/hls/{id}/index.m3u8 or /api/hls/{id}/seg-{n}.ts
+// An optional ?audio={n} selects the n-th audio stream of the source (default: first)
+func (s *Server) handleAPIHLS(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/hls/"), "/")
+	if len(pathParts) != 2 {
+		http.Error(w, "Invalid HLS path", http.StatusBadRequest)
+		return
+	}
+
+	videoID := parseVideoID(pathParts[0])
+	video, err := s.db.GetVideoByID(videoID)
+	if err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	audio := 0
+	if audioParam := r.URL.Query().Get("audio"); audioParam != "" {
+		audio, err = strconv.Atoi(audioParam)
+		if err != nil || audio < 0 {
+			http.Error(w, "Invalid audio track", http.StatusBadRequest)
+			return
+		}
+	}
+
+	name := pathParts[1]
+	switch {
+	case name == "index.m3u8":
+		s.serveHLSPlaylist(w, video, audio)
+	case strings.HasPrefix(name, "seg-") && strings.HasSuffix(name, ".ts"):
+		segment, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "seg-"), ".ts"))
+		if err != nil || segment < 0 {
+			http.Error(w, "Invalid segment number", http.StatusBadRequest)
+			return
+		}
+		s.serveHLSSegment(w, r, video, segment, audio)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// serveHLSPlaylist writes a VOD playlist covering the whole file in fixed-length segments
+// The duration is probed from the headers, so a file evicted from local disk is not downloaded yet
+func (s *Server) serveHLSPlaylist(w http.ResponseWriter, video *database.Video, audio int) {
+	src, err := s.probeSource(video)
+	if err != nil {
+		log.Printf("Error resolving source for HLS video %d: %v", video.ID, err)
+		http.Error(w, fmt.Sprintf("Video source not available: %v", err), http.StatusBadGateway)
+		return
+	}
+	duration, err := probeDuration(src)
+	if err != nil {
+		log.Printf("Error probing duration of video %d: %v", video.ID, err)
+		http.Error(w, "Failed to read video duration", http.StatusInternalServerError)
+		return
+	}
+
+	var playlist strings.Builder
+	playlist.WriteString("#EXTM3U\n")
+	playlist.WriteString("#EXT-X-VERSION:3\n")
+	fmt.Fprintf(&playlist, "#EXT-X-TARGETDURATION:%d\n", hlsSegmentSeconds)
+	playlist.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+	playlist.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+
+	segments := int(math.Ceil(duration / hlsSegmentSeconds))
+	for i := 0; i < segments; i++ {
+		segmentDuration := math.Min(hlsSegmentSeconds, duration-float64(i*hlsSegmentSeconds))
+		fmt.Fprintf(&playlist, "#EXTINF:%.3f,\nseg-%d.ts?audio=%d\n", segmentDuration, i, audio)
+	}
+	playlist.WriteString("#EXT-X-ENDLIST\n")
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write([]byte(playlist.String()))
+}
+
+// serveHLSSegment serves a single segment, transcoding it first if it is not cached yet
+// The first segment of a file evicted from local disk waits for it to be downloaded again
+func (s *Server) serveHLSSegment(w http.ResponseWriter, r *http.Request, video *database.Video, segment, audio int) {
+	segmentPath, err := s.transcodeHLSSegment(video, segment, audio)
+	if err != nil {
+		log.Printf("Error transcoding video %d segment %d: %v", video.ID, segment, err)
+		http.Error(w, "Failed to transcode segment", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "video/mp2t")
+	http.ServeFile(w, r, segmentPath)
+}
+
+// transcodeHLSSegment transcodes a single segment with ffmpeg input seeking and caches it on disk
+func (s *Server) transcodeHLSSegment(video *database.Video, segment, audio int) (string, error) {
+	videoID := video.ID
+	segmentPath := filepath.Join(s.hlsDir(videoID), fmt.Sprintf("seg-%d-a%d.ts", segment, audio))
+
+	// Serialize transcodes of the same segment so repeated requests reuse the cached result,
+	// while the player's prefetches of other segments run alongside
+	unlock := s.cacheLocks.Lock(fmt.Sprintf("hls-%d-%d-a%d", videoID, segment, audio))
+	defer unlock()
+
+	if _, err := os.Stat(segmentPath); err == nil {
+		return segmentPath, nil
+	}
+	src, err := s.sourcePath(video)
+	if err != nil {
+		return "", fmt.Errorf("video source not available: %w", err)
+	}
+	if err := os.MkdirAll(s.hlsDir(videoID), 0755); err != nil {
+		return "", fmt.Errorf("failed to create segment cache: %w", err)
+	}
+
+	start := segment * hlsSegmentSeconds
+	tmpPath := segmentPath + ".part"
+
+	// -ss before -i seeks the input quickly; re-encoding makes the cut frame-accurate
+	// -output_ts_offset keeps timestamps continuous across independently encoded segments
+	cmd := exec.Command("ffmpeg",
+		"-ss", strconv.Itoa(start),
+		"-i", src,
+		"-t", strconv.Itoa(hlsSegmentSeconds),
+		"-map", "0:v:0",
+		"-map", fmt.Sprintf("0:a:%d?", audio),
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "23",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-ac", "2",
+		"-output_ts_offset", strconv.Itoa(start),
+		"-muxdelay", "0",
+		"-f", "mpegts",
+		"-y",
+		tmpPath,
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("ffmpeg failed: %w\nStderr: %s", err, stderr.String())
+	}
+	if err := os.Rename(tmpPath, segmentPath); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("failed to cache segment: %w", err)
+	}
+	return segmentPath, nil
+}
@@ -0,0 +1,138 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/parser"
+)
+
+func TestKeyedMutex(t *testing.T) {
+	var k keyedMutex
+
+	unlock := k.Lock("hls-1-0-a0")
+	acquired := make(chan struct{})
+	released := make(chan struct{})
+	go func() {
+		unlock := k.Lock("hls-1-0-a0")
+		close(acquired)
+		unlock()
+		close(released)
+	}()
+
+	// Other keys are not blocked by a held one
+	k.Lock("hls-1-1-a0")()
+
+	select {
+	case <-acquired:
+		t.Fatal("second Lock() of a held key did not wait")
+	case <-time.After(50 * time.Millisecond):
+	}
+	unlock()
+	<-acquired
+	<-released
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			k.Lock("source-1")()
+		}()
+	}
+	wg.Wait()
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if len(k.locks) != 0 {
+		t.Errorf("%d locks left after all were released, want 0", len(k.locks))
+	}
+}
+
+func TestHLSJSVersionMatchesDockerfile(t *testing.T) {
+	dockerfile, err := os.ReadFile(filepath.Join("..", "..", "Dockerfile.web"))
+	if err != nil {
+		t.Fatalf("failed to read Dockerfile.web: %v", err)
+	}
+	matches := regexp.MustCompile(`ARG HLS_JS_VERSION=(\S+)`).FindSubmatch(dockerfile)
+	if matches == nil {
+		t.Fatal("Dockerfile.web has no HLS_JS_VERSION")
+	}
+	if string(matches[1]) != hlsJSVersion {
+		t.Errorf("Dockerfile.web downloads hls.js %s, pages load %s", matches[1], hlsJSVersion)
+	}
+}
+
+func TestHandleStatic(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "hls-"+hlsJSVersion+".min.js"), []byte("// hls.js"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		staticDir string
+		path      string
+		status    int
+	}{
+		{"hls.js", dir, "/static/hls-" + hlsJSVersion + ".min.js", http.StatusOK},
+		{"missing file", dir, "/static/hls.min.js", http.StatusNotFound},
+		{"directory listing", dir, "/static/", http.StatusNotFound},
+		{"subdirectory listing", dir, "/static/sub/", http.StatusNotFound},
+		{"subdirectory", dir, "/static/sub", http.StatusNotFound},
+		{"outside the directory", dir, "/static/../hls_test.go", http.StatusNotFound},
+		{"no static directory", "", "/static/hls-" + hlsJSVersion + ".min.js", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{staticDir: tt.staticDir}
+			w := httptest.NewRecorder()
+			s.handleStatic(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if w.Code != tt.status {
+				t.Errorf("GET %s = %d, want %d", tt.path, w.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestNeedsTranscodingByRelease(t *testing.T) {
+	tests := []struct {
+		name     string
+		filePath string
+		release  parser.Release
+		expected bool
+	}{
+		{"H.264 AAC MP4", "Show.S01E01.mp4", parser.Release{VideoCodec: "H.264", AudioCodec: "AAC"}, false},
+		{"H.264 MKV", "Show.S01E01.mkv", parser.Release{VideoCodec: "H.264"}, false},
+		{"unknown codecs", "Show.S01E01.mkv", parser.Release{}, false},
+		{"AV1", "Show.S01E01.mkv", parser.Release{VideoCodec: "AV1"}, false},
+		{"H.265", "Show.S01E01.mkv", parser.Release{VideoCodec: "H.265"}, true},
+		{"XviD", "Show.S01E01.mkv", parser.Release{VideoCodec: "XviD"}, true},
+		{"AC-3 audio", "Show.S01E01.mp4", parser.Release{VideoCodec: "H.264", AudioCodec: "AC-3"}, true},
+		{"DTS audio", "Show.S01E01.mkv", parser.Release{AudioCodec: "DTS"}, true},
+		{"TrueHD audio", "Show.S01E01.mkv", parser.Release{AudioCodec: "TrueHD"}, true},
+		{"AVI container", "Show.S01E01.avi", parser.Release{}, true},
+		{"uppercase extension", "Show.S01E01.WMV", parser.Release{}, true},
+		{"MPEG container", "Show.S01E01.mpg", parser.Release{VideoCodec: "H.264", AudioCodec: "AAC"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			video := &database.Video{FilePath: tt.filePath, Release: tt.release}
+			if got := needsTranscodingByRelease(video); got != tt.expected {
+				t.Errorf("needsTranscodingByRelease(%s, %+v) = %v, want %v", tt.filePath, tt.release, got, tt.expected)
+			}
+		})
+	}
+}
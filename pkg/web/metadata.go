@@ -0,0 +1,38 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/metadata"
+)
+
+// metadataTimeout bounds how long a page waits for the metadata provider; without a
+// response it is shown without titles and artwork
+const metadataTimeout = 5 * time.Second
+
+// SetMetadataClient enables episode titles, overviews and artwork from a metadata provider
+func (s *Server) SetMetadataClient(client *metadata.Client) {
+	s.metadata = client
+}
+
+// showMetadata returns the provider's details of a show, or nil if metadata is disabled,
+// the show is unknown or the provider fails
+func (s *Server) showMetadata(r *http.Request, showName string) *metadata.ShowDetails {
+	if s.metadata == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), metadataTimeout)
+	defer cancel()
+	details, err := s.metadata.Lookup(ctx, showName, 0)
+	if err != nil {
+		if !errors.Is(err, metadata.ErrNotFound) {
+			log.Printf("Warning: Failed to get metadata of %s: %v", showName, err)
+		}
+		return nil
+	}
+	return details
+}
@@ -0,0 +1,325 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/rusik69/trtg/pkg/parser"
+)
+
+// movieLabel formats a movie as "Title (Year)"
+func movieLabel(movie parser.Movie) string {
+	if movie.Year == 0 {
+		return movie.Title
+	}
+	return fmt.Sprintf("%s (%d)", movie.Title, movie.Year)
+}
+
+// handleAPIMovies returns the movies in the archive, the files of each movie (editions, resolutions)
+// grouped under its title and year
+func (s *Server) handleAPIMovies(w http.ResponseWriter, r *http.Request) {
+	videos, err := s.db.GetMovies()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	type File struct {
+		ID               int64    `json:"id"`
+		FileName         string   `json:"fileName"`
+		Edition          string   `json:"edition,omitempty"`
+		Badges           []string `json:"badges"`
+		Uploaded         bool     `json:"uploaded"`
+		TorrentStreamURL string   `json:"torrentStreamUrl,omitempty"`
+		Watched          bool     `json:"watched"`
+		Position         float64  `json:"position"`
+		Duration         float64  `json:"duration"`
+	}
+	type Movie struct {
+		Title string `json:"title"`
+		Year  int    `json:"year,omitempty"`
+		Files []File `json:"files"`
+	}
+
+	videoIDs := make([]int64, 0, len(videos))
+	for _, video := range videos {
+		videoIDs = append(videoIDs, video.ID)
+	}
+	progress, err := s.db.GetWatchProgressForVideos(s.currentUser(r).ID, videoIDs)
+	if err != nil {
+		log.Printf("Warning: Failed to load watch progress: %v", err)
+	}
+
+	movies := []Movie{}
+	index := make(map[string]int)
+	for _, video := range videos {
+		key := fmt.Sprintf("%s:%d", strings.ToLower(video.Movie.Title), video.Movie.Year)
+		i, ok := index[key]
+		if !ok {
+			i = len(movies)
+			index[key] = i
+			movies = append(movies, Movie{Title: video.Movie.Title, Year: video.Movie.Year, Files: []File{}})
+		}
+
+		file := File{
+			ID:       video.ID,
+			FileName: filepath.Base(video.FilePath),
+			Edition:  video.Movie.Edition,
+			Badges:   video.Release.Badges(),
+			Uploaded: video.Uploaded(),
+		}
+		if !file.Uploaded {
+			file.TorrentStreamURL = s.torrentStreamURL(video)
+		}
+		if p, ok := progress[video.ID]; ok {
+			file.Watched = p.Watched
+			file.Position = p.Position
+			file.Duration = p.Duration
+		}
+		movies[i].Files = append(movies[i].Files, file)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(movies)
+}
+
+// handleMoviesPage shows the movies in a grid, each with a player for its files
+func (s *Server) handleMoviesPage(w http.ResponseWriter, r *http.Request) {
+	tmpl := `<!DOCTYPE html>
+<html>
+<head>
+	<title>Movies</title>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<style>
+		* { margin: 0; padding: 0; box-sizing: border-box; }
+		body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #1a1a1a; color: #fff; padding: 20px; }
+		.container { max-width: 1200px; margin: 0 auto; }
+		.header { display: flex; justify-content: space-between; align-items: center; margin-bottom: 30px; }
+		.back-btn { background: #4a9eff; color: white; border: none; padding: 10px 20px; border-radius: 4px; cursor: pointer; text-decoration: none; display: inline-block; }
+		.back-btn:hover { background: #5aaeff; }
+		.movies { display: grid; grid-template-columns: repeat(auto-fill, minmax(260px, 1fr)); gap: 20px; }
+		.movie-card { background: #2a2a2a; border-radius: 8px; padding: 20px; transition: background 0.2s; }
+		.movie-card:hover { background: #3a3a3a; }
+		.movie-title { font-size: 18px; font-weight: bold; margin-bottom: 4px; }
+		.movie-year { color: #aaa; font-size: 14px; margin-bottom: 10px; }
+		.movie-file { border-top: 1px solid #444; padding-top: 10px; margin-top: 10px; }
+		.movie-edition { color: #ff9800; font-size: 13px; margin-bottom: 6px; }
+		.release-badges { margin-bottom: 8px; }
+		.release-badge { display: inline-block; background: #3a3a3a; color: #ccc; font-size: 11px; padding: 2px 6px; border-radius: 3px; margin: 0 4px 4px 0; }
+		.watched-badge { color: #28a745; font-size: 12px; margin-left: 6px; }
+		.play-btn { background: #28a745; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer; }
+		.play-btn:hover { background: #34ce57; }
+		.play-btn:disabled { background: #555; cursor: not-allowed; }
+		.progress-bar { height: 4px; background: #444; border-radius: 2px; margin-bottom: 8px; overflow: hidden; }
+		.progress-bar div { height: 100%; background: #dc3545; }
+		.empty { color: #aaa; }
+		.video-player { display: none; position: fixed; top: 0; left: 0; width: 100%; height: 100%; background: rgba(0,0,0,0.95); z-index: 1000; }
+		.video-player.active { display: flex; align-items: center; justify-content: center; }
+		.video-player video { max-width: 100%; max-height: 100%; }
+		.close-btn { position: absolute; top: 20px; right: 20px; background: #dc3545; color: white; border: none; padding: 10px 20px; border-radius: 4px; cursor: pointer; font-size: 18px; z-index: 1002; }
+		.close-btn:hover { background: #c82333; }
+	</style>
+	` + hlsScript + `
+	` + csrfFetchScript + `
+	` + eventsScript + `
+	` + searchScript + `
+</head>
+<body>
+	<div class="container">
+		<div class="header">
+			<h1>Movies</h1>
+			<a href="/" class="back-btn">← Back to Shows</a>
+		</div>
+		<div class="movies" id="movies"></div>
+	</div>
+	<div class="video-player" id="videoPlayer">
+		<button class="close-btn" onclick="closePlayer()">×</button>
+		<video id="videoElement" controls autoplay></video>
+	</div>
+	<script>
+		let currentVideoId = null;
+		let hlsPlayer = null;
+		let lastProgressSave = 0;
+		let initial = true;
+
+		function loadMovies() {
+			fetch('/api/movies')
+				.then(r => r.json())
+				.then(movies => {
+					const container = document.getElementById('movies');
+					container.innerHTML = '';
+					if (movies.length === 0) {
+						container.innerHTML = '<div class="empty">No movies yet. Files without episode numbering whose name carries a title and year are listed here.</div>';
+					}
+					const files = {};
+					movies.forEach(movie => {
+						const card = document.createElement('div');
+						card.className = 'movie-card';
+						let html = '<div class="movie-title">' + escapeHtml(movie.title) + '</div>' +
+							'<div class="movie-year">' + (movie.year || '') + '</div>';
+						movie.files.forEach(file => {
+							files[file.id] = file;
+							const edition = file.edition ? '<div class="movie-edition">' + escapeHtml(file.edition) + '</div>' : '';
+							const badges = file.badges && file.badges.length
+								? '<div class="release-badges">' + file.badges.map(b => '<span class="release-badge">' + escapeHtml(b) + '</span>').join('') + '</div>'
+								: '';
+							let progress = '';
+							if (!file.watched && file.position > 0 && file.duration > 0) {
+								progress = '<div class="progress-bar"><div style="width: ' + Math.min(100, file.position / file.duration * 100).toFixed(1) + '%"></div></div>';
+							}
+							let playBtn = '<button class="play-btn" onclick="playFile(' + file.id + ')">Play</button>';
+							if (!file.uploaded) {
+								playBtn = file.torrentStreamUrl
+									? '<button class="play-btn" onclick="playFile(' + file.id + ')">Play from torrent</button>'
+									: '<button class="play-btn" disabled>Not uploaded yet</button>';
+							}
+							const watched = file.watched ? '<span class="watched-badge">✓ Watched</span>' : '';
+							html += '<div class="movie-file" title="' + escapeHtml(file.fileName) + '">' + edition + badges + progress + playBtn + watched + '</div>';
+						});
+						card.innerHTML = html;
+						container.appendChild(card);
+					});
+					window.movieFiles = files;
+
+					// Links from search and "Continue watching" open the player directly
+					if (!initial) return;
+					initial = false;
+					const play = parseInt(new URLSearchParams(location.search).get('play'), 10);
+					if (files[play]) {
+						playFile(play);
+					}
+				});
+		}
+
+		function playFile(videoId) {
+			const file = window.movieFiles[videoId];
+			currentVideoId = videoId;
+			lastProgressSave = Date.now();
+			const video = document.getElementById('videoElement');
+			stopHls();
+			document.getElementById('videoPlayer').classList.add('active');
+
+			video.ontimeupdate = function() {
+				if (Date.now() - lastProgressSave > 10000) {
+					saveProgress();
+				}
+			};
+			video.onpause = saveProgress;
+			video.onended = saveProgress;
+			resumeProgress(videoId);
+
+			// Movies that are still downloading stream from the torrent swarm instead of Telegram
+			if (file && !file.uploaded && file.torrentStreamUrl) {
+				video.src = file.torrentStreamUrl;
+				video.load();
+				return;
+			}
+
+			// Ask the server whether the file plays natively or needs on-the-fly HLS transcoding
+			fetch('/api/playback/' + videoId)
+				.then(r => r.json())
+				.then(playback => {
+					if (playback.mode === 'hls') {
+						playHls(playback.url, playback.fallbackUrl);
+					} else {
+						video.src = playback.url;
+						video.load();
+					}
+				})
+				.catch(() => {
+					video.src = '/api/stream/' + videoId;
+					video.load();
+				});
+		}
+
+		function playHls(url, fallbackUrl) {
+			const video = document.getElementById('videoElement');
+			if (window.Hls && Hls.isSupported()) {
+				hlsPlayer = new Hls();
+				hlsPlayer.on(Hls.Events.ERROR, function(event, data) {
+					if (data.fatal) {
+						// Fall back to the full-file transcode if segmenting fails
+						stopHls();
+						video.src = fallbackUrl;
+						video.load();
+					}
+				});
+				hlsPlayer.loadSource(url);
+				hlsPlayer.attachMedia(video);
+			} else if (video.canPlayType('application/vnd.apple.mpegurl')) {
+				// Safari plays HLS natively
+				video.src = url;
+				video.load();
+			} else {
+				video.src = fallbackUrl;
+				video.load();
+			}
+		}
+
+		function stopHls() {
+			if (hlsPlayer) {
+				hlsPlayer.destroy();
+				hlsPlayer = null;
+			}
+		}
+
+		function closePlayer() {
+			const video = document.getElementById('videoElement');
+			document.getElementById('videoPlayer').classList.remove('active');
+			video.pause();
+			saveProgress();
+			currentVideoId = null;
+			stopHls();
+			video.src = '';
+		}
+
+		function resumeProgress(videoId) {
+			fetch('/api/progress/' + videoId)
+				.then(r => r.json())
+				.then(progress => {
+					// Start over if the movie was finished or barely started
+					if (videoId !== currentVideoId || progress.watched || progress.position < 10) {
+						return;
+					}
+					const video = document.getElementById('videoElement');
+					if (video.readyState >= 1) {
+						video.currentTime = progress.position;
+					} else {
+						video.addEventListener('loadedmetadata', () => { video.currentTime = progress.position; }, { once: true });
+					}
+				});
+		}
+
+		function saveProgress() {
+			const video = document.getElementById('videoElement');
+			if (!currentVideoId || !video.currentTime) {
+				return;
+			}
+			lastProgressSave = Date.now();
+			fetch('/api/progress/' + currentVideoId, {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ position: video.currentTime, duration: isFinite(video.duration) ? video.duration : 0 })
+			}).catch(err => console.log('Failed to save progress:', err));
+		}
+
+		function escapeHtml(text) {
+			const div = document.createElement('div');
+			div.textContent = text;
+			return div.innerHTML;
+		}
+
+		loadMovies();
+		onArchiveChange(e => e.table === 'videos', loadMovies);
+	</script>
+</body>
+</html>`
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, tmpl)
+}
@@ -0,0 +1,109 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/notify"
+)
+
+// SetNotifier enables notification subscriptions and browser notifications on /api/events
+func (s *Server) SetNotifier(d *notify.Dispatcher) {
+	s.notifier = d
+}
+
+// handleAPINotifications lists (GET) and creates (POST) the current user's notification subscriptions
+// POST body: {"kind": "webhook"|"telegram"|"browser", "target": "...", "showName": "...", "events": ["episode.uploaded", ...]}
+// Webhook and Telegram subscriptions make the server contact other hosts, so they need the admin role
+func (s *Server) handleAPINotifications(w http.ResponseWriter, r *http.Request) {
+	user := s.currentUser(r)
+
+	switch r.Method {
+	case "GET":
+		subs, err := s.db.ListNotificationSubscriptions(user.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"subscriptions": subs,
+			"eventTypes":    notify.EventTypes,
+		})
+
+	case "POST":
+		var body struct {
+			Kind     string   `json:"kind"`
+			Target   string   `json:"target"`
+			Secret   string   `json:"secret"`
+			ShowName string   `json:"showName"`
+			Events   []string `json:"events"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if body.Kind != database.NotifyBrowser && !s.checkAdmin(w, r) {
+			return
+		}
+		for _, e := range body.Events {
+			if !notify.ValidEventType(e) {
+				http.Error(w, fmt.Sprintf("Unknown event type %q", e), http.StatusBadRequest)
+				return
+			}
+		}
+
+		sub := &database.NotificationSubscription{
+			UserID:   user.ID,
+			Kind:     body.Kind,
+			Target:   body.Target,
+			Secret:   body.Secret,
+			ShowName: body.ShowName,
+			Events:   body.Events,
+		}
+		if err := s.db.CreateNotificationSubscription(sub); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("User %s subscribed to notifications by %s (show: %q)", user.Username, sub.Kind, sub.ShowName)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(struct {
+			*database.NotificationSubscription
+			Secret string `json:"secret,omitempty"`
+		}{sub, sub.Secret})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPINotification deletes one of the current user's notification subscriptions
+// URL: DELETE /api/notifications/{id}
+func (s *Server) handleAPINotification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/notifications/"), 10, 64)
+	if err != nil {
+		http.Error(w, "Subscription ID required", http.StatusBadRequest)
+		return
+	}
+
+	user := s.currentUser(r)
+	if err := s.db.DeleteNotificationSubscription(user.ID, id); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to delete subscription: %v", err), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// continueWatchingLimit is how many entries the index page "Continue watching" row shows
+const continueWatchingLimit = 12
+
+// handleAPIProgress returns (GET) or saves (POST) the current user's playback position in a video
+// URL: /api/progress/{id}, POST body: {"position": seconds, "duration": seconds}
+func (s *Server) handleAPIProgress(w http.ResponseWriter, r *http.Request) {
+	videoID := parseVideoID(strings.TrimPrefix(r.URL.Path, "/api/progress/"))
+	if videoID == 0 {
+		http.Error(w, "Video ID required", http.StatusBadRequest)
+		return
+	}
+	user := s.currentUser(r)
+
+	switch r.Method {
+	case "GET":
+		progress, err := s.db.GetWatchProgress(user.ID, videoID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if progress == nil {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"videoId":  videoID,
+				"position": 0,
+				"watched":  false,
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(progress)
+
+	case "POST":
+		var body struct {
+			Position float64 `json:"position"`
+			Duration float64 `json:"duration"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Position < 0 || body.Duration < 0 {
+			http.Error(w, "Invalid progress", http.StatusBadRequest)
+			return
+		}
+		if err := s.db.SaveWatchProgress(user.ID, videoID, body.Position, body.Duration); err != nil {
+			log.Printf("Error saving progress of video %d for %s: %v", videoID, user.Username, err)
+			http.Error(w, fmt.Sprintf("Failed to save progress: %v", err), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIContinueWatching returns the current user's unfinished and next episodes
+func (s *Server) handleAPIContinueWatching(w http.ResponseWriter, r *http.Request) {
+	items, err := s.db.GetContinueWatching(s.currentUser(r).ID, continueWatchingLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(items)
+}
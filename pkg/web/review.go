@@ -0,0 +1,239 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rusik69/trtg/pkg/parser"
+)
+
+// maxReviewItems limits how many videos the review queue returns at once
+const maxReviewItems = 200
+
+// handleAPIReview returns the videos the parser was not confident about and that were not corrected yet
+// Query: threshold (default parser.ReviewThreshold), limit (default and maximum maxReviewItems)
+func (s *Server) handleAPIReview(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	threshold := parser.ReviewThreshold
+	if value := r.URL.Query().Get("threshold"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			http.Error(w, "threshold must be between 0 and 1", http.StatusBadRequest)
+			return
+		}
+		threshold = parsed
+	}
+	limit := maxReviewItems
+	if value, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && value > 0 && value < limit {
+		limit = value
+	}
+
+	videos, err := s.db.GetReviewQueue(threshold, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to get review queue: %v", err), http.StatusInternalServerError)
+		return
+	}
+	total, err := s.db.CountReviewQueue(threshold)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to count review queue: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	type ReviewItem struct {
+		ID            int64   `json:"id"`
+		TorrentName   string  `json:"torrentName"`
+		FilePath      string  `json:"filePath"`
+		ShowName      string  `json:"showName"`
+		SeasonNumber  int     `json:"seasonNumber"`
+		EpisodeNumber int     `json:"episodeNumber"`
+		Confidence    float64 `json:"confidence"`
+		Rule          string  `json:"rule"`
+	}
+	items := make([]ReviewItem, 0, len(videos))
+	for _, v := range videos {
+		items = append(items, ReviewItem{
+			ID:            v.ID,
+			TorrentName:   v.Title,
+			FilePath:      v.FilePath,
+			ShowName:      v.ShowName,
+			SeasonNumber:  v.SeasonNumber,
+			EpisodeNumber: v.EpisodeNumber,
+			Confidence:    v.ParseConfidence,
+			Rule:          v.ParseRule,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"threshold": threshold,
+		"total":     total,
+		"items":     items,
+	})
+}
+
+// handleAPIReviewVideo corrects (POST) a video from the review queue, or removes its correction (DELETE)
+// so the next reparse numbers it again
+// POST body: {"showName": "...", "seasonNumber": 1, "episodeNumber": 2}; omitted fields stay with the parser
+func (s *Server) handleAPIReviewVideo(w http.ResponseWriter, r *http.Request) {
+	videoID := parseVideoID(strings.TrimPrefix(r.URL.Path, "/api/review/"))
+	if videoID == 0 {
+		http.Error(w, "Video ID required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "POST":
+		var edit episodeEdit
+		if err := json.NewDecoder(r.Body).Decode(&edit); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if err := edit.validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user := s.currentUser(r)
+		if err := s.db.SaveVideoOverride(edit.override(videoID, user.Username)); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to save correction: %v", err), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("User %s corrected video %d", user.Username, videoID)
+
+	case "DELETE":
+		if err := s.db.DeleteVideoOverride(videoID); err != nil {
+			http.Error(w, fmt.Sprintf("Failed to delete correction: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
+
+// handleReviewPage lists the review queue with inline forms to correct each video
+func (s *Server) handleReviewPage(w http.ResponseWriter, r *http.Request) {
+	tmpl := `<!DOCTYPE html>
+<html>
+<head>
+	<title>Needs Review</title>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<style>
+		* { margin: 0; padding: 0; box-sizing: border-box; }
+		body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #1a1a1a; color: #fff; padding: 20px; }
+		.container { max-width: 1200px; margin: 0 auto; }
+		.header { display: flex; justify-content: space-between; align-items: center; margin-bottom: 30px; }
+		.back-btn { background: #4a9eff; color: white; border: none; padding: 10px 20px; border-radius: 4px; cursor: pointer; text-decoration: none; display: inline-block; }
+		.back-btn:hover { background: #5aaeff; }
+		.hint { color: #aaa; font-size: 14px; margin-bottom: 20px; }
+		.item { background: #2a2a2a; border-radius: 8px; padding: 15px 20px; margin-bottom: 10px; }
+		.item.saved { opacity: 0.5; }
+		.path { font-family: monospace; word-break: break-all; }
+		.torrent { color: #aaa; font-size: 13px; margin: 4px 0 10px; word-break: break-all; }
+		.guess { color: #aaa; font-size: 13px; margin-bottom: 10px; }
+		.confidence { color: #ffc107; }
+		.fields { display: flex; gap: 10px; align-items: center; flex-wrap: wrap; }
+		.fields input { padding: 8px; border: 1px solid #444; border-radius: 4px; background: #1a1a1a; color: #fff; font-size: 14px; }
+		.fields .show { flex: 1; min-width: 200px; }
+		.fields .number { width: 90px; }
+		button { background: #28a745; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer; }
+		button:hover { background: #218838; }
+		.status { color: #aaa; font-size: 13px; }
+		.status.error { color: #dc3545; }
+	</style>
+	` + csrfFetchScript + `
+	` + searchScript + `
+</head>
+<body>
+	<div class="container">
+		<div class="header">
+			<h1>Needs Review</h1>
+			<a href="/" class="back-btn">← Back to Shows</a>
+		</div>
+		<p class="hint" id="summary">Loading…</p>
+		<div id="items"></div>
+	</div>
+	<script>
+		function escapeHtml(text) {
+			const div = document.createElement('div');
+			div.textContent = text;
+			return div.innerHTML;
+		}
+
+		function loadQueue() {
+			fetch('/api/review')
+				.then(r => r.json())
+				.then(data => {
+					document.getElementById('summary').textContent = data.total === 0
+						? 'Nothing to review.'
+						: data.total + ' files were parsed with a confidence below ' + data.threshold +
+							'. Correct the show, season and episode, or save the guess if it is right; reparse keeps corrections.';
+					const container = document.getElementById('items');
+					container.innerHTML = '';
+					data.items.forEach(item => {
+						const card = document.createElement('div');
+						card.className = 'item';
+						card.id = 'item-' + item.id;
+						card.innerHTML = '<div class="path">' + escapeHtml(item.filePath) + '</div>' +
+							'<div class="torrent">' + escapeHtml(item.torrentName) + '</div>' +
+							'<div class="guess">Guessed by <strong>' + escapeHtml(item.rule || 'not parsed') + '</strong>, confidence <span class="confidence">' + item.confidence.toFixed(2) + '</span></div>' +
+							'<div class="fields">' +
+								'<input type="text" class="show" id="show-' + item.id + '" placeholder="Show">' +
+								'<input type="number" class="number" id="season-' + item.id + '" value="' + item.seasonNumber + '" min="0" title="Season">' +
+								'<input type="number" class="number" id="episode-' + item.id + '" value="' + item.episodeNumber + '" min="0" title="Episode">' +
+								'<button onclick="saveItem(' + item.id + ')">Save</button>' +
+								'<span class="status" id="status-' + item.id + '"></span>' +
+							'</div>';
+						card.querySelector('.show').value = item.showName;
+						container.appendChild(card);
+					});
+				});
+		}
+
+		function saveItem(id) {
+			const status = document.getElementById('status-' + id);
+			status.className = 'status';
+			status.textContent = 'Saving…';
+			fetch('/api/review/' + id, {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({
+					showName: document.getElementById('show-' + id).value,
+					seasonNumber: parseInt(document.getElementById('season-' + id).value, 10) || 0,
+					episodeNumber: parseInt(document.getElementById('episode-' + id).value, 10) || 0
+				})
+			})
+				.then(r => r.ok ? r.json() : r.text().then(text => { throw new Error(text); }))
+				.then(() => {
+					status.textContent = 'Saved';
+					document.getElementById('item-' + id).classList.add('saved');
+				})
+				.catch(error => {
+					status.className = 'status error';
+					status.textContent = error.message;
+				});
+		}
+
+		loadQueue();
+	</script>
+</body>
+</html>`
+
+	t, _ := template.New("review").Parse(tmpl)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t.Execute(w, nil)
+}
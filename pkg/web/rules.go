@@ -0,0 +1,211 @@
+package web
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/rusik69/trtg/pkg/parser"
+)
+
+// maxRulesSize limits the size of parser rules uploaded through the API
+const maxRulesSize = 1 << 20
+
+// SetParserRulesFile sets the JSON file parser rules edited in the web UI are saved to
+func (s *Server) SetParserRulesFile(path string) {
+	s.rulesFile = path
+}
+
+// handleAPIParserRules returns (GET) or replaces (PUT) the parser rules
+// PUT body: the rules as JSON; they take effect immediately and are saved to the rules file
+func (s *Server) handleAPIParserRules(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			File  string       `json:"file,omitempty"`
+			Rules parser.Rules `json:"rules"`
+		}{s.rulesFile, parser.CurrentRules()})
+
+	case "PUT":
+		if s.rulesFile == "" {
+			http.Error(w, "No parser rules file configured (set PARSER_RULES_FILE)", http.StatusConflict)
+			return
+		}
+		data, err := io.ReadAll(io.LimitReader(r.Body, maxRulesSize))
+		if err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		rules, err := parser.ParseRules(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := parser.SaveRules(s.rulesFile, rules); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Printf("User %s updated the parser rules", s.currentUser(r).Username)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIParserTest parses a torrent name and file path with the current rules
+// POST body: {"torrentName": "...", "filePath": "..."}
+func (s *Server) handleAPIParserTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var body struct {
+		TorrentName string `json:"torrentName"`
+		FilePath    string `json:"filePath"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.FilePath == "" {
+		http.Error(w, "torrentName and filePath required", http.StatusBadRequest)
+		return
+	}
+
+	info := parser.ParseVideoInfo(body.TorrentName, body.FilePath)
+	result := map[string]interface{}{
+		"showName":      info.ShowName,
+		"seasonNumber":  info.SeasonNumber,
+		"episodeNumber": info.EpisodeNumber,
+		"release":       info.Release,
+		"confidence":    info.Confidence,
+		"matchedRule":   info.MatchedRule,
+		"mediaType":     info.MediaType,
+	}
+	if info.MediaType == parser.MediaMovie {
+		result["movie"] = info.Movie
+	}
+	if info.EpisodeEnd > 0 {
+		result["episodeEnd"] = info.EpisodeEnd
+	}
+	if !info.AirDate.IsZero() {
+		result["airDate"] = info.AirDate.Format("2006-01-02")
+	}
+	if info.AbsoluteNumber > 0 {
+		result["absoluteNumber"] = info.AbsoluteNumber
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleRulesPage shows the parser rules in an editor with a form to try them on a file name
+func (s *Server) handleRulesPage(w http.ResponseWriter, r *http.Request) {
+	tmpl := `<!DOCTYPE html>
+<html>
+<head>
+	<title>Parser Rules</title>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<style>
+		* { margin: 0; padding: 0; box-sizing: border-box; }
+		body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #1a1a1a; color: #fff; padding: 20px; }
+		.container { max-width: 900px; margin: 0 auto; }
+		.header { display: flex; justify-content: space-between; align-items: center; margin-bottom: 30px; }
+		.back-btn { background: #4a9eff; color: white; border: none; padding: 10px 20px; border-radius: 4px; cursor: pointer; text-decoration: none; display: inline-block; }
+		.back-btn:hover { background: #5aaeff; }
+		.panel { background: #2a2a2a; border-radius: 8px; padding: 20px; margin-bottom: 20px; }
+		.panel h2 { font-size: 18px; margin-bottom: 10px; }
+		.hint { color: #aaa; font-size: 14px; margin-bottom: 10px; }
+		textarea, input { width: 100%; padding: 10px; border: 1px solid #444; border-radius: 4px; background: #1a1a1a; color: #fff; font-size: 14px; margin-bottom: 10px; }
+		textarea { height: 420px; font-family: monospace; }
+		button { background: #28a745; color: white; border: none; padding: 10px 20px; border-radius: 4px; cursor: pointer; }
+		button:hover { background: #218838; }
+		button:disabled { background: #555; cursor: not-allowed; }
+		.status { margin-left: 10px; color: #aaa; }
+		.status.error { color: #dc3545; }
+		pre { margin-top: 10px; padding: 10px; background: #1a1a1a; border-radius: 4px; white-space: pre-wrap; }
+	</style>
+	` + csrfFetchScript + `
+	` + searchScript + `
+</head>
+<body>
+	<div class="container">
+		<div class="header">
+			<h1>Parser Rules</h1>
+			<a href="/" class="back-btn">← Back to Shows</a>
+		</div>
+		<div class="panel">
+			<p class="hint">Rules are checked before the built-in heuristics: <em>shows</em> override the name, extras and episode patterns of matching torrents, <em>episodes</em> map file names to seasons and episodes for every show. {{if .File}}Saved to {{.File}} and reloaded by every process using it.{{else}}Set PARSER_RULES_FILE to save changes.{{end}}</p>
+			<textarea id="rules" spellcheck="false"></textarea>
+			<button id="saveBtn" onclick="saveRules()" {{if not .File}}disabled{{end}}>Save</button>
+			<span class="status" id="status"></span>
+		</div>
+		<div class="panel">
+			<h2>Try it</h2>
+			<p class="hint">Parses with the saved rules.</p>
+			<input type="text" id="torrentName" placeholder="Torrent name, e.g. The Office Season 2">
+			<input type="text" id="filePath" placeholder="File path, e.g. Season 2/The.Office.S02E01.mkv">
+			<button onclick="testRules()">Parse</button>
+			<pre id="result" style="display: none;"></pre>
+		</div>
+	</div>
+	<script>
+		function setStatus(text, error) {
+			const status = document.getElementById('status');
+			status.textContent = text;
+			status.className = error ? 'status error' : 'status';
+		}
+
+		function loadRules() {
+			fetch('/api/parser/rules')
+				.then(r => r.json())
+				.then(data => {
+					document.getElementById('rules').value = JSON.stringify(data.rules, null, 2);
+				});
+		}
+
+		function saveRules() {
+			setStatus('Saving…');
+			fetch('/api/parser/rules', {
+				method: 'PUT',
+				headers: { 'Content-Type': 'application/json' },
+				body: document.getElementById('rules').value
+			})
+				.then(r => r.ok ? r.json() : r.text().then(text => { throw new Error(text); }))
+				.then(() => setStatus('Saved'))
+				.catch(error => setStatus(error.message, true));
+		}
+
+		function testRules() {
+			fetch('/api/parser/test', {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({
+					torrentName: document.getElementById('torrentName').value,
+					filePath: document.getElementById('filePath').value
+				})
+			})
+				.then(r => r.ok ? r.json() : r.text().then(text => { throw new Error(text); }))
+				.then(info => { showResult(JSON.stringify(info, null, 2)); })
+				.catch(error => { showResult(error.message); });
+		}
+
+		function showResult(text) {
+			const result = document.getElementById('result');
+			result.textContent = text;
+			result.style.display = 'block';
+		}
+
+		loadRules();
+	</script>
+</body>
+</html>`
+
+	t, _ := template.New("rules").Parse(tmpl)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t.Execute(w, map[string]interface{}{
+		"File": s.rulesFile,
+	})
+}
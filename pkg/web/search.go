@@ -0,0 +1,185 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+const (
+	// defaultSearchLimit and maxSearchLimit bound the number of episodes /api/search returns
+	defaultSearchLimit = 50
+	maxSearchLimit     = 200
+)
+
+// searchScript adds a search box with instant results to the top of the page's .container
+const searchScript = `<script>
+		document.addEventListener('DOMContentLoaded', function() {
+			const container = document.querySelector('.container');
+			if (!container) return;
+			const style = document.createElement('style');
+			style.textContent = '.search-box { position: relative; margin-bottom: 20px; }' +
+				'.search-box input { width: 100%; padding: 10px 14px; border: 1px solid #444; border-radius: 4px; background: #2a2a2a; color: #fff; font-size: 15px; }' +
+				'.search-box input:focus { outline: none; border-color: #4a9eff; }' +
+				'.search-results { display: none; position: absolute; left: 0; right: 0; top: 100%; z-index: 900; background: #2a2a2a; border: 1px solid #444; border-radius: 4px; margin-top: 4px; max-height: 70vh; overflow-y: auto; }' +
+				'.search-show { padding: 10px 14px 4px; font-weight: bold; }' +
+				'.search-show a { color: #4a9eff; }' +
+				'.search-episode { display: block; padding: 6px 14px 6px 28px; color: #ddd; text-decoration: none; font-size: 14px; }' +
+				'.search-episode:hover { background: #3a3a3a; }' +
+				'.search-episode .label { color: #aaa; margin-right: 8px; }' +
+				'.search-empty { padding: 10px 14px; color: #aaa; }';
+			document.head.appendChild(style);
+
+			const box = document.createElement('div');
+			box.className = 'search-box';
+			box.innerHTML = '<input type="search" placeholder="Search shows, episodes and files…" autocomplete="off"><div class="search-results"></div>';
+			container.insertBefore(box, container.firstChild);
+			const input = box.querySelector('input');
+			const results = box.querySelector('.search-results');
+
+			const escape = function(text) {
+				const div = document.createElement('div');
+				div.textContent = text;
+				return div.innerHTML;
+			};
+			let timer = null;
+			let latest = 0;
+			input.addEventListener('input', function() {
+				clearTimeout(timer);
+				const q = input.value.trim();
+				if (q.length < 2) {
+					results.style.display = 'none';
+					return;
+				}
+				timer = setTimeout(function() {
+					const request = ++latest;
+					fetch('/api/search?q=' + encodeURIComponent(q))
+						.then(r => r.json())
+						.then(data => {
+							if (request !== latest) return;
+							if (data.shows.length === 0) {
+								results.innerHTML = '<div class="search-empty">No results</div>';
+							} else {
+								results.innerHTML = data.shows.map(show =>
+									'<div class="search-show"><a href="' + show.url + '">' + escape(show.name) + '</a></div>' +
+									show.episodes.map(ep =>
+										'<a class="search-episode" href="' + ep.url + '"><span class="label">' + escape(ep.label) + '</span>' + escape(ep.title) + '</a>'
+									).join('')
+								).join('');
+							}
+							results.style.display = 'block';
+						});
+				}, 200);
+			});
+			input.addEventListener('keydown', function(e) {
+				if (e.key === 'Escape') {
+					results.style.display = 'none';
+					input.blur();
+				}
+			});
+			document.addEventListener('click', function(e) {
+				if (!box.contains(e.target)) results.style.display = 'none';
+			});
+		});
+	</script>`
+
+// searchEpisode is an episode in /api/search results
+type searchEpisode struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Season   int    `json:"season"`
+	Episode  int    `json:"episode"`
+	Label    string `json:"label"` // SxxEyy, or Extras for videos without an episode number
+	Uploaded bool   `json:"uploaded"`
+	URL      string `json:"url"` // Season page that opens the player
+}
+
+// searchShow groups the episodes of one show in /api/search results
+type searchShow struct {
+	Name     string          `json:"name"`
+	URL      string          `json:"url"`
+	Episodes []searchEpisode `json:"episodes"`
+}
+
+// handleAPISearch searches shows, episode titles, file paths and torrent names
+// URL: GET /api/search?q=...&limit=50; results are grouped by show, best matching show first
+func (s *Server) handleAPISearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	limit := defaultSearchLimit
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	videos, err := s.db.SearchVideos(query, limit)
+	if err != nil {
+		log.Printf("Error searching for %q: %v", query, err)
+		http.Error(w, "Search failed", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"query": query,
+		"shows": groupSearchResults(videos),
+	})
+}
+
+// groupSearchResults groups videos by show, keeping the order in which shows first appear.
+// Movies are grouped under "Movies"
+func groupSearchResults(videos []database.Video) []searchShow {
+	shows := []searchShow{}
+	index := make(map[string]int)
+	movies := -1
+	for _, v := range videos {
+		if v.IsMovie() {
+			if movies < 0 {
+				movies = len(shows)
+				shows = append(shows, searchShow{Name: "Movies", URL: "/movies"})
+			}
+			shows[movies].Episodes = append(shows[movies].Episodes, searchEpisode{
+				ID:       v.ID,
+				Title:    v.Title,
+				Label:    movieLabel(v.Movie),
+				Uploaded: v.Uploaded(),
+				URL:      fmt.Sprintf("/movies?play=%d", v.ID),
+			})
+			continue
+		}
+
+		name := v.ShowName
+		if name == "" {
+			name = v.Title
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(shows)
+			index[name] = i
+			shows = append(shows, searchShow{Name: name, URL: "/show/" + url.PathEscape(name)})
+		}
+
+		label := "Extras"
+		if v.SeasonNumber > 0 && v.EpisodeNumber > 0 {
+			label = fmt.Sprintf("S%02dE%02d", v.SeasonNumber, v.EpisodeNumber)
+		} else if v.SeasonNumber > 0 {
+			label = fmt.Sprintf("S%02d", v.SeasonNumber)
+		}
+		shows[i].Episodes = append(shows[i].Episodes, searchEpisode{
+			ID:       v.ID,
+			Title:    v.Title,
+			Season:   v.SeasonNumber,
+			Episode:  v.EpisodeNumber,
+			Label:    label,
+			Uploaded: v.Uploaded(),
+			URL:      fmt.Sprintf("/show/%s/season/%d?play=%d", url.PathEscape(name), v.SeasonNumber, v.ID),
+		})
+	}
+	return shows
+}
@@ -0,0 +1,276 @@
+package web
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Login throttling: after loginMaxFailures failed logins from one IP within
+// loginFailureWindow, further attempts are refused for loginLockout
+const (
+	loginMaxFailures   = 5
+	loginFailureWindow = 15 * time.Minute
+	loginLockout       = 15 * time.Minute
+)
+
+// csrfCookieName is the script-readable cookie holding the CSRF token of the session
+const csrfCookieName = "csrf_token"
+
+// csrfHeaderName is the header state-changing requests must echo the CSRF token in
+const csrfHeaderName = "X-CSRF-Token"
+
+// csrfFetchScript makes fetch() send the CSRF token with state-changing requests
+// It is included in the <head> of every page that modifies data
+const csrfFetchScript = `<script>
+		(function() {
+			const originalFetch = window.fetch;
+			window.fetch = function(input, init) {
+				init = init || {};
+				const method = (init.method || 'GET').toUpperCase();
+				const match = document.cookie.match(/(?:^|; )csrf_token=([^;]*)/);
+				if (method !== 'GET' && method !== 'HEAD' && match) {
+					init.headers = new Headers(init.headers || {});
+					init.headers.set('X-CSRF-Token', decodeURIComponent(match[1]));
+				}
+				return originalFetch(input, init);
+			};
+		})();
+	</script>`
+
+// securityHeaders wraps a handler to add headers that keep pages out of frames and stop MIME sniffing
+func securityHeaders(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.Header().Set("X-Frame-Options", "DENY")
+		w.Header().Set("Referrer-Policy", "same-origin")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// TrustedProxies are the reverse proxies whose X-Forwarded-* and X-Real-IP headers are believed
+// Requests from any other address are taken at face value, so those headers cannot be spoofed
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses a comma-separated list of IP addresses and CIDR ranges, e.g.
+// "127.0.0.1, 10.0.0.0/8"; an empty list trusts no proxy
+func ParseTrustedProxies(list string) (TrustedProxies, error) {
+	var proxies TrustedProxies
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies, nil
+}
+
+// Contains reports whether ip (without a port) is one of the trusted proxies
+func (p TrustedProxies) Contains(ip string) bool {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return false
+	}
+	for _, network := range p {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTrustedProxies sets the reverse proxies allowed to report the client address and scheme
+func (s *Server) SetTrustedProxies(proxies TrustedProxies) {
+	s.proxies = proxies
+}
+
+// remoteHost returns the address the request's connection came from
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// fromTrustedProxy reports whether a request came through one of the configured reverse proxies
+func (s *Server) fromTrustedProxy(r *http.Request) bool {
+	return s.proxies.Contains(remoteHost(r))
+}
+
+// isSecureRequest reports whether the client connected over HTTPS, directly or through a trusted proxy
+func (s *Server) isSecureRequest(r *http.Request) bool {
+	if r.TLS != nil {
+		return true
+	}
+	return s.fromTrustedProxy(r) && strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
+// clientIP returns the address of the client, taken from X-Real-IP or X-Forwarded-For behind a trusted proxy
+func (s *Server) clientIP(r *http.Request) string {
+	if !s.fromTrustedProxy(r) {
+		return remoteHost(r)
+	}
+	if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); ip != "" {
+		return ip
+	}
+	// Entries are appended by each proxy; the rightmost one not added by a trusted proxy is the
+	// client, anything left of it is client-controlled
+	parts := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(parts) - 1; i >= 0; i-- {
+		ip := strings.TrimSpace(parts[i])
+		if ip != "" && !s.proxies.Contains(ip) {
+			return ip
+		}
+	}
+	return remoteHost(r)
+}
+
+// setCookie sets a cookie with SameSite=Lax, marked Secure when the request arrived over HTTPS
+func (s *Server) setCookie(w http.ResponseWriter, r *http.Request, name, value string, maxAge int, httpOnly bool) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: httpOnly,
+		Secure:   s.isSecureRequest(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// csrfToken derives the CSRF token of a session
+// Binding it to the secret session ID means a token set by a sibling domain cannot be forged
+func csrfToken(sessionID string) string {
+	sum := sha256.Sum256([]byte("csrf:" + sessionID))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// checkCSRF validates the CSRF token of a cookie-authenticated request
+// Safe methods pass; others must send the session's token in X-CSRF-Token or a csrf_token form field
+func checkCSRF(r *http.Request, sessionID string) bool {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	sent := r.Header.Get(csrfHeaderName)
+	if sent == "" {
+		sent = r.PostFormValue(csrfCookieName)
+	}
+	return sent != "" && subtle.ConstantTimeCompare([]byte(sent), []byte(csrfToken(sessionID))) == 1
+}
+
+// ensureCSRFCookie gives the page scripts the session's CSRF token, e.g. for sessions created before it existed
+func (s *Server) ensureCSRFCookie(w http.ResponseWriter, r *http.Request, sessionID string) {
+	token := csrfToken(sessionID)
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && cookie.Value == token {
+		return
+	}
+	s.setCookie(w, r, csrfCookieName, token, int(sessionTTL.Seconds()), false)
+}
+
+// safeRedirect returns target if it is a path on this site, and "/" otherwise
+// This prevents the login form from being used as an open redirect
+func safeRedirect(target string) string {
+	if target == "" || !strings.HasPrefix(target, "/") || strings.HasPrefix(target, "//") || strings.HasPrefix(target, "/\\") {
+		return "/"
+	}
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "" || u.Host != "" || strings.ContainsAny(target, "\r\n") {
+		return "/"
+	}
+	return target
+}
+
+// loginFailures tracks failed logins of one client
+type loginFailures struct {
+	count       int
+	first       time.Time
+	lockedUntil time.Time
+}
+
+// LoginLimiter throttles password guessing per client IP
+type LoginLimiter struct {
+	mu       sync.Mutex
+	failures map[string]*loginFailures
+}
+
+// NewLoginLimiter creates an empty login limiter
+func NewLoginLimiter() *LoginLimiter {
+	return &LoginLimiter{failures: make(map[string]*loginFailures)}
+}
+
+// Allowed reports whether ip may attempt a login, and if not, how long it is locked out
+func (l *LoginLimiter) Allowed(ip string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, ok := l.failures[ip]
+	if !ok {
+		return true, 0
+	}
+	if wait := time.Until(f.lockedUntil); wait > 0 {
+		return false, wait
+	}
+	return true, 0
+}
+
+// Failure records a failed login and locks the ip out once it reaches the limit
+func (l *LoginLimiter) Failure(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	f, ok := l.failures[ip]
+	if !ok || now.Sub(f.first) > loginFailureWindow {
+		f = &loginFailures{first: now}
+		l.failures[ip] = f
+	}
+	f.count++
+	if f.count >= loginMaxFailures {
+		f.lockedUntil = now.Add(loginLockout)
+		f.count = 0
+		f.first = now
+	}
+}
+
+// Success forgets the failures of ip
+func (l *LoginLimiter) Success(ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.failures, ip)
+}
+
+// Cleanup drops entries whose window and lockout have passed
+func (l *LoginLimiter) Cleanup() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	for ip, f := range l.failures {
+		if now.Sub(f.first) > loginFailureWindow && now.After(f.lockedUntil) {
+			delete(l.failures, ip)
+		}
+	}
+}
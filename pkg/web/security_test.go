@@ -0,0 +1,202 @@
+package web
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSafeRedirect(t *testing.T) {
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{"", "/"},
+		{"/", "/"},
+		{"/show/Breaking%20Bad", "/show/Breaking%20Bad"},
+		{"/watch/12?t=30", "/watch/12?t=30"},
+		{"https://evil.example/", "/"},
+		{"//evil.example/", "/"},
+		{"/\\evil.example/", "/"},
+		{"evil.example", "/"},
+		{"javascript:alert(1)", "/"},
+		{"/ok\r\nSet-Cookie: session=x", "/"},
+	}
+
+	for _, tt := range tests {
+		if got := safeRedirect(tt.target); got != tt.expected {
+			t.Errorf("safeRedirect(%q) = %q, expected %q", tt.target, got, tt.expected)
+		}
+	}
+}
+
+func TestCheckCSRF(t *testing.T) {
+	const sessionID = "session-id"
+	valid := csrfToken(sessionID)
+
+	tests := []struct {
+		name     string
+		method   string
+		header   string
+		form     string
+		expected bool
+	}{
+		{"GET without token", "GET", "", "", true},
+		{"HEAD without token", "HEAD", "", "", true},
+		{"OPTIONS without token", "OPTIONS", "", "", true},
+		{"POST without token", "POST", "", "", false},
+		{"POST with header", "POST", valid, "", true},
+		{"POST with form field", "POST", "", valid, true},
+		{"POST with wrong header", "POST", "wrong", "", false},
+		{"POST with other session's token", "POST", csrfToken("other-session"), "", false},
+		{"DELETE with header", "DELETE", valid, "", true},
+		{"DELETE without token", "DELETE", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := ""
+			if tt.form != "" {
+				body = url.Values{csrfCookieName: {tt.form}}.Encode()
+			}
+			req := httptest.NewRequest(tt.method, "/api/videos/1", strings.NewReader(body))
+			if tt.form != "" {
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			}
+			if tt.header != "" {
+				req.Header.Set(csrfHeaderName, tt.header)
+			}
+			if got := checkCSRF(req, sessionID); got != tt.expected {
+				t.Errorf("checkCSRF() = %v, expected %v", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLoginLimiter(t *testing.T) {
+	l := NewLoginLimiter()
+	const ip = "203.0.113.7"
+
+	for i := 1; i < loginMaxFailures; i++ {
+		l.Failure(ip)
+		if allowed, _ := l.Allowed(ip); !allowed {
+			t.Fatalf("locked out after %d failures, expected %d", i, loginMaxFailures)
+		}
+	}
+	l.Failure(ip)
+	allowed, wait := l.Allowed(ip)
+	if allowed {
+		t.Fatalf("still allowed after %d failures", loginMaxFailures)
+	}
+	if wait <= 0 || wait > loginLockout {
+		t.Errorf("lockout of %v, expected up to %v", wait, loginLockout)
+	}
+	if allowed, _ := l.Allowed("203.0.113.8"); !allowed {
+		t.Error("lockout applies to another address")
+	}
+
+	// The lockout expires
+	l.failures[ip].lockedUntil = time.Now().Add(-time.Second)
+	if allowed, _ := l.Allowed(ip); !allowed {
+		t.Error("still locked out after the lockout passed")
+	}
+
+	// Failures older than the window are forgotten
+	for i := 1; i < loginMaxFailures; i++ {
+		l.Failure(ip)
+	}
+	l.failures[ip].first = time.Now().Add(-loginFailureWindow - time.Minute)
+	l.Failure(ip)
+	if allowed, _ := l.Allowed(ip); !allowed {
+		t.Error("failures outside the window counted towards the lockout")
+	}
+
+	// A successful login resets the count
+	l.Success(ip)
+	for i := 1; i < loginMaxFailures; i++ {
+		l.Failure(ip)
+	}
+	if allowed, _ := l.Allowed(ip); !allowed {
+		t.Error("failures before a successful login counted towards the lockout")
+	}
+
+	// Cleanup drops stale entries only
+	l.failures["198.51.100.1"] = &loginFailures{count: 1, first: time.Now().Add(-2 * loginFailureWindow)}
+	l.Cleanup()
+	if _, ok := l.failures["198.51.100.1"]; ok {
+		t.Error("Cleanup kept a stale entry")
+	}
+	if _, ok := l.failures[ip]; !ok {
+		t.Error("Cleanup dropped a current entry")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies("127.0.0.1, 10.0.0.0/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		proxies    TrustedProxies
+		remoteAddr string
+		realIP     string
+		forwarded  string
+		expected   string
+	}{
+		{"direct", proxies, "203.0.113.7:5000", "", "", "203.0.113.7"},
+		{"spoofed X-Real-IP from a client", proxies, "203.0.113.7:5000", "198.51.100.1", "", "203.0.113.7"},
+		{"spoofed X-Real-IP from an untrusted private address", proxies, "192.168.1.5:5000", "198.51.100.1", "", "192.168.1.5"},
+		{"no proxies configured", nil, "127.0.0.1:5000", "198.51.100.1", "", "127.0.0.1"},
+		{"X-Real-IP from a trusted proxy", proxies, "127.0.0.1:5000", "198.51.100.1", "", "198.51.100.1"},
+		{"X-Forwarded-For from a trusted proxy", proxies, "10.1.2.3:5000", "", "198.51.100.9, 198.51.100.1", "198.51.100.1"},
+		{"X-Forwarded-For through two trusted proxies", proxies, "127.0.0.1:5000", "", "198.51.100.1, 10.1.2.3", "198.51.100.1"},
+		{"trusted proxy without headers", proxies, "127.0.0.1:5000", "", "", "127.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{proxies: tt.proxies}
+			req := httptest.NewRequest("POST", "/login", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if got := s.clientIP(req); got != tt.expected {
+				t.Errorf("clientIP() = %q, expected %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	proxies, err := ParseTrustedProxies(" 127.0.0.1 ,::1, 172.16.0.0/12,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ip := range []string{"127.0.0.1", "::1", "172.16.0.1", "172.31.255.255"} {
+		if !proxies.Contains(ip) {
+			t.Errorf("%s not trusted", ip)
+		}
+	}
+	for _, ip := range []string{"127.0.0.2", "172.32.0.1", "192.168.0.1", "not-an-ip"} {
+		if proxies.Contains(ip) {
+			t.Errorf("%s trusted", ip)
+		}
+	}
+
+	if proxies, err := ParseTrustedProxies(""); err != nil || len(proxies) != 0 {
+		t.Errorf("ParseTrustedProxies(\"\") = %v, %v, expected no proxies", proxies, err)
+	}
+	for _, list := range []string{"localhost", "10.0.0.0/33", "10.0.0.1,bogus"} {
+		if _, err := ParseTrustedProxies(list); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) accepted an invalid entry", list)
+		}
+	}
+}
@@ -2,9 +2,6 @@
 package web
 
 import (
-	"bytes"
-	"crypto/rand"
-	"encoding/base64"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -14,37 +11,48 @@ import (
 	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/events"
+	"github.com/rusik69/trtg/pkg/metadata"
+	"github.com/rusik69/trtg/pkg/notify"
+	"github.com/rusik69/trtg/pkg/parser"
 	"github.com/rusik69/trtg/pkg/telegram"
+	"github.com/rusik69/trtg/pkg/torrent"
 )
 
 // Server handles HTTP requests for the web interface
 type Server struct {
-	db             *database.DB
-	downloadDir    string
-	trtgAPIURL     string // URL for trtg download API (fallback)
-	downloader     *telegram.Downloader
-	mux            *http.ServeMux
-	username       string
-	password       string
-	sessions       map[string]time.Time
-	sessionsMu     sync.RWMutex
-	currentVideo   int64 // Track currently playing video for cleanup
-	currentVideoMu sync.Mutex
-	token          string // Telegram bot token for local file access
-	chatID         int64  // Telegram chat ID
-	apiURL         string // Telegram API URL
+	db            *database.DB
+	auth          authStore // Session and API token lookups, db outside tests
+	downloadDir   string
+	trtgAPIURL    string // URL for trtg download API (fallback)
+	downloader    *telegram.Downloader
+	mux           *http.ServeMux
+	token         string             // Telegram bot token for local file access
+	chatID        int64              // Telegram chat ID
+	apiURL        string             // Telegram API URL
+	torrents      *torrent.Streams   // Optional torrent client for streaming in-progress downloads
+	cacheLocks    keyedMutex         // Per-key mutexes for source downloads and HLS segment transcodes
+	transcodes    *TranscodeManager  // Background full-file transcodes
+	logins        *LoginLimiter      // Per-IP login throttling
+	proxies       TrustedProxies     // Reverse proxies whose forwarded headers are believed
+	telegramLogin bool               // Offer the Telegram Login Widget on the login page
+	notifier      *notify.Dispatcher // Optional notification subscriptions and browser notifications
+	events        *events.Bus        // Optional database changes for live page updates
+	rulesFile     string             // JSON file parser rules edited in the UI are saved to
+	metadata      *metadata.Client   // Optional episode titles and artwork
+	staticDir     string             // Files served under /static/
 }
 
 // NewServer creates a new web server
-func NewServer(db *database.DB, downloadDir, trtgAPIURL, username, password, telegramToken string, telegramChatID int64, telegramAPIURL string) *Server {
+func NewServer(db *database.DB, downloadDir, trtgAPIURL, telegramToken string, telegramChatID int64, telegramAPIURL string) *Server {
 	var downloader *telegram.Downloader
 	if telegramToken != "" && telegramAPIURL != "" {
 		var err error
@@ -58,16 +66,16 @@ func NewServer(db *database.DB, downloadDir, trtgAPIURL, username, password, tel
 
 	s := &Server{
 		db:          db,
+		auth:        db,
 		downloadDir: downloadDir,
 		trtgAPIURL:  trtgAPIURL,
 		downloader:  downloader,
 		mux:         http.NewServeMux(),
-		username:    username,
-		password:    password,
-		sessions:    make(map[string]time.Time),
 		token:       telegramToken,
 		chatID:      telegramChatID,
 		apiURL:      telegramAPIURL,
+		transcodes:  NewTranscodeManager(downloadDir, DefaultTranscodeWorkers),
+		logins:      NewLoginLimiter(),
 	}
 
 	log.Printf("Initializing web server with trtg API URL: %s", trtgAPIURL)
@@ -76,9 +84,13 @@ func NewServer(db *database.DB, downloadDir, trtgAPIURL, username, password, tel
 	if err := os.MkdirAll(downloadDir, 0755); err != nil {
 		log.Printf("Warning: Failed to create download directory: %v", err)
 	}
+	if err := os.MkdirAll(s.CacheDir(), 0755); err != nil {
+		log.Printf("Warning: Failed to create cache directory: %v", err)
+	}
 
 	// Setup routes
 	s.mux.HandleFunc("/login", s.handleLogin)
+	s.mux.HandleFunc("/login/telegram", s.handleTelegramLogin)
 	s.mux.HandleFunc("/logout", s.handleLogout)
 	s.mux.HandleFunc("/", s.requireAuth(s.handleIndex))
 	s.mux.HandleFunc("/channel/", s.requireAuth(s.handleChannel))
@@ -87,10 +99,35 @@ func NewServer(db *database.DB, downloadDir, trtgAPIURL, username, password, tel
 	s.mux.HandleFunc("/api/channel/", s.requireAuth(s.handleAPIChannel))
 	s.mux.HandleFunc("/api/shows", s.requireAuth(s.handleAPIShows))
 	s.mux.HandleFunc("/api/show/", s.requireAuth(s.handleAPIShow))
-	s.mux.HandleFunc("/api/episode/", s.requireAuth(s.handleAPIDeleteEpisode))
-	s.mux.HandleFunc("/api/move-to-extras/", s.requireAuth(s.handleAPIMoveToExtras))
+	s.mux.HandleFunc("/movies", s.requireAuth(s.handleMoviesPage))
+	s.mux.HandleFunc("/api/movies", s.requireAuth(s.handleAPIMovies))
+	s.mux.HandleFunc("/api/episode/", s.requireAdmin(s.handleAPIEpisode))
+	s.mux.HandleFunc("/api/episodes/bulk", s.requireAdmin(s.handleAPIBulkEditEpisodes))
+	s.mux.HandleFunc("/api/move-to-extras/", s.requireAdmin(s.handleAPIMoveToExtras))
 	s.mux.HandleFunc("/api/stream/", s.requireAuth(s.handleAPIStream))
+	s.mux.HandleFunc("/api/playback/", s.requireAuth(s.handleAPIPlayback))
+	s.mux.HandleFunc("/api/hls/", s.requireAuth(s.handleAPIHLS))
+	s.mux.HandleFunc("/api/transcode/", s.requireAuth(s.handleAPITranscode))
+	s.mux.HandleFunc("/api/tracks/", s.requireAuth(s.handleAPITracks))
+	s.mux.HandleFunc("/api/subtitles/", s.requireAuth(s.handleAPISubtitles))
+	s.mux.HandleFunc("/api/progress/", s.requireAuth(s.handleAPIProgress))
+	s.mux.HandleFunc("/api/continue-watching", s.requireAuth(s.handleAPIContinueWatching))
+	s.mux.HandleFunc("/api/me", s.requireAuth(s.handleAPIMe))
+	s.mux.HandleFunc("/api/tokens", s.requireAuth(s.handleAPITokens))
+	s.mux.HandleFunc("/api/tokens/", s.requireAuth(s.handleAPIToken))
+	s.mux.HandleFunc("/tokens", s.requireAuth(s.handleTokensPage))
+	s.mux.HandleFunc("/api/notifications", s.requireAuth(s.handleAPINotifications))
+	s.mux.HandleFunc("/api/notifications/", s.requireAuth(s.handleAPINotification))
+	s.mux.HandleFunc("/api/events", s.requireAuth(s.handleAPIEvents))
+	s.mux.HandleFunc("/api/search", s.requireAuth(s.handleAPISearch))
+	s.mux.HandleFunc("/api/parser/rules", s.requireAdmin(s.handleAPIParserRules))
+	s.mux.HandleFunc("/api/parser/test", s.requireAdmin(s.handleAPIParserTest))
+	s.mux.HandleFunc("/rules", s.requireAdmin(s.handleRulesPage))
+	s.mux.HandleFunc("/api/review", s.requireAdmin(s.handleAPIReview))
+	s.mux.HandleFunc("/api/review/", s.requireAdmin(s.handleAPIReviewVideo))
+	s.mux.HandleFunc("/review", s.requireAdmin(s.handleReviewPage))
 	s.mux.HandleFunc("/api/status/", s.requireAuth(s.handleAPIStatus))
+	s.mux.HandleFunc("/api/torrent-stream/", s.requireAuth(s.handleAPITorrentStream))
 	s.mux.HandleFunc("/static/", s.handleStatic)
 
 	// Clean up expired sessions periodically
@@ -101,7 +138,7 @@ func NewServer(db *database.DB, downloadDir, trtgAPIURL, username, password, tel
 
 // ServeHTTP implements http.Handler
 func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	s.mux.ServeHTTP(w, r)
+	securityHeaders(s.mux).ServeHTTP(w, r)
 }
 
 // handleIndex shows the channel list page
@@ -135,7 +172,18 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 		.show-name { font-size: 18px; font-weight: bold; margin-bottom: 10px; }
 		.show-info { color: #aaa; font-size: 14px; }
 		a { text-decoration: none; color: inherit; }
+		.continue-watching { display: none; margin-bottom: 30px; }
+		.continue-watching h2 { font-size: 20px; margin-bottom: 15px; }
+		.continue-row { display: flex; gap: 15px; overflow-x: auto; padding-bottom: 10px; }
+		.continue-card { background: #2a2a2a; border-radius: 8px; padding: 15px; min-width: 240px; max-width: 240px; transition: background 0.2s; }
+		.continue-card:hover { background: #3a3a3a; }
+		.continue-kind { color: #4a9eff; font-size: 12px; text-transform: uppercase; margin-bottom: 5px; }
+		.continue-title { color: #aaa; font-size: 12px; margin-top: 5px; white-space: nowrap; overflow: hidden; text-overflow: ellipsis; }
+		.progress-bar { height: 4px; background: #444; border-radius: 2px; margin-top: 10px; overflow: hidden; }
+		.progress-bar div { height: 100%; background: #dc3545; }
 	</style>
+	` + eventsScript + `
+	` + searchScript + `
 </head>
 <body>
 	<div class="container">
@@ -144,25 +192,75 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 				<h1>TV Shows</h1>
 			</div>
 			<div style="display: flex; gap: 10px; align-items: center;">
+				<a href="/movies" class="view-btn">Movies</a>
+				<a href="/review" class="view-btn" id="reviewLink" style="display: none;">Needs Review</a>
+				<a href="/rules" class="view-btn" id="rulesLink" style="display: none;">Parser Rules</a>
+				<a href="/tokens" class="view-btn">API Tokens</a>
 				<a href="/logout" class="logout-btn">Logout</a>
 			</div>
 		</div>
+		<div class="continue-watching" id="continueWatching">
+			<h2>Continue watching</h2>
+			<div class="continue-row" id="continueRow"></div>
+		</div>
 		<div class="shows" id="shows"></div>
 	</div>
 	<script>
-		fetch('/api/shows')
+		fetch('/api/continue-watching')
 			.then(r => r.json())
-			.then(shows => {
-				const container = document.getElementById('shows');
-				shows.forEach(show => {
+			.then(items => {
+				if (items.length === 0) {
+					return;
+				}
+				const row = document.getElementById('continueRow');
+				items.forEach(item => {
 					const card = document.createElement('a');
-					card.href = '/show/' + encodeURIComponent(show.name);
-					card.className = 'show-card';
-					const seasonText = show.seasonCount === 1 ? '1 season' : show.seasonCount + ' seasons';
-					const episodeText = show.episodeCount === 1 ? '1 episode' : show.episodeCount + ' episodes';
-					card.innerHTML = '<div class="show-name">' + escapeHtml(show.name) + '</div><div class="show-info">' + seasonText + ' • ' + episodeText + '</div>';
-					container.appendChild(card);
+					card.href = item.movie
+						? '/movies?play=' + item.videoId
+						: '/show/' + encodeURIComponent(item.showName) + '/season/' + item.seasonNumber + '?play=' + item.videoId;
+					card.className = 'continue-card';
+					const episode = item.seasonNumber > 0 && item.episodeNumber > 0
+						? 'S' + String(item.seasonNumber).padStart(2, '0') + 'E' + String(item.episodeNumber).padStart(2, '0')
+						: '';
+					let progress = '';
+					if (item.kind === 'continue' && item.duration > 0) {
+						progress = '<div class="progress-bar"><div style="width: ' + Math.min(100, item.position / item.duration * 100).toFixed(1) + '%"></div></div>';
+					}
+					card.innerHTML = '<div class="continue-kind">' + (item.kind === 'next' ? 'Next episode' : 'Continue') + '</div>' +
+						'<div class="show-name">' + escapeHtml(item.showName) + ' ' + episode + '</div>' +
+						'<div class="continue-title">' + escapeHtml(item.title) + '</div>' + progress;
+					row.appendChild(card);
 				});
+				document.getElementById('continueWatching').style.display = 'block';
+			});
+
+		function loadShows() {
+			fetch('/api/shows')
+				.then(r => r.json())
+				.then(shows => {
+					const container = document.getElementById('shows');
+					container.innerHTML = '';
+					(shows || []).forEach(show => {
+						const card = document.createElement('a');
+						card.href = '/show/' + encodeURIComponent(show.name);
+						card.className = 'show-card';
+						const seasonText = show.seasonCount === 1 ? '1 season' : show.seasonCount + ' seasons';
+						const episodeText = show.episodeCount === 1 ? '1 episode' : show.episodeCount + ' episodes';
+						card.innerHTML = '<div class="show-name">' + escapeHtml(show.name) + '</div><div class="show-info">' + seasonText + ' • ' + episodeText + '</div>';
+						container.appendChild(card);
+					});
+				});
+		}
+		loadShows();
+		onArchiveChange(e => e.table === 'videos' && (e.op !== 'UPDATE' || e.show !== e.oldShow), loadShows);
+
+		fetch('/api/me')
+			.then(r => r.json())
+			.then(user => {
+				if (user.role === 'admin') {
+					document.getElementById('reviewLink').style.display = 'inline-block';
+					document.getElementById('rulesLink').style.display = 'inline-block';
+				}
 			});
 
 		function escapeHtml(text) {
@@ -222,6 +320,9 @@ func (s *Server) handleChannel(w http.ResponseWriter, r *http.Request) {
 		.audio-track-selector select { background: #2a2a2a; color: white; border: 1px solid #444; border-radius: 4px; padding: 5px 10px; font-size: 14px; cursor: pointer; }
 		.audio-track-selector select:focus { outline: none; border-color: #4a9eff; }
 	</style>
+	` + hlsScript + `
+	` + csrfFetchScript + `
+	` + searchScript + `
 </head>
 <body>
 	<div class="container">
@@ -277,6 +378,8 @@ func (s *Server) handleChannel(w http.ResponseWriter, r *http.Request) {
 				});
 		}
 		
+		let hlsPlayer = null;
+
 		function playVideo(videoId) {
 			currentVideoId = videoId;
 			
@@ -284,6 +387,7 @@ func (s *Server) handleChannel(w http.ResponseWriter, r *http.Request) {
 			const video = document.getElementById('videoElement');
 			
 			// Clear any previous error state
+			stopHls();
 			video.src = '';
 			video.load();
 			
@@ -293,10 +397,6 @@ func (s *Server) handleChannel(w http.ResponseWriter, r *http.Request) {
 			player.appendChild(statusMsg);
 			player.classList.add('active');
 			
-			// Stream directly from trtg (which handles downloads on-demand)
-			const streamUrl = '/api/stream/' + videoId;
-			console.log('Starting stream from:', streamUrl);
-			
 			// Clear previous error handlers
 			video.onerror = null;
 			video.oncanplay = null;
@@ -334,11 +434,56 @@ func (s *Server) handleChannel(w http.ResponseWriter, r *http.Request) {
 				detectAudioTracks();
 			};
 
-			// Set the source and load (trtg will download on-demand)
-			video.src = streamUrl;
-			video.load();
+			// Ask the server whether the file plays natively or needs on-the-fly HLS transcoding
+			fetch('/api/playback/' + videoId)
+				.then(r => r.json())
+				.then(playback => {
+					if (playback.mode === 'hls') {
+						playHls(playback.url, playback.fallbackUrl);
+					} else {
+						video.src = playback.url;
+						video.load();
+					}
+				})
+				.catch(() => {
+					video.src = '/api/stream/' + videoId;
+					video.load();
+				});
 		}
-		
+
+		function playHls(url, fallbackUrl) {
+			const video = document.getElementById('videoElement');
+
+			if (window.Hls && Hls.isSupported()) {
+				hlsPlayer = new Hls();
+				hlsPlayer.on(Hls.Events.ERROR, function(event, data) {
+					if (data.fatal) {
+						// Fall back to the full-file transcode if segmenting fails
+						console.log('HLS playback failed, falling back to full transcode', data);
+						stopHls();
+						video.src = fallbackUrl;
+						video.load();
+					}
+				});
+				hlsPlayer.loadSource(url);
+				hlsPlayer.attachMedia(video);
+			} else if (video.canPlayType('application/vnd.apple.mpegurl')) {
+				// Safari plays HLS natively
+				video.src = url;
+				video.load();
+			} else {
+				video.src = fallbackUrl;
+				video.load();
+			}
+		}
+
+		function stopHls() {
+			if (hlsPlayer) {
+				hlsPlayer.destroy();
+				hlsPlayer = null;
+			}
+		}
+
 		function closePlayer() {
 			currentVideoId = null;
 
@@ -348,6 +493,7 @@ func (s *Server) handleChannel(w http.ResponseWriter, r *http.Request) {
 			player.classList.remove('active');
 			audioTrackSelector.classList.remove('active');
 			video.pause();
+			stopHls();
 			video.src = '';
 		}
 
@@ -453,7 +599,20 @@ func (s *Server) handleShow(w http.ResponseWriter, r *http.Request) {
 		.season-card:hover { transform: translateY(-2px); background: #3a3a3a; }
 		.season-name { font-size: 18px; font-weight: bold; margin-bottom: 10px; }
 		.season-info { color: #aaa; font-size: 14px; }
+		.notify-btn { background: #4a9eff; color: white; border: none; padding: 10px 20px; border-radius: 4px; cursor: pointer; margin-right: 10px; }
+		.notify-btn:hover { background: #5aaeff; }
+		.show-details { display: none; gap: 20px; margin-bottom: 30px; }
+		.show-details.active { display: flex; }
+		.show-details img { width: 160px; border-radius: 8px; align-self: flex-start; }
+		.show-premiered { color: #aaa; font-size: 14px; margin-bottom: 10px; }
+		.show-overview { color: #ccc; line-height: 1.5; max-width: 800px; }
+		.season-poster { width: 100%; border-radius: 4px; margin-bottom: 10px; }
+		.gap-badge { display: inline-block; background: #ff9800; color: #1a1a1a; font-size: 12px; font-weight: bold; padding: 2px 8px; border-radius: 10px; margin-top: 8px; }
+		.gap-badge.complete { background: #4caf50; }
 	</style>
+	` + csrfFetchScript + `
+	` + eventsScript + `
+	` + searchScript + `
 </head>
 <body>
 	<div class="container">
@@ -463,17 +622,60 @@ func (s *Server) handleShow(w http.ResponseWriter, r *http.Request) {
 				<h1 id="showName"></h1>
 			</div>
 			<div>
+				<button class="notify-btn" id="notifyBtn" onclick="toggleNotify()" style="display: none;"></button>
+				{{if .IsAdmin}}
 				<button class="move-to-extras-btn" onclick="moveToExtras()">Move Episodes Without Numbers to Extras</button>
 				<button class="delete-show-btn" onclick="deleteShow()">Delete Show</button>
+				{{end}}
 				<a href="/logout" class="logout-btn">Logout</a>
 			</div>
 		</div>
+		<div class="show-details" id="showDetails">
+			<img id="showPoster" alt="" style="display: none;">
+			<div>
+				<div class="show-premiered" id="showPremiered"></div>
+				<p class="show-overview" id="showOverview"></p>
+			</div>
+		</div>
 		<div class="seasons" id="seasons"></div>
 	</div>
 	<script>
 		const showName = decodeURIComponent('{{.ShowName}}');
 		document.getElementById('showName').textContent = showName;
 
+		// Browser notification subscription for new episodes of this show
+		let notifySubscription = null;
+		function updateNotifyButton() {
+			const btn = document.getElementById('notifyBtn');
+			btn.textContent = notifySubscription ? '🔕 Stop notifications' : '🔔 Notify me of new episodes';
+			btn.style.display = 'inline-block';
+		}
+		function toggleNotify() {
+			if (notifySubscription) {
+				fetch('/api/notifications/' + notifySubscription.id, { method: 'DELETE' })
+					.then(r => { if (r.ok) { notifySubscription = null; updateNotifyButton(); } });
+				return;
+			}
+			if (window.Notification && Notification.permission === 'default') {
+				Notification.requestPermission();
+			}
+			fetch('/api/notifications', {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ kind: 'browser', showName: showName, events: ['episode.uploaded'] })
+			})
+				.then(r => r.ok ? r.json() : Promise.reject(new Error('HTTP ' + r.status)))
+				.then(sub => { notifySubscription = sub; updateNotifyButton(); })
+				.catch(err => alert('Error: ' + err.message));
+		}
+		fetch('/api/notifications')
+			.then(r => r.ok ? r.json() : null)
+			.then(data => {
+				if (!data) return;
+				notifySubscription = data.subscriptions.find(sub => sub.kind === 'browser' && sub.showName === showName) || null;
+				updateNotifyButton();
+			});
+
 		function moveToExtras() {
 			if (!confirm('Move all episodes without episode numbers to Extras (Season 0)? This will affect episodes that don\'t have proper episode numbers.')) {
 				return;
@@ -525,27 +727,89 @@ func (s *Server) handleShow(w http.ResponseWriter, r *http.Request) {
 				});
 		}
 
-		fetch('/api/show/' + encodeURIComponent(showName))
-			.then(r => r.json())
-			.then(data => {
-				const container = document.getElementById('seasons');
-				data.seasons.forEach(season => {
-					const card = document.createElement('a');
-					card.href = '/show/' + encodeURIComponent(showName) + '/season/' + season.seasonNumber;
-					card.className = 'season-card';
-					const seasonLabel = season.seasonNumber === 0 ? 'Specials' : 'Season ' + season.seasonNumber;
-					const episodeText = season.episodeCount === 1 ? '1 episode' : season.episodeCount + ' episodes';
-					card.innerHTML = '<div class="season-name">' + seasonLabel + '</div><div class="season-info">' + episodeText + '</div>';
-					container.appendChild(card);
+		function loadSeasons() {
+			fetch('/api/show/' + encodeURIComponent(showName))
+				.then(r => r.json())
+				.then(data => {
+					if (data.metadata) {
+						showDetails(data.metadata);
+					}
+					const container = document.getElementById('seasons');
+					container.innerHTML = '';
+					const cards = {};
+					(data.seasons || []).forEach(season => {
+						const card = document.createElement('a');
+						card.href = '/show/' + encodeURIComponent(showName) + '/season/' + season.seasonNumber;
+						card.className = 'season-card';
+						const seasonLabel = season.seasonNumber === 0 ? 'Specials' : 'Season ' + season.seasonNumber;
+						const episodeText = season.episodeCount === 1 ? '1 episode' : season.episodeCount + ' episodes';
+						card.innerHTML = '<div class="season-name">' + seasonLabel + '</div><div class="season-info">' + episodeText + '</div>';
+						if (season.poster) {
+							const poster = document.createElement('img');
+							poster.className = 'season-poster';
+							poster.src = season.poster;
+							poster.alt = '';
+							card.insertBefore(poster, card.firstChild);
+						}
+						cards[season.seasonNumber] = card;
+						container.appendChild(card);
+					});
+					loadGaps(cards);
 				});
-			});
+		}
+
+		// Badges with the missing episodes of each season
+		function loadGaps(cards) {
+			fetch('/api/show/' + encodeURIComponent(showName) + '/gaps')
+				.then(r => r.json())
+				.then(data => {
+					(data.seasons || []).forEach(season => {
+						const card = cards[season.seasonNumber];
+						if (!card) {
+							return;
+						}
+						const badge = document.createElement('div');
+						badge.className = 'gap-badge';
+						const of = season.official ? ' of ' + season.expected + ' aired' : '';
+						if (season.missing.length === 0) {
+							if (!season.official) {
+								return;
+							}
+							badge.classList.add('complete');
+							badge.textContent = 'Complete';
+						} else {
+							badge.textContent = season.missing.length + ' missing' + of;
+							badge.title = 'Missing episodes: ' + season.missing.join(', ');
+						}
+						card.appendChild(badge);
+					});
+				})
+				.catch(() => {});
+		}
+
+		// Poster and overview from the metadata provider
+		function showDetails(show) {
+			if (show.poster) {
+				const poster = document.getElementById('showPoster');
+				poster.src = show.poster;
+				poster.style.display = 'block';
+			}
+			document.getElementById('showPremiered').textContent = show.premiered ? 'Premiered ' + show.premiered : '';
+			document.getElementById('showOverview').textContent = show.overview || '';
+			document.getElementById('showDetails').classList.add('active');
+		}
+		loadSeasons();
+		onArchiveChange(e => e.table === 'videos' && (e.show === showName || e.oldShow === showName), loadSeasons);
 	</script>
 </body>
 </html>`
 
 	t, _ := template.New("show").Parse(tmpl)
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	t.Execute(w, map[string]string{"ShowName": showName})
+	t.Execute(w, map[string]interface{}{
+		"ShowName": showName,
+		"IsAdmin":  s.isAdmin(r),
+	})
 }
 
 // handleSeasonView shows episodes for a specific season
@@ -573,9 +837,43 @@ func (s *Server) handleSeasonView(w http.ResponseWriter, r *http.Request, showNa
 		.video-info { color: #aaa; font-size: 12px; margin-bottom: 10px; }
 		.play-btn { background: #28a745; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer; margin-right: 10px; }
 		.play-btn:hover { background: #34ce57; }
+		.play-btn:disabled { background: #555; cursor: not-allowed; }
+		.transcode-btn { background: #4a9eff; color: white; border: none; padding: 10px 20px; border-radius: 4px; cursor: pointer; margin-right: 10px; }
+		.transcode-btn:hover { background: #5aaeff; }
+		.transcode-btn:disabled { background: #555; cursor: not-allowed; }
+		.watched-badge { background: #28a745; color: white; font-size: 11px; padding: 2px 6px; border-radius: 3px; vertical-align: middle; }
+		.progress-bar { height: 4px; background: #444; border-radius: 2px; margin-bottom: 10px; overflow: hidden; }
+		.progress-bar div { height: 100%; background: #dc3545; }
+		.downloading-badge { background: #ff9800; color: white; font-size: 11px; padding: 2px 6px; border-radius: 3px; vertical-align: middle; }
+		.season-overview { color: #ccc; line-height: 1.5; max-width: 800px; margin-bottom: 10px; }
+		.episode-image { width: 100%; border-radius: 4px; margin-bottom: 10px; }
+		.episode-overview { color: #aaa; font-size: 13px; line-height: 1.4; margin-bottom: 10px; display: -webkit-box; -webkit-line-clamp: 3; -webkit-box-orient: vertical; overflow: hidden; }
+		.release-badges { margin-bottom: 10px; }
+		.release-badge { display: inline-block; background: #3a3a3a; color: #ccc; font-size: 11px; padding: 2px 6px; border-radius: 3px; margin: 0 4px 4px 0; }
 		.delete-btn { background: #dc3545; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer; }
 		.delete-btn:hover { background: #c82333; }
 		.delete-btn:disabled { background: #555; cursor: not-allowed; }
+		.edit-btn { background: #4a9eff; color: white; border: none; padding: 8px 16px; border-radius: 4px; cursor: pointer; margin-right: 10px; }
+		.edit-btn:hover { background: #5aaeff; }
+		.select-box { margin-right: 8px; vertical-align: middle; }
+		.locked-badge { color: #aaa; font-size: 12px; vertical-align: middle; }
+		.bulk-bar { display: none; background: #2a2a2a; border-radius: 8px; padding: 10px 15px; margin-bottom: 20px; align-items: center; gap: 10px; flex-wrap: wrap; }
+		.bulk-bar.active { display: flex; }
+		.bulk-bar input, .edit-dialog input { padding: 6px; border: 1px solid #444; border-radius: 4px; background: #1a1a1a; color: #fff; font-size: 14px; }
+		.bulk-bar input { width: 70px; }
+		.bulk-bar button, .edit-dialog button { background: #4a9eff; color: white; border: none; padding: 6px 12px; border-radius: 4px; cursor: pointer; }
+		.bulk-bar button:hover, .edit-dialog button:hover { background: #5aaeff; }
+		.edit-dialog { display: none; position: fixed; top: 0; left: 0; width: 100%; height: 100%; background: rgba(0,0,0,0.7); z-index: 1100; align-items: center; justify-content: center; }
+		.edit-dialog.active { display: flex; }
+		.edit-form { background: #2a2a2a; border-radius: 8px; padding: 20px; width: 90%; max-width: 500px; }
+		.edit-form h3 { margin-bottom: 5px; }
+		.edit-form .file { color: #aaa; font-size: 12px; font-family: monospace; word-break: break-all; margin-bottom: 15px; }
+		.edit-form label { display: block; color: #aaa; font-size: 13px; margin-bottom: 4px; }
+		.edit-form input { width: 100%; margin-bottom: 12px; }
+		.edit-form .hint { color: #aaa; font-size: 12px; margin-bottom: 15px; }
+		.edit-form .actions { display: flex; gap: 10px; }
+		.edit-form .actions .cancel { background: #555; }
+		.edit-form .actions .unlock { background: #ff9800; margin-left: auto; }
 		.video-player { display: none; position: fixed; top: 0; left: 0; width: 100%; height: 100%; background: rgba(0,0,0,0.95); z-index: 1000; flex-direction: column; }
 		.video-player.active { display: flex; align-items: center; justify-content: center; }
 		.video-player video { max-width: 100%; max-height: 100%; }
@@ -584,9 +882,14 @@ func (s *Server) handleSeasonView(w http.ResponseWriter, r *http.Request, showNa
 		.audio-track-selector { position: absolute; top: 20px; left: 20px; background: rgba(0,0,0,0.8); padding: 10px 15px; border-radius: 4px; z-index: 1002; display: none; }
 		.audio-track-selector.active { display: block; }
 		.audio-track-selector label { margin-right: 10px; font-size: 14px; }
+		.audio-track-selector span + span { margin-left: 15px; }
 		.audio-track-selector select { background: #2a2a2a; color: white; border: 1px solid #444; border-radius: 4px; padding: 5px 10px; font-size: 14px; cursor: pointer; }
 		.audio-track-selector select:focus { outline: none; border-color: #4a9eff; }
 	</style>
+	` + hlsScript + `
+	` + csrfFetchScript + `
+	` + eventsScript + `
+	` + searchScript + `
 </head>
 <body>
 	<div class="container">
@@ -595,46 +898,165 @@ func (s *Server) handleSeasonView(w http.ResponseWriter, r *http.Request, showNa
 				<a href="/show/{{.ShowNameEncoded}}" class="back-link">← Back to {{.ShowName}}</a>
 				<h1 id="showName"></h1>
 				<h2 id="seasonLabel"></h2>
+				<p class="season-overview" id="seasonOverview"></p>
 			</div>
-			<a href="/logout" class="logout-btn">Logout</a>
+			<div>
+				{{if .IsAdmin}}<button class="transcode-btn" id="transcodeBtn" onclick="transcodeSeason()">Pre-transcode Season</button>{{end}}
+				<a href="/logout" class="logout-btn">Logout</a>
+			</div>
+		</div>
+		{{if .IsAdmin}}
+		<div class="bulk-bar" id="bulkBar">
+			<span id="selectedCount"></span>
+			<button onclick="selectAll()">Select all</button>
+			<button onclick="clearSelection()">Clear</button>
+			<span>Season <input type="number" id="bulkSeason" min="0"></span>
+			<button onclick="bulkSetSeason()">Set season</button>
+			<span>From <input type="number" id="renumberFrom" min="0" value="1"></span>
+			<button onclick="bulkRenumber()">Renumber by filename</button>
 		</div>
+		{{end}}
 		<div class="videos" id="videos"></div>
 	</div>
+	{{if .IsAdmin}}
+	<div class="edit-dialog" id="editDialog">
+		<div class="edit-form">
+			<h3>Edit episode</h3>
+			<div class="file" id="editFile"></div>
+			<label for="editShow">Show</label>
+			<input type="text" id="editShow">
+			<label for="editSeason">Season</label>
+			<input type="number" id="editSeason" min="0">
+			<label for="editEpisode">Episode</label>
+			<input type="number" id="editEpisode" min="0">
+			<label for="editTitle">Title</label>
+			<input type="text" id="editTitle" placeholder="File name">
+			<p class="hint">Saved values are locked: reparse keeps them.</p>
+			<div class="actions">
+				<button onclick="saveEdit()">Save</button>
+				<button class="cancel" onclick="closeEdit()">Cancel</button>
+				<button class="unlock" id="unlockBtn" onclick="unlockEpisode()">Unlock</button>
+			</div>
+		</div>
+	</div>
+	{{end}}
 	<div class="video-player" id="videoPlayer">
 		<button class="close-btn" onclick="closePlayer()">×</button>
 		<div class="audio-track-selector" id="audioTrackSelector">
-			<label for="audioTrackSelect">Audio Track:</label>
-			<select id="audioTrackSelect" onchange="changeAudioTrack()"></select>
+			<span id="audioTrackControls">
+				<label for="audioTrackSelect">Audio Track:</label>
+				<select id="audioTrackSelect" onchange="changeAudioTrack()"></select>
+			</span>
+			<span id="subtitleControls">
+				<label for="subtitleSelect">Subtitles:</label>
+				<select id="subtitleSelect" onchange="changeSubtitle()"></select>
+			</span>
 		</div>
 		<video id="videoElement" controls autoplay></video>
 	</div>
 	<script>
 		const showName = decodeURIComponent('{{.ShowName}}');
+		const isAdmin = {{.IsAdmin}};
 		const seasonNumber = {{.SeasonNumber}};
 		const seasonLabel = seasonNumber === 0 ? 'Specials' : 'Season ' + seasonNumber;
 
 		document.getElementById('showName').textContent = showName;
 		document.getElementById('seasonLabel').textContent = seasonLabel;
 
-		fetch('/api/show/' + encodeURIComponent(showName) + '/season/' + seasonNumber)
-			.then(r => r.json())
-			.then(data => {
-				const container = document.getElementById('videos');
-				data.episodes.forEach(video => {
-					const card = document.createElement('div');
-					card.className = 'video-card';
-					const episodeLabel = video.episodeNumber > 0 ? 'E' + video.episodeNumber + ' - ' : '';
-					const playBtn = '<button class="play-btn" onclick="playVideo(' + video.id + ')">Play</button>';
-					const deleteBtn = '<button class="delete-btn" onclick="deleteEpisode(' + video.id + ', this)">Delete</button>';
-					card.innerHTML = '<div class="video-title">' + episodeLabel + escapeHtml(video.title) + '</div><div class="video-info">Downloaded: ' + video.downloadedAt + '</div>' + playBtn + deleteBtn;
-					container.appendChild(card);
+		let episodes = [];
+		const selected = new Set();
+
+		function loadEpisodes(initial) {
+			fetch('/api/show/' + encodeURIComponent(showName) + '/season/' + seasonNumber)
+				.then(r => r.json())
+				.then(data => {
+					document.getElementById('seasonOverview').textContent = (data.metadata && data.metadata.overview) || '';
+					const container = document.getElementById('videos');
+					container.innerHTML = '';
+					(data.episodes || []).forEach(video => {
+						const card = document.createElement('div');
+						card.className = 'video-card';
+						let episodeLabel = '';
+						if (video.airDate) {
+							episodeLabel = video.airDate + ' - ';
+						} else if (video.episodeEnd > video.episodeNumber) {
+							episodeLabel = 'E' + video.episodeNumber + '-E' + video.episodeEnd + ' - ';
+						} else if (video.episodeNumber > 0) {
+							episodeLabel = 'E' + video.episodeNumber + ' - ';
+						}
+						if (video.absoluteNumber > 0 && video.absoluteNumber !== video.episodeNumber) {
+							episodeLabel += '#' + video.absoluteNumber + ' ';
+						}
+						let playBtn = '<button class="play-btn" onclick="playVideo(' + video.id + ')">Play</button>';
+						let status = '';
+						let progress = '';
+						if (video.watched) {
+							status = ' <span class="watched-badge">✓ Watched</span>';
+						} else if (video.position > 0 && video.duration > 0) {
+							progress = '<div class="progress-bar"><div style="width: ' + Math.min(100, video.position / video.duration * 100).toFixed(1) + '%"></div></div>';
+						}
+						if (!video.uploaded) {
+							status = ' <span class="downloading-badge">Downloading</span>';
+							playBtn = video.torrentStreamUrl
+								? '<button class="play-btn" onclick="playVideo(' + video.id + ', \'' + video.torrentStreamUrl + '\')">Play from torrent</button>'
+								: '<button class="play-btn" disabled>Not uploaded yet</button>';
+						}
+						const deleteBtn = isAdmin ? '<button class="delete-btn" onclick="deleteEpisode(' + video.id + ', this)">Delete</button>' : '';
+						const editBtn = isAdmin ? '<button class="edit-btn" onclick="editEpisode(' + video.id + ')">Edit</button>' : '';
+						const selectBox = isAdmin ? '<input type="checkbox" class="select-box" onchange="toggleSelected(' + video.id + ', this.checked)"' + (selected.has(video.id) ? ' checked' : '') + '>' : '';
+						if (video.locked) {
+							status += ' <span class="locked-badge" title="Edited by hand, kept by reparse">🔒</span>';
+						}
+						const badges = (video.badges || []).length
+							? '<div class="release-badges">' + video.badges.map(b => '<span class="release-badge">' + escapeHtml(b) + '</span>').join('') + '</div>'
+							: '';
+						const overview = video.metadata && video.metadata.overview
+							? '<div class="episode-overview">' + escapeHtml(video.metadata.overview) + '</div>'
+							: '';
+						card.innerHTML = '<div class="video-title">' + selectBox + episodeLabel + escapeHtml(video.title) + status + '</div><div class="video-info">Downloaded: ' + video.downloadedAt + '</div>' + overview + badges + progress + playBtn + editBtn + deleteBtn;
+						if (video.metadata && video.metadata.image) {
+							const image = document.createElement('img');
+							image.className = 'episode-image';
+							image.src = video.metadata.image;
+							image.alt = '';
+							image.loading = 'lazy';
+							card.insertBefore(image, card.firstChild);
+						}
+						container.appendChild(card);
+					});
+					episodes = data.episodes || [];
+					selected.forEach(id => {
+						if (!episodes.some(video => video.id === id)) selected.delete(id);
+					});
+					updateBulkBar();
+
+					// Links from "Continue watching" open the player directly
+					if (!initial) return;
+					const playId = parseInt(new URLSearchParams(location.search).get('play'));
+					const episode = (data.episodes || []).find(video => video.id === playId);
+					if (episode) {
+						playVideo(episode.id, episode.torrentStreamUrl);
+					}
 				});
-			});
+		}
+		loadEpisodes(true);
+		onArchiveChange(e => e.table === 'videos' &&
+			((e.show === showName && e.season === seasonNumber) || (e.oldShow === showName && e.oldSeason === seasonNumber)),
+			() => loadEpisodes(false));
 
-		function playVideo(videoId) {
+		let hlsPlayer = null;
+		let currentVideoId = null;
+		let pendingSeek = null;
+		let lastProgressSave = 0;
+
+		function playVideo(videoId, torrentStreamUrl) {
 			const player = document.getElementById('videoPlayer');
 			const video = document.getElementById('videoElement');
 
+			saveProgress();
+			currentVideoId = videoId;
+			pendingSeek = null;
+			stopHls();
 			video.src = '';
 			video.load();
 
@@ -644,8 +1066,6 @@ func (s *Server) handleSeasonView(w http.ResponseWriter, r *http.Request, showNa
 			player.appendChild(statusMsg);
 			player.classList.add('active');
 
-			const streamUrl = '/api/stream/' + videoId;
-
 			video.onerror = function(e) {
 				let errorMsg = 'Failed to load video';
 				if (video.error) {
@@ -667,14 +1087,81 @@ func (s *Server) handleSeasonView(w http.ResponseWriter, r *http.Request, showNa
 				});
 			};
 
-			// Set up loadedmetadata handler to detect audio tracks
+			// Restore the position after switching audio tracks
 			video.onloadedmetadata = function() {
-				console.log('Video metadata loaded, detecting audio tracks');
-				detectAudioTracks();
+				if (pendingSeek !== null) {
+					video.currentTime = pendingSeek;
+					pendingSeek = null;
+				}
 			};
 
-			video.src = streamUrl;
-			video.load();
+			// Save the position periodically and whenever playback stops
+			video.ontimeupdate = function() {
+				if (Date.now() - lastProgressSave > 10000) {
+					saveProgress();
+				}
+			};
+			video.onpause = saveProgress;
+			video.onended = saveProgress;
+
+			loadTracks(videoId);
+			resumeProgress(videoId);
+
+			// Episodes that are still downloading stream from the torrent swarm instead of Telegram
+			if (torrentStreamUrl) {
+				video.src = torrentStreamUrl;
+				video.load();
+				return;
+			}
+
+			// Ask the server whether the file plays natively or needs on-the-fly HLS transcoding
+			fetch('/api/playback/' + videoId)
+				.then(r => r.json())
+				.then(playback => {
+					if (playback.mode === 'hls') {
+						playHls(playback.url, playback.fallbackUrl);
+					} else {
+						video.src = playback.url;
+						video.load();
+					}
+				})
+				.catch(() => {
+					video.src = '/api/stream/' + videoId;
+					video.load();
+				});
+		}
+
+		function playHls(url, fallbackUrl) {
+			const video = document.getElementById('videoElement');
+
+			if (window.Hls && Hls.isSupported()) {
+				hlsPlayer = new Hls();
+				hlsPlayer.on(Hls.Events.ERROR, function(event, data) {
+					if (data.fatal) {
+						// Fall back to the full-file transcode if segmenting fails
+						console.log('HLS playback failed, falling back to full transcode', data);
+						stopHls();
+						video.src = fallbackUrl;
+						video.load();
+					}
+				});
+				hlsPlayer.loadSource(url);
+				hlsPlayer.attachMedia(video);
+			} else if (video.canPlayType('application/vnd.apple.mpegurl')) {
+				// Safari plays HLS natively
+				video.src = url;
+				video.load();
+			} else {
+				video.src = fallbackUrl;
+				video.load();
+			}
+		}
+
+		function stopHls() {
+			if (hlsPlayer) {
+				hlsPlayer.destroy();
+				hlsPlayer = null;
+			}
 		}
 
 		function closePlayer() {
@@ -684,49 +1171,144 @@ func (s *Server) handleSeasonView(w http.ResponseWriter, r *http.Request, showNa
 			player.classList.remove('active');
 			audioTrackSelector.classList.remove('active');
 			video.pause();
+			saveProgress();
+			currentVideoId = null;
+			stopHls();
 			video.src = '';
 		}
 
-		function detectAudioTracks() {
+		function resumeProgress(videoId) {
+			fetch('/api/progress/' + videoId)
+				.then(r => r.json())
+				.then(progress => {
+					// Start over if the episode was finished or barely started
+					if (videoId !== currentVideoId || progress.watched || progress.position < 10) {
+						return;
+					}
+					const video = document.getElementById('videoElement');
+					if (video.readyState >= 1) {
+						video.currentTime = progress.position;
+					} else {
+						pendingSeek = progress.position;
+					}
+				});
+		}
+
+		function saveProgress() {
+			const video = document.getElementById('videoElement');
+			if (!currentVideoId || !video.currentTime) {
+				return;
+			}
+			lastProgressSave = Date.now();
+			fetch('/api/progress/' + currentVideoId, {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({ position: video.currentTime, duration: isFinite(video.duration) ? video.duration : 0 })
+			}).catch(err => console.log('Failed to save progress:', err));
+		}
+
+		function loadTracks(videoId) {
 			const video = document.getElementById('videoElement');
 			const selector = document.getElementById('audioTrackSelector');
-			const select = document.getElementById('audioTrackSelect');
+			const audioSelect = document.getElementById('audioTrackSelect');
+			const subtitleSelect = document.getElementById('subtitleSelect');
 
-			// Clear existing options
-			select.innerHTML = '';
+			// Clear tracks of the previous video
+			audioSelect.innerHTML = '';
+			subtitleSelect.innerHTML = '<option value="-1">Off</option>';
+			video.querySelectorAll('track').forEach(track => track.remove());
+			selector.classList.remove('active');
 
-			// Check for audio tracks
-			const audioTracks = video.audioTracks;
-			if (audioTracks && audioTracks.length > 1) {
-				// Add options for each audio track
-				for (let i = 0; i < audioTracks.length; i++) {
-					const option = document.createElement('option');
-					option.value = i;
-					option.textContent = audioTracks[i].label || audioTracks[i].language || 'Track ' + (i + 1);
-					if (audioTracks[i].enabled) {
-						option.selected = true;
+			fetch('/api/tracks/' + videoId)
+				.then(r => r.json())
+				.then(tracks => {
+					if (videoId !== currentVideoId) {
+						return;
 					}
-					select.appendChild(option);
-				}
-				selector.classList.add('active');
-			} else {
-				selector.classList.remove('active');
-			}
+
+					tracks.audio.forEach(track => {
+						const option = document.createElement('option');
+						option.value = track.index;
+						option.textContent = track.label + (track.channels > 2 ? ' ' + track.channels + 'ch' : '');
+						audioSelect.appendChild(option);
+					});
+
+					tracks.subtitles.forEach(subtitle => {
+						const track = document.createElement('track');
+						track.kind = 'subtitles';
+						track.src = subtitle.url;
+						track.label = subtitle.label;
+						if (subtitle.language) {
+							track.srclang = subtitle.language;
+						}
+						video.appendChild(track);
+
+						const option = document.createElement('option');
+						option.value = subtitle.index;
+						option.textContent = subtitle.label + (subtitle.external ? ' (external)' : '');
+						subtitleSelect.appendChild(option);
+					});
+
+					document.getElementById('audioTrackControls').style.display = tracks.audio.length > 1 ? '' : 'none';
+					document.getElementById('subtitleControls').style.display = tracks.subtitles.length > 0 ? '' : 'none';
+					if (tracks.audio.length > 1 || tracks.subtitles.length > 0) {
+						selector.classList.add('active');
+					}
+					changeSubtitle();
+				})
+				.catch(err => console.log('Failed to load tracks:', err));
 		}
 
 		function changeAudioTrack() {
+			// Browsers cannot switch audio streams of a single file, so the
+			// selected stream is transcoded through HLS from the current position
 			const video = document.getElementById('videoElement');
-			const select = document.getElementById('audioTrackSelect');
-			const selectedIndex = parseInt(select.value);
+			const audio = parseInt(document.getElementById('audioTrackSelect').value);
 
-			const audioTracks = video.audioTracks;
-			if (audioTracks) {
-				for (let i = 0; i < audioTracks.length; i++) {
-					audioTracks[i].enabled = (i === selectedIndex);
-				}
+			pendingSeek = video.currentTime;
+			stopHls();
+			playHls('/api/hls/' + currentVideoId + '/index.m3u8?audio=' + audio, '/api/stream/' + currentVideoId);
+		}
+
+		function changeSubtitle() {
+			const video = document.getElementById('videoElement');
+			const selected = parseInt(document.getElementById('subtitleSelect').value);
+			for (let i = 0; i < video.textTracks.length; i++) {
+				video.textTracks[i].mode = i === selected ? 'showing' : 'disabled';
 			}
 		}
 
+		function transcodeSeason() {
+			const btn = document.getElementById('transcodeBtn');
+			btn.disabled = true;
+			btn.textContent = 'Queueing...';
+			fetch('/api/show/' + encodeURIComponent(showName) + '/season/' + seasonNumber + '/transcode', { method: 'POST' })
+				.then(r => r.json())
+				.then(data => {
+					const ids = (data.jobs || []).map(job => job.videoId);
+					pollTranscodes(ids, btn);
+				})
+				.catch(err => {
+					alert('Error: ' + err.message);
+					btn.disabled = false;
+					btn.textContent = 'Pre-transcode Season';
+				});
+		}
+
+		function pollTranscodes(ids, btn) {
+			Promise.all(ids.map(id => fetch('/api/transcode/' + id).then(r => r.json())))
+				.then(jobs => {
+					const finished = jobs.filter(job => ['done', 'skipped', 'failed'].includes(job.status)).length;
+					const running = jobs.find(job => job.status === 'running');
+					if (finished === jobs.length) {
+						btn.textContent = 'Season transcoded';
+						return;
+					}
+					btn.textContent = 'Transcoding ' + finished + '/' + jobs.length + (running ? ' (' + Math.round(running.progress) + '%)' : '');
+					setTimeout(() => pollTranscodes(ids, btn), 5000);
+				});
+		}
+
 		function deleteEpisode(videoId, btn) {
 			if (!confirm('Are you sure you want to delete this episode? This will remove it from Telegram, local cache, and database.')) {
 				return;
@@ -751,6 +1333,141 @@ func (s *Server) handleSeasonView(w http.ResponseWriter, r *http.Request, showNa
 				});
 		}
 
+		// Editing: one episode in the dialog, or the selected ones from the bulk bar
+		let editingId = null;
+
+		function editEpisode(videoId) {
+			const video = episodes.find(v => v.id === videoId);
+			if (!video) return;
+			editingId = videoId;
+			document.getElementById('editFile').textContent = video.filePath;
+			document.getElementById('editShow').value = showName;
+			document.getElementById('editSeason').value = seasonNumber;
+			document.getElementById('editEpisode').value = video.episodeNumber;
+			document.getElementById('editTitle').value = video.episodeTitle || '';
+			document.getElementById('unlockBtn').style.display = video.locked ? 'inline-block' : 'none';
+			document.getElementById('editDialog').classList.add('active');
+		}
+
+		function closeEdit() {
+			editingId = null;
+			document.getElementById('editDialog').classList.remove('active');
+		}
+
+		function saveEdit() {
+			const edit = {
+				showName: document.getElementById('editShow').value,
+				seasonNumber: parseInt(document.getElementById('editSeason').value, 10) || 0,
+				episodeNumber: parseInt(document.getElementById('editEpisode').value, 10) || 0,
+				title: document.getElementById('editTitle').value
+			};
+			fetch('/api/episode/' + editingId, {
+				method: 'PATCH',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify(edit)
+			})
+				.then(r => r.ok ? r.json() : r.text().then(text => { throw new Error(text); }))
+				.then(() => {
+					closeEdit();
+					leaveIfMoved(edit.showName, edit.seasonNumber);
+				})
+				.catch(err => alert('Error: ' + err.message));
+		}
+
+		function unlockEpisode() {
+			if (!confirm('Unlock this episode? The next reparse will number it from its file name again.')) {
+				return;
+			}
+			fetch('/api/review/' + editingId, { method: 'DELETE' })
+				.then(r => r.ok ? r.json() : r.text().then(text => { throw new Error(text); }))
+				.then(() => {
+					closeEdit();
+					loadEpisodes(false);
+				})
+				.catch(err => alert('Error: ' + err.message));
+		}
+
+		function toggleSelected(videoId, checked) {
+			if (checked) {
+				selected.add(videoId);
+			} else {
+				selected.delete(videoId);
+			}
+			updateBulkBar();
+		}
+
+		function selectAll() {
+			episodes.forEach(video => selected.add(video.id));
+			document.querySelectorAll('.select-box').forEach(box => { box.checked = true; });
+			updateBulkBar();
+		}
+
+		function clearSelection() {
+			selected.clear();
+			document.querySelectorAll('.select-box').forEach(box => { box.checked = false; });
+			updateBulkBar();
+		}
+
+		function updateBulkBar() {
+			const bar = document.getElementById('bulkBar');
+			if (!bar) return;
+			document.getElementById('selectedCount').textContent = selected.size + ' selected';
+			bar.classList.toggle('active', selected.size > 0);
+		}
+
+		function bulkEdit(edit, description) {
+			if (!confirm(description + ' for ' + selected.size + ' episodes? They will be locked against reparse.')) {
+				return;
+			}
+			edit.ids = Array.from(selected);
+			fetch('/api/episodes/bulk', {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify(edit)
+			})
+				.then(r => r.ok ? r.json() : r.text().then(text => { throw new Error(text); }))
+				.then(() => {
+					selected.clear();
+					if (edit.seasonNumber !== undefined) {
+						leaveIfMoved(showName, edit.seasonNumber);
+					} else {
+						loadEpisodes(false);
+					}
+				})
+				.catch(err => alert('Error: ' + err.message));
+		}
+
+		function bulkSetSeason() {
+			const season = parseInt(document.getElementById('bulkSeason').value, 10);
+			if (isNaN(season) || season < 0) {
+				alert('Enter a season');
+				return;
+			}
+			bulkEdit({ seasonNumber: season }, 'Set season ' + season);
+		}
+
+		function bulkRenumber() {
+			const from = parseInt(document.getElementById('renumberFrom').value, 10);
+			if (isNaN(from) || from < 0) {
+				alert('Enter the first episode number');
+				return;
+			}
+			bulkEdit({ renumberFrom: from }, 'Number episodes from ' + from + ' in file name order');
+		}
+
+		// Edited episodes may belong to another season or show now; follow them there
+		function leaveIfMoved(newShow, newSeason) {
+			if (newShow === showName && newSeason === seasonNumber) {
+				loadEpisodes(false);
+				return;
+			}
+			if (episodes.length > 1 && !confirm('Moved to ' + (newSeason === 0 ? 'Specials' : 'Season ' + newSeason) + (newShow !== showName ? ' of ' + newShow : '') + '. Go there?')) {
+				loadEpisodes(false);
+				return;
+			}
+			window.location.href = '/show/' + encodeURIComponent(newShow) + '/season/' + newSeason;
+		}
+
 		function escapeHtml(text) {
 			const div = document.createElement('div');
 			div.textContent = text;
@@ -772,6 +1489,7 @@ func (s *Server) handleSeasonView(w http.ResponseWriter, r *http.Request, showNa
 		"ShowNameEncoded": url.QueryEscape(showName),
 		"SeasonLabel":     seasonLabel,
 		"SeasonNumber":    seasonNumber,
+		"IsAdmin":         s.isAdmin(r),
 	})
 }
 
@@ -906,7 +1624,7 @@ func (s *Server) handleAPIShows(w http.ResponseWriter, r *http.Request) {
 // handleAPIShow returns seasons for a show or episodes if season is specified
 // Also handles DELETE requests for deleting shows/seasons
 func (s *Server) handleAPIShow(w http.ResponseWriter, r *http.Request) {
-	// Parse URL: /api/show/{showName} or /api/show/{showName}/season/{seasonNumber}
+	// Parse URL: /api/show/{showName}, /api/show/{showName}/gaps or /api/show/{showName}/season/{seasonNumber}
 	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/show/"), "/")
 	if len(pathParts) == 0 || pathParts[0] == "" {
 		http.Error(w, "Show name required", http.StatusBadRequest)
@@ -921,6 +1639,10 @@ func (s *Server) handleAPIShow(w http.ResponseWriter, r *http.Request) {
 	// Check if we're requesting a specific season
 	if len(pathParts) >= 3 && pathParts[1] == "season" {
 		seasonNum, _ := strconv.Atoi(pathParts[2])
+		if len(pathParts) >= 4 && pathParts[3] == "transcode" {
+			s.handleAPITranscodeSeason(w, r, showName, seasonNum)
+			return
+		}
 		if r.Method == "DELETE" {
 			s.handleAPIDeleteShow(w, r)
 			return
@@ -929,6 +1651,11 @@ func (s *Server) handleAPIShow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if len(pathParts) >= 2 && pathParts[1] == "gaps" {
+		s.handleAPIShowGaps(w, r, showName)
+		return
+	}
+
 	// Handle DELETE for entire show
 	if r.Method == "DELETE" {
 		s.handleAPIDeleteShow(w, r)
@@ -942,12 +1669,36 @@ func (s *Server) handleAPIShow(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	type Season struct {
+		database.Season
+		Name     string `json:"name,omitempty"` // From the metadata provider
+		Overview string `json:"overview,omitempty"`
+		Poster   string `json:"poster,omitempty"`
+	}
+
 	result := struct {
-		ShowName string            `json:"showName"`
-		Seasons  []database.Season `json:"seasons"`
+		ShowName string         `json:"showName"`
+		Metadata *metadata.Show `json:"metadata,omitempty"`
+		Seasons  []Season       `json:"seasons"`
 	}{
 		ShowName: showName,
-		Seasons:  seasons,
+		Seasons:  make([]Season, 0, len(seasons)),
+	}
+
+	details := s.showMetadata(r, showName)
+	if details != nil {
+		result.Metadata = &details.Show
+	}
+	for _, season := range seasons {
+		enriched := Season{Season: season}
+		if details != nil {
+			if info := details.Season(season.SeasonNumber); info != nil {
+				enriched.Name = info.Name
+				enriched.Overview = info.Overview
+				enriched.Poster = info.Poster
+			}
+		}
+		result.Seasons = append(result.Seasons, enriched)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -963,36 +1714,99 @@ func (s *Server) handleAPIEpisodes(w http.ResponseWriter, r *http.Request, showN
 	}
 
 	type Episode struct {
-		ID            int64  `json:"id"`
-		Title         string `json:"title"`
-		FilePath      string `json:"filePath"`
-		EpisodeNumber int    `json:"episodeNumber"`
-		DownloadedAt  string `json:"downloadedAt"`
+		ID               int64             `json:"id"`
+		Title            string            `json:"title"`
+		EpisodeTitle     string            `json:"episodeTitle,omitempty"` // Title set by hand
+		FilePath         string            `json:"filePath"`
+		EpisodeNumber    int               `json:"episodeNumber"`
+		EpisodeEnd       int               `json:"episodeEnd,omitempty"`     // Last episode of a multi-episode file
+		AirDate          string            `json:"airDate,omitempty"`        // YYYY-MM-DD, for daily shows
+		AbsoluteNumber   int               `json:"absoluteNumber,omitempty"` // Anime absolute numbering
+		DownloadedAt     string            `json:"downloadedAt"`
+		Uploaded         bool              `json:"uploaded"`
+		TorrentStreamURL string            `json:"torrentStreamUrl,omitempty"`
+		Watched          bool              `json:"watched"`
+		Position         float64           `json:"position"` // Seconds played by the current user
+		Duration         float64           `json:"duration"`
+		Release          parser.Release    `json:"release"`
+		Badges           []string          `json:"badges"`             // Release metadata as display labels
+		Locked           bool              `json:"locked"`             // Numbering set by hand, kept by reparse
+		Metadata         *metadata.Episode `json:"metadata,omitempty"` // Title, overview and image from the metadata provider
 	}
 
 	result := struct {
-		ShowName     string    `json:"showName"`
-		SeasonNumber int       `json:"seasonNumber"`
-		Episodes     []Episode `json:"episodes"`
+		ShowName     string           `json:"showName"`
+		SeasonNumber int              `json:"seasonNumber"`
+		Metadata     *metadata.Season `json:"metadata,omitempty"`
+		Episodes     []Episode        `json:"episodes"`
 	}{
 		ShowName:     showName,
 		SeasonNumber: seasonNumber,
 		Episodes:     []Episode{},
 	}
 
+	details := s.showMetadata(r, showName)
+	if details != nil {
+		result.Metadata = details.Season(seasonNumber)
+	}
+
+	videoIDs := make([]int64, 0, len(episodes))
+	for _, video := range episodes {
+		videoIDs = append(videoIDs, video.ID)
+	}
+	progress, err := s.db.GetWatchProgressForVideos(s.currentUser(r).ID, videoIDs)
+	if err != nil {
+		log.Printf("Warning: Failed to load watch progress: %v", err)
+	}
+
 	for _, video := range episodes {
 		// Extract a better title from the file path
 		videoTitle := filepath.Base(video.FilePath)
-		if videoTitle == "" || videoTitle == "." {
+		if video.EpisodeTitle != "" {
+			videoTitle = video.EpisodeTitle
+		} else if videoTitle == "" || videoTitle == "." {
 			videoTitle = fmt.Sprintf("Video %d", video.ID)
 		}
 
 		ep := Episode{
-			ID:            video.ID,
-			Title:         videoTitle,
-			FilePath:      video.FilePath,
-			EpisodeNumber: video.EpisodeNumber,
-			DownloadedAt:  video.DownloadedAt.Format(time.RFC3339),
+			ID:             video.ID,
+			Title:          videoTitle,
+			EpisodeTitle:   video.EpisodeTitle,
+			FilePath:       video.FilePath,
+			EpisodeNumber:  video.EpisodeNumber,
+			EpisodeEnd:     video.EpisodeEnd,
+			AbsoluteNumber: video.AbsoluteNumber,
+			DownloadedAt:   video.DownloadedAt.Format(time.RFC3339),
+			Uploaded:       video.Uploaded(),
+			Release:        video.Release,
+			Badges:         video.Release.Badges(),
+			Locked:         video.Locked(),
+		}
+		if video.AirDate != nil {
+			ep.AirDate = video.AirDate.Format("2006-01-02")
+		}
+
+		// Titles set by hand win over the provider's
+		if details != nil {
+			if ep.AirDate != "" {
+				ep.Metadata = details.EpisodeByAirDate(ep.AirDate)
+			} else if video.EpisodeNumber > 0 {
+				ep.Metadata = details.Episode(video.SeasonNumber, video.EpisodeNumber)
+			}
+			if ep.Metadata != nil && ep.Metadata.Title != "" && video.EpisodeTitle == "" {
+				ep.Title = ep.Metadata.Title
+			}
+		}
+
+		// Episodes still downloading can be watched straight from the swarm
+		if !ep.Uploaded {
+			ep.TorrentStreamURL = s.torrentStreamURL(video)
+		}
+
+		if p, ok := progress[video.ID]; ok {
+			ep.Watched = p.Watched
+			ep.Position = p.Position
+			ep.Duration = p.Duration
 		}
 
 		result.Episodes = append(result.Episodes, ep)
@@ -1012,11 +1826,6 @@ func (s *Server) handleAPIStream(w http.ResponseWriter, r *http.Request) {
 
 	videoID := parseVideoID(videoIDStr)
 
-	// Track current video for cleanup
-	s.currentVideoMu.Lock()
-	s.currentVideo = videoID
-	s.currentVideoMu.Unlock()
-
 	// Verify video exists in database
 	videos, err := s.db.GetAllVideos()
 	if err != nil {
@@ -1042,59 +1851,23 @@ func (s *Server) handleAPIStream(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Try to serve directly from local disk first (faster and more reliable)
-	if s.token != "" {
-		// Construct expected local path: /var/lib/telegram-bot-api/<TOKEN>/<path_from_db>
-		// The path in DB is relative to the token directory (e.g., "documents/file.mp4")
-		localPath := filepath.Join("/var/lib/telegram-bot-api", s.token, video.TelegramFilePath)
-
-		log.Printf("Checking for local file at: %s", localPath)
-		if _, err := os.Stat(localPath); err == nil {
-			log.Printf("Serving video %d directly from local disk: %s", videoID, localPath)
-			// Check if it needs transcoding for browser compatibility (check codecs, not just extension)
-			if needsTranscodingByCodec(localPath) {
-				log.Printf("File requires transcoding for browser compatibility (incompatible audio/video codec): %s", localPath)
-				s.transcodeAndServe(w, r, localPath, videoID)
-				return
-			}
-			http.ServeFile(w, r, localPath)
+	// Serve from local disk: telegram-bot-api storage, or the web cache after a re-download from Telegram
+	localPath, err := s.sourcePath(video)
+	if err == nil {
+		log.Printf("Serving video %d from local disk: %s", videoID, localPath)
+		// Check if it needs transcoding for browser compatibility (check codecs, not just extension)
+		// The player prefers HLS for such files; this full transcode is the fallback
+		if needsTranscodingByCodec(localPath) {
+			log.Printf("File requires transcoding for browser compatibility (incompatible audio/video codec): %s", localPath)
+			s.transcodeAndServe(w, r, localPath, videoID)
 			return
 		}
-		log.Printf("Local file not found at %s (cleaned from cache), will re-download from Telegram", localPath)
+		http.ServeFile(w, r, localPath)
+		return
 	}
-
-	// File not in cache - need to re-download from Telegram
-	// Download to temporary file first, then stream it
 	if s.downloader != nil {
-		log.Printf("Re-downloading video %d from Telegram (not in cache)", videoID)
-
-		// Create temporary file
-		tmpFile, err := os.CreateTemp(s.downloadDir, fmt.Sprintf("stream-%d-*.mp4", videoID))
-		if err != nil {
-			log.Printf("Error creating temp file for video %d: %v", videoID, err)
-			http.Error(w, "Failed to create temporary file", http.StatusInternalServerError)
-			return
-		}
-		tmpPath := tmpFile.Name()
-		tmpFile.Close()
-		defer os.Remove(tmpPath) // Clean up after streaming
-
-		// Download file from Telegram
-		err = s.downloader.DownloadFileWithPath(video.TelegramFileID, video.TelegramFilePath, tmpPath)
-		if err != nil {
-			log.Printf("Error re-downloading video %d from Telegram: %v", videoID, err)
-			http.Error(w, fmt.Sprintf("Failed to download video from Telegram: %v", err), http.StatusInternalServerError)
-			return
-		}
-
-		log.Printf("Successfully re-downloaded video %d to %s, now streaming", videoID, tmpPath)
-		// Check if it needs transcoding for browser compatibility (check codecs, not just extension)
-		if needsTranscodingByCodec(tmpPath) {
-			log.Printf("File requires transcoding for browser compatibility (incompatible audio/video codec): %s", tmpPath)
-			s.transcodeAndServe(w, r, tmpPath, videoID)
-			return
-		}
-		http.ServeFile(w, r, tmpPath)
+		log.Printf("Error re-downloading video %d from Telegram: %v", videoID, err)
+		http.Error(w, fmt.Sprintf("Failed to download video from Telegram: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -1189,192 +1962,31 @@ func (s *Server) handleAPIStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleStatic serves static files
-func (s *Server) handleStatic(w http.ResponseWriter, r *http.Request) {
-	http.NotFound(w, r)
-}
-
-// parseVideoID parses video ID from string
-func parseVideoID(s string) int64 {
-	id, _ := strconv.ParseInt(s, 10, 64)
-	return id
-}
-
-// requireAuth wraps a handler to require authentication
-func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		sessionID := s.getSessionID(r)
-		if !s.isValidSession(sessionID) {
-			http.Redirect(w, r, "/login?redirect="+r.URL.Path, http.StatusFound)
-			return
-		}
-		next(w, r)
-	}
+// SetStaticDir sets the directory served under /static/ (hls.js); "" serves nothing
+func (s *Server) SetStaticDir(dir string) {
+	s.staticDir = dir
 }
 
-// handleLogin handles login requests
-func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
-	if r.Method == "GET" {
-		// Show login page
-		tmpl := `<!DOCTYPE html>
-<html>
-<head>
-	<title>Login</title>
-	<meta charset="utf-8">
-	<meta name="viewport" content="width=device-width, initial-scale=1">
-	<style>
-		* { margin: 0; padding: 0; box-sizing: border-box; }
-		body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #1a1a1a; color: #fff; display: flex; align-items: center; justify-content: center; min-height: 100vh; }
-		.login-container { background: #2a2a2a; border-radius: 8px; padding: 40px; width: 100%; max-width: 400px; }
-		h1 { margin-bottom: 30px; text-align: center; }
-		.form-group { margin-bottom: 20px; }
-		label { display: block; margin-bottom: 8px; }
-		input { width: 100%; padding: 12px; border: 1px solid #444; border-radius: 4px; background: #1a1a1a; color: #fff; font-size: 16px; }
-		input:focus { outline: none; border-color: #4a9eff; }
-		button { width: 100%; padding: 12px; background: #4a9eff; color: white; border: none; border-radius: 4px; cursor: pointer; font-size: 16px; }
-		button:hover { background: #5aaeff; }
-		.error { color: #dc3545; margin-top: 10px; text-align: center; }
-	</style>
-</head>
-<body>
-	<div class="login-container">
-		<h1>Login</h1>
-		<form method="POST" action="/login">
-			<input type="hidden" name="redirect" value="{{.Redirect}}">
-			<div class="form-group">
-				<label for="username">Username</label>
-				<input type="text" id="username" name="username" required autofocus>
-			</div>
-			<div class="form-group">
-				<label for="password">Password</label>
-				<input type="password" id="password" name="password" required>
-			</div>
-			<button type="submit">Login</button>
-			{{if .Error}}<div class="error">{{.Error}}</div>{{end}}
-		</form>
-	</div>
-</body>
-</html>`
-
-		redirect := r.URL.Query().Get("redirect")
-		if redirect == "" {
-			redirect = "/"
-		}
-
-		t, _ := template.New("login").Parse(tmpl)
-		w.Header().Set("Content-Type", "text/html; charset=utf-8")
-		t.Execute(w, map[string]string{
-			"Redirect": redirect,
-			"Error":    r.URL.Query().Get("error"),
-		})
+// handleStatic serves the files of the static directory, without directory listings
+func (s *Server) handleStatic(w http.ResponseWriter, r *http.Request) {
+	if s.staticDir == "" {
+		http.NotFound(w, r)
 		return
 	}
-
-	// Handle POST login
-	if r.Method == "POST" {
-		username := r.FormValue("username")
-		password := r.FormValue("password")
-		redirect := r.FormValue("redirect")
-		if redirect == "" {
-			redirect = "/"
-		}
-
-		if username == s.username && password == s.password {
-			// Create session
-			sessionID := s.createSession()
-			http.SetCookie(w, &http.Cookie{
-				Name:     "session",
-				Value:    sessionID,
-				Path:     "/",
-				MaxAge:   86400, // 24 hours
-				HttpOnly: true,
-				Secure:   false, // Set to true if using HTTPS
-			})
-			http.Redirect(w, r, redirect, http.StatusFound)
-			return
-		}
-
-		// Invalid credentials
-		http.Redirect(w, r, "/login?redirect="+redirect+"&error=Invalid+username+or+password", http.StatusFound)
+	name := filepath.Join(s.staticDir, filepath.FromSlash(path.Clean("/"+strings.TrimPrefix(r.URL.Path, "/static/"))))
+	if info, err := os.Stat(name); err != nil || info.IsDir() {
+		http.NotFound(w, r)
 		return
 	}
-
-	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-}
-
-// handleLogout handles logout requests
-func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
-	sessionID := s.getSessionID(r)
-	if sessionID != "" {
-		s.deleteSession(sessionID)
-	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-	})
-	http.Redirect(w, r, "/login", http.StatusFound)
-}
-
-// getSessionID retrieves session ID from cookie
-func (s *Server) getSessionID(r *http.Request) string {
-	cookie, err := r.Cookie("session")
-	if err != nil {
-		return ""
-	}
-	return cookie.Value
-}
-
-// isValidSession checks if a session is valid
-func (s *Server) isValidSession(sessionID string) bool {
-	if sessionID == "" {
-		return false
-	}
-	s.sessionsMu.RLock()
-	defer s.sessionsMu.RUnlock()
-	expiry, exists := s.sessions[sessionID]
-	return exists && time.Now().Before(expiry)
-}
-
-// createSession creates a new session
-func (s *Server) createSession() string {
-	sessionID := generateSessionID()
-	s.sessionsMu.Lock()
-	defer s.sessionsMu.Unlock()
-	s.sessions[sessionID] = time.Now().Add(24 * time.Hour)
-	return sessionID
-}
-
-// deleteSession deletes a session
-func (s *Server) deleteSession(sessionID string) {
-	s.sessionsMu.Lock()
-	defer s.sessionsMu.Unlock()
-	delete(s.sessions, sessionID)
-}
-
-// cleanupSessions removes expired sessions periodically
-func (s *Server) cleanupSessions() {
-	ticker := time.NewTicker(1 * time.Hour)
-	defer ticker.Stop()
-	for range ticker.C {
-		s.sessionsMu.Lock()
-		now := time.Now()
-		for sessionID, expiry := range s.sessions {
-			if now.After(expiry) {
-				delete(s.sessions, sessionID)
-			}
-		}
-		s.sessionsMu.Unlock()
-	}
+	// File names carry their version, so browsers can keep them
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	http.ServeFile(w, r, name)
 }
 
-// generateSessionID generates a random session ID
-func generateSessionID() string {
-	b := make([]byte, 32)
-	rand.Read(b)
-	return base64.URLEncoding.EncodeToString(b)
+// parseVideoID parses video ID from string
+func parseVideoID(s string) int64 {
+	id, _ := strconv.ParseInt(s, 10, 64)
+	return id
 }
 
 // needsTranscodingByCodec checks if a video file needs transcoding by inspecting actual codecs
@@ -1442,61 +2054,28 @@ func needsTranscodingByCodec(filePath string) bool {
 }
 
 // transcodeAndServe transcodes video files to MP4 and caches the result on disk
+// The transcode runs as a background job, so concurrent requests share one ffmpeg process
+// and a client disconnect does not leave a half-written cache file behind
 func (s *Server) transcodeAndServe(w http.ResponseWriter, r *http.Request, inputPath string, videoID int64) {
-	// Check if transcoded version already exists in cache
-	cachedPath := filepath.Join(s.downloadDir, fmt.Sprintf("transcoded-%d.mp4", videoID))
+	job := s.transcodes.Start(videoID, func() (string, error) {
+		return inputPath, nil
+	})
 
-	if _, err := os.Stat(cachedPath); err == nil {
-		log.Printf("Serving cached transcoded video %d from: %s", videoID, cachedPath)
-		http.ServeFile(w, r, cachedPath)
+	status, err := s.transcodes.Wait(r.Context(), job)
+	if err != nil {
+		log.Printf("Client stopped waiting for transcode of video %d, continuing in background: %v", videoID, err)
 		return
 	}
 
-	// Transcode to cache file
-	log.Printf("Transcoding video %d to browser-compatible MP4: %s -> %s", videoID, inputPath, cachedPath)
-
-	// Use ffmpeg to transcode to browser-compatible MP4 (H.264 video + AAC audio)
-	// -c:v libx264: H.264 video codec (universally supported)
-	// -preset veryfast: Fast encoding with reasonable quality
-	// -crf 23: Constant quality (lower = better quality, 23 is good balance)
-	// -map 0:v:0: Map first video stream
-	// -map 0:a: Map ALL audio streams (important for multi-audio videos)
-	// -c:a aac: AAC audio codec (universally supported)
-	// -b:a 128k: 128kbps audio bitrate
-	// -ac 2: Force stereo output (browser compatible)
-	// -movflags +faststart: Enable streaming before full download
-	// -max_muxing_queue_size 1024: Handle high bitrate streams
-	cmd := exec.Command("ffmpeg",
-		"-i", inputPath,
-		"-map", "0:v:0", // Explicitly map first video stream
-		"-map", "0:a",   // Map all audio streams
-		"-c:v", "libx264",
-		"-preset", "veryfast",
-		"-crf", "23",
-		"-c:a", "aac",
-		"-b:a", "128k",
-		"-ac", "2", // Force stereo output
-		"-movflags", "+faststart",
-		"-max_muxing_queue_size", "1024", // Handle complex files better
-		"-y",
-		cachedPath,
-	)
-
-	// Capture stderr for logging
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-
-	// Run ffmpeg and wait for completion
-	if err := cmd.Run(); err != nil {
-		log.Printf("ffmpeg error for video %d: %v\nStderr: %s", videoID, err, stderr.String())
+	switch status.Status {
+	case TranscodeFailed:
 		http.Error(w, "Failed to transcode video", http.StatusInternalServerError)
-		return
+	case TranscodeSkipped:
+		http.ServeFile(w, r, inputPath)
+	default:
+		log.Printf("Serving transcoded video %d from: %s", videoID, s.transcodes.OutputPath(videoID))
+		http.ServeFile(w, r, s.transcodes.OutputPath(videoID))
 	}
-
-	log.Printf("Successfully transcoded video %d to cache", videoID)
-
-	// Serve the transcoded file
-	http.ServeFile(w, r, cachedPath)
 }
 
 // handleAPIDeleteEpisode handles deletion of a single episode
@@ -1541,11 +2120,8 @@ func (s *Server) handleAPIDeleteEpisode(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	// Delete transcoded cache if exists
-	transcodedPath := filepath.Join(s.downloadDir, fmt.Sprintf("transcoded-%d.mp4", videoID))
-	if err := os.Remove(transcodedPath); err != nil && !os.IsNotExist(err) {
-		log.Printf("Warning: Failed to delete transcoded cache file %s: %v", transcodedPath, err)
-	}
+	// Delete transcoded and re-downloaded cache files if they exist
+	s.removeVideoCache(videoID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
@@ -1561,6 +2137,9 @@ func (s *Server) handleAPIDeleteShow(w http.ResponseWriter, r *http.Request) {
 		s.handleAPIShow(w, r)
 		return
 	}
+	if !s.checkAdmin(w, r) {
+		return
+	}
 
 	// Parse URL: /api/show/{showName} or /api/show/{showName}/season/{seasonNumber}
 	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/show/"), "/")
@@ -1614,14 +2193,15 @@ func (s *Server) handleAPIDeleteShow(w http.ResponseWriter, r *http.Request) {
 						log.Printf("Warning: Failed to delete local cache file %s: %v", localPath, err)
 					}
 				}
-
-				// Delete transcoded cache
-				transcodedPath := filepath.Join(s.downloadDir, fmt.Sprintf("transcoded-%d.mp4", video.ID))
-				os.Remove(transcodedPath) // Ignore errors
 			}
 		}
 	}
 
+	// Delete transcoded and re-downloaded cache files
+	for _, video := range videos {
+		s.removeVideoCache(video.ID)
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"success": true,
@@ -1656,8 +2236,8 @@ func (s *Server) handleAPIMoveToExtras(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"success":      true,
-		"message":      fmt.Sprintf("Moved %d episodes to extras", rowsAffected),
+		"success":       true,
+		"message":       fmt.Sprintf("Moved %d episodes to extras", rowsAffected),
 		"episodesMoved": rowsAffected,
 	})
 }
@@ -0,0 +1,98 @@
+package web
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/telegram"
+)
+
+// telegramLoginMaxAge is how old a Telegram Login Widget authorization may be
+const telegramLoginMaxAge = 24 * time.Hour
+
+// SetTelegramLogin enables the Telegram Login Widget on the login page
+// The bot's domain must be set with /setdomain in @BotFather for the widget to work
+func (s *Server) SetTelegramLogin(enabled bool) {
+	if enabled && s.downloader == nil {
+		log.Printf("Warning: Telegram login needs TELEGRAM_BOT_TOKEN, TELEGRAM_CHAT_ID and TELEGRAM_API_URL, leaving it disabled")
+		return
+	}
+	s.telegramLogin = enabled
+	if enabled {
+		log.Printf("Telegram login enabled for members of chat %d via @%s", s.chatID, s.downloader.BotUsername())
+	}
+}
+
+// handleTelegramLogin handles the Telegram Login Widget callback
+// The widget redirects here with the signed user fields; members of the bot's chat get a
+// session for their linked user, which is created on first login
+func (s *Server) handleTelegramLogin(w http.ResponseWriter, r *http.Request) {
+	if !s.telegramLogin {
+		http.NotFound(w, r)
+		return
+	}
+
+	query := r.URL.Query()
+	redirect := safeRedirect(query.Get("redirect"))
+	ip := s.clientIP(r)
+	fail := func(reason string) {
+		http.Redirect(w, r, "/login?redirect="+url.QueryEscape(redirect)+"&error="+reason, http.StatusFound)
+	}
+
+	if allowed, _ := s.logins.Allowed(ip); !allowed {
+		fail("locked")
+		return
+	}
+
+	// The widget appends its signed fields to our callback URL; our own parameter is not signed
+	fields := url.Values{}
+	for key, values := range query {
+		if key != "redirect" {
+			fields[key] = values
+		}
+	}
+	login, err := telegram.VerifyLogin(s.token, fields, telegramLoginMaxAge)
+	if err != nil {
+		s.logins.Failure(ip)
+		log.Printf("Rejected Telegram login from %s: %v", ip, err)
+		fail("telegram")
+		return
+	}
+
+	// Membership is checked on every login, so people who left the chat lose access to new sessions
+	member, err := s.downloader.ChatMember(login.ID)
+	if err != nil {
+		log.Printf("Error checking chat membership of Telegram user %d: %v", login.ID, err)
+		fail("telegram")
+		return
+	}
+	if !telegram.IsChatMember(member) {
+		log.Printf("Rejected Telegram login of %d (@%s): not a member of chat %d (%s)", login.ID, login.Username, s.chatID, member.Status)
+		fail("notmember")
+		return
+	}
+
+	user, err := s.db.GetUserByTelegramID(login.ID)
+	if err != nil {
+		log.Printf("Error looking up Telegram user %d: %v", login.ID, err)
+		http.Error(w, "Login failed", http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		// Everybody starts as a viewer, chat admins included; admins are promoted with trtg-user role
+		user, err = s.db.CreateTelegramUser(login.ID, login.Username, database.RoleViewer)
+		if err != nil {
+			log.Printf("Error creating user for Telegram user %d: %v", login.ID, err)
+			http.Error(w, "Login failed", http.StatusInternalServerError)
+			return
+		}
+		log.Printf("Created %s user %s for Telegram user %d", user.Role, user.Username, login.ID)
+	}
+
+	s.logins.Success(ip)
+	log.Printf("Telegram user %d logged in as %s", login.ID, user.Username)
+	s.startSession(w, r, user, redirect)
+}
@@ -0,0 +1,216 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rusik69/trtg/pkg/database"
+)
+
+// handleAPITokens lists (GET) and creates (POST) the current user's API tokens
+// POST body: {"name": "...", "scope": "read"|"admin"}; the response contains the token secret once
+func (s *Server) handleAPITokens(w http.ResponseWriter, r *http.Request) {
+	user := s.currentUser(r)
+
+	switch r.Method {
+	case "GET":
+		tokens, err := s.db.ListAPITokens(user.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(tokens)
+
+	case "POST":
+		var body struct {
+			Name  string `json:"name"`
+			Scope string `json:"scope"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if body.Scope == "" {
+			body.Scope = database.TokenScopeRead
+		}
+		// A token cannot grant more than the request creating it has
+		if body.Scope == database.TokenScopeAdmin && !s.isAdmin(r) {
+			writeAuthError(w, r, http.StatusForbidden, "Admin role required for admin tokens")
+			return
+		}
+
+		secret, token, err := s.db.CreateAPIToken(user, body.Name, body.Scope)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Printf("User %s created %s API token %q", user.Username, token.Scope, token.Name)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(struct {
+			*database.APIToken
+			Token string `json:"token"`
+		}{token, secret})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAPIToken revokes one of the current user's API tokens
+// URL: DELETE /api/tokens/{id}
+func (s *Server) handleAPIToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "DELETE" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tokenID, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/tokens/"), 10, 64)
+	if err != nil {
+		http.Error(w, "Token ID required", http.StatusBadRequest)
+		return
+	}
+
+	user := s.currentUser(r)
+	if err := s.db.RevokeAPIToken(user.ID, tokenID); err != nil {
+		http.Error(w, fmt.Sprintf("Failed to revoke token: %v", err), http.StatusNotFound)
+		return
+	}
+	log.Printf("User %s revoked API token %d", user.Username, tokenID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"success": true})
+}
+
+// handleTokensPage shows the current user's API tokens with forms to create and revoke them
+func (s *Server) handleTokensPage(w http.ResponseWriter, r *http.Request) {
+	tmpl := `<!DOCTYPE html>
+<html>
+<head>
+	<title>API Tokens</title>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<style>
+		* { margin: 0; padding: 0; box-sizing: border-box; }
+		body { font-family: -apple-system, BlinkMacSystemFont, 'Segoe UI', Roboto, sans-serif; background: #1a1a1a; color: #fff; padding: 20px; }
+		.container { max-width: 900px; margin: 0 auto; }
+		.header { display: flex; justify-content: space-between; align-items: center; margin-bottom: 30px; }
+		.back-btn { background: #4a9eff; color: white; border: none; padding: 10px 20px; border-radius: 4px; cursor: pointer; text-decoration: none; display: inline-block; }
+		.back-btn:hover { background: #5aaeff; }
+		.create-form { background: #2a2a2a; border-radius: 8px; padding: 20px; margin-bottom: 20px; display: flex; gap: 10px; flex-wrap: wrap; }
+		input, select { padding: 10px; border: 1px solid #444; border-radius: 4px; background: #1a1a1a; color: #fff; font-size: 14px; }
+		input { flex: 1; min-width: 200px; }
+		button { background: #28a745; color: white; border: none; padding: 10px 20px; border-radius: 4px; cursor: pointer; }
+		button:hover { background: #218838; }
+		.revoke-btn { background: #dc3545; padding: 6px 12px; }
+		.revoke-btn:hover { background: #c82333; }
+		.new-token { display: none; background: #2a2a2a; border: 1px solid #28a745; border-radius: 8px; padding: 20px; margin-bottom: 20px; }
+		.new-token code { display: block; margin-top: 10px; padding: 10px; background: #1a1a1a; border-radius: 4px; word-break: break-all; }
+		table { width: 100%; border-collapse: collapse; background: #2a2a2a; border-radius: 8px; overflow: hidden; }
+		th, td { padding: 12px; text-align: left; border-bottom: 1px solid #444; }
+		th { color: #aaa; font-weight: normal; font-size: 14px; }
+		.empty { color: #aaa; padding: 20px; }
+	</style>
+	` + csrfFetchScript + `
+	` + searchScript + `
+</head>
+<body>
+	<div class="container">
+		<div class="header">
+			<h1>API Tokens</h1>
+			<a href="/" class="back-btn">← Back to Shows</a>
+		</div>
+		<form class="create-form" onsubmit="createToken(event)">
+			<input type="text" id="tokenName" placeholder="Token name, e.g. phone" required>
+			<select id="tokenScope">
+				<option value="read">Read-only</option>
+				{{if .IsAdmin}}<option value="admin">Admin</option>{{end}}
+			</select>
+			<button type="submit">Create Token</button>
+		</form>
+		<div class="new-token" id="newToken">
+			Copy the token now, it will not be shown again. Use it as <em>Authorization: Bearer &lt;token&gt;</em>.
+			<code id="newTokenValue"></code>
+		</div>
+		<table>
+			<thead><tr><th>Name</th><th>Scope</th><th>Created</th><th>Last used</th><th></th></tr></thead>
+			<tbody id="tokens"></tbody>
+		</table>
+	</div>
+	<script>
+		function escapeHtml(text) {
+			const div = document.createElement('div');
+			div.textContent = text;
+			return div.innerHTML;
+		}
+
+		function loadTokens() {
+			fetch('/api/tokens')
+				.then(r => r.json())
+				.then(tokens => {
+					const body = document.getElementById('tokens');
+					if (tokens.length === 0) {
+						body.innerHTML = '<tr><td colspan="5" class="empty">No tokens yet</td></tr>';
+						return;
+					}
+					body.innerHTML = tokens.map(token =>
+						'<tr><td>' + escapeHtml(token.name) + '</td><td>' + token.scope + '</td>' +
+						'<td>' + new Date(token.createdAt).toLocaleString() + '</td>' +
+						'<td>' + (token.lastUsedAt ? new Date(token.lastUsedAt).toLocaleString() : 'Never') + '</td>' +
+						'<td><button class="revoke-btn" onclick="revokeToken(' + token.id + ')">Revoke</button></td></tr>'
+					).join('');
+				});
+		}
+
+		function createToken(event) {
+			event.preventDefault();
+			fetch('/api/tokens', {
+				method: 'POST',
+				headers: { 'Content-Type': 'application/json' },
+				body: JSON.stringify({
+					name: document.getElementById('tokenName').value,
+					scope: document.getElementById('tokenScope').value
+				})
+			})
+				.then(r => r.ok ? r.json() : r.text().then(text => { throw new Error(text); }))
+				.then(token => {
+					document.getElementById('newTokenValue').textContent = token.token;
+					document.getElementById('newToken').style.display = 'block';
+					document.getElementById('tokenName').value = '';
+					loadTokens();
+				})
+				.catch(error => alert('Failed to create token: ' + error.message));
+		}
+
+		function revokeToken(id) {
+			if (!confirm('Revoke this token? Clients using it will stop working.')) {
+				return;
+			}
+			fetch('/api/tokens/' + id, { method: 'DELETE' })
+				.then(r => {
+					if (!r.ok) {
+						throw new Error('HTTP ' + r.status);
+					}
+					loadTokens();
+				})
+				.catch(error => alert('Failed to revoke token: ' + error.message));
+		}
+
+		loadTokens();
+	</script>
+</body>
+</html>`
+
+	t, _ := template.New("tokens").Parse(tmpl)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	t.Execute(w, map[string]interface{}{
+		"IsAdmin": s.isAdmin(r),
+	})
+}
@@ -0,0 +1,89 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rusik69/trtg/pkg/database"
+	"github.com/rusik69/trtg/pkg/torrent"
+)
+
+const (
+	// torrentMetadataTimeout bounds how long the stream endpoint waits for torrent metadata
+	torrentMetadataTimeout = 2 * time.Minute
+	// torrentIdleTimeout is how long a streamed torrent is kept after its last reader is closed
+	torrentIdleTimeout = 10 * time.Minute
+)
+
+// SetTorrentDownloader enables streaming of not-yet-uploaded episodes straight from the torrent swarm.
+// Torrents are only added when an episode is played and dropped once nobody watches them
+func (s *Server) SetTorrentDownloader(d *torrent.Downloader) {
+	s.torrents = torrent.NewStreams(d, torrentIdleTimeout)
+	s.torrents.Start()
+}
+
+// handleAPITorrentStream streams a video that has not been uploaded yet directly from the torrent swarm
+// URL: /api/torrent-stream/{videoID}
+func (s *Server) handleAPITorrentStream(w http.ResponseWriter, r *http.Request) {
+	if s.torrents == nil {
+		http.Error(w, "Torrent streaming is not enabled", http.StatusNotFound)
+		return
+	}
+
+	videoID, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/torrent-stream/"), "/"), 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid video ID", http.StatusBadRequest)
+		return
+	}
+	video, err := s.db.GetVideoByID(videoID)
+	if err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+	if !canStreamFromTorrent(*video) {
+		http.Error(w, "Video is not streamable from a torrent", http.StatusNotFound)
+		return
+	}
+
+	// The stored magnet link carries the tracker URLs
+	metadataCtx, cancel := context.WithTimeout(r.Context(), torrentMetadataTimeout)
+	reader, file, err := s.torrents.Open(r.Context(), metadataCtx, video.VideoID, video.FilePath)
+	cancel()
+	if err != nil {
+		log.Printf("Error opening torrent stream for video %d: %v", video.ID, err)
+		status := http.StatusBadGateway
+		if errors.Is(err, torrent.ErrFileNotFound) {
+			status = http.StatusNotFound
+		}
+		http.Error(w, fmt.Sprintf("Failed to open torrent stream: %v", err), status)
+		return
+	}
+	defer reader.Close()
+
+	log.Printf("Streaming %s from torrent %s (%.1f%% downloaded)", file.Path(), torrent.InfoHashFromURL(video.VideoID),
+		float64(file.BytesCompleted())/float64(max(file.Length(), 1))*100)
+
+	// ServeContent handles Range requests by seeking the reader, which reprioritizes pieces
+	http.ServeContent(w, r, filepath.Base(file.Path()), time.Time{}, reader)
+}
+
+// canStreamFromTorrent reports whether a video is still waiting for upload and comes from a magnet link
+func canStreamFromTorrent(video database.Video) bool {
+	return !video.Uploaded() && torrent.InfoHashFromURL(video.VideoID) != ""
+}
+
+// torrentStreamURL returns the torrent stream URL for a video that has not been uploaded yet, or ""
+// Nothing is fetched until the URL is played
+func (s *Server) torrentStreamURL(video database.Video) string {
+	if s.torrents == nil || !canStreamFromTorrent(video) {
+		return ""
+	}
+	return fmt.Sprintf("/api/torrent-stream/%d", video.ID)
+}
@@ -0,0 +1,273 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// textSubtitleCodecs are embedded subtitle codecs ffmpeg can convert to WebVTT
+// Bitmap formats (PGS, VobSub) would need OCR and are not offered
+var textSubtitleCodecs = map[string]bool{
+	"subrip": true, "ass": true, "ssa": true, "webvtt": true, "mov_text": true, "text": true,
+}
+
+// srtTimestamp matches SRT timestamps, which use a comma before the milliseconds
+var srtTimestamp = regexp.MustCompile(`(\d{2}:\d{2}:\d{2}),(\d{3})`)
+
+// Track is an audio or subtitle track the player can switch to
+type Track struct {
+	Kind     string `json:"kind"`            // "audio" or "subtitle"
+	Index    int    `json:"index"`           // Audio: position among audio streams; subtitle: position in the list
+	Language string `json:"language"`        // Language tag as found in the file (e.g. "eng"), may be empty
+	Label    string `json:"label"`           // Display name
+	Codec    string `json:"codec,omitempty"` // Source codec
+	Channels int    `json:"channels,omitempty"`
+	Default  bool   `json:"default"`
+	URL      string `json:"url,omitempty"` // WebVTT URL for subtitles
+	External bool   `json:"external"`      // Subtitle shipped as a separate file
+}
+
+// probeStream is the subset of ffprobe stream output used for track listing
+type probeStream struct {
+	Index       int    `json:"index"`
+	CodecType   string `json:"codec_type"`
+	CodecName   string `json:"codec_name"`
+	Channels    int    `json:"channels"`
+	Disposition struct {
+		Default int `json:"default"`
+	} `json:"disposition"`
+	Tags struct {
+		Language string `json:"language"`
+		Title    string `json:"title"`
+	} `json:"tags"`
+}
+
+// probeStreams lists the audio and subtitle streams of a media file using ffprobe
+func probeStreams(filePath string) ([]probeStream, error) {
+	cmd := exec.Command("ffprobe",
+		"-v", "error",
+		"-show_entries", "stream=index,codec_type,codec_name,channels:stream_tags=language,title:stream_disposition=default",
+		"-of", "json",
+		filePath,
+	)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe streams: %w", err)
+	}
+
+	var result struct {
+		Streams []probeStream `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %w", err)
+	}
+	return result.Streams, nil
+}
+
+// trackLabel builds a display name from stream tags
+func trackLabel(title, language string, fallback string) string {
+	switch {
+	case title != "" && language != "":
+		return fmt.Sprintf("%s (%s)", title, language)
+	case title != "":
+		return title
+	case language != "":
+		return language
+	}
+	return fallback
+}
+
+// handleAPITracks lists the audio and subtitle tracks of a video
+// URL: /api/tracks/{id}
+func (s *Server) handleAPITracks(w http.ResponseWriter, r *http.Request) {
+	videoID := parseVideoID(strings.TrimPrefix(r.URL.Path, "/api/tracks/"))
+	video, err := s.db.GetVideoByID(videoID)
+	if err != nil {
+		http.Error(w, "Video not found", http.StatusNotFound)
+		return
+	}
+
+	result := struct {
+		Audio     []Track `json:"audio"`
+		Subtitles []Track `json:"subtitles"`
+	}{
+		Audio:     []Track{},
+		Subtitles: []Track{},
+	}
+
+	// Embedded tracks need the source file; external subtitles are listed regardless
+	if video.TelegramFileID != "" {
+		if src, err := s.sourcePath(video); err != nil {
+			log.Printf("Warning: No local source for video %d, listing external subtitles only: %v", videoID, err)
+		} else if streams, err := probeStreams(src); err != nil {
+			log.Printf("Warning: Failed to list tracks of video %d: %v", videoID, err)
+		} else {
+			for _, stream := range streams {
+				switch stream.CodecType {
+				case "audio":
+					index := len(result.Audio)
+					result.Audio = append(result.Audio, Track{
+						Kind:     "audio",
+						Index:    index,
+						Language: stream.Tags.Language,
+						Label:    trackLabel(stream.Tags.Title, stream.Tags.Language, fmt.Sprintf("Track %d", index+1)),
+						Codec:    stream.CodecName,
+						Channels: stream.Channels,
+						Default:  stream.Disposition.Default == 1,
+					})
+				case "subtitle":
+					if !textSubtitleCodecs[stream.CodecName] {
+						continue
+					}
+					result.Subtitles = append(result.Subtitles, Track{
+						Kind:     "subtitle",
+						Index:    len(result.Subtitles),
+						Language: stream.Tags.Language,
+						Label:    trackLabel(stream.Tags.Title, stream.Tags.Language, fmt.Sprintf("Subtitles %d", len(result.Subtitles)+1)),
+						Codec:    stream.CodecName,
+						Default:  stream.Disposition.Default == 1,
+						URL:      fmt.Sprintf("/api/subtitles/%d/s%d", videoID, stream.Index),
+					})
+				}
+			}
+		}
+	}
+
+	subtitles, err := s.db.GetSubtitlesByVideo(videoID)
+	if err != nil {
+		log.Printf("Warning: Failed to load external subtitles of video %d: %v", videoID, err)
+	}
+	for _, subtitle := range subtitles {
+		result.Subtitles = append(result.Subtitles, Track{
+			Kind:     "subtitle",
+			Index:    len(result.Subtitles),
+			Language: subtitle.Language,
+			Label:    subtitle.Label,
+			Codec:    subtitle.Format,
+			URL:      fmt.Sprintf("/api/subtitles/%d/x%d", videoID, subtitle.ID),
+			External: true,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleAPISubtitles serves a subtitle track converted to WebVTT
+// URL: /api/subtitles/{id}/s{streamIndex} for embedded tracks, /api/subtitles/{id}/x{subtitleID} for external files
+func (s *Server) handleAPISubtitles(w http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/api/subtitles/"), "/")
+	if len(pathParts) != 2 || len(pathParts[1]) < 2 {
+		http.Error(w, "Video ID and track required", http.StatusBadRequest)
+		return
+	}
+
+	videoID := parseVideoID(pathParts[0])
+	key, trackID := pathParts[1][0], strings.TrimSuffix(pathParts[1][1:], ".vtt")
+	id, err := strconv.ParseInt(trackID, 10, 64)
+	if err != nil {
+		http.Error(w, "Invalid track", http.StatusBadRequest)
+		return
+	}
+
+	var vtt []byte
+	switch key {
+	case 'x':
+		subtitle, err := s.db.GetSubtitle(id)
+		if err != nil || subtitle.VideoID != videoID {
+			http.Error(w, "Subtitle not found", http.StatusNotFound)
+			return
+		}
+		vtt, err = subtitleToWebVTT(subtitle.Format, subtitle.Content)
+		if err != nil {
+			log.Printf("Error converting subtitle %d to WebVTT: %v", id, err)
+			http.Error(w, "Failed to convert subtitle", http.StatusInternalServerError)
+			return
+		}
+	case 's':
+		video, err := s.db.GetVideoByID(videoID)
+		if err != nil {
+			http.Error(w, "Video not found", http.StatusNotFound)
+			return
+		}
+		vtt, err = s.extractSubtitle(video.ID, int(id), func() (string, error) {
+			return s.sourcePath(video)
+		})
+		if err != nil {
+			log.Printf("Error extracting subtitle stream %d of video %d: %v", id, videoID, err)
+			http.Error(w, "Failed to extract subtitle", http.StatusInternalServerError)
+			return
+		}
+	default:
+		http.Error(w, "Invalid track", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+	w.Write(vtt)
+}
+
+// extractSubtitle converts an embedded subtitle stream to WebVTT, caching the result
+func (s *Server) extractSubtitle(videoID int64, streamIndex int, source func() (string, error)) ([]byte, error) {
+	cachedPath := filepath.Join(s.CacheDir(), fmt.Sprintf("subs-%d-%d.vtt", videoID, streamIndex))
+	if vtt, err := os.ReadFile(cachedPath); err == nil {
+		return vtt, nil
+	}
+
+	src, err := source()
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := exec.Command("ffmpeg",
+		"-i", src,
+		"-map", fmt.Sprintf("0:%d", streamIndex),
+		"-f", "webvtt",
+		"pipe:1",
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	vtt, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg error: %w\nStderr: %s", err, stderr.String())
+	}
+
+	if err := os.WriteFile(cachedPath+".part", vtt, 0644); err == nil {
+		os.Rename(cachedPath+".part", cachedPath)
+	}
+	return vtt, nil
+}
+
+// subtitleToWebVTT converts subtitle file content to WebVTT
+// SRT only differs in its header and timestamp separator; ASS/SSA styling is converted by ffmpeg
+func subtitleToWebVTT(format, content string) ([]byte, error) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+
+	switch format {
+	case "vtt":
+		return []byte(content), nil
+	case "srt":
+		return []byte("WEBVTT\n\n" + srtTimestamp.ReplaceAllString(content, "$1.$2")), nil
+	case "ass", "ssa":
+		cmd := exec.Command("ffmpeg", "-f", "ass", "-i", "pipe:0", "-f", "webvtt", "pipe:1")
+		cmd.Stdin = strings.NewReader(content)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		vtt, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("ffmpeg error: %w\nStderr: %s", err, stderr.String())
+		}
+		return vtt, nil
+	}
+	return nil, fmt.Errorf("unsupported subtitle format: %s", format)
+}
@@ -0,0 +1,397 @@
+package web
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Transcode job states
+const (
+	TranscodeQueued  = "queued"
+	TranscodeRunning = "running"
+	TranscodeDone    = "done"
+	TranscodeSkipped = "skipped" // Source is already browser-compatible
+	TranscodeFailed  = "failed"
+)
+
+// DefaultTranscodeWorkers is the number of ffmpeg full-file transcodes allowed to run at once
+const DefaultTranscodeWorkers = 1
+
+// transcodeJobRetention is how long finished jobs are kept for status polling; afterwards the
+// status of a finished transcode comes from its output file
+const transcodeJobRetention = time.Hour
+
+// TranscodeJob describes a full-file transcode of one video
+type TranscodeJob struct {
+	VideoID    int64      `json:"videoId"`
+	Status     string     `json:"status"`
+	Progress   float64    `json:"progress"` // Percent of the source duration encoded so far
+	Error      string     `json:"error,omitempty"`
+	QueuedAt   time.Time  `json:"queuedAt"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+	done chan struct{} // Closed when the job finishes (done, skipped or failed)
+}
+
+// TranscodeManager runs full-file transcodes in the background
+// There is at most one job per video, and at most workers ffmpeg processes at a time
+type TranscodeManager struct {
+	outputDir string
+	slots     chan struct{}
+	mu        sync.Mutex
+	jobs      map[int64]*TranscodeJob
+}
+
+// NewTranscodeManager creates a transcode manager writing transcoded-{id}.mp4 files to outputDir
+func NewTranscodeManager(outputDir string, workers int) *TranscodeManager {
+	if workers < 1 {
+		workers = 1
+	}
+	return &TranscodeManager{
+		outputDir: outputDir,
+		slots:     make(chan struct{}, workers),
+		jobs:      make(map[int64]*TranscodeJob),
+	}
+}
+
+// OutputPath returns where the transcoded file for a video is cached
+func (m *TranscodeManager) OutputPath(videoID int64) string {
+	return filepath.Join(m.outputDir, fmt.Sprintf("transcoded-%d.mp4", videoID))
+}
+
+// Start queues a transcode of a video unless one is already queued, running or finished
+// source resolves the local input file once the job gets a worker slot
+func (m *TranscodeManager) Start(videoID int64, source func() (string, error)) *TranscodeJob {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.evictFinished(time.Now())
+	if job, ok := m.jobs[videoID]; ok && job.Status != TranscodeFailed {
+		if job.Status != TranscodeDone {
+			return job
+		}
+		// Re-run if the cached output was removed (deleted episode or cache cleanup)
+		if _, err := os.Stat(m.OutputPath(videoID)); err == nil {
+			return job
+		}
+	}
+
+	job := &TranscodeJob{
+		VideoID:  videoID,
+		Status:   TranscodeQueued,
+		QueuedAt: time.Now(),
+		done:     make(chan struct{}),
+	}
+
+	// A transcode left by an earlier process is reused as is
+	if _, err := os.Stat(m.OutputPath(videoID)); err == nil {
+		now := time.Now()
+		job.Status = TranscodeDone
+		job.Progress = 100
+		job.FinishedAt = &now
+		close(job.done)
+		m.jobs[videoID] = job
+		return job
+	}
+
+	m.jobs[videoID] = job
+	go m.run(job, source)
+	return job
+}
+
+// Status returns a snapshot of the latest job for a video
+func (m *TranscodeManager) Status(videoID int64) (TranscodeJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[videoID]
+	if !ok {
+		if _, err := os.Stat(m.OutputPath(videoID)); err == nil {
+			return TranscodeJob{VideoID: videoID, Status: TranscodeDone, Progress: 100}, true
+		}
+		return TranscodeJob{}, false
+	}
+	return *job, true
+}
+
+// Forget drops the job record of a video (used when the video is deleted)
+func (m *TranscodeManager) Forget(videoID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if job, ok := m.jobs[videoID]; ok && (job.Status == TranscodeDone || job.Status == TranscodeSkipped || job.Status == TranscodeFailed) {
+		delete(m.jobs, videoID)
+	}
+}
+
+// evictFinished drops jobs that finished more than transcodeJobRetention before now
+// The caller must hold the manager lock
+func (m *TranscodeManager) evictFinished(now time.Time) {
+	for videoID, job := range m.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > transcodeJobRetention {
+			delete(m.jobs, videoID)
+		}
+	}
+}
+
+// Wait blocks until the job finishes or ctx is done
+// The job keeps running in the background if ctx is cancelled (e.g. the client disconnected)
+func (m *TranscodeManager) Wait(ctx context.Context, job *TranscodeJob) (TranscodeJob, error) {
+	select {
+	case <-job.done:
+		status, _ := m.Status(job.VideoID)
+		return status, nil
+	case <-ctx.Done():
+		return TranscodeJob{}, ctx.Err()
+	}
+}
+
+// update modifies a job while holding the manager lock
+func (m *TranscodeManager) update(job *TranscodeJob, fn func(*TranscodeJob)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(job)
+}
+
+// run waits for a worker slot, then transcodes the source to a temporary file and renames it into place
+func (m *TranscodeManager) run(job *TranscodeJob, source func() (string, error)) {
+	defer close(job.done)
+
+	m.slots <- struct{}{}
+	defer func() { <-m.slots }()
+
+	now := time.Now()
+	m.update(job, func(j *TranscodeJob) {
+		j.Status = TranscodeRunning
+		j.StartedAt = &now
+	})
+
+	err := m.transcode(job, source)
+
+	finished := time.Now()
+	var status string
+	m.update(job, func(j *TranscodeJob) {
+		j.FinishedAt = &finished
+		switch {
+		case err != nil:
+			j.Status = TranscodeFailed
+			j.Error = err.Error()
+		case j.Status == TranscodeRunning:
+			j.Status = TranscodeDone
+			j.Progress = 100
+		}
+		status = j.Status
+	})
+
+	if err != nil {
+		log.Printf("Transcode of video %d failed: %v", job.VideoID, err)
+	} else {
+		log.Printf("Transcode of video %d finished (%s) in %v", job.VideoID, status, finished.Sub(now).Round(time.Second))
+	}
+}
+
+// transcode runs ffmpeg for a job, reporting progress from its -progress output
+func (m *TranscodeManager) transcode(job *TranscodeJob, source func() (string, error)) error {
+	inputPath, err := source()
+	if err != nil {
+		return fmt.Errorf("failed to get source file: %w", err)
+	}
+
+	if !needsTranscodingByCodec(inputPath) {
+		m.update(job, func(j *TranscodeJob) { j.Status = TranscodeSkipped })
+		return nil
+	}
+
+	// Progress is reported relative to the source duration; without it only completion is known
+	duration, err := probeDuration(inputPath)
+	if err != nil {
+		log.Printf("Warning: Unknown duration for video %d, progress will not be reported: %v", job.VideoID, err)
+	}
+
+	outputPath := m.OutputPath(job.VideoID)
+	tmpPath := outputPath + ".part"
+
+	log.Printf("Transcoding video %d to browser-compatible MP4: %s -> %s", job.VideoID, inputPath, outputPath)
+
+	// Use ffmpeg to transcode to browser-compatible MP4 (H.264 video + AAC audio)
+	// -c:v libx264: H.264 video codec (universally supported)
+	// -preset veryfast: Fast encoding with reasonable quality
+	// -crf 23: Constant quality (lower = better quality, 23 is good balance)
+	// -map 0:v:0: Map first video stream
+	// -map 0:a: Map ALL audio streams (important for multi-audio videos)
+	// -c:a aac: AAC audio codec (universally supported)
+	// -b:a 128k: 128kbps audio bitrate
+	// -ac 2: Force stereo output (browser compatible)
+	// -movflags +faststart: Enable streaming before full download
+	// -max_muxing_queue_size 1024: Handle high bitrate streams
+	// -progress pipe:1: Machine-readable progress on stdout
+	// -f mp4: Output format must be explicit since the temporary file has a .part extension
+	cmd := exec.Command("ffmpeg",
+		"-i", inputPath,
+		"-map", "0:v:0", // Explicitly map first video stream
+		"-map", "0:a", // Map all audio streams
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "23",
+		"-c:a", "aac",
+		"-b:a", "128k",
+		"-ac", "2", // Force stereo output
+		"-movflags", "+faststart",
+		"-max_muxing_queue_size", "1024", // Handle complex files better
+		"-progress", "pipe:1",
+		"-nostats",
+		"-f", "mp4",
+		"-y",
+		tmpPath,
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open ffmpeg progress pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start ffmpeg: %w", err)
+	}
+
+	readProgress(stdout, duration, func(progress float64) {
+		m.update(job, func(j *TranscodeJob) { j.Progress = progress })
+	})
+
+	if err := cmd.Wait(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("ffmpeg error: %w\nStderr: %s", err, stderr.String())
+	}
+
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to move transcoded file into place: %w", err)
+	}
+	return nil
+}
+
+// readProgress reads ffmpeg -progress output until EOF, calling report with the percent of a source
+// of duration seconds encoded so far. Progress stays below 100 until the transcode is known to be done
+func readProgress(r io.Reader, duration float64, report func(float64)) {
+	// -progress writes key=value lines; out_time_us is the position encoded so far
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok || key != "out_time_us" || duration <= 0 {
+			continue
+		}
+		outTime, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			continue
+		}
+		progress := float64(outTime) / 1e6 / duration * 100
+		report(min(max(progress, 0), 99.9))
+	}
+}
+
+// SetTranscodeWorkers limits how many full-file transcodes run concurrently
+// Must be called before the server starts handling requests
+func (s *Server) SetTranscodeWorkers(workers int) {
+	s.transcodes = NewTranscodeManager(s.downloadDir, workers)
+}
+
+// startTranscode queues a background transcode of a video
+func (s *Server) startTranscode(videoID int64) (*TranscodeJob, error) {
+	video, err := s.db.GetVideoByID(videoID)
+	if err != nil {
+		return nil, err
+	}
+	return s.transcodes.Start(videoID, func() (string, error) {
+		return s.sourcePath(video)
+	}), nil
+}
+
+// handleAPITranscode reports (GET) or starts (POST) the background transcode of a video
+// URL: /api/transcode/{id}
+func (s *Server) handleAPITranscode(w http.ResponseWriter, r *http.Request) {
+	videoID := parseVideoID(strings.TrimPrefix(r.URL.Path, "/api/transcode/"))
+	if videoID == 0 {
+		http.Error(w, "Video ID required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+	case "POST":
+		if _, err := s.startTranscode(videoID); err != nil {
+			http.Error(w, "Video not found", http.StatusNotFound)
+			return
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	job, ok := s.transcodes.Status(videoID)
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"videoId": videoID,
+			"status":  "none",
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(job)
+}
+
+// handleAPITranscodeSeason queues background transcodes for every episode of a season (admins only)
+// Episodes that are already browser-compatible finish as skipped
+// URL: POST /api/show/{showName}/season/{seasonNumber}/transcode
+func (s *Server) handleAPITranscodeSeason(w http.ResponseWriter, r *http.Request, showName string, seasonNumber int) {
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.checkAdmin(w, r) {
+		return
+	}
+
+	episodes, err := s.db.GetEpisodesByShowAndSeason(showName, seasonNumber)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	jobs := make([]TranscodeJob, 0, len(episodes))
+	for _, video := range episodes {
+		if video.TelegramFileID == "" {
+			continue
+		}
+		s.transcodes.Start(video.ID, func() (string, error) {
+			return s.sourcePath(&video)
+		})
+		if job, ok := s.transcodes.Status(video.ID); ok {
+			jobs = append(jobs, job)
+		}
+	}
+
+	log.Printf("Queued pre-transcode of %d episodes of %s season %d", len(jobs), showName, seasonNumber)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"success": true,
+		"message": fmt.Sprintf("Queued %d episodes for transcoding", len(jobs)),
+		"jobs":    jobs,
+	})
+}
@@ -0,0 +1,180 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTranscodeManagerSingleFlight(t *testing.T) {
+	m := NewTranscodeManager(t.TempDir(), 1)
+
+	release := make(chan struct{})
+	var calls atomic.Int32
+	source := func() (string, error) {
+		calls.Add(1)
+		<-release
+		return "", errors.New("source not available")
+	}
+
+	job := m.Start(1, source)
+	if again := m.Start(1, source); again != job {
+		t.Error("Start() of a video with a pending job queued another one")
+	}
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	status, err := m.Wait(ctx, job)
+	if err != nil {
+		t.Fatalf("Wait() error = %v", err)
+	}
+	if status.Status != TranscodeFailed || !strings.Contains(status.Error, "source not available") {
+		t.Errorf("Wait() = %s (%q), want %s", status.Status, status.Error, TranscodeFailed)
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("source called %d times, want 1", n)
+	}
+
+	// A failed transcode can be retried
+	if retry := m.Start(1, source); retry == job {
+		t.Error("Start() after a failure returned the failed job")
+	}
+}
+
+func TestTranscodeManagerWorkers(t *testing.T) {
+	m := NewTranscodeManager(t.TempDir(), 1)
+
+	started := make(chan int64, 2)
+	release := make(chan struct{})
+	source := func(videoID int64) func() (string, error) {
+		return func() (string, error) {
+			started <- videoID
+			<-release
+			return "", errors.New("stopped")
+		}
+	}
+
+	first := m.Start(1, source(1))
+	second := m.Start(2, source(2))
+	<-started
+	select {
+	case id := <-started:
+		t.Fatalf("video %d started while the only worker was busy", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+	queued := 0
+	for _, id := range []int64{1, 2} {
+		if status, _ := m.Status(id); status.Status == TranscodeQueued {
+			queued++
+		}
+	}
+	if queued != 1 {
+		t.Errorf("%d jobs queued with one worker busy, want 1", queued)
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, job := range []*TranscodeJob{first, second} {
+		if _, err := m.Wait(ctx, job); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+}
+
+func TestTranscodeManagerReusesOutput(t *testing.T) {
+	m := NewTranscodeManager(t.TempDir(), 1)
+	if err := os.WriteFile(m.OutputPath(7), []byte("mp4"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	job := m.Start(7, func() (string, error) {
+		t.Error("source fetched for a video that is already transcoded")
+		return "", nil
+	})
+	if job.Status != TranscodeDone || job.Progress != 100 {
+		t.Errorf("Start() = %s at %v%%, want %s at 100%%", job.Status, job.Progress, TranscodeDone)
+	}
+}
+
+func TestTranscodeManagerEvictFinished(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-transcodeJobRetention - time.Minute)
+	recent := now.Add(-time.Minute)
+
+	m := NewTranscodeManager(t.TempDir(), 1)
+	m.jobs = map[int64]*TranscodeJob{
+		1: {VideoID: 1, Status: TranscodeDone, FinishedAt: &old},
+		2: {VideoID: 2, Status: TranscodeFailed, FinishedAt: &old},
+		3: {VideoID: 3, Status: TranscodeDone, FinishedAt: &recent},
+		4: {VideoID: 4, Status: TranscodeRunning},
+		5: {VideoID: 5, Status: TranscodeQueued},
+	}
+	m.evictFinished(now)
+
+	var kept []int64
+	for _, id := range []int64{1, 2, 3, 4, 5} {
+		if _, ok := m.jobs[id]; ok {
+			kept = append(kept, id)
+		}
+	}
+	if expected := []int64{3, 4, 5}; !reflect.DeepEqual(kept, expected) {
+		t.Errorf("kept jobs %v, want %v", kept, expected)
+	}
+}
+
+func TestReadProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		duration float64
+		expected []float64
+	}{
+		{
+			name:     "progress blocks",
+			output:   "frame=10\nout_time_us=15000000\nprogress=continue\nframe=20\nout_time_us=30000000\nprogress=continue\n",
+			duration: 60,
+			expected: []float64{25, 50},
+		},
+		{
+			name:     "capped below done",
+			output:   "out_time_us=60000000\nout_time_us=61000000\nprogress=end\n",
+			duration: 60,
+			expected: []float64{99.9, 99.9},
+		},
+		{
+			name:     "negative start",
+			output:   "out_time_us=-23000\n",
+			duration: 60,
+			expected: []float64{0},
+		},
+		{
+			name:     "unknown duration",
+			output:   "out_time_us=15000000\n",
+			duration: 0,
+		},
+		{
+			name:     "not a number",
+			output:   "out_time_us=N/A\nout_time=00:00:15.000000\n",
+			duration: 60,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var reported []float64
+			readProgress(strings.NewReader(tt.output), tt.duration, func(progress float64) {
+				reported = append(reported, progress)
+			})
+			if !reflect.DeepEqual(reported, tt.expected) {
+				t.Errorf("readProgress() reported %v, want %v", reported, tt.expected)
+			}
+		})
+	}
+}